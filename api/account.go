@@ -0,0 +1,49 @@
+package api
+
+import (
+	"delta/core"
+	model "delta/models"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// ConfigureAccountRouter It configures the routes of the account of the caller, its tenant.
+func ConfigureAccountRouter(e *echo.Group, node *core.DeltaNode) {
+	e.GET("/account/usage", func(c echo.Context) error {
+		return handleGetAccountUsage(c, node)
+	})
+}
+
+// handleGetAccountUsage It returns the limits of the tenant of the caller and what it uses of them
+// @Summary It returns the limits of the tenant of the caller and what it uses of them
+// @Description It returns the limits of the tenant of the caller and what it uses of them: the contents being
+// @Description transferred, the bytes of the last 24 hours and the deals of the last hour. A zero limit is unlimited.
+// @Tags Account
+// @Produce  json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /api/v1/account/usage [get]
+func handleGetAccountUsage(c echo.Context, node *core.DeltaNode) error {
+	tenantId := callerTenantId(c)
+
+	var tenant model.Tenant
+	node.DB.Model(&model.Tenant{}).Where("id = ?", tenantId).Find(&tenant)
+
+	usage, err := core.GetTenantUsage(node.DB, tenantId, time.Now())
+	if err != nil {
+		return c.JSON(500, map[string]interface{}{
+			"message": "failed to get the usage of the tenant",
+			"error":   err.Error(),
+		})
+	}
+
+	return c.JSON(200, map[string]interface{}{
+		"tenant": map[string]interface{}{
+			"uuid": tenant.UuId,
+			"name": tenant.Name,
+		},
+		"limits": core.GetTenantLimits(node.DB, node.Config, tenantId),
+		"usage":  usage,
+	})
+}
@@ -8,11 +8,11 @@ import (
 	"encoding/json"
 	"fmt"
 	model "delta/models"
+	"delta/utils"
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
-	"strings"
 	"time"
 )
 
@@ -22,7 +22,12 @@ type AddWalletRequest struct {
 	PrivateKey string `json:"private_key"`
 }
 
+type CreateWalletRequest struct {
+	Tenant string `json:"tenant"` // uuid of the tenant of the wallet, the tenant of the caller if empty
+}
+
 type ImportWalletRequest struct {
+	Tenant     string `json:"tenant"` // uuid of the tenant of the wallet, the tenant of the caller if empty
 	KeyType    string `json:"key_type"`
 	PrivateKey string `json:"private_key"`
 }
@@ -33,9 +38,31 @@ type ImportWalletRequestHex struct {
 }
 
 type ImportWalletWithHexRequest struct {
+	Tenant string `json:"tenant"` // uuid of the tenant of the wallet, the tenant of the caller if empty
 	HexKey string `json:"hex_key"`
 }
 
+type MinerListRequest struct {
+	Miner  string `json:"miner"`
+	Reason string `json:"reason"`
+}
+
+type MapRemoteTokenRequest struct {
+	Token string `json:"token"` // token of estuary-auth
+}
+
+type CreateApiKeyRequest struct {
+	Tenant        string   `json:"tenant"` // uuid of the tenant of the key, a new tenant if empty
+	Label         string   `json:"label"`
+	Role          string   `json:"role"`   // admin, dealmaker or read-only, dealmaker if empty
+	Scopes        []string `json:"scopes"` // all the scopes of the role if empty
+	ExpiresInDays int      `json:"expires_in_days"` // never expires if zero
+}
+
+type CreateTenantRequest struct {
+	Name string `json:"name"`
+}
+
 // ConfigureAdminRouter It creates a new wallet and saves it to the database
 // It configures the admin router
 func ConfigureAdminRouter(e *echo.Group, node *core.DeltaNode) {
@@ -45,6 +72,25 @@ func ConfigureAdminRouter(e *echo.Group, node *core.DeltaNode) {
 	adminWallet.POST("/create", handleAdminCreateWallet(node))
 	adminWallet.GET("/list", handleAdminListWallets(node))
 	adminWallet.GET("/balance/:address", handleAdminGetBalance(node))
+
+	adminJobs := e.Group("/jobs")
+	adminJobs.POST("/cancel/:contentId", handleAdminCancelContentJobs(node))
+
+	adminMiners := e.Group("/miners")
+	adminMiners.GET("/:listType", handleAdminListMiners(node))
+	adminMiners.POST("/:listType", handleAdminAddMiner(node))
+	adminMiners.DELETE("/:listType/:minerId", handleAdminRemoveMiner(node))
+
+	adminKeys := e.Group("/keys")
+	adminKeys.POST("/create", handleAdminCreateApiKey(node))
+	adminKeys.GET("/list", handleAdminListApiKeys(node))
+	adminKeys.POST("/revoke/:uuid", handleAdminRevokeApiKey(node))
+
+	adminTenants := e.Group("/tenants")
+	adminTenants.POST("/create", handleAdminCreateTenant(node))
+	adminTenants.GET("/list", handleAdminListTenants(node))
+	adminTenants.POST("/limits/:uuid", handleAdminSetTenantLimits(node))
+	adminTenants.POST("/remote-token/:uuid", handleAdminMapRemoteToken(node))
 }
 
 // handleAdminRegisterWallet It creates a new wallet and saves it to the database
@@ -60,12 +106,11 @@ func ConfigureAdminRouter(e *echo.Group, node *core.DeltaNode) {
 // @Router /admin/wallet/balance/:address [post]
 func handleAdminGetBalance(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
+		tenantId := callerTenantId(c)
 
 		// check if the address is registered in the database
 		var wallet model.Wallet
-		node.DB.Model(&model.Wallet{}).Where("addr = ? and owner =?", c.Param("address"), authParts[1]).First(&wallet)
+		node.DB.Model(&model.Wallet{}).Where("addr = ? and tenant_id = ?", c.Param("address"), tenantId).First(&wallet)
 
 		if wallet.ID == 0 {
 			return c.JSON(400, map[string]interface{}{
@@ -136,24 +181,30 @@ func handleAdminGetBalance(node *core.DeltaNode) func(c echo.Context) error {
 // @Param address path string true "address"
 // @Param key_type path string true "key_type"
 // @Param private_key path string true "private_key"
+// @Param tenant body string false "uuid of the tenant of the wallet, the tenant of the caller if empty"
 // @Success 200 {object} AddWalletRequest
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /admin/wallet/register [post]
 func handleAdminCreateWallet(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
-
-		if len(authParts) != 2 {
-			return c.JSON(401, map[string]interface{}{
-				"message": "unauthorized",
+		var createWalletRequest CreateWalletRequest
+		if err := c.Bind(&createWalletRequest); err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "invalid request body",
+			})
+		}
+		tenantId, err := walletTenantId(c, node, createWalletRequest.Tenant)
+		if err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "failed to create the wallet",
+				"error":   err.Error(),
 			})
 		}
 
 		var createWalletParam core.CreateWalletParam
 		walletService := core.NewWalletService(node)
-		createWalletParam.RequestingApiKey = authParts[1]
+		createWalletParam.TenantID = tenantId
 		create, err := walletService.Create(createWalletParam)
 		if err != nil {
 			return err
@@ -168,7 +219,7 @@ func handleAdminCreateWallet(node *core.DeltaNode) func(c echo.Context) error {
 		newWallet := &model.Wallet{
 			UuId:       walletUuid.String(),
 			Addr:       create.WalletAddress.String(),
-			Owner:      authParts[1],
+			TenantID:   tenantId,
 			KeyType:    create.Wallet.KeyType,
 			PrivateKey: create.Wallet.PrivateKey,
 			CreatedAt:  time.Now(),
@@ -194,23 +245,23 @@ func handleAdminCreateWallet(node *core.DeltaNode) func(c echo.Context) error {
 // @Param address path string true "address"
 // @Param key_type path string true "key_type"
 // @Param private_key path string true "private_key"
+// @Param tenant body string false "uuid of the tenant of the wallet, the tenant of the caller if empty"
 // @Success 200 {object} AddWalletRequest
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /admin/wallet/register-hex [post]
 func handleAdminRegisterWalletWithHex(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
 		walletService := core.NewWalletService(node)
-
-		if len(authParts) != 2 {
-			return c.JSON(401, map[string]interface{}{
-				"message": "unauthorized",
-			})
-		}
 		var hexedKey ImportWalletWithHexRequest
 		c.Bind(&hexedKey)
+		tenantId, err := walletTenantId(c, node, hexedKey.Tenant)
+		if err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "failed to import wallet",
+				"error":   err.Error(),
+			})
+		}
 
 		hexString, err := hex.DecodeString(hexedKey.HexKey)
 
@@ -229,7 +280,7 @@ func handleAdminRegisterWalletWithHex(node *core.DeltaNode) func(c echo.Context)
 
 		importedWallet, err := walletService.Import(core.ImportWalletParam{
 			WalletParam: core.WalletParam{
-				RequestingApiKey: authParts[1],
+				TenantID: tenantId,
 			},
 			KeyType:    types.KeyType(importWithHexKey.KeyType),
 			PrivateKey: decodedPrivateKey,
@@ -260,21 +311,21 @@ func handleAdminRegisterWalletWithHex(node *core.DeltaNode) func(c echo.Context)
 // @Param address path string true "address"
 // @Param key_type path string true "key_type"
 // @Param private_key path string true "private_key"
+// @Param tenant body string false "uuid of the tenant of the wallet, the tenant of the caller if empty"
 // @Success 200 {object} AddWalletRequest
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /admin/wallet/register [post]
 func handleAdminRegisterWallet(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
 		walletService := core.NewWalletService(node)
 		var importWalletRequest ImportWalletRequest
 		c.Bind(&importWalletRequest)
-
-		if len(authParts) != 2 {
-			return c.JSON(401, map[string]interface{}{
-				"message": "unauthorized",
+		tenantId, err := walletTenantId(c, node, importWalletRequest.Tenant)
+		if err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "failed to import wallet",
+				"error":   err.Error(),
 			})
 		}
 
@@ -293,7 +344,7 @@ func handleAdminRegisterWallet(node *core.DeltaNode) func(c echo.Context) error
 		}
 		importedWallet, err := walletService.Import(core.ImportWalletParam{
 			WalletParam: core.WalletParam{
-				RequestingApiKey: authParts[1],
+				TenantID: tenantId,
 			},
 			KeyType:    types.KeyType(importWalletRequest.KeyType),
 			PrivateKey: encodedPrivateKey,
@@ -323,26 +374,464 @@ func handleAdminRegisterWallet(node *core.DeltaNode) func(c echo.Context) error
 // @Param address path string true "address"
 // @Param key_type path string true "key_type"
 // @Param private_key path string true "private_key"
+// @Param tenant query string false "uuid of the tenant of the wallet, the tenant of the caller if empty"
 // @Success 200 {object} AddWalletRequest
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /admin/wallet/register [post]
 func handleAdminListWallets(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
-
-		if len(authParts) != 2 {
-			return c.JSON(401, map[string]interface{}{
-				"message": "unauthorized",
+		tenantId, err := walletTenantId(c, node, c.QueryParam("tenant"))
+		if err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "failed to list the wallets",
+				"error":   err.Error(),
 			})
 		}
 
 		var wallets []model.Wallet
-		node.DB.Where("owner = ?", authParts[1]).Find(&wallets)
+		node.DB.Where("tenant_id = ?", tenantId).Find(&wallets)
 
 		return c.JSON(200, map[string]interface{}{
 			"wallets": wallets,
 		})
 	}
 }
+
+// walletTenantId returns the id of the tenant the admins create, import or list the wallets of: the tenant of the
+// uuid of the request, or the tenant of the caller if there is none.
+func walletTenantId(c echo.Context, node *core.DeltaNode, tenantUuid string) (int64, error) {
+	if tenantUuid == "" {
+		return callerTenantId(c), nil
+	}
+	tenant, err := core.GetTenant(node.DB, tenantUuid)
+	if err != nil {
+		return 0, err
+	}
+	return tenant.ID, nil
+}
+
+// handleAdminCancelContentJobs It cancels all the queued and running jobs of a content
+// @Summary It cancels all the queued and running jobs of a content
+// @Description It cancels all the queued and running jobs of a content and marks the content as cancelled
+// @Tags Admin
+// @Produce  json
+// @Param contentId path string true "contentId"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /admin/jobs/cancel/:contentId [post]
+func handleAdminCancelContentJobs(node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		// the admins cancel the contents of every tenant
+		scope := callerStatsParam(c)
+
+		var content model.Content
+		node.DB.Raw("select c.* from contents c where c.id = ? and "+core.TenantScope, c.Param("contentId"), scope.AllTenants, scope.TenantID).Scan(&content)
+		if content.ID == 0 {
+			return c.JSON(400, map[string]interface{}{
+				"message": "content not found",
+			})
+		}
+
+		if !core.CanTransitionContentStatus(content.Status, utils.CONTENT_CANCELLED) {
+			return c.JSON(400, map[string]interface{}{
+				"message": "content can't be cancelled in status " + content.Status,
+			})
+		}
+
+		cancelled := node.Dispatcher.CancelContentJobs(content.ID)
+
+		err := core.TransitionContentStatus(node.DB, &content, utils.CONTENT_CANCELLED, "content jobs were cancelled", utils.STATUS_SOURCE_API)
+		if err != nil {
+			return c.JSON(500, map[string]interface{}{
+				"message": "failed to cancel the content",
+				"error":   err.Error(),
+			})
+		}
+
+		return c.JSON(200, map[string]interface{}{
+			"message":        "successfully cancelled the jobs of the content",
+			"content_id":     content.ID,
+			"cancelled_jobs": cancelled,
+		})
+	}
+}
+
+// isValidMinerListType returns true if the list type is either the blocklist or the allowlist
+func isValidMinerListType(listType string) bool {
+	return listType == model.MINER_LIST_BLOCKED || listType == model.MINER_LIST_ALLOWED
+}
+
+// handleAdminListMiners It lists the storage providers on the blocklist or on the allowlist
+// @Summary It lists the storage providers on the blocklist or on the allowlist
+// @Description It lists the storage providers on the blocklist or on the allowlist
+// @Tags Admin
+// @Produce  json
+// @Param listType path string true "blocked or allowed"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /admin/miners/:listType [get]
+func handleAdminListMiners(node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		listType := c.Param("listType")
+		if !isValidMinerListType(listType) {
+			return c.JSON(400, map[string]interface{}{
+				"message": "list type must be either blocked or allowed",
+			})
+		}
+
+		var miners []model.MinerListEntry
+		node.DB.Model(&model.MinerListEntry{}).Where("list_type = ?", listType).Order("id asc").Find(&miners)
+
+		return c.JSON(200, map[string]interface{}{
+			"list_type": listType,
+			"miners":    miners,
+		})
+	}
+}
+
+// handleAdminAddMiner It adds a storage provider to the blocklist or to the allowlist
+// @Summary It adds a storage provider to the blocklist or to the allowlist
+// @Description It adds a storage provider to the blocklist or to the allowlist. Blocked providers and providers
+// @Description missing from a non-empty allowlist are never assigned to a content, including on auto retries.
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param listType path string true "blocked or allowed"
+// @Param body body MinerListRequest true "miner and reason"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/miners/:listType [post]
+func handleAdminAddMiner(node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		listType := c.Param("listType")
+		if !isValidMinerListType(listType) {
+			return c.JSON(400, map[string]interface{}{
+				"message": "list type must be either blocked or allowed",
+			})
+		}
+
+		var minerListRequest MinerListRequest
+		if err := c.Bind(&minerListRequest); err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "invalid request body",
+			})
+		}
+
+		if _, err := address.NewFromString(minerListRequest.Miner); err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "invalid miner address",
+			})
+		}
+
+		var entry model.MinerListEntry
+		node.DB.Model(&model.MinerListEntry{}).Where("miner = ? and list_type = ?", minerListRequest.Miner, listType).Find(&entry)
+		entry.Miner = minerListRequest.Miner
+		entry.ListType = listType
+		entry.Reason = minerListRequest.Reason
+		entry.UpdatedAt = time.Now()
+		if err := node.DB.Save(&entry).Error; err != nil {
+			return c.JSON(500, map[string]interface{}{
+				"message": "failed to save the miner",
+				"error":   err.Error(),
+			})
+		}
+
+		return c.JSON(200, map[string]interface{}{
+			"message": "successfully added the miner to the " + listType + " list",
+			"miner":   entry,
+		})
+	}
+}
+
+// handleAdminRemoveMiner It removes a storage provider from the blocklist or from the allowlist
+// @Summary It removes a storage provider from the blocklist or from the allowlist
+// @Description It removes a storage provider from the blocklist or from the allowlist
+// @Tags Admin
+// @Produce  json
+// @Param listType path string true "blocked or allowed"
+// @Param minerId path string true "minerId"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /admin/miners/:listType/:minerId [delete]
+func handleAdminRemoveMiner(node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		listType := c.Param("listType")
+		if !isValidMinerListType(listType) {
+			return c.JSON(400, map[string]interface{}{
+				"message": "list type must be either blocked or allowed",
+			})
+		}
+
+		result := node.DB.Where("miner = ? and list_type = ?", c.Param("minerId"), listType).Delete(&model.MinerListEntry{})
+		if result.RowsAffected == 0 {
+			return c.JSON(400, map[string]interface{}{
+				"message": "miner is not on the " + listType + " list",
+			})
+		}
+
+		return c.JSON(200, map[string]interface{}{
+			"message": "successfully removed the miner from the " + listType + " list",
+		})
+	}
+}
+
+// handleAdminCreateApiKey It issues a new API key
+// @Summary It issues a new API key
+// @Description It issues a new API key with a label, a role, scopes and an optional expiry. Only the hash of the key
+// @Description is stored, the key is returned once and can't be retrieved afterwards. A key of an existing tenant
+// @Description sees the data of the tenant, so the keys of a tenant can be rotated.
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param body body CreateApiKeyRequest true "tenant, label, role, scopes and expiry"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /admin/keys/create [post]
+func handleAdminCreateApiKey(node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var createApiKeyRequest CreateApiKeyRequest
+		if err := c.Bind(&createApiKeyRequest); err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "invalid request body",
+			})
+		}
+		if createApiKeyRequest.ExpiresInDays < 0 {
+			return c.JSON(400, map[string]interface{}{
+				"message": "expires_in_days cannot be negative",
+			})
+		}
+
+		param := core.CreateApiKeyParam{
+			Label:  createApiKeyRequest.Label,
+			Role:   createApiKeyRequest.Role,
+			Scopes: createApiKeyRequest.Scopes,
+		}
+		if createApiKeyRequest.Tenant != "" {
+			tenant, err := core.GetTenant(node.DB, createApiKeyRequest.Tenant)
+			if err != nil {
+				return c.JSON(400, map[string]interface{}{
+					"message": "failed to create the api key",
+					"error":   err.Error(),
+				})
+			}
+			param.TenantID = tenant.ID
+		}
+		if createApiKeyRequest.ExpiresInDays > 0 {
+			param.ExpiresAt = time.Now().AddDate(0, 0, createApiKeyRequest.ExpiresInDays)
+		}
+		apiKey, key, err := core.CreateApiKey(node.DB, param)
+		if err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "failed to create the api key",
+				"error":   err.Error(),
+			})
+		}
+
+		return c.JSON(200, map[string]interface{}{
+			"message": "successfully created the api key, store it safely, it won't be shown again",
+			"key":     key,
+			"api_key": apiKey,
+		})
+	}
+}
+
+// handleAdminListApiKeys It lists the API keys issued by the node
+// @Summary It lists the API keys issued by the node
+// @Description It lists the API keys issued by the node, the revoked and expired ones included. The keys themselves
+// @Description are never returned.
+// @Tags Admin
+// @Produce  json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/keys/list [get]
+func handleAdminListApiKeys(node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		apiKeys, err := core.ListApiKeys(node.DB)
+		if err != nil {
+			return c.JSON(500, map[string]interface{}{
+				"message": "failed to list the api keys",
+				"error":   err.Error(),
+			})
+		}
+
+		return c.JSON(200, map[string]interface{}{
+			"api_keys": apiKeys,
+		})
+	}
+}
+
+// handleAdminRevokeApiKey It revokes an API key
+// @Summary It revokes an API key
+// @Description It revokes an API key, the requests made with it are rejected from then on
+// @Tags Admin
+// @Produce  json
+// @Param uuid path string true "uuid of the api key"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /admin/keys/revoke/:uuid [post]
+func handleAdminRevokeApiKey(node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		apiKey, err := core.RevokeApiKey(node.DB, c.Param("uuid"))
+		if err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "failed to revoke the api key",
+				"error":   err.Error(),
+			})
+		}
+
+		return c.JSON(200, map[string]interface{}{
+			"message": "successfully revoked the api key",
+			"api_key": apiKey,
+		})
+	}
+}
+
+// handleAdminCreateTenant It creates a tenant
+// @Summary It creates a tenant
+// @Description It creates a tenant, the API keys created for it share its contents, wallets and stats
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param body body CreateTenantRequest true "name"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Router /admin/tenants/create [post]
+func handleAdminCreateTenant(node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var createTenantRequest CreateTenantRequest
+		if err := c.Bind(&createTenantRequest); err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "invalid request body",
+			})
+		}
+		if createTenantRequest.Name == "" {
+			return c.JSON(400, map[string]interface{}{
+				"message": "name is required",
+			})
+		}
+
+		tenant, err := core.CreateTenant(node.DB, createTenantRequest.Name)
+		if err != nil {
+			return c.JSON(500, map[string]interface{}{
+				"message": "failed to create the tenant",
+				"error":   err.Error(),
+			})
+		}
+
+		return c.JSON(200, map[string]interface{}{
+			"message": "successfully created the tenant",
+			"tenant":  tenant,
+		})
+	}
+}
+
+// handleAdminListTenants It lists the tenants of the node
+// @Summary It lists the tenants of the node
+// @Description It lists the tenants of the node, the ones created by the admins and the ones of the callers of the
+// @Description auth backend
+// @Tags Admin
+// @Produce  json
+// @Success 200 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /admin/tenants/list [get]
+func handleAdminListTenants(node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		tenants, err := core.ListTenants(node.DB)
+		if err != nil {
+			return c.JSON(500, map[string]interface{}{
+				"message": "failed to list the tenants",
+				"error":   err.Error(),
+			})
+		}
+
+		return c.JSON(200, map[string]interface{}{
+			"tenants": tenants,
+		})
+	}
+}
+
+// handleAdminSetTenantLimits It sets the limits of a tenant
+// @Summary It sets the limits of a tenant
+// @Description It sets the limits of the deal requests of a tenant, a zero limit is the one of the config
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param uuid path string true "uuid of the tenant"
+// @Param body body core.TenantLimits true "limits"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /admin/tenants/limits/{uuid} [post]
+func handleAdminSetTenantLimits(node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var limits core.TenantLimits
+		if err := c.Bind(&limits); err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "invalid request body",
+			})
+		}
+
+		tenant, err := core.GetTenant(node.DB, c.Param("uuid"))
+		if err != nil {
+			return c.JSON(404, map[string]interface{}{
+				"message": err.Error(),
+			})
+		}
+		if err := core.SetTenantLimits(node.DB, tenant.ID, limits); err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "failed to set the limits of the tenant",
+				"error":   err.Error(),
+			})
+		}
+
+		return c.JSON(200, map[string]interface{}{
+			"message": "successfully set the limits of the tenant",
+			"limits":  core.GetTenantLimits(node.DB, node.Config, tenant.ID),
+		})
+	}
+}
+
+// handleAdminMapRemoteToken It maps a token of estuary-auth onto a tenant
+// @Summary It maps a token of estuary-auth onto a tenant
+// @Description It maps a token of estuary-auth onto an existing tenant, e.g. the new token of a rotated key onto the
+// @Description tenant of the old one, so the tenant keeps its contents and wallets. The tenant of a token is returned by
+// @Description /api/v1/account/usage.
+// @Tags Admin
+// @Accept  json
+// @Produce  json
+// @Param uuid path string true "uuid of the tenant"
+// @Param body body MapRemoteTokenRequest true "token"
+// @Success 200 {object} map[string]interface{}
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Router /admin/tenants/remote-token/{uuid} [post]
+func handleAdminMapRemoteToken(node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var mapRemoteTokenRequest MapRemoteTokenRequest
+		if err := c.Bind(&mapRemoteTokenRequest); err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "invalid request body",
+			})
+		}
+
+		tenant, err := core.GetTenant(node.DB, c.Param("uuid"))
+		if err != nil {
+			return c.JSON(404, map[string]interface{}{
+				"message": err.Error(),
+			})
+		}
+		if err := core.MapRemoteToken(node.DB, node.Authenticator, mapRemoteTokenRequest.Token, tenant.ID); err != nil {
+			return c.JSON(400, map[string]interface{}{
+				"message": "failed to map the token onto the tenant",
+				"error":   err.Error(),
+			})
+		}
+
+		return c.JSON(200, map[string]interface{}{
+			"message": "successfully mapped the token onto the tenant",
+			"tenant":  tenant,
+		})
+	}
+}
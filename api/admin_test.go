@@ -0,0 +1,45 @@
+package api
+
+import (
+	"delta/core"
+	model "delta/models"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestWalletTenantId(t *testing.T) {
+	db, err := model.OpenDatabase("file:" + t.Name() + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	node := &core.DeltaNode{DB: db}
+	tenant, err := core.CreateTenant(db, "dealmaker")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tenantUuid   string
+		wantTenantId int64
+		wantErr      bool
+	}{
+		{"", 42, false}, // the tenant of the caller
+		{tenant.UuId, tenant.ID, false},
+		{"unknown", 0, true},
+	}
+	for _, tt := range tests {
+		c := echo.New().NewContext(httptest.NewRequest(http.MethodPost, "/admin/wallet/create", nil), httptest.NewRecorder())
+		c.Set(ApiKeyContextKey, model.ApiKey{TenantID: 42})
+
+		tenantId, err := walletTenantId(c, node, tt.tenantUuid)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("tenant %q: expected error %v, got %v", tt.tenantUuid, tt.wantErr, err)
+		}
+		if tenantId != tt.wantTenantId {
+			t.Errorf("tenant %q: expected tenant %d, got %d", tt.tenantUuid, tt.wantTenantId, tenantId)
+		}
+	}
+}
@@ -65,12 +65,16 @@ type DealRequest struct {
 	StartEpoch             int64                  `json:"start_epoch,omitempty"`
 	StartEpochInDays       int64                  `json:"start_epoch_in_days,omitempty"`
 	Replication            int                    `json:"replication,omitempty"`
+	ReplicationDiversity   string                 `json:"replication_diversity,omitempty"`
 	RemoveUnsealedCopy     bool                   `json:"remove_unsealed_copy"`
 	SkipIPNIAnnounce       bool                   `json:"skip_ipni_announce"`
 	AutoRetry              bool                   `json:"auto_retry"`
+	AutoRenew              bool                   `json:"auto_renew"`
+	AutoReplace            bool                   `json:"auto_replace"`
 	Label                  string                 `json:"label,omitempty"`
 	DealVerifyState        string                 `json:"deal_verify_state,omitempty"`
 	UnverifiedDealMaxPrice string                 `json:"unverified_deal_max_price,omitempty"`
+	MinerSelectionStrategy string                 `json:"miner_selection_strategy,omitempty"`
 }
 
 // DealResponse Creating a new struct called DealResponse and then returning it.
@@ -80,6 +84,7 @@ type DealResponse struct {
 	ContentId                    int64          `json:"content_id,omitempty"`
 	DealRequest                  interface{}    `json:"deal_request_meta,omitempty"`
 	DealProposalParameterRequest interface{}    `json:"deal_proposal_parameter_request_meta,omitempty"`
+	ReplicationGroup             int64          `json:"replication_group,omitempty"`
 	ReplicatedContents           []DealResponse `json:"replicated_contents,omitempty"`
 }
 
@@ -89,6 +94,30 @@ type DealReplication struct {
 	DealRequest                  DealRequest                         `json:"deal_request"`
 }
 
+// setDealProposalEpochs sets the epochs and the duration of the deals of a request of the given kind. The duration in
+// days of a content already on the node counts from its start epoch, the one of the other deals counts from now.
+// Without days, the deals last the default duration and a content already on the node starts at the start epoch of
+// the request, the other deals start when the deal maker picks.
+func setDealProposalEpochs(dealProposalParam *model.ContentDealProposalParameters, dealRequest DealRequest, kind string) {
+	if dealRequest.StartEpochInDays == 0 || dealRequest.DurationInDays == 0 {
+		dealProposalParam.StartEpoch = 0
+		if kind == DealRequestContent {
+			dealProposalParam.StartEpoch = dealRequest.StartEpoch
+		}
+		dealProposalParam.Duration = utils.DEFAULT_DURATION
+		return
+	}
+
+	durationInDays := dealRequest.DurationInDays
+	if kind != DealRequestContent {
+		durationInDays -= dealRequest.StartEpochInDays
+	}
+	startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
+	dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
+	dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * durationInDays)
+	dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
+}
+
 var statsService *core.StatsService
 
 //var replicationService *core.ReplicationService
@@ -108,6 +137,26 @@ func ConfigureDealRouter(e *echo.Group, node *core.DeltaNode) {
 		return checkMetaFlags(next, node)
 	})
 
+	// idempotency key middleware, the read only requests and the quotes don't make deals
+	dealMake.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return checkIdempotencyKey(next, node, func(c echo.Context) bool {
+			return c.Request().Method == http.MethodGet || strings.HasSuffix(c.Path(), "/deal/quote")
+		})
+	})
+
+	// dispatcher backpressure middleware
+	dealMake.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return checkDispatcherCapacity(next, node)
+	})
+
+	// tenant quota middleware, the read only requests, the quotes and the status of the deals don't make deals
+	dealMake.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return checkTenantQuota(next, node, func(c echo.Context) bool {
+			return c.Request().Method == http.MethodGet || strings.HasSuffix(c.Path(), "/deal/quote") ||
+				strings.Contains(c.Path(), "/deal/status/")
+		})
+	})
+
 	dealPrepare := dealMake.Group("/prepare")
 	dealAnnounce := dealMake.Group("/announce")
 	dealStatus := dealMake.Group("/status")
@@ -132,6 +181,10 @@ func ConfigureDealRouter(e *echo.Group, node *core.DeltaNode) {
 		return handleFetchCidForEndToEndDeal(c, node)
 	})
 
+	dealMake.POST("/quote", func(c echo.Context) error {
+		return handleDealQuote(c, node)
+	})
+
 	dealMake.POST("/import", func(c echo.Context) error {
 		return handleImportDeal(c, node)
 	})
@@ -211,6 +264,23 @@ func checkMetaFlags(next echo.HandlerFunc, node *core.DeltaNode) func(c echo.Con
 	}
 }
 
+// checkDispatcherCapacity is a middleware that rejects new deal requests with 429 when the commp or deal making
+// queues of the dispatcher are full. Read only requests and quotes are always let through.
+func checkDispatcherCapacity(next echo.HandlerFunc, node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		if c.Request().Method == http.MethodGet || strings.HasSuffix(c.Path(), "/deal/quote") {
+			return next(c)
+		}
+		if node.Dispatcher.IsFull(utils.JOB_QUEUE_COMMP) || node.Dispatcher.IsFull(utils.JOB_QUEUE_DEAL_MAKING) {
+			return c.JSON(http.StatusTooManyRequests, DealResponse{
+				Status:  "error",
+				Message: "The node is busy processing other deals, please try again later",
+			})
+		}
+		return next(c)
+	}
+}
+
 // It checks if the sum of the size of all the files that are currently being transferred is greater than the number of
 // CPUs multiplied by the number of bytes per CPU. If it is, then it returns an error
 func checkResourceLimits(next echo.HandlerFunc) func(c echo.Context) error {
@@ -244,6 +314,13 @@ func checkResourceLimits(next echo.HandlerFunc) func(c echo.Context) error {
 	}
 }
 
+// errInvalidDealRequestBody is returned for a deal request whose body can't be parsed.
+var errInvalidDealRequestBody = &HttpError{
+	Code:    http.StatusBadRequest,
+	Reason:  http.StatusText(http.StatusBadRequest),
+	Details: "Error parsing the request, please check the request body if it complies with the spec",
+}
+
 // handleExistingContentsAdd handles the request to add existing content to the network
 // @Summary Add existing content to the network
 // @Description Add existing content to the network
@@ -254,25 +331,24 @@ func handleExistingContentsAdd(c echo.Context, node *core.DeltaNode) error {
 	var dealRequests []DealRequest
 
 	// lets record this.
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	tenantId := callerTenantId(c)
 
 	//	validate the meta
 	err := c.Bind(&dealRequests)
 
 	if err != nil {
-		return errors.New("Error parsing the request, please check the request body if it complies with the spec")
+		return errInvalidDealRequestBody
+	}
+
+	// validated before the transaction, so the error reaches the error handler as it is
+	err = ValidateDealRequests(dealRequests, DealRequestContent, node)
+	if err != nil {
+		return err
 	}
 
 	errTxn := node.DB.Transaction(func(tx *gorm.DB) error {
 		var dealResponses []DealResponse
 		for _, dealRequest := range dealRequests {
-			err = ValidateMeta(dealRequest, node)
-			if err != nil {
-				// return the error from the validation
-				return err
-			}
-
 			decodeCid, err := cid.Decode(dealRequest.Cid)
 			if err != nil {
 				return errors.New("Error decoding the cid")
@@ -323,9 +399,11 @@ func handleExistingContentsAdd(c echo.Context, node *core.DeltaNode) error {
 				Name:              cidName,
 				Size:              int64(cidSize),
 				Cid:               addNode.Cid().String(),
-				RequestingApiKey:  authParts[1],
+				TenantID:          tenantId,
 				PieceCommitmentId: pieceCommp.ID,
 				AutoRetry:         dealRequest.AutoRetry,
+				AutoRenew:         dealRequest.AutoRenew,
+				AutoReplace:       dealRequest.AutoReplace,
 				Status:            utils.CONTENT_PINNED,
 				ConnectionMode:    connMode,
 				CreatedAt:         time.Now(),
@@ -342,7 +420,9 @@ func handleExistingContentsAdd(c echo.Context, node *core.DeltaNode) error {
 					CreatedAt: time.Now(),
 					UpdatedAt: time.Now(),
 				}
-				node.DB.Create(&contentMinerAssignment)
+				if err := node.DB.Create(&contentMinerAssignment).Error; err != nil {
+					return err
+				}
 				dealRequest.Miner = contentMinerAssignment.Miner
 			}
 
@@ -352,11 +432,11 @@ func handleExistingContentsAdd(c echo.Context, node *core.DeltaNode) error {
 				var wallet model.Wallet
 
 				if dealRequest.Wallet.Address != "" {
-					tx.Where("addr = ? and owner = ?", dealRequest.Wallet.Address, authParts[1]).First(&wallet)
+					tx.Where("addr = ? and tenant_id = ?", dealRequest.Wallet.Address, tenantId).First(&wallet)
 				} else if dealRequest.Wallet.Uuid != "" {
-					tx.Where("uuid = ? and owner = ?", dealRequest.Wallet.Uuid, authParts[1]).First(&wallet)
+					tx.Where("uuid = ? and tenant_id = ?", dealRequest.Wallet.Uuid, tenantId).First(&wallet)
 				} else {
-					tx.Where("id = ? and owner = ?", dealRequest.Wallet.Id, authParts[1]).First(&wallet)
+					tx.Where("id = ? and tenant_id = ?", dealRequest.Wallet.Id, tenantId).First(&wallet)
 				}
 
 				if wallet.ID == 0 {
@@ -412,14 +492,10 @@ func handleExistingContentsAdd(c echo.Context, node *core.DeltaNode) error {
 				return false
 			}()
 
-			if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-				startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-				dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-				dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays))
-				dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-			}
+			setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestContent)
 
 			dealProposalParam.RemoveUnsealedCopy = dealRequest.RemoveUnsealedCopy
+			dealProposalParam.MinerSelectionStrategy = dealRequest.MinerSelectionStrategy
 			dealProposalParam.SkipIPNIAnnounce = dealRequest.SkipIPNIAnnounce
 
 			// deal proposal parameters
@@ -446,7 +522,6 @@ func handleExistingContentsAdd(c echo.Context, node *core.DeltaNode) error {
 				DealProposalParameterRequest: dealProposalParam,
 			})
 		}
-		node.Dispatcher.Start(len(dealRequests))
 		return c.JSON(http.StatusOK, dealResponses)
 	})
 	if errTxn != nil {
@@ -467,10 +542,13 @@ func handleExistingContentAdd(c echo.Context, node *core.DeltaNode) error {
 	var dealRequest DealRequest
 
 	// lets record this.
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	tenantId := callerTenantId(c)
 	err := c.Bind(&dealRequest)
-	err = ValidateMeta(dealRequest, node)
+	if err != nil {
+		return errInvalidDealRequestBody
+	}
+
+	err = ValidateDealRequest(&dealRequest, DealRequestContent, node)
 	if err != nil {
 		// return the error from the validation
 		return err
@@ -528,9 +606,11 @@ func handleExistingContentAdd(c echo.Context, node *core.DeltaNode) error {
 			Name:              cidName,
 			Size:              int64(cidSize),
 			Cid:               addNode.Cid().String(),
-			RequestingApiKey:  authParts[1],
+			TenantID:          tenantId,
 			PieceCommitmentId: pieceCommp.ID,
 			AutoRetry:         dealRequest.AutoRetry,
+			AutoRenew:         dealRequest.AutoRenew,
+			AutoReplace:       dealRequest.AutoReplace,
 			Status:            utils.CONTENT_PINNED,
 			ConnectionMode:    connMode,
 			CreatedAt:         time.Now(),
@@ -547,7 +627,9 @@ func handleExistingContentAdd(c echo.Context, node *core.DeltaNode) error {
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			}
-			node.DB.Create(&contentMinerAssignment)
+			if err := node.DB.Create(&contentMinerAssignment).Error; err != nil {
+				return err
+			}
 			dealRequest.Miner = contentMinerAssignment.Miner
 		}
 
@@ -558,11 +640,11 @@ func handleExistingContentAdd(c echo.Context, node *core.DeltaNode) error {
 			var wallet model.Wallet
 
 			if dealRequest.Wallet.Address != "" {
-				node.DB.Where("addr = ? and owner = ?", dealRequest.Wallet.Address, authParts[1]).First(&wallet)
+				node.DB.Where("addr = ? and tenant_id = ?", dealRequest.Wallet.Address, tenantId).First(&wallet)
 			} else if dealRequest.Wallet.Uuid != "" {
-				node.DB.Where("uuid = ? and owner = ?", dealRequest.Wallet.Uuid, authParts[1]).First(&wallet)
+				node.DB.Where("uuid = ? and tenant_id = ?", dealRequest.Wallet.Uuid, tenantId).First(&wallet)
 			} else {
-				node.DB.Where("id = ? and owner = ?", dealRequest.Wallet.Id, authParts[1]).First(&wallet)
+				node.DB.Where("id = ? and tenant_id = ?", dealRequest.Wallet.Id, tenantId).First(&wallet)
 			}
 
 			if wallet.ID == 0 {
@@ -617,17 +699,9 @@ func handleExistingContentAdd(c echo.Context, node *core.DeltaNode) error {
 			return true
 		}()
 
-		// start epoch
-		if dealRequest.StartEpoch != 0 {
-			dealProposalParam.StartEpoch = dealRequest.StartEpoch
-		}
-		if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-			startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-			dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-			dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays))
-			dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-		}
+		setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestContent)
 		dealProposalParam.RemoveUnsealedCopy = dealRequest.RemoveUnsealedCopy
+		dealProposalParam.MinerSelectionStrategy = dealRequest.MinerSelectionStrategy
 		dealProposalParam.SkipIPNIAnnounce = dealRequest.SkipIPNIAnnounce
 
 		// deal proposal parameters
@@ -644,7 +718,7 @@ func handleExistingContentAdd(c echo.Context, node *core.DeltaNode) error {
 			dispatchJobs = jobs.NewPieceCommpProcessor(node, content) // straight to pieceCommp
 		}
 
-		node.Dispatcher.AddJobAndDispatch(dispatchJobs, 1)
+		node.Dispatcher.AddJob(dispatchJobs)
 
 		err = c.JSON(200, DealResponse{
 			Status:                       "success",
@@ -668,8 +742,7 @@ func handleExistingContentAdd(c echo.Context, node *core.DeltaNode) error {
 func handleEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 	var dealRequest DealRequest
 
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	tenantId := callerTenantId(c)
 	file, err := c.FormFile("data") // file
 	if err != nil {
 		return err
@@ -687,22 +760,15 @@ func handleEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 		return err
 	}
 
-	if dealRequest.ConnectionMode == "import" {
-		return errors.New("Connection mode import is not supported for end-to-end deal endpoint")
-	}
-
-	// fail safe
-	dealRequest.ConnectionMode = "e2e"
-
-	err = ValidateMeta(dealRequest, node)
-
-	// validate the file if it's more than 1mb (1mb is baked into lotus)
-	if file.Size < (1<<20) && dealRequest.DealVerifyState == utils.DEAL_VERIFIED {
-		return errors.New("File size is too small")
+	err = ValidateDealRequest(&dealRequest, DealRequestE2E, node)
+	if err != nil {
+		// return the error from the validation
+		return err
 	}
 
+	// lotus rejects the verified deals of small files
+	err = ValidateDealSize(dealRequest, file.Size, node.Config)
 	if err != nil {
-		// return the error from the validation
 		return err
 	}
 
@@ -750,10 +816,12 @@ func handleEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 			Name:              file.Filename,
 			Size:              file.Size,
 			Cid:               addNode.Cid().String(),
-			RequestingApiKey:  authParts[1],
+			TenantID:          tenantId,
 			PieceCommitmentId: pieceCommp.ID,
 			Status:            utils.CONTENT_PINNED,
 			AutoRetry:         dealRequest.AutoRetry,
+			AutoRenew:         dealRequest.AutoRenew,
+			AutoReplace:       dealRequest.AutoReplace,
 			ConnectionMode:    dealRequest.ConnectionMode,
 			CreatedAt:         time.Now(),
 			UpdatedAt:         time.Now(),
@@ -764,7 +832,7 @@ func handleEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 		//	assign a miner
 		if dealRequest.Miner == "" {
 			minerAssignService := core.NewMinerAssignmentService(*node)
-			provider, errOnPv := minerAssignService.GetSPWithGivenBytes(file.Size)
+			provider, errOnPv := minerAssignService.GetSPWithStrategy(dealRequest.MinerSelectionStrategy, core.MinerSelectionParam{Size: file.Size})
 			if errOnPv != nil {
 				return errOnPv
 			}
@@ -777,7 +845,9 @@ func handleEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			}
-			tx.Create(&contentMinerAssignment)
+			if err := tx.Create(&contentMinerAssignment).Error; err != nil {
+				return err
+			}
 			dealRequest.Miner = contentMinerAssignment.Miner
 		}
 
@@ -787,11 +857,11 @@ func handleEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 			var wallet model.Wallet
 
 			if dealRequest.Wallet.Address != "" {
-				tx.Where("addr = ? and owner = ?", dealRequest.Wallet.Address, authParts[1]).First(&wallet)
+				tx.Where("addr = ? and tenant_id = ?", dealRequest.Wallet.Address, tenantId).First(&wallet)
 			} else if dealRequest.Wallet.Uuid != "" {
-				tx.Where("uuid = ? and owner = ?", dealRequest.Wallet.Uuid, authParts[1]).First(&wallet)
+				tx.Where("uuid = ? and tenant_id = ?", dealRequest.Wallet.Uuid, tenantId).First(&wallet)
 			} else {
-				tx.Where("id = ? and owner = ?", dealRequest.Wallet.Id, authParts[1]).First(&wallet)
+				tx.Where("id = ? and tenant_id = ?", dealRequest.Wallet.Id, tenantId).First(&wallet)
 			}
 
 			if wallet.ID == 0 {
@@ -845,17 +915,10 @@ func handleEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 			return true
 		}()
 
-		if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-			startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-			dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-			dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays - dealRequest.StartEpochInDays))
-			dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-		} else {
-			dealProposalParam.StartEpoch = 0
-			dealProposalParam.Duration = utils.DEFAULT_DURATION
-		}
+		setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestE2E)
 
 		dealProposalParam.RemoveUnsealedCopy = dealRequest.RemoveUnsealedCopy
+		dealProposalParam.MinerSelectionStrategy = dealRequest.MinerSelectionStrategy
 		dealProposalParam.SkipIPNIAnnounce = dealRequest.SkipIPNIAnnounce
 
 		dealProposalParam.TransferParams = func() string {
@@ -894,7 +957,7 @@ func handleEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 				dispatchJobs = jobs.NewPieceCommpProcessor(node, content) // straight to pieceCommp
 			}
 
-			node.Dispatcher.AddJobAndDispatch(dispatchJobs, 1)
+			node.Dispatcher.AddJob(dispatchJobs)
 
 			err = c.JSON(200, DealResponse{
 				Status:                       "success",
@@ -912,7 +975,10 @@ func handleEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 			}
 
 			// TODO: Improve this, this is a hack to make sure the replication is done before the deal is made
-			contents := ReplicateContent(node, dealReplication, dealRequest, tx)
+			contents, errOnRep := ReplicateContent(node, dealReplication, dealRequest, tx)
+			if errOnRep != nil {
+				return errOnRep
+			}
 			var dispatchJobs core.IProcessor
 			for _, contentRep := range contents {
 				dispatchJobs = jobs.NewPieceCommpProcessor(node, contentRep.Content) // straight to pieceCommp
@@ -921,13 +987,13 @@ func handleEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 			dispatchJobs = jobs.NewPieceCommpProcessor(node, content) // straight to pieceCommp
 			node.Dispatcher.AddJob(dispatchJobs)
 
-			node.Dispatcher.Start(len(contents) + 1)
 			err = c.JSON(200, DealResponse{
 				Status:                       "success",
 				Message:                      "Deal request received. Please take note of the content_id. You can use the content_id to check the status of the deal.",
 				ContentId:                    content.ID,
 				DealRequest:                  dealRequest,
 				DealProposalParameterRequest: dealProposalParam,
+				ReplicationGroup:             contents[0].Content.ReplicationGroup,
 				ReplicatedContents: func() []DealResponse {
 					var dealResponses []DealResponse
 					for _, contentRep := range contents {
@@ -956,8 +1022,7 @@ func handleEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 func handlePullFileFromUrlForEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 	var dealRequest DealRequest
 
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	tenantId := callerTenantId(c)
 	edgeUrlSource := c.FormValue("url")
 	cidToPull := c.FormValue("cid")
 	meta := c.FormValue("metadata")
@@ -998,22 +1063,15 @@ func handlePullFileFromUrlForEndToEndDeal(c echo.Context, node *core.DeltaNode)
 		return err
 	}
 
-	if dealRequest.ConnectionMode == "import" {
-		return errors.New("Connection mode import is not supported for end-to-end deal endpoint")
-	}
-
-	// fail safe
-	dealRequest.ConnectionMode = "e2e"
-
-	err = ValidateMeta(dealRequest, node)
-
-	// validate the file if it's more than 1mb
-	if fileSize < 1000000 && dealRequest.DealVerifyState == utils.DEAL_VERIFIED {
-		return errors.New("File size is too small")
+	err = ValidateDealRequest(&dealRequest, DealRequestE2E, node)
+	if err != nil {
+		// return the error from the validation
+		return err
 	}
 
+	// lotus rejects the verified deals of small files
+	err = ValidateDealSize(dealRequest, fileSize, node.Config)
 	if err != nil {
-		// return the error from the validation
 		return err
 	}
 
@@ -1053,10 +1111,12 @@ func handlePullFileFromUrlForEndToEndDeal(c echo.Context, node *core.DeltaNode)
 			Name:              addNode.Cid().String(),
 			Size:              fileSize,
 			Cid:               addNode.Cid().String(),
-			RequestingApiKey:  authParts[1],
+			TenantID:          tenantId,
 			PieceCommitmentId: pieceCommp.ID,
 			Status:            utils.CONTENT_PINNED,
 			AutoRetry:         dealRequest.AutoRetry,
+			AutoRenew:         dealRequest.AutoRenew,
+			AutoReplace:       dealRequest.AutoReplace,
 			ConnectionMode:    dealRequest.ConnectionMode,
 			CreatedAt:         time.Now(),
 			UpdatedAt:         time.Now(),
@@ -1067,7 +1127,7 @@ func handlePullFileFromUrlForEndToEndDeal(c echo.Context, node *core.DeltaNode)
 		//	assign a miner
 		if dealRequest.Miner == "" {
 			minerAssignService := core.NewMinerAssignmentService(*node)
-			provider, errOnPv := minerAssignService.GetSPWithGivenBytes(fileSize)
+			provider, errOnPv := minerAssignService.GetSPWithStrategy(dealRequest.MinerSelectionStrategy, core.MinerSelectionParam{Size: fileSize})
 			if errOnPv != nil {
 				return errOnPv
 			}
@@ -1080,7 +1140,9 @@ func handlePullFileFromUrlForEndToEndDeal(c echo.Context, node *core.DeltaNode)
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			}
-			tx.Create(&contentMinerAssignment)
+			if err := tx.Create(&contentMinerAssignment).Error; err != nil {
+				return err
+			}
 			dealRequest.Miner = contentMinerAssignment.Miner
 		}
 
@@ -1090,11 +1152,11 @@ func handlePullFileFromUrlForEndToEndDeal(c echo.Context, node *core.DeltaNode)
 			var wallet model.Wallet
 
 			if dealRequest.Wallet.Address != "" {
-				tx.Where("addr = ? and owner = ?", dealRequest.Wallet.Address, authParts[1]).First(&wallet)
+				tx.Where("addr = ? and tenant_id = ?", dealRequest.Wallet.Address, tenantId).First(&wallet)
 			} else if dealRequest.Wallet.Uuid != "" {
-				tx.Where("uuid = ? and owner = ?", dealRequest.Wallet.Uuid, authParts[1]).First(&wallet)
+				tx.Where("uuid = ? and tenant_id = ?", dealRequest.Wallet.Uuid, tenantId).First(&wallet)
 			} else {
-				tx.Where("id = ? and owner = ?", dealRequest.Wallet.Id, authParts[1]).First(&wallet)
+				tx.Where("id = ? and tenant_id = ?", dealRequest.Wallet.Id, tenantId).First(&wallet)
 			}
 
 			if wallet.ID == 0 {
@@ -1148,17 +1210,10 @@ func handlePullFileFromUrlForEndToEndDeal(c echo.Context, node *core.DeltaNode)
 			return true
 		}()
 
-		if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-			startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-			dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-			dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays - dealRequest.StartEpochInDays))
-			dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-		} else {
-			dealProposalParam.StartEpoch = 0
-			dealProposalParam.Duration = utils.DEFAULT_DURATION
-		}
+		setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestE2E)
 
 		dealProposalParam.RemoveUnsealedCopy = dealRequest.RemoveUnsealedCopy
+		dealProposalParam.MinerSelectionStrategy = dealRequest.MinerSelectionStrategy
 		dealProposalParam.SkipIPNIAnnounce = dealRequest.SkipIPNIAnnounce
 
 		dealProposalParam.TransferParams = func() string {
@@ -1198,7 +1253,7 @@ func handlePullFileFromUrlForEndToEndDeal(c echo.Context, node *core.DeltaNode)
 				dispatchJobs = jobs.NewPieceCommpProcessor(node, content) // straight to pieceCommp
 			}
 
-			node.Dispatcher.AddJobAndDispatch(dispatchJobs, 1)
+			node.Dispatcher.AddJob(dispatchJobs)
 
 			err = c.JSON(200, DealResponse{
 				Status:                       "success",
@@ -1216,7 +1271,10 @@ func handlePullFileFromUrlForEndToEndDeal(c echo.Context, node *core.DeltaNode)
 			}
 
 			// TODO: Improve this, this is a hack to make sure the replication is done before the deal is made
-			contents := ReplicateContent(node, dealReplication, dealRequest, tx)
+			contents, errOnRep := ReplicateContent(node, dealReplication, dealRequest, tx)
+			if errOnRep != nil {
+				return errOnRep
+			}
 			var dispatchJobs core.IProcessor
 			for _, contentRep := range contents {
 				dispatchJobs = jobs.NewPieceCommpProcessor(node, contentRep.Content) // straight to pieceCommp
@@ -1225,13 +1283,13 @@ func handlePullFileFromUrlForEndToEndDeal(c echo.Context, node *core.DeltaNode)
 			dispatchJobs = jobs.NewPieceCommpProcessor(node, content) // straight to pieceCommp
 			node.Dispatcher.AddJob(dispatchJobs)
 
-			node.Dispatcher.Start(len(contents) + 1)
 			err = c.JSON(200, DealResponse{
 				Status:                       "success",
 				Message:                      "Deal request received. Please take note of the content_id. You can use the content_id to check the status of the deal.",
 				ContentId:                    content.ID,
 				DealRequest:                  dealRequest,
 				DealProposalParameterRequest: dealProposalParam,
+				ReplicationGroup:             contents[0].Content.ReplicationGroup,
 				ReplicatedContents: func() []DealResponse {
 					var dealResponses []DealResponse
 					for _, contentRep := range contents {
@@ -1259,8 +1317,7 @@ func handlePullFileFromUrlForEndToEndDeal(c echo.Context, node *core.DeltaNode)
 func handleFetchCidForEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 	var dealRequest DealRequest
 
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	tenantId := callerTenantId(c)
 	cidToPick := c.FormValue("cid")              // file
 	sourceToPickFile := c.FormValue("multiaddr") // multiaddr
 
@@ -1287,22 +1344,15 @@ func handleFetchCidForEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 		return err
 	}
 
-	if dealRequest.ConnectionMode == "import" {
-		return errors.New("Connection mode import is not supported for end-to-end deal endpoint")
-	}
-
-	// fail safe
-	dealRequest.ConnectionMode = "e2e"
-
-	err = ValidateMeta(dealRequest, node)
-
-	// validate the file if it's more than 1mb
-	if fileSize < 1000000 && dealRequest.DealVerifyState == utils.DEAL_VERIFIED {
-		return errors.New("File size is too small")
+	err = ValidateDealRequest(&dealRequest, DealRequestE2E, node)
+	if err != nil {
+		// return the error from the validation
+		return err
 	}
 
+	// lotus rejects the verified deals of small files
+	err = ValidateDealSize(dealRequest, fileSize, node.Config)
 	if err != nil {
-		// return the error from the validation
 		return err
 	}
 
@@ -1342,10 +1392,12 @@ func handleFetchCidForEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 			Name:              cidToPickCid.String(),
 			Size:              fileSize,
 			Cid:               cidToPickCid.String(),
-			RequestingApiKey:  authParts[1],
+			TenantID:          tenantId,
 			PieceCommitmentId: pieceCommp.ID,
 			Status:            utils.CONTENT_PINNED,
 			AutoRetry:         dealRequest.AutoRetry,
+			AutoRenew:         dealRequest.AutoRenew,
+			AutoReplace:       dealRequest.AutoReplace,
 			ConnectionMode:    dealRequest.ConnectionMode,
 			CreatedAt:         time.Now(),
 			UpdatedAt:         time.Now(),
@@ -1356,7 +1408,7 @@ func handleFetchCidForEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 		//	assign a miner
 		if dealRequest.Miner == "" {
 			minerAssignService := core.NewMinerAssignmentService(*node)
-			provider, errOnPv := minerAssignService.GetSPWithGivenBytes(fileSize)
+			provider, errOnPv := minerAssignService.GetSPWithStrategy(dealRequest.MinerSelectionStrategy, core.MinerSelectionParam{Size: fileSize})
 			if errOnPv != nil {
 				return errOnPv
 			}
@@ -1369,7 +1421,9 @@ func handleFetchCidForEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			}
-			tx.Create(&contentMinerAssignment)
+			if err := tx.Create(&contentMinerAssignment).Error; err != nil {
+				return err
+			}
 			dealRequest.Miner = contentMinerAssignment.Miner
 		}
 
@@ -1379,11 +1433,11 @@ func handleFetchCidForEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 			var wallet model.Wallet
 
 			if dealRequest.Wallet.Address != "" {
-				tx.Where("addr = ? and owner = ?", dealRequest.Wallet.Address, authParts[1]).First(&wallet)
+				tx.Where("addr = ? and tenant_id = ?", dealRequest.Wallet.Address, tenantId).First(&wallet)
 			} else if dealRequest.Wallet.Uuid != "" {
-				tx.Where("uuid = ? and owner = ?", dealRequest.Wallet.Uuid, authParts[1]).First(&wallet)
+				tx.Where("uuid = ? and tenant_id = ?", dealRequest.Wallet.Uuid, tenantId).First(&wallet)
 			} else {
-				tx.Where("id = ? and owner = ?", dealRequest.Wallet.Id, authParts[1]).First(&wallet)
+				tx.Where("id = ? and tenant_id = ?", dealRequest.Wallet.Id, tenantId).First(&wallet)
 			}
 
 			if wallet.ID == 0 {
@@ -1437,17 +1491,10 @@ func handleFetchCidForEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 			return true
 		}()
 
-		if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-			startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-			dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-			dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays - dealRequest.StartEpochInDays))
-			dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-		} else {
-			dealProposalParam.StartEpoch = 0
-			dealProposalParam.Duration = utils.DEFAULT_DURATION
-		}
+		setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestE2E)
 
 		dealProposalParam.RemoveUnsealedCopy = dealRequest.RemoveUnsealedCopy
+		dealProposalParam.MinerSelectionStrategy = dealRequest.MinerSelectionStrategy
 		dealProposalParam.SkipIPNIAnnounce = dealRequest.SkipIPNIAnnounce
 
 		dealProposalParam.TransferParams = func() string {
@@ -1487,7 +1534,7 @@ func handleFetchCidForEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 				dispatchJobs = jobs.NewPieceCommpProcessor(node, content) // straight to pieceCommp
 			}
 
-			node.Dispatcher.AddJobAndDispatch(dispatchJobs, 1)
+			node.Dispatcher.AddJob(dispatchJobs)
 
 			err = c.JSON(200, DealResponse{
 				Status:                       "success",
@@ -1505,7 +1552,10 @@ func handleFetchCidForEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 			}
 
 			// TODO: Improve this, this is a hack to make sure the replication is done before the deal is made
-			contents := ReplicateContent(node, dealReplication, dealRequest, tx)
+			contents, errOnRep := ReplicateContent(node, dealReplication, dealRequest, tx)
+			if errOnRep != nil {
+				return errOnRep
+			}
 			var dispatchJobs core.IProcessor
 			for _, contentRep := range contents {
 				dispatchJobs = jobs.NewPieceCommpProcessor(node, contentRep.Content) // straight to pieceCommp
@@ -1514,13 +1564,13 @@ func handleFetchCidForEndToEndDeal(c echo.Context, node *core.DeltaNode) error {
 			dispatchJobs = jobs.NewPieceCommpProcessor(node, content) // straight to pieceCommp
 			node.Dispatcher.AddJob(dispatchJobs)
 
-			node.Dispatcher.Start(len(contents) + 1)
 			err = c.JSON(200, DealResponse{
 				Status:                       "success",
 				Message:                      "Deal request received. Please take note of the content_id. You can use the content_id to check the status of the deal.",
 				ContentId:                    content.ID,
 				DealRequest:                  dealRequest,
 				DealProposalParameterRequest: dealProposalParam,
+				ReplicationGroup:             contents[0].Content.ReplicationGroup,
 				ReplicatedContents: func() []DealResponse {
 					var dealResponses []DealResponse
 					for _, contentRep := range contents {
@@ -1556,26 +1606,14 @@ func handleImportDeal(c echo.Context, node *core.DeltaNode) error {
 	var dealRequest DealRequest
 
 	// lets record this.
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	tenantId := callerTenantId(c)
 	err := c.Bind(&dealRequest)
 
 	if err != nil {
 		return errors.New("Error parsing the request, please check the request body if it complies with the spec")
 	}
 
-	if dealRequest.ConnectionMode == "e2e" {
-		return errors.New("Connection mode e2e is not supported on this import endpoint")
-	}
-
-	dealRequest.ConnectionMode = "import"
-	err = ValidateMeta(dealRequest, node)
-
-	if err != nil {
-		return err
-	}
-
-	err = ValidatePieceCommitmentMeta(dealRequest.PieceCommitment, node)
+	err = ValidateDealRequest(&dealRequest, DealRequestImport, node)
 	if err != nil {
 		return err
 	}
@@ -1612,9 +1650,11 @@ func handleImportDeal(c echo.Context, node *core.DeltaNode) error {
 			Name:              dealRequest.Cid,
 			Size:              dealRequest.Size,
 			Cid:               dealRequest.Cid,
-			RequestingApiKey:  authParts[1],
+			TenantID:          tenantId,
 			PieceCommitmentId: pieceCommp.ID,
 			AutoRetry:         dealRequest.AutoRetry,
+			AutoRenew:         dealRequest.AutoRenew,
+			AutoReplace:       dealRequest.AutoReplace,
 			Status:            utils.CONTENT_DEAL_MAKING_PROPOSAL,
 			ConnectionMode:    dealRequest.ConnectionMode,
 			CreatedAt:         time.Now(),
@@ -1626,7 +1666,7 @@ func handleImportDeal(c echo.Context, node *core.DeltaNode) error {
 		//	assign a miner
 		if dealRequest.Miner == "" {
 			minerAssignService := core.NewMinerAssignmentService(*node)
-			provider, errOnPv := minerAssignService.GetSPWithGivenBytes(dealRequest.Size)
+			provider, errOnPv := minerAssignService.GetSPWithStrategy(dealRequest.MinerSelectionStrategy, core.MinerSelectionParam{Size: dealRequest.Size})
 			if errOnPv != nil {
 				return errOnPv
 			}
@@ -1639,7 +1679,9 @@ func handleImportDeal(c echo.Context, node *core.DeltaNode) error {
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			}
-			tx.Create(&contentMinerAssignment)
+			if err := tx.Create(&contentMinerAssignment).Error; err != nil {
+				return err
+			}
 			dealRequest.Miner = contentMinerAssignment.Miner
 		}
 
@@ -1648,11 +1690,11 @@ func handleImportDeal(c echo.Context, node *core.DeltaNode) error {
 			// get wallet from wallets database
 			var wallet model.Wallet
 			if dealRequest.Wallet.Address != "" {
-				tx.Where("addr = ? and owner = ?", dealRequest.Wallet.Address, authParts[1]).First(&wallet)
+				tx.Where("addr = ? and tenant_id = ?", dealRequest.Wallet.Address, tenantId).First(&wallet)
 			} else if dealRequest.Wallet.Uuid != "" {
-				tx.Where("uuid = ? and owner = ?", dealRequest.Wallet.Uuid, authParts[1]).First(&wallet)
+				tx.Where("uuid = ? and tenant_id = ?", dealRequest.Wallet.Uuid, tenantId).First(&wallet)
 			} else {
-				tx.Where("id = ? and owner = ?", dealRequest.Wallet.Id, authParts[1]).First(&wallet)
+				tx.Where("id = ? and tenant_id = ?", dealRequest.Wallet.Id, tenantId).First(&wallet)
 			}
 
 			if wallet.ID == 0 {
@@ -1746,16 +1788,9 @@ func handleImportDeal(c echo.Context, node *core.DeltaNode) error {
 
 		}()
 
-		if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-			startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-			dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-			dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays - dealRequest.StartEpochInDays))
-			dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-		} else {
-			dealProposalParam.StartEpoch = 0
-			dealProposalParam.Duration = utils.DEFAULT_DURATION
-		}
+		setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestImport)
 		dealProposalParam.RemoveUnsealedCopy = dealRequest.RemoveUnsealedCopy
+		dealProposalParam.MinerSelectionStrategy = dealRequest.MinerSelectionStrategy
 		dealProposalParam.SkipIPNIAnnounce = dealRequest.SkipIPNIAnnounce
 
 		// deal proposal parameters
@@ -1772,7 +1807,7 @@ func handleImportDeal(c echo.Context, node *core.DeltaNode) error {
 			dispatchJobs = jobs.NewPieceCommpProcessor(node, content) // straight to pieceCommp
 		}
 
-		node.Dispatcher.AddJobAndDispatch(dispatchJobs, 1)
+		node.Dispatcher.AddJob(dispatchJobs)
 
 		err = c.JSON(200, DealResponse{
 			Status:                       "success",
@@ -1798,26 +1833,14 @@ func handleOnlineRemoteUrlDeal(c echo.Context, node *core.DeltaNode) error {
 	var dealRequest DealRequest
 
 	// lets record this.
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	tenantId := callerTenantId(c)
 	err := c.Bind(&dealRequest)
 
 	if err != nil {
 		return errors.New("Error parsing the request, please check the request body if it complies with the spec")
 	}
 
-	if dealRequest.ConnectionMode == "import" {
-		return errors.New("Connection mode e2e is not supported on this import endpoint")
-	}
-
-	dealRequest.ConnectionMode = "e2e"
-	err = ValidateMeta(dealRequest, node)
-
-	if err != nil {
-		return err
-	}
-
-	err = ValidatePieceCommitmentMeta(dealRequest.PieceCommitment, node)
+	err = ValidateDealRequest(&dealRequest, DealRequestRemote, node)
 	if err != nil {
 		return err
 	}
@@ -1854,9 +1877,11 @@ func handleOnlineRemoteUrlDeal(c echo.Context, node *core.DeltaNode) error {
 			Name:              dealRequest.Cid,
 			Size:              dealRequest.Size,
 			Cid:               dealRequest.Cid,
-			RequestingApiKey:  authParts[1],
+			TenantID:          tenantId,
 			PieceCommitmentId: pieceCommp.ID,
 			AutoRetry:         dealRequest.AutoRetry,
+			AutoRenew:         dealRequest.AutoRenew,
+			AutoReplace:       dealRequest.AutoReplace,
 			Status:            utils.CONTENT_DEAL_MAKING_PROPOSAL,
 			ConnectionMode:    dealRequest.ConnectionMode,
 			CreatedAt:         time.Now(),
@@ -1868,7 +1893,7 @@ func handleOnlineRemoteUrlDeal(c echo.Context, node *core.DeltaNode) error {
 		//	assign a miner
 		if dealRequest.Miner == "" {
 			minerAssignService := core.NewMinerAssignmentService(*node)
-			provider, errOnPv := minerAssignService.GetSPWithGivenBytes(dealRequest.Size)
+			provider, errOnPv := minerAssignService.GetSPWithStrategy(dealRequest.MinerSelectionStrategy, core.MinerSelectionParam{Size: dealRequest.Size})
 			if errOnPv != nil {
 				return errOnPv
 			}
@@ -1881,7 +1906,9 @@ func handleOnlineRemoteUrlDeal(c echo.Context, node *core.DeltaNode) error {
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			}
-			tx.Create(&contentMinerAssignment)
+			if err := tx.Create(&contentMinerAssignment).Error; err != nil {
+				return err
+			}
 			dealRequest.Miner = contentMinerAssignment.Miner
 		}
 
@@ -1890,11 +1917,11 @@ func handleOnlineRemoteUrlDeal(c echo.Context, node *core.DeltaNode) error {
 			// get wallet from wallets database
 			var wallet model.Wallet
 			if dealRequest.Wallet.Address != "" {
-				tx.Where("addr = ? and owner = ?", dealRequest.Wallet.Address, authParts[1]).First(&wallet)
+				tx.Where("addr = ? and tenant_id = ?", dealRequest.Wallet.Address, tenantId).First(&wallet)
 			} else if dealRequest.Wallet.Uuid != "" {
-				tx.Where("uuid = ? and owner = ?", dealRequest.Wallet.Uuid, authParts[1]).First(&wallet)
+				tx.Where("uuid = ? and tenant_id = ?", dealRequest.Wallet.Uuid, tenantId).First(&wallet)
 			} else {
-				tx.Where("id = ? and owner = ?", dealRequest.Wallet.Id, authParts[1]).First(&wallet)
+				tx.Where("id = ? and tenant_id = ?", dealRequest.Wallet.Id, tenantId).First(&wallet)
 			}
 
 			if wallet.ID == 0 {
@@ -1988,16 +2015,9 @@ func handleOnlineRemoteUrlDeal(c echo.Context, node *core.DeltaNode) error {
 
 		}()
 
-		if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-			startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-			dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-			dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays - dealRequest.StartEpochInDays))
-			dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-		} else {
-			dealProposalParam.StartEpoch = 0
-			dealProposalParam.Duration = utils.DEFAULT_DURATION
-		}
+		setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestRemote)
 		dealProposalParam.RemoveUnsealedCopy = dealRequest.RemoveUnsealedCopy
+		dealProposalParam.MinerSelectionStrategy = dealRequest.MinerSelectionStrategy
 		dealProposalParam.SkipIPNIAnnounce = dealRequest.SkipIPNIAnnounce
 
 		// deal proposal parameters
@@ -2014,7 +2034,7 @@ func handleOnlineRemoteUrlDeal(c echo.Context, node *core.DeltaNode) error {
 			dispatchJobs = jobs.NewPieceCommpProcessor(node, content) // straight to pieceCommp
 		}
 
-		node.Dispatcher.AddJobAndDispatch(dispatchJobs, 1)
+		node.Dispatcher.AddJob(dispatchJobs)
 
 		err = c.JSON(200, DealResponse{
 			Status:                       "success",
@@ -2039,8 +2059,7 @@ func handleMultipleOnlineImportDeals(c echo.Context, node *core.DeltaNode) error
 	var dealRequests []DealRequest
 
 	// lets record this.
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	tenantId := callerTenantId(c)
 
 	//	validate the meta
 	err := c.Bind(&dealRequests)
@@ -2048,25 +2067,14 @@ func handleMultipleOnlineImportDeals(c echo.Context, node *core.DeltaNode) error
 		return errors.New("Error parsing the request, please check the request body if it complies with the spec")
 	}
 
+	err = ValidateDealRequests(dealRequests, DealRequestRemote, node)
+	if err != nil {
+		return err
+	}
+
 	errTxn := node.DB.Transaction(func(tx *gorm.DB) error {
 		var dealResponses []DealResponse
 		for _, dealRequest := range dealRequests {
-			if dealRequest.ConnectionMode == "import" {
-				return errors.New("Connection mode import is not supported on this online endpoint")
-			}
-			dealRequest.ConnectionMode = "e2e"
-			err = ValidateMeta(dealRequest, node)
-			if err != nil {
-				tx.Rollback()
-				return err
-			}
-
-			err = ValidatePieceCommitmentMeta(dealRequest.PieceCommitment, node)
-			if err != nil {
-				tx.Rollback()
-				return err
-			}
-
 			// let's create a commp but only if we have
 			// a cid, a piece_cid, a padded_piece_size, size
 			var pieceCommp model.PieceCommitment
@@ -2097,9 +2105,11 @@ func handleMultipleOnlineImportDeals(c echo.Context, node *core.DeltaNode) error
 				Name:              dealRequest.Cid,
 				Size:              dealRequest.Size,
 				Cid:               dealRequest.Cid,
-				RequestingApiKey:  authParts[1],
+				TenantID:          tenantId,
 				PieceCommitmentId: pieceCommp.ID,
 				AutoRetry:         dealRequest.AutoRetry,
+				AutoRenew:         dealRequest.AutoRenew,
+				AutoReplace:       dealRequest.AutoReplace,
 				Status:            utils.CONTENT_DEAL_MAKING_PROPOSAL,
 				ConnectionMode:    dealRequest.ConnectionMode,
 				CreatedAt:         time.Now(),
@@ -2111,7 +2121,7 @@ func handleMultipleOnlineImportDeals(c echo.Context, node *core.DeltaNode) error
 			//	assign a miner
 			if dealRequest.Miner == "" {
 				minerAssignService := core.NewMinerAssignmentService(*node)
-				provider, errOnPv := minerAssignService.GetSPWithGivenBytes(dealRequest.Size)
+				provider, errOnPv := minerAssignService.GetSPWithStrategy(dealRequest.MinerSelectionStrategy, core.MinerSelectionParam{Size: dealRequest.Size})
 				if errOnPv != nil {
 					return errOnPv
 				}
@@ -2124,7 +2134,9 @@ func handleMultipleOnlineImportDeals(c echo.Context, node *core.DeltaNode) error
 					CreatedAt: time.Now(),
 					UpdatedAt: time.Now(),
 				}
-				tx.Create(&contentMinerAssignment)
+				if err := tx.Create(&contentMinerAssignment).Error; err != nil {
+					return err
+				}
 				dealRequest.Miner = contentMinerAssignment.Miner
 			}
 
@@ -2134,11 +2146,11 @@ func handleMultipleOnlineImportDeals(c echo.Context, node *core.DeltaNode) error
 				// get wallet from wallets database
 				var wallet model.Wallet
 				if dealRequest.Wallet.Address != "" {
-					tx.Where("addr = ? and owner = ?", dealRequest.Wallet.Address, authParts[1]).First(&wallet)
+					tx.Where("addr = ? and tenant_id = ?", dealRequest.Wallet.Address, tenantId).First(&wallet)
 				} else if dealRequest.Wallet.Uuid != "" {
-					tx.Where("uu_id = ? and owner = ?", dealRequest.Wallet.Uuid, authParts[1]).First(&wallet)
+					tx.Where("uu_id = ? and tenant_id = ?", dealRequest.Wallet.Uuid, tenantId).First(&wallet)
 				} else {
-					tx.Where("id = ? and owner = ?", dealRequest.Wallet.Id, authParts[1]).First(&wallet)
+					tx.Where("id = ? and tenant_id = ?", dealRequest.Wallet.Id, tenantId).First(&wallet)
 				}
 
 				if wallet.ID == 0 {
@@ -2221,17 +2233,10 @@ func handleMultipleOnlineImportDeals(c echo.Context, node *core.DeltaNode) error
 				return string(stringTP)
 
 			}()
-			if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-				startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-				dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-				dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays - dealRequest.StartEpochInDays))
-				dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-			} else {
-				dealProposalParam.StartEpoch = 0
-				dealProposalParam.Duration = utils.DEFAULT_DURATION
-			}
+			setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestRemote)
 
 			dealProposalParam.RemoveUnsealedCopy = dealRequest.RemoveUnsealedCopy
+			dealProposalParam.MinerSelectionStrategy = dealRequest.MinerSelectionStrategy
 			dealProposalParam.SkipIPNIAnnounce = dealRequest.SkipIPNIAnnounce
 
 			// deal proposal parameters
@@ -2253,7 +2258,6 @@ func handleMultipleOnlineImportDeals(c echo.Context, node *core.DeltaNode) error
 			})
 
 		}
-		go node.Dispatcher.Start(len(dealRequests))
 		err = c.JSON(http.StatusOK, dealResponses)
 		if err != nil {
 			tx.Rollback()
@@ -2271,8 +2275,7 @@ func handleMultipleBatchImportDeals(c echo.Context, node *core.DeltaNode) error
 	var dealRequests []DealRequest
 
 	// lets record this.
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	tenantId := callerTenantId(c)
 
 	//	validate the meta
 	err := c.Bind(&dealRequests)
@@ -2280,11 +2283,17 @@ func handleMultipleBatchImportDeals(c echo.Context, node *core.DeltaNode) error
 		return errors.New("Error parsing the request, please check the request body if it complies with the spec")
 	}
 
+	err = ValidateDealRequests(dealRequests, DealRequestImport, node)
+	if err != nil {
+		return err
+	}
+
 	// create a batch import object
 	batchImportUuid := uuid.New().String()
 	batchImport := model.BatchImport{
 		Uuid:      batchImportUuid,
 		Status:    utils.BATCH_IMPORT_STATUS_STARTED,
+		TenantID:  tenantId,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}
@@ -2302,22 +2311,6 @@ func handleMultipleBatchImportDeals(c echo.Context, node *core.DeltaNode) error
 		//errTxn := node.DB.Transaction(func(tx *gorm.DB) error {
 		var dealResponses []DealResponse
 		for _, dealRequest := range dealRequests {
-			if dealRequest.ConnectionMode == "e2e" {
-				return errors.New("Connection mode e2e is not supported on this import endpoint")
-			}
-			dealRequest.ConnectionMode = "import"
-			err = ValidateMeta(dealRequest, node)
-			if err != nil {
-				fmt.Println("Error validating the meta", err)
-				return err
-			}
-
-			err = ValidatePieceCommitmentMeta(dealRequest.PieceCommitment, node)
-			if err != nil {
-				fmt.Println("Error validating the piece commitment meta", err)
-				return err
-			}
-
 			// let's create a commp but only if we have
 			// a cid, a piece_cid, a padded_piece_size, size
 			var pieceCommp model.PieceCommitment
@@ -2348,9 +2341,11 @@ func handleMultipleBatchImportDeals(c echo.Context, node *core.DeltaNode) error
 				Name:              dealRequest.Cid,
 				Size:              dealRequest.Size,
 				Cid:               dealRequest.Cid,
-				RequestingApiKey:  authParts[1],
+				TenantID:          tenantId,
 				PieceCommitmentId: pieceCommp.ID,
 				AutoRetry:         dealRequest.AutoRetry,
+				AutoRenew:         dealRequest.AutoRenew,
+				AutoReplace:       dealRequest.AutoReplace,
 				Status:            utils.CONTENT_DEAL_MAKING_PROPOSAL,
 				ConnectionMode:    dealRequest.ConnectionMode,
 				CreatedAt:         time.Now(),
@@ -2372,7 +2367,7 @@ func handleMultipleBatchImportDeals(c echo.Context, node *core.DeltaNode) error
 			//	assign a miner
 			if dealRequest.Miner == "" {
 				minerAssignService := core.NewMinerAssignmentService(*node)
-				provider, errOnPv := minerAssignService.GetSPWithGivenBytes(dealRequest.Size)
+				provider, errOnPv := minerAssignService.GetSPWithStrategy(dealRequest.MinerSelectionStrategy, core.MinerSelectionParam{Size: dealRequest.Size})
 				if errOnPv != nil {
 					return errOnPv
 				}
@@ -2385,7 +2380,9 @@ func handleMultipleBatchImportDeals(c echo.Context, node *core.DeltaNode) error
 					CreatedAt: time.Now(),
 					UpdatedAt: time.Now(),
 				}
-				tx.Create(&contentMinerAssignment)
+				if err := tx.Create(&contentMinerAssignment).Error; err != nil {
+					return err
+				}
 				dealRequest.Miner = contentMinerAssignment.Miner
 			}
 
@@ -2395,11 +2392,11 @@ func handleMultipleBatchImportDeals(c echo.Context, node *core.DeltaNode) error
 				// get wallet from wallets database
 				var wallet model.Wallet
 				if dealRequest.Wallet.Address != "" {
-					tx.Where("addr = ? and owner = ?", dealRequest.Wallet.Address, authParts[1]).First(&wallet)
+					tx.Where("addr = ? and tenant_id = ?", dealRequest.Wallet.Address, tenantId).First(&wallet)
 				} else if dealRequest.Wallet.Uuid != "" {
-					tx.Where("uu_id = ? and owner = ?", dealRequest.Wallet.Uuid, authParts[1]).First(&wallet)
+					tx.Where("uu_id = ? and tenant_id = ?", dealRequest.Wallet.Uuid, tenantId).First(&wallet)
 				} else {
-					tx.Where("id = ? and owner = ?", dealRequest.Wallet.Id, authParts[1]).First(&wallet)
+					tx.Where("id = ? and tenant_id = ?", dealRequest.Wallet.Id, tenantId).First(&wallet)
 				}
 
 				if wallet.ID == 0 {
@@ -2478,17 +2475,10 @@ func handleMultipleBatchImportDeals(c echo.Context, node *core.DeltaNode) error
 				return string(stringTP)
 
 			}()
-			if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-				startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-				dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-				dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays - dealRequest.StartEpochInDays))
-				dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-			} else {
-				dealProposalParam.StartEpoch = 0
-				dealProposalParam.Duration = utils.DEFAULT_DURATION
-			}
+			setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestImport)
 
 			dealProposalParam.RemoveUnsealedCopy = dealRequest.RemoveUnsealedCopy
+			dealProposalParam.MinerSelectionStrategy = dealRequest.MinerSelectionStrategy
 			dealProposalParam.SkipIPNIAnnounce = dealRequest.SkipIPNIAnnounce
 
 			// deal proposal parameters
@@ -2510,7 +2500,6 @@ func handleMultipleBatchImportDeals(c echo.Context, node *core.DeltaNode) error
 			})
 
 		}
-		go node.Dispatcher.Start(len(dealRequests))
 		err = c.JSON(http.StatusOK, dealResponses)
 		if err != nil {
 			//tx.Rollback()
@@ -2548,8 +2537,7 @@ func handleMultipleImportDeals(c echo.Context, node *core.DeltaNode) error {
 	var dealRequests []DealRequest
 
 	// lets record this.
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	tenantId := callerTenantId(c)
 
 	//	validate the meta
 	err := c.Bind(&dealRequests)
@@ -2557,25 +2545,14 @@ func handleMultipleImportDeals(c echo.Context, node *core.DeltaNode) error {
 		return errors.New("Error parsing the request, please check the request body if it complies with the spec")
 	}
 
+	err = ValidateDealRequests(dealRequests, DealRequestImport, node)
+	if err != nil {
+		return err
+	}
+
 	errTxn := node.DB.Transaction(func(tx *gorm.DB) error {
 		var dealResponses []DealResponse
 		for _, dealRequest := range dealRequests {
-			if dealRequest.ConnectionMode == "e2e" {
-				return errors.New("Connection mode e2e is not supported on this import endpoint")
-			}
-			dealRequest.ConnectionMode = "import"
-			err = ValidateMeta(dealRequest, node)
-			if err != nil {
-				tx.Rollback()
-				return err
-			}
-
-			err = ValidatePieceCommitmentMeta(dealRequest.PieceCommitment, node)
-			if err != nil {
-				tx.Rollback()
-				return err
-			}
-
 			// let's create a commp but only if we have
 			// a cid, a piece_cid, a padded_piece_size, size
 			var pieceCommp model.PieceCommitment
@@ -2606,9 +2583,11 @@ func handleMultipleImportDeals(c echo.Context, node *core.DeltaNode) error {
 				Name:              dealRequest.Cid,
 				Size:              dealRequest.Size,
 				Cid:               dealRequest.Cid,
-				RequestingApiKey:  authParts[1],
+				TenantID:          tenantId,
 				PieceCommitmentId: pieceCommp.ID,
 				AutoRetry:         dealRequest.AutoRetry,
+				AutoRenew:         dealRequest.AutoRenew,
+				AutoReplace:       dealRequest.AutoReplace,
 				Status:            utils.CONTENT_DEAL_MAKING_PROPOSAL,
 				ConnectionMode:    dealRequest.ConnectionMode,
 				CreatedAt:         time.Now(),
@@ -2620,7 +2599,7 @@ func handleMultipleImportDeals(c echo.Context, node *core.DeltaNode) error {
 			//	assign a miner
 			if dealRequest.Miner == "" {
 				minerAssignService := core.NewMinerAssignmentService(*node)
-				provider, errOnPv := minerAssignService.GetSPWithGivenBytes(dealRequest.Size)
+				provider, errOnPv := minerAssignService.GetSPWithStrategy(dealRequest.MinerSelectionStrategy, core.MinerSelectionParam{Size: dealRequest.Size})
 				if errOnPv != nil {
 					return errOnPv
 				}
@@ -2633,7 +2612,9 @@ func handleMultipleImportDeals(c echo.Context, node *core.DeltaNode) error {
 					CreatedAt: time.Now(),
 					UpdatedAt: time.Now(),
 				}
-				tx.Create(&contentMinerAssignment)
+				if err := tx.Create(&contentMinerAssignment).Error; err != nil {
+					return err
+				}
 				dealRequest.Miner = contentMinerAssignment.Miner
 			}
 
@@ -2643,11 +2624,11 @@ func handleMultipleImportDeals(c echo.Context, node *core.DeltaNode) error {
 				// get wallet from wallets database
 				var wallet model.Wallet
 				if dealRequest.Wallet.Address != "" {
-					tx.Where("addr = ? and owner = ?", dealRequest.Wallet.Address, authParts[1]).First(&wallet)
+					tx.Where("addr = ? and tenant_id = ?", dealRequest.Wallet.Address, tenantId).First(&wallet)
 				} else if dealRequest.Wallet.Uuid != "" {
-					tx.Where("uu_id = ? and owner = ?", dealRequest.Wallet.Uuid, authParts[1]).First(&wallet)
+					tx.Where("uu_id = ? and tenant_id = ?", dealRequest.Wallet.Uuid, tenantId).First(&wallet)
 				} else {
-					tx.Where("id = ? and owner = ?", dealRequest.Wallet.Id, authParts[1]).First(&wallet)
+					tx.Where("id = ? and tenant_id = ?", dealRequest.Wallet.Id, tenantId).First(&wallet)
 				}
 
 				if wallet.ID == 0 {
@@ -2741,17 +2722,10 @@ func handleMultipleImportDeals(c echo.Context, node *core.DeltaNode) error {
 				return string(stringTP)
 
 			}()
-			if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-				startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-				dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-				dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays - dealRequest.StartEpochInDays))
-				dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-			} else {
-				dealProposalParam.StartEpoch = 0
-				dealProposalParam.Duration = utils.DEFAULT_DURATION
-			}
+			setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestImport)
 
 			dealProposalParam.RemoveUnsealedCopy = dealRequest.RemoveUnsealedCopy
+			dealProposalParam.MinerSelectionStrategy = dealRequest.MinerSelectionStrategy
 			dealProposalParam.SkipIPNIAnnounce = dealRequest.SkipIPNIAnnounce
 
 			// deal proposal parameters
@@ -2775,7 +2749,6 @@ func handleMultipleImportDeals(c echo.Context, node *core.DeltaNode) error {
 			})
 
 		}
-		go node.Dispatcher.Start(len(dealRequests))
 		err = c.JSON(http.StatusOK, dealResponses)
 		if err != nil {
 			tx.Rollback()
@@ -2793,8 +2766,7 @@ func handleMultipleRemoteOnlineDeals(c echo.Context, node *core.DeltaNode) error
 	var dealRequests []DealRequest
 
 	// lets record this.
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	tenantId := callerTenantId(c)
 
 	//	validate the meta
 	err := c.Bind(&dealRequests)
@@ -2802,25 +2774,14 @@ func handleMultipleRemoteOnlineDeals(c echo.Context, node *core.DeltaNode) error
 		return errors.New("Error parsing the request, please check the request body if it complies with the spec")
 	}
 
+	err = ValidateDealRequests(dealRequests, DealRequestRemote, node)
+	if err != nil {
+		return err
+	}
+
 	errTxn := node.DB.Transaction(func(tx *gorm.DB) error {
 		var dealResponses []DealResponse
 		for _, dealRequest := range dealRequests {
-			if dealRequest.ConnectionMode == "import" {
-				return errors.New("Connection mode e2e is not supported on this import endpoint")
-			}
-			dealRequest.ConnectionMode = "e2e"
-			err = ValidateMeta(dealRequest, node)
-			if err != nil {
-				tx.Rollback()
-				return err
-			}
-
-			err = ValidatePieceCommitmentMeta(dealRequest.PieceCommitment, node)
-			if err != nil {
-				tx.Rollback()
-				return err
-			}
-
 			// let's create a commp but only if we have
 			// a cid, a piece_cid, a padded_piece_size, size
 			var pieceCommp model.PieceCommitment
@@ -2851,9 +2812,11 @@ func handleMultipleRemoteOnlineDeals(c echo.Context, node *core.DeltaNode) error
 				Name:              dealRequest.Cid,
 				Size:              dealRequest.Size,
 				Cid:               dealRequest.Cid,
-				RequestingApiKey:  authParts[1],
+				TenantID:          tenantId,
 				PieceCommitmentId: pieceCommp.ID,
 				AutoRetry:         dealRequest.AutoRetry,
+				AutoRenew:         dealRequest.AutoRenew,
+				AutoReplace:       dealRequest.AutoReplace,
 				Status:            utils.CONTENT_DEAL_MAKING_PROPOSAL,
 				ConnectionMode:    dealRequest.ConnectionMode,
 				CreatedAt:         time.Now(),
@@ -2865,7 +2828,7 @@ func handleMultipleRemoteOnlineDeals(c echo.Context, node *core.DeltaNode) error
 			//	assign a miner
 			if dealRequest.Miner == "" {
 				minerAssignService := core.NewMinerAssignmentService(*node)
-				provider, errOnPv := minerAssignService.GetSPWithGivenBytes(dealRequest.Size)
+				provider, errOnPv := minerAssignService.GetSPWithStrategy(dealRequest.MinerSelectionStrategy, core.MinerSelectionParam{Size: dealRequest.Size})
 				if errOnPv != nil {
 					return errOnPv
 				}
@@ -2878,7 +2841,9 @@ func handleMultipleRemoteOnlineDeals(c echo.Context, node *core.DeltaNode) error
 					CreatedAt: time.Now(),
 					UpdatedAt: time.Now(),
 				}
-				tx.Create(&contentMinerAssignment)
+				if err := tx.Create(&contentMinerAssignment).Error; err != nil {
+					return err
+				}
 				dealRequest.Miner = contentMinerAssignment.Miner
 			}
 
@@ -2888,11 +2853,11 @@ func handleMultipleRemoteOnlineDeals(c echo.Context, node *core.DeltaNode) error
 				// get wallet from wallets database
 				var wallet model.Wallet
 				if dealRequest.Wallet.Address != "" {
-					tx.Where("addr = ? and owner = ?", dealRequest.Wallet.Address, authParts[1]).First(&wallet)
+					tx.Where("addr = ? and tenant_id = ?", dealRequest.Wallet.Address, tenantId).First(&wallet)
 				} else if dealRequest.Wallet.Uuid != "" {
-					tx.Where("uu_id = ? and owner = ?", dealRequest.Wallet.Uuid, authParts[1]).First(&wallet)
+					tx.Where("uu_id = ? and tenant_id = ?", dealRequest.Wallet.Uuid, tenantId).First(&wallet)
 				} else {
-					tx.Where("id = ? and owner = ?", dealRequest.Wallet.Id, authParts[1]).First(&wallet)
+					tx.Where("id = ? and tenant_id = ?", dealRequest.Wallet.Id, tenantId).First(&wallet)
 				}
 
 				if wallet.ID == 0 {
@@ -2986,17 +2951,10 @@ func handleMultipleRemoteOnlineDeals(c echo.Context, node *core.DeltaNode) error
 				return string(stringTP)
 
 			}()
-			if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-				startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-				dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-				dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays - dealRequest.StartEpochInDays))
-				dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-			} else {
-				dealProposalParam.StartEpoch = 0
-				dealProposalParam.Duration = utils.DEFAULT_DURATION
-			}
+			setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestRemote)
 
 			dealProposalParam.RemoveUnsealedCopy = dealRequest.RemoveUnsealedCopy
+			dealProposalParam.MinerSelectionStrategy = dealRequest.MinerSelectionStrategy
 			dealProposalParam.SkipIPNIAnnounce = dealRequest.SkipIPNIAnnounce
 
 			// deal proposal parameters
@@ -3020,7 +2978,6 @@ func handleMultipleRemoteOnlineDeals(c echo.Context, node *core.DeltaNode) error
 			})
 
 		}
-		go node.Dispatcher.Start(len(dealRequests))
 		err = c.JSON(http.StatusOK, dealResponses)
 		if err != nil {
 			tx.Rollback()
@@ -3043,7 +3000,8 @@ func handleContentStats(c echo.Context, statsService core.StatsService) error {
 	}
 
 	status, err := statsService.ContentStatus(core.ContentStatsParam{
-		ContentId: int64(contentId),
+		StatsParam: callerStatsParam(c),
+		ContentId:  int64(contentId),
 	})
 
 	if err != nil {
@@ -3063,6 +3021,7 @@ func handleCommitmentPieceStats(c echo.Context, statsService core.StatsService)
 	}
 
 	status, err := statsService.PieceCommitmentStatus(core.PieceCommitmentStatsParam{
+		StatsParam:   callerStatsParam(c),
 		PieceCommpId: int64(pieceCommitmentId),
 	})
 
@@ -3073,152 +3032,61 @@ func handleCommitmentPieceStats(c echo.Context, statsService core.StatsService)
 	return c.JSON(200, status)
 }
 
-type ValidateMetaResult struct {
-	IsValid bool
-	Message string
-}
-
-// ValidatePieceCommitmentMeta `ValidateMeta` validates the `DealRequest` struct and returns an error if the request is invalid
-func ValidatePieceCommitmentMeta(pieceCommitmentRequest PieceCommitmentRequest, node *core.DeltaNode) error {
-	if (PieceCommitmentRequest{} == pieceCommitmentRequest) {
-		return errors.New("invalid piece_commitment request. piece_commitment is required")
-	}
-
-	return nil
-}
-
+// ValidateFileLimit rejects an end-to-end file smaller than the minimum file size of the node.
 func ValidateFileLimit(file *multipart.FileHeader) error {
-
 	if file.Size < deltaNode.Config.Common.MinE2EFileSize {
-		return errors.New("file size of " + strconv.FormatInt(file.Size, 10) + " bytes is less than the minimum file size of " + strconv.FormatInt(deltaNode.Config.Common.MinE2EFileSize, 10) + " bytes")
+		return &ValidationError{Errors: []FieldError{{
+			Field:   "data",
+			Code:    FieldErrorTooSmall,
+			Message: "file size of " + strconv.FormatInt(file.Size, 10) + " bytes is less than the minimum file size of " + strconv.FormatInt(deltaNode.Config.Common.MinE2EFileSize, 10) + " bytes",
+		}}}
 	}
 	return nil
 }
 
-// It validates the deal request and returns an error if the request is invalid
-func ValidateMeta(dealRequest DealRequest, node *core.DeltaNode) error {
-
-	if (DealRequest{} == dealRequest) {
-		return errors.New("invalid deal request")
-	}
+type ReplicatedContent struct {
+	Content      model.Content
+	DealRequest  DealRequest
+	DealResponse DealResponse
+}
 
-	if (DealRequest{} != dealRequest && dealRequest.UnverifiedDealMaxPrice != "") {
-		if dealRequest.DealVerifyState != utils.DEAL_UNVERIFIED {
-			return errors.New("unverified_deal_max_price is only valid for unverified deals, make sure to pass deal_verify_state as unverified")
-		}
-	}
+// ReplicateContent creates a copy of the content for each replica, each one assigned to a storage provider that no
+// other copy uses. The copies are linked to the source content with a replication group, which is created on the
+// first replication. It fails if there are not enough distinct storage providers.
+func ReplicateContent(node *core.DeltaNode, contentSource DealReplication, dealRequest DealRequest, txn *gorm.DB) ([]ReplicatedContent, error) {
+	var replicatedContents []ReplicatedContent
 
-	if (DealRequest{} != dealRequest && dealRequest.DealVerifyState == utils.DEAL_UNVERIFIED) {
-		if dealRequest.UnverifiedDealMaxPrice == "" {
-			return errors.New("unverified_deal_max_price is required for unverified deals")
+	if contentSource.Content.ReplicationGroup == 0 {
+		replicationGroup := model.ReplicationGroup{
+			SourceContent: contentSource.Content.ID,
+			Copies:        dealRequest.Replication + 1,
+			Diversity:     dealRequest.ReplicationDiversity,
+			TenantID:      contentSource.Content.TenantID,
+			CreatedAt:     time.Now(),
+			UpdatedAt:     time.Now(),
 		}
-	}
-
-	// check if dealRequest.UnverifiedDealMaxPrice is a valid number
-	if (DealRequest{} != dealRequest && dealRequest.UnverifiedDealMaxPrice != "") {
-		_, err := strconv.ParseFloat(dealRequest.UnverifiedDealMaxPrice, 64)
-		if err != nil {
-			return errors.New("unverified_deal_max_price is not a valid number")
+		if err := txn.Create(&replicationGroup).Error; err != nil {
+			return nil, err
 		}
-	}
-
-	if (DealRequest{} != dealRequest && dealRequest.Replication > 0 && dealRequest.ConnectionMode == utils.CONNECTION_MODE_IMPORT) {
-		return errors.New("replication factor is not supported for import mode")
-	}
-
-	if (DealRequest{} != dealRequest && dealRequest.DurationInDays > 0 && dealRequest.StartEpochInDays == 0) {
-		return errors.New("start_epoch_in_days is required when duration_in_days is set")
-	}
-
-	if (DealRequest{} != dealRequest && dealRequest.Replication > node.Config.Common.MaxReplicationFactor) {
-		return errors.New("replication factor can only be up to " + strconv.Itoa(node.Config.Common.MaxReplicationFactor))
-	}
-
-	if (DealRequest{} != dealRequest && dealRequest.StartEpochInDays > 0 && dealRequest.DurationInDays == 0) {
-		return errors.New("duration_in_days is required when start_epoch_in_days is set")
-	}
-
-	if (DealRequest{} != dealRequest && dealRequest.StartEpochInDays > 14) {
-		return errors.New("start_epoch_in_days can only be 14 days or less")
-	}
-
-	if (DealRequest{} != dealRequest && dealRequest.DurationInDays > 540) {
-		return errors.New("duration_in_days can only be 540 days or less")
-	}
-
-	if dealRequest.StartEpochInDays > dealRequest.DurationInDays {
-		return errors.New("start_epoch_in_days cannot be greater than duration_in_days")
-	}
-
-	if (DealRequest{} != dealRequest && dealRequest.Replication > 6) {
-		return errors.New("replication count is more than allowed (6)")
-	}
-
-	// label length must be less than 100
-	if (DealRequest{} != dealRequest && len(dealRequest.Label) > 100) {
-		return errors.New("label length must be less than 100")
-	}
-
-	if (DealRequest{} != dealRequest && dealRequest.DealVerifyState == utils.DEAL_UNVERIFIED) {
-		dealRequest.DealVerifyState = utils.DEAL_UNVERIFIED
-	} else {
-		dealRequest.DealVerifyState = utils.DEAL_VERIFIED
-	}
-
-	// connection mode is required
-	if (DealRequest{} != dealRequest) {
-		switch dealRequest.ConnectionMode {
-		case "":
-			dealRequest.ConnectionMode = utils.CONNECTION_MODE_E2E
-		case utils.CONNECTION_MODE_E2E:
-		case utils.CONNECTION_MODE_IMPORT:
-		default:
-			return errors.New("connection mode can only be e2e or import")
+		if err := txn.Model(&model.Content{}).Where("id = ?", contentSource.Content.ID).Update("replication_group", replicationGroup.ID).Error; err != nil {
+			return nil, err
 		}
+		contentSource.Content.ReplicationGroup = replicationGroup.ID
 	}
 
-	if dealRequest.ConnectionMode == utils.CONNECTION_MODE_E2E && dealRequest.TransferParameters.URL != "" {
-		return errors.New("transfer_parameters is not supported for e2e mode.")
+	//	assign distinct miners, the miners of the other copies are never reused
+	minerAssignService := core.NewMinerAssignmentService(*node)
+	selectionParam := core.MinerSelectionParam{Size: contentSource.Content.Size}
+	selectionParam.ExcludedMiners = core.GetReplicationGroupMiners(txn, contentSource.Content.ReplicationGroup)
+	if dealRequest.Miner != "" {
+		selectionParam.ExcludedMiners = append(selectionParam.ExcludedMiners, dealRequest.Miner)
 	}
-
-	// piece commitment is required
-	if (PieceCommitmentRequest{} != dealRequest.PieceCommitment && dealRequest.PieceCommitment.Piece == "") {
-		return errors.New("piece commitment is invalid, make sure you have the cid, piece_cid, size and padded_piece_size or unpadded_piece_size")
-	}
-
-	if (PieceCommitmentRequest{} != dealRequest.PieceCommitment && dealRequest.PieceCommitment.Piece != "") &&
-		(dealRequest.PieceCommitment.PaddedPieceSize == 0 && dealRequest.PieceCommitment.UnPaddedPieceSize == 0) &&
-		(dealRequest.Size == 0) {
-		return errors.New("piece commitment is invalid, make sure you have the cid, piece_cid, size and padded_piece_size or unpadded_piece_size")
-	}
-
-	// size is required
-	if (PieceCommitmentRequest{} != dealRequest.PieceCommitment && dealRequest.Size == 0) {
-		return errors.New("piece commitment is invalid, make sure you have the cid, piece_cid, size and padded_piece_size or unpadded_piece_size")
-	}
-
-	if (WalletRequest{} != dealRequest.Wallet && dealRequest.Wallet.Address == "") {
-		return errors.New("wallet address is required")
+	providers, err := minerAssignService.SelectDistinctProviders(contentSource.ContentDealProposalParameter.MinerSelectionStrategy, selectionParam, dealRequest.Replication, dealRequest.ReplicationDiversity)
+	if err != nil {
+		return nil, err
 	}
 
-	// catch any panics
-	defer func() {
-		if r := recover(); r != nil {
-			fmt.Println("Recovered in f", r)
-		}
-	}()
-	return nil
-}
-
-type ReplicatedContent struct {
-	Content      model.Content
-	DealRequest  DealRequest
-	DealResponse DealResponse
-}
-
-func ReplicateContent(node *core.DeltaNode, contentSource DealReplication, dealRequest DealRequest, txn *gorm.DB) []ReplicatedContent {
-	var replicatedContents []ReplicatedContent
-	for i := 0; i < dealRequest.Replication; i++ {
+	for _, provider := range providers {
 		var replicatedContent ReplicatedContent
 		var dealResponse DealResponse
 		var newContent model.Content
@@ -3230,7 +3098,7 @@ func ReplicateContent(node *core.DeltaNode, contentSource DealReplication, dealR
 		err := txn.Create(&newContent).Error
 		if err != nil {
 			fmt.Println(err)
-			return nil
+			return nil, err
 		}
 
 		newContentDealProposalParameter.ID = 0
@@ -3239,14 +3107,7 @@ func ReplicateContent(node *core.DeltaNode, contentSource DealReplication, dealR
 		if err != nil {
 			//tx.Rollback()
 			fmt.Println(err)
-			return nil
-		}
-		//	assign a miner
-		minerAssignService := core.NewMinerAssignmentService(*node)
-		provider, errOnPv := minerAssignService.GetSPWithGivenBytes(newContent.Size)
-		if errOnPv != nil {
-			fmt.Println(errOnPv)
-			return nil
+			return nil, err
 		}
 
 		contentMinerAssignment := model.ContentMiner{
@@ -3259,12 +3120,13 @@ func ReplicateContent(node *core.DeltaNode, contentSource DealReplication, dealR
 		if err != nil {
 			//tx.Rollback()
 			fmt.Println(err)
-			return nil
+			return nil, err
 		}
 		dealRequest.Miner = provider.Address
 		dealResponse.DealRequest = dealRequest
 		dealResponse.ContentId = newContent.ID
 		dealResponse.DealProposalParameterRequest = newContentDealProposalParameter
+		dealResponse.ReplicationGroup = newContent.ReplicationGroup
 		dealResponse.Status = utils.CONTENT_PINNED
 		dealResponse.Message = "Content replication request successful"
 
@@ -3275,7 +3137,7 @@ func ReplicateContent(node *core.DeltaNode, contentSource DealReplication, dealR
 		replicatedContents = append(replicatedContents, replicatedContent)
 
 	}
-	return replicatedContents
+	return replicatedContents, nil
 }
 
 // It takes a request, and returns a response
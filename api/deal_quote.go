@@ -0,0 +1,120 @@
+package api
+
+import (
+	"delta/core"
+	model "delta/models"
+	"delta/utils"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// DealQuoteResponse is the answer to a dry run of a deal request.
+type DealQuoteResponse struct {
+	Status      string         `json:"status"`
+	Message     string         `json:"message"`
+	DealRequest DealRequest    `json:"deal_request_meta"`
+	Quote       core.DealQuote `json:"quote"`
+}
+
+// handleDealQuote handles the dry run of a deal request
+// @Summary Quote a deal request without making it
+// @Description Validates the deal request, selects the storage providers and returns the proposals that would be sent,
+// @Description with their epochs and price, and whether the wallet has enough funds or DataCap. Nothing is saved.
+// @Tags deal
+// @Accept  json
+// @Produce  json
+func handleDealQuote(c echo.Context, node *core.DeltaNode) error {
+	tenantId := callerTenantId(c)
+
+	var dealRequest DealRequest
+	if err := c.Bind(&dealRequest); err != nil {
+		return c.JSON(http.StatusBadRequest, DealResponse{
+			Status:  "error",
+			Message: "invalid deal request: " + err.Error(),
+		})
+	}
+	kind := DealRequestContent
+	if dealRequest.ConnectionMode == utils.CONNECTION_MODE_IMPORT {
+		kind = DealRequestImport
+	}
+	if err := ValidateDealRequest(&dealRequest, kind, node); err != nil {
+		return err
+	}
+	if dealRequest.Size <= 0 {
+		return &ValidationError{Errors: []FieldError{{Field: "size", Code: FieldErrorRequired, Message: "size is required to quote a deal"}}}
+	}
+	if err := ValidateDealSize(dealRequest, dealRequest.Size, node.Config); err != nil {
+		return err
+	}
+
+	// the wallet of the deals, the node wallet unless the request names one of the caller
+	walletAddr := node.FilClient.ClientAddr.String()
+	if (WalletRequest{} != dealRequest.Wallet) {
+		var wallet model.Wallet
+		if dealRequest.Wallet.Address != "" {
+			node.DB.Where("addr = ? and tenant_id = ?", dealRequest.Wallet.Address, tenantId).First(&wallet)
+		} else if dealRequest.Wallet.Uuid != "" {
+			node.DB.Where("uuid = ? and tenant_id = ?", dealRequest.Wallet.Uuid, tenantId).First(&wallet)
+		} else {
+			node.DB.Where("id = ? and tenant_id = ?", dealRequest.Wallet.Id, tenantId).First(&wallet)
+		}
+		if wallet.ID == 0 {
+			return c.JSON(http.StatusBadRequest, DealResponse{
+				Status:  "error",
+				Message: "Wallet not found, please make sure the wallet is registered",
+			})
+		}
+		walletAddr = wallet.Addr
+	}
+
+	quote, err := core.NewDealQuoteService(*node).Quote(c.Request().Context(), core.DealQuoteParam{
+		Miner:                dealRequest.Miner,
+		Replication:          dealRequest.Replication,
+		ReplicationDiversity: dealRequest.ReplicationDiversity,
+		Size:                 dealRequest.Size,
+		Piece:                dealRequest.PieceCommitment.Piece,
+		PaddedPieceSize:      dealRequest.PieceCommitment.PaddedPieceSize,
+		Wallet:               walletAddr,
+		ProposalParameters:   quoteDealProposalParameters(dealRequest, kind),
+	})
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, DealResponse{
+			Status:  "error",
+			Message: "Error quoting the deal request: " + err.Error(),
+		})
+	}
+
+	message := "Deal request quoted, nothing was saved."
+	if !quote.SufficientFunds || !quote.SufficientDataCap {
+		message = "Deal request quoted, the wallet can't pay for all the deals."
+	}
+	return c.JSON(http.StatusOK, DealQuoteResponse{
+		Status:      "success",
+		Message:     message,
+		DealRequest: dealRequest,
+		Quote:       quote,
+	})
+}
+
+// quoteDealProposalParameters returns the deal proposal parameters a deal request of the given kind would be saved
+// with.
+func quoteDealProposalParameters(dealRequest DealRequest, kind string) model.ContentDealProposalParameters {
+	dealProposalParam := model.ContentDealProposalParameters{
+		Label:                  dealRequest.Label,
+		VerifiedDeal:           dealRequest.DealVerifyState != utils.DEAL_UNVERIFIED,
+		UnverifiedDealMaxPrice: "0",
+		RemoveUnsealedCopy:     dealRequest.RemoveUnsealedCopy,
+		SkipIPNIAnnounce:       dealRequest.SkipIPNIAnnounce,
+		MinerSelectionStrategy: dealRequest.MinerSelectionStrategy,
+	}
+	if dealRequest.UnverifiedDealMaxPrice != "" {
+		dealProposalParam.UnverifiedDealMaxPrice = dealRequest.UnverifiedDealMaxPrice
+	}
+	if dealProposalParam.Label == "" {
+		dealProposalParam.Label = dealRequest.Cid
+	}
+
+	setDealProposalEpochs(&dealProposalParam, dealRequest, kind)
+	return dealProposalParam
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"delta/config"
+	"delta/core"
+	model "delta/models"
+	"delta/utils"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHandleDealQuote_BlockedMiner(t *testing.T) {
+	db, err := model.OpenDatabase("file:" + t.Name() + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&model.MinerListEntry{Miner: "f09999", ListType: model.MINER_LIST_BLOCKED, Reason: "faulty"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.DeltaConfig{}
+	cfg.DealLimits.MaxStartEpochInDays = 14
+	cfg.DealLimits.MaxDurationInDays = 540
+	node := &core.DeltaNode{DB: db, Config: cfg}
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/deal/quote", strings.NewReader(`{"miner": "f09999", "size": 1024}`))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	c := e.NewContext(req, httptest.NewRecorder())
+	c.Set(ApiKeyContextKey, model.ApiKey{TenantID: 1})
+
+	// the quote is rejected like the deal request would be, before a provider is asked
+	var validationErr *ValidationError
+	if err := handleDealQuote(c, node); !errors.As(err, &validationErr) || validationErr.Errors[0].Field != "miner" ||
+		validationErr.Errors[0].Code != FieldErrorNotAllowed {
+		t.Fatalf("expected the blocked miner to be rejected, got %v", err)
+	}
+}
+
+func TestSetDealProposalEpochs(t *testing.T) {
+	tests := []struct {
+		name         string
+		kind         string
+		request      DealRequest
+		wantDuration int64
+		wantStart    bool // the start epoch is set
+	}{
+		{"content", DealRequestContent, DealRequest{StartEpochInDays: 2, DurationInDays: 180}, utils.EPOCH_PER_DAY * 180, true},
+		{"import", DealRequestImport, DealRequest{StartEpochInDays: 2, DurationInDays: 180}, utils.EPOCH_PER_DAY * 178, true},
+		{"repair", DealRequestRepair, DealRequest{StartEpochInDays: 2, DurationInDays: 180}, utils.EPOCH_PER_DAY * 178, true},
+		{"content without days", DealRequestContent, DealRequest{StartEpoch: 1000}, utils.DEFAULT_DURATION, true},
+		{"e2e without days", DealRequestE2E, DealRequest{StartEpoch: 1000}, utils.DEFAULT_DURATION, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dealProposalParam := model.ContentDealProposalParameters{StartEpoch: 42, EndEpoch: 43}
+			setDealProposalEpochs(&dealProposalParam, tt.request, tt.kind)
+			if dealProposalParam.Duration != tt.wantDuration {
+				t.Errorf("expected a duration of %d, got %d", tt.wantDuration, dealProposalParam.Duration)
+			}
+			if (dealProposalParam.StartEpoch != 0) != tt.wantStart {
+				t.Errorf("unexpected start epoch %d", dealProposalParam.StartEpoch)
+			}
+
+			// the quote has the epochs the deal request is saved with
+			quoted := quoteDealProposalParameters(tt.request, tt.kind)
+			if quoted.StartEpoch != dealProposalParam.StartEpoch || quoted.Duration != dealProposalParam.Duration {
+				t.Errorf("expected the quote to match, got %+v", quoted)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"delta/config"
+	"delta/core"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestHandleExistingContentAdd_InvalidRequests(t *testing.T) {
+	node := &core.DeltaNode{Config: &config.DeltaConfig{}}
+	tests := []struct {
+		name       string
+		handler    func(c echo.Context, node *core.DeltaNode) error
+		body       string
+		wantStatus int
+	}{
+		{"malformed content", handleExistingContentAdd, `{"cid": `, http.StatusBadRequest},
+		{"invalid content", handleExistingContentAdd, `{"connection_mode": "ftp"}`, http.StatusUnprocessableEntity},
+		{"malformed contents", handleExistingContentsAdd, `[{"cid": `, http.StatusBadRequest},
+		{"invalid contents", handleExistingContentsAdd, `[{"connection_mode": "ftp"}]`, http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		e := echo.New()
+		e.HTTPErrorHandler = ErrorHandler
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/deal/existing", strings.NewReader(tt.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		if err := tt.handler(c, node); err != nil {
+			e.HTTPErrorHandler(err, c)
+		}
+		if rec.Code != tt.wantStatus {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.name, tt.wantStatus, rec.Code, rec.Body.String())
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"delta/config"
+	"delta/core"
+	model "delta/models"
+	"delta/utils"
+	"fmt"
+	"strings"
+
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// DealRequestValidationVersion is the version of the rules the deal requests are validated with. It is returned with
+// the validation errors, and changes whenever a rule changes.
+const DealRequestValidationVersion = "v1"
+
+// the kinds of deal requests, each endpoint validates its requests as one of them
+const (
+	DealRequestE2E     = "e2e"     // the data is sent by the node
+	DealRequestRemote  = "remote"  // the data is sent by the node or pulled from an url, the piece is computed by the client
+	DealRequestImport  = "import"  // the data is imported by the storage provider, the piece is computed by the client
+	DealRequestContent = "content" // a content already on the node, in either connection mode
+	DealRequestRepair  = "repair"  // only the storage provider and the epochs of a deal can change
+)
+
+// the codes of the field errors
+const (
+	FieldErrorRequired   = "required"
+	FieldErrorInvalid    = "invalid"
+	FieldErrorNotAllowed = "not_allowed"
+	FieldErrorTooLarge   = "too_large"
+	FieldErrorTooSmall   = "too_small"
+	FieldErrorConflict   = "conflict"
+)
+
+// dealRequestRules are the rules that differ between the kinds of deal requests.
+// @property ConnectionMode - The connection mode of the kind, any mode if empty.
+// @property TransferUrl - The storage provider can pull the data from the transfer url.
+// @property PieceCommitment - The piece commitment and the cid are computed by the client.
+// @property EpochsOnly - Only the epochs are validated.
+type dealRequestRules struct {
+	ConnectionMode  string
+	TransferUrl     bool
+	PieceCommitment bool
+	EpochsOnly      bool
+}
+
+var dealRequestKinds = map[string]dealRequestRules{
+	DealRequestE2E:     {ConnectionMode: utils.CONNECTION_MODE_E2E},
+	DealRequestRemote:  {ConnectionMode: utils.CONNECTION_MODE_E2E, TransferUrl: true, PieceCommitment: true},
+	DealRequestImport:  {ConnectionMode: utils.CONNECTION_MODE_IMPORT, PieceCommitment: true},
+	DealRequestContent: {},
+	DealRequestRepair:  {EpochsOnly: true},
+}
+
+// FieldError is the validation error of one field of a request.
+// @property Field - The json path of the field, prefixed with the index of the request in a batch.
+// @property Code - What is wrong with the field: required, invalid, not_allowed, too_large, too_small or conflict.
+// @property Message - The description of the error.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError is the list of field errors of an invalid request, the API answers it with 422.
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (v *ValidationError) Error() string {
+	var messages []string
+	for _, fieldError := range v.Errors {
+		messages = append(messages, fieldError.Field+": "+fieldError.Message)
+	}
+	return "invalid request: " + strings.Join(messages, "; ")
+}
+
+func (v *ValidationError) add(field string, code string, message string) {
+	v.Errors = append(v.Errors, FieldError{Field: field, Code: code, Message: message})
+}
+
+// ValidationErrorResponse is the body of a 422 answer.
+type ValidationErrorResponse struct {
+	Status  string       `json:"status"`
+	Message string       `json:"message"`
+	Version string       `json:"version"`
+	Errors  []FieldError `json:"errors"`
+}
+
+// ValidateDealRequest validates a deal request of the given kind against the limits of the node, its miner selection
+// strategies and its storage provider lists, and returns a ValidationError with every invalid field. A valid request is
+// normalized: its connection mode and its deal verify state get their defaults.
+func ValidateDealRequest(dealRequest *DealRequest, kind string, node *core.DeltaNode) error {
+	validationErr := &ValidationError{}
+	validateDealRequest(dealRequest, kind, node, "", validationErr)
+	if len(validationErr.Errors) > 0 {
+		return validationErr
+	}
+	return nil
+}
+
+// ValidateDealRequests validates every deal request of a batch like ValidateDealRequest, the fields of the errors are
+// prefixed with the index of their request.
+func ValidateDealRequests(dealRequests []DealRequest, kind string, node *core.DeltaNode) error {
+	validationErr := &ValidationError{}
+	if len(dealRequests) == 0 {
+		validationErr.add("", FieldErrorRequired, "at least one deal request is required")
+	}
+	for i := range dealRequests {
+		validateDealRequest(&dealRequests[i], kind, node, fmt.Sprintf("[%d].", i), validationErr)
+	}
+	if len(validationErr.Errors) > 0 {
+		return validationErr
+	}
+	return nil
+}
+
+// ValidateDealSize validates the size of the data of a deal request, lotus rejects the verified deals that are too
+// small.
+func ValidateDealSize(dealRequest DealRequest, size int64, cfg *config.DeltaConfig) error {
+	if dealRequest.DealVerifyState != utils.DEAL_UNVERIFIED && size < cfg.DealLimits.MinVerifiedDealSize {
+		return &ValidationError{Errors: []FieldError{{
+			Field:   "size",
+			Code:    FieldErrorTooSmall,
+			Message: fmt.Sprintf("the data of a verified deal must be at least %d bytes", cfg.DealLimits.MinVerifiedDealSize),
+		}}}
+	}
+	return nil
+}
+
+func validateDealRequest(dealRequest *DealRequest, kind string, node *core.DeltaNode, prefix string, validationErr *ValidationError) {
+	rules, ok := dealRequestKinds[kind]
+	if !ok {
+		validationErr.add(prefix, FieldErrorInvalid, "unknown kind of deal request "+kind)
+		return
+	}
+	cfg := node.Config
+	limits := cfg.DealLimits
+
+	// epochs
+	if dealRequest.StartEpochInDays < 0 {
+		validationErr.add(prefix+"start_epoch_in_days", FieldErrorInvalid, "start_epoch_in_days cannot be negative")
+	}
+	if dealRequest.DurationInDays < 0 {
+		validationErr.add(prefix+"duration_in_days", FieldErrorInvalid, "duration_in_days cannot be negative")
+	}
+	if dealRequest.DurationInDays > 0 && dealRequest.StartEpochInDays == 0 {
+		validationErr.add(prefix+"start_epoch_in_days", FieldErrorRequired, "start_epoch_in_days is required when duration_in_days is set")
+	}
+	if dealRequest.StartEpochInDays > 0 && dealRequest.DurationInDays == 0 {
+		validationErr.add(prefix+"duration_in_days", FieldErrorRequired, "duration_in_days is required when start_epoch_in_days is set")
+	}
+	if dealRequest.StartEpochInDays > limits.MaxStartEpochInDays {
+		validationErr.add(prefix+"start_epoch_in_days", FieldErrorTooLarge, fmt.Sprintf("start_epoch_in_days can only be %d days or less", limits.MaxStartEpochInDays))
+	}
+	if dealRequest.DurationInDays > limits.MaxDurationInDays {
+		validationErr.add(prefix+"duration_in_days", FieldErrorTooLarge, fmt.Sprintf("duration_in_days can only be %d days or less", limits.MaxDurationInDays))
+	}
+	if dealRequest.StartEpochInDays > dealRequest.DurationInDays && dealRequest.DurationInDays > 0 {
+		validationErr.add(prefix+"start_epoch_in_days", FieldErrorConflict, "start_epoch_in_days cannot be greater than duration_in_days")
+	}
+
+	// the storage provider, it is checked again when it is assigned
+	if dealRequest.Miner != "" && node.DB != nil {
+		if err := model.CheckMinerAccess(node.DB, dealRequest.Miner); err != nil {
+			validationErr.add(prefix+"miner", FieldErrorNotAllowed, err.Error())
+		}
+	}
+	if rules.EpochsOnly {
+		return
+	}
+	if dealRequest.MinerSelectionStrategy != "" {
+		if _, err := core.NewMinerAssignmentService(*node).GetStrategy(dealRequest.MinerSelectionStrategy); err != nil {
+			validationErr.add(prefix+"miner_selection_strategy", FieldErrorInvalid, err.Error())
+		}
+	}
+
+	// connection mode
+	switch dealRequest.ConnectionMode {
+	case "", utils.CONNECTION_MODE_E2E, utils.CONNECTION_MODE_IMPORT:
+		if rules.ConnectionMode != "" && dealRequest.ConnectionMode != "" && dealRequest.ConnectionMode != rules.ConnectionMode {
+			validationErr.add(prefix+"connection_mode", FieldErrorNotAllowed, "connection mode "+dealRequest.ConnectionMode+" is not supported on this endpoint")
+		}
+		if dealRequest.ConnectionMode == "" {
+			dealRequest.ConnectionMode = rules.ConnectionMode
+		}
+		if dealRequest.ConnectionMode == "" {
+			dealRequest.ConnectionMode = utils.CONNECTION_MODE_E2E
+		}
+	default:
+		validationErr.add(prefix+"connection_mode", FieldErrorInvalid, "connection mode can only be e2e or import")
+	}
+
+	// the data of an end-to-end deal is deleted after its transfer unless the node keeps copies, a renewal or a
+	// replacement sends it again
+	if dealRequest.ConnectionMode == utils.CONNECTION_MODE_E2E && !cfg.Node.KeepCopies {
+		if dealRequest.AutoRenew {
+			validationErr.add(prefix+"auto_renew", FieldErrorNotAllowed, "auto_renew of an e2e deal needs the node to keep copies of the data")
+		}
+		if dealRequest.AutoReplace {
+			validationErr.add(prefix+"auto_replace", FieldErrorNotAllowed, "auto_replace of an e2e deal needs the node to keep copies of the data")
+		}
+	}
+
+	// verified or unverified deal, verified by default
+	switch dealRequest.DealVerifyState {
+	case "":
+		dealRequest.DealVerifyState = utils.DEAL_VERIFIED
+	case utils.DEAL_VERIFIED, utils.DEAL_UNVERIFIED:
+	default:
+		validationErr.add(prefix+"deal_verify_state", FieldErrorInvalid, "deal_verify_state can only be verified or unverified")
+	}
+	if dealRequest.UnverifiedDealMaxPrice != "" {
+		if dealRequest.DealVerifyState != utils.DEAL_UNVERIFIED {
+			validationErr.add(prefix+"unverified_deal_max_price", FieldErrorNotAllowed, "unverified_deal_max_price is only valid for unverified deals, make sure to pass deal_verify_state as unverified")
+		}
+		// the price is in attoFIL per epoch, like the deal maker reads it
+		if price, err := types.BigFromString(dealRequest.UnverifiedDealMaxPrice); err != nil || price.Sign() < 0 {
+			validationErr.add(prefix+"unverified_deal_max_price", FieldErrorInvalid, "unverified_deal_max_price is not a valid whole number of attoFIL")
+		}
+	} else if dealRequest.DealVerifyState == utils.DEAL_UNVERIFIED {
+		validationErr.add(prefix+"unverified_deal_max_price", FieldErrorRequired, "unverified_deal_max_price is required for unverified deals")
+	}
+
+	// replication
+	if dealRequest.Replication < 0 {
+		validationErr.add(prefix+"replication", FieldErrorInvalid, "replication cannot be negative")
+	}
+	if dealRequest.Replication > cfg.Common.MaxReplicationFactor {
+		validationErr.add(prefix+"replication", FieldErrorTooLarge, fmt.Sprintf("replication factor can only be up to %d", cfg.Common.MaxReplicationFactor))
+	}
+	if dealRequest.Replication > 0 && dealRequest.ConnectionMode == utils.CONNECTION_MODE_IMPORT {
+		validationErr.add(prefix+"replication", FieldErrorNotAllowed, "replication factor is not supported for import mode")
+	}
+	if dealRequest.ReplicationDiversity != "" {
+		switch dealRequest.ReplicationDiversity {
+		case utils.REPLICATION_DIVERSITY_OWNER, utils.REPLICATION_DIVERSITY_NETWORK:
+			if dealRequest.Replication == 0 {
+				validationErr.add(prefix+"replication_diversity", FieldErrorNotAllowed, "replication_diversity is only valid when replication is set")
+			}
+		default:
+			validationErr.add(prefix+"replication_diversity", FieldErrorInvalid, "replication_diversity can only be owner or network")
+		}
+	}
+
+	if len(dealRequest.Label) > limits.MaxLabelLength {
+		validationErr.add(prefix+"label", FieldErrorTooLarge, fmt.Sprintf("label can only be %d characters or less", limits.MaxLabelLength))
+	}
+
+	if dealRequest.TransferParameters.URL != "" && (!rules.TransferUrl || dealRequest.ConnectionMode != utils.CONNECTION_MODE_E2E) {
+		validationErr.add(prefix+"transfer_parameters.url", FieldErrorNotAllowed, "transfer_parameters is not supported on this endpoint")
+	}
+
+	// piece commitment, a partial one is always invalid
+	pieceCommitment := dealRequest.PieceCommitment
+	if rules.PieceCommitment || (PieceCommitmentRequest{} != pieceCommitment) {
+		if pieceCommitment.Piece == "" {
+			validationErr.add(prefix+"piece_commitment.piece_cid", FieldErrorRequired, "piece_commitment.piece_cid is required")
+		}
+		if pieceCommitment.PaddedPieceSize == 0 && pieceCommitment.UnPaddedPieceSize == 0 {
+			validationErr.add(prefix+"piece_commitment.padded_piece_size", FieldErrorRequired, "piece_commitment.padded_piece_size or piece_commitment.unpadded_piece_size is required")
+		}
+		if dealRequest.Size <= 0 {
+			validationErr.add(prefix+"size", FieldErrorRequired, "size is required with a piece commitment")
+		}
+	}
+	if rules.PieceCommitment && dealRequest.Cid == "" {
+		validationErr.add(prefix+"cid", FieldErrorRequired, "cid is required")
+	}
+
+	wallet := dealRequest.Wallet
+	if (WalletRequest{} != wallet) && wallet.Address == "" && wallet.Uuid == "" && wallet.Id == 0 {
+		validationErr.add(prefix+"wallet", FieldErrorRequired, "wallet address, uuid or id is required")
+	}
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"delta/config"
+	"delta/core"
+	model "delta/models"
+	"delta/utils"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateDealRequest(t *testing.T) {
+	db, err := model.OpenDatabase("file:" + t.Name() + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&model.MinerListEntry{Miner: "f09999", ListType: model.MINER_LIST_BLOCKED, Reason: "faulty"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.DeltaConfig{}
+	cfg.DealLimits.MaxStartEpochInDays = 14
+	cfg.DealLimits.MaxDurationInDays = 540
+	cfg.DealLimits.MaxLabelLength = 10
+	cfg.Common.MaxReplicationFactor = 3
+	node := &core.DeltaNode{DB: db, Config: cfg}
+
+	pieceCommitment := PieceCommitmentRequest{Piece: "baga", PaddedPieceSize: 2048}
+	tests := []struct {
+		name      string
+		kind      string
+		request   DealRequest
+		wantField string // the field of the first error, valid if empty
+		wantCode  string
+	}{
+		{"valid e2e", DealRequestE2E, DealRequest{Miner: "f01000"}, "", ""},
+		{"valid import", DealRequestImport, DealRequest{Cid: "bafy", Size: 1024, PieceCommitment: pieceCommitment}, "", ""},
+		{"valid unverified", DealRequestE2E, DealRequest{DealVerifyState: utils.DEAL_UNVERIFIED, UnverifiedDealMaxPrice: "1000"}, "", ""},
+		{"unknown kind", "other", DealRequest{}, "", FieldErrorInvalid},
+		{"negative start epoch", DealRequestE2E, DealRequest{StartEpochInDays: -1, DurationInDays: 10}, "start_epoch_in_days", FieldErrorInvalid},
+		{"negative duration", DealRequestE2E, DealRequest{StartEpochInDays: 1, DurationInDays: -1}, "duration_in_days", FieldErrorInvalid},
+		{"duration without start epoch", DealRequestE2E, DealRequest{DurationInDays: 10}, "start_epoch_in_days", FieldErrorRequired},
+		{"start epoch without duration", DealRequestE2E, DealRequest{StartEpochInDays: 1}, "duration_in_days", FieldErrorRequired},
+		{"start epoch too late", DealRequestE2E, DealRequest{StartEpochInDays: 15, DurationInDays: 180}, "start_epoch_in_days", FieldErrorTooLarge},
+		{"duration too long", DealRequestE2E, DealRequest{StartEpochInDays: 1, DurationInDays: 541}, "duration_in_days", FieldErrorTooLarge},
+		{"start epoch after duration", DealRequestE2E, DealRequest{StartEpochInDays: 10, DurationInDays: 5}, "start_epoch_in_days", FieldErrorConflict},
+		{"blocked miner", DealRequestE2E, DealRequest{Miner: "f09999"}, "miner", FieldErrorNotAllowed},
+		{"blocked miner of a repair", DealRequestRepair, DealRequest{Miner: "f09999"}, "miner", FieldErrorNotAllowed},
+		{"unknown strategy", DealRequestE2E, DealRequest{MinerSelectionStrategy: "cheapest"}, "miner_selection_strategy", FieldErrorInvalid},
+		{"known strategy", DealRequestE2E, DealRequest{MinerSelectionStrategy: utils.MINER_SELECTION_LOWEST_PRICE}, "", ""},
+		{"auto renew of an e2e deal", DealRequestE2E, DealRequest{AutoRenew: true}, "auto_renew", FieldErrorNotAllowed},
+		{"auto renew of an import", DealRequestImport, DealRequest{Cid: "bafy", Size: 1024, PieceCommitment: pieceCommitment, AutoRenew: true}, "", ""},
+		{"auto replace of an e2e deal", DealRequestE2E, DealRequest{AutoReplace: true}, "auto_replace", FieldErrorNotAllowed},
+		{"auto replace of an import", DealRequestImport, DealRequest{Cid: "bafy", Size: 1024, PieceCommitment: pieceCommitment, AutoReplace: true}, "", ""},
+		{"unknown connection mode", DealRequestContent, DealRequest{ConnectionMode: "ftp"}, "connection_mode", FieldErrorInvalid},
+		{"connection mode of another endpoint", DealRequestE2E, DealRequest{ConnectionMode: utils.CONNECTION_MODE_IMPORT}, "connection_mode", FieldErrorNotAllowed},
+		{"unknown deal verify state", DealRequestE2E, DealRequest{DealVerifyState: "maybe"}, "deal_verify_state", FieldErrorInvalid},
+		{"price of a verified deal", DealRequestE2E, DealRequest{UnverifiedDealMaxPrice: "1000"}, "unverified_deal_max_price", FieldErrorNotAllowed},
+		{"unverified without price", DealRequestE2E, DealRequest{DealVerifyState: utils.DEAL_UNVERIFIED}, "unverified_deal_max_price", FieldErrorRequired},
+		{"fractional price", DealRequestE2E, DealRequest{DealVerifyState: utils.DEAL_UNVERIFIED, UnverifiedDealMaxPrice: "0.5"}, "unverified_deal_max_price", FieldErrorInvalid},
+		{"negative price", DealRequestE2E, DealRequest{DealVerifyState: utils.DEAL_UNVERIFIED, UnverifiedDealMaxPrice: "-1"}, "unverified_deal_max_price", FieldErrorInvalid},
+		{"negative replication", DealRequestE2E, DealRequest{Replication: -1}, "replication", FieldErrorInvalid},
+		{"replication too large", DealRequestE2E, DealRequest{Replication: 4}, "replication", FieldErrorTooLarge},
+		{"replication of an import", DealRequestImport, DealRequest{Cid: "bafy", Size: 1024, PieceCommitment: pieceCommitment, Replication: 1}, "replication", FieldErrorNotAllowed},
+		{"diversity without replication", DealRequestE2E, DealRequest{ReplicationDiversity: utils.REPLICATION_DIVERSITY_OWNER}, "replication_diversity", FieldErrorNotAllowed},
+		{"unknown diversity", DealRequestE2E, DealRequest{Replication: 1, ReplicationDiversity: "region"}, "replication_diversity", FieldErrorInvalid},
+		{"label too long", DealRequestE2E, DealRequest{Label: "a label that is too long"}, "label", FieldErrorTooLarge},
+		{"transfer url of an upload", DealRequestE2E, DealRequest{TransferParameters: TransferParameters{URL: "https://data"}}, "transfer_parameters.url", FieldErrorNotAllowed},
+		{"missing piece cid", DealRequestImport, DealRequest{Cid: "bafy", Size: 1024, PieceCommitment: PieceCommitmentRequest{PaddedPieceSize: 2048}}, "piece_commitment.piece_cid", FieldErrorRequired},
+		{"missing piece size", DealRequestImport, DealRequest{Cid: "bafy", Size: 1024, PieceCommitment: PieceCommitmentRequest{Piece: "baga"}}, "piece_commitment.padded_piece_size", FieldErrorRequired},
+		{"missing size", DealRequestImport, DealRequest{Cid: "bafy", PieceCommitment: pieceCommitment}, "size", FieldErrorRequired},
+		{"missing cid", DealRequestImport, DealRequest{Size: 1024, PieceCommitment: pieceCommitment}, "cid", FieldErrorRequired},
+		{"wallet without identity", DealRequestE2E, DealRequest{Wallet: WalletRequest{KeyType: "secp256k1"}}, "wallet", FieldErrorRequired},
+	}
+	for _, tt := range tests {
+		request := tt.request
+		err := ValidateDealRequest(&request, tt.kind, node)
+		if tt.wantCode == "" {
+			if err != nil {
+				t.Errorf("%s: expected no error, got %v", tt.name, err)
+			}
+			continue
+		}
+		var validationErr *ValidationError
+		if !errors.As(err, &validationErr) {
+			t.Errorf("%s: expected a ValidationError, got %v", tt.name, err)
+			continue
+		}
+		if first := validationErr.Errors[0]; first.Field != tt.wantField || first.Code != tt.wantCode {
+			t.Errorf("%s: expected %s %s, got %+v", tt.name, tt.wantField, tt.wantCode, validationErr.Errors)
+		}
+	}
+
+	// the node keeps the data of the end-to-end deals to renew
+	cfg.Node.KeepCopies = true
+	if err := ValidateDealRequest(&DealRequest{AutoRenew: true, AutoReplace: true}, DealRequestE2E, node); err != nil {
+		t.Fatalf("expected the renewal and the replacement of an e2e deal with the copies kept, got %v", err)
+	}
+	cfg.Node.KeepCopies = false
+
+	// the defaults of a valid request
+	request := DealRequest{}
+	if err := ValidateDealRequest(&request, DealRequestContent, node); err != nil {
+		t.Fatal(err)
+	}
+	if request.ConnectionMode != utils.CONNECTION_MODE_E2E || request.DealVerifyState != utils.DEAL_VERIFIED {
+		t.Fatalf("expected the e2e and verified defaults, got %s and %s", request.ConnectionMode, request.DealVerifyState)
+	}
+}
+
+func TestValidateDealRequests(t *testing.T) {
+	cfg := &config.DeltaConfig{}
+	cfg.DealLimits.MaxStartEpochInDays = 14
+	cfg.DealLimits.MaxDurationInDays = 540
+	cfg.DealLimits.MaxLabelLength = 100
+	node := &core.DeltaNode{Config: cfg}
+
+	var validationErr *ValidationError
+	if err := ValidateDealRequests(nil, DealRequestContent, node); !errors.As(err, &validationErr) || validationErr.Errors[0].Code != FieldErrorRequired {
+		t.Fatalf("expected an error for an empty batch, got %v", err)
+	}
+	err := ValidateDealRequests([]DealRequest{{}, {DurationInDays: -1, StartEpochInDays: 1}}, DealRequestContent, node)
+	if !errors.As(err, &validationErr) || !strings.HasPrefix(validationErr.Errors[0].Field, "[1].") {
+		t.Fatalf("expected the error of the second request, got %v", err)
+	}
+}
+
+func TestValidateDealSize(t *testing.T) {
+	cfg := &config.DeltaConfig{}
+	cfg.DealLimits.MinVerifiedDealSize = 1024
+	tests := []struct {
+		request DealRequest
+		size    int64
+		wantErr bool
+	}{
+		{DealRequest{DealVerifyState: utils.DEAL_VERIFIED}, 1024, false},
+		{DealRequest{DealVerifyState: utils.DEAL_VERIFIED}, 1023, true},
+		{DealRequest{DealVerifyState: utils.DEAL_UNVERIFIED}, 1, false},
+	}
+	for i, tt := range tests {
+		if err := ValidateDealSize(tt.request, tt.size, cfg); (err != nil) != tt.wantErr {
+			t.Errorf("request %d: expected error %v, got %v", i, tt.wantErr, err)
+		}
+	}
+}
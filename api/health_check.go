@@ -18,7 +18,7 @@ func ConfigureHealthCheckRouter(healthCheckApiGroup *echo.Group, node *core.Delt
 	})
 
 	//	health check api
-	healthCheckAuthApiGroup.Use(Authenticate(*DeltaNodeConfig))
+	healthCheckAuthApiGroup.Use(Authenticate(node))
 	healthCheckAuthApiGroup.GET("/ping", func(c echo.Context) error {
 		return c.String(http.StatusOK, "pong")
 	})
@@ -0,0 +1,185 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"delta/core"
+	model "delta/models"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// IdempotencyKeyHeader is the header a client sets to make a deal request safe to retry.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader is set on the responses replayed from an earlier request with the same idempotency key.
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+const maxIdempotencyKeyLength = 255
+
+// idempotentResponseRecorder keeps a copy of the body written to the client, so it can be replayed.
+type idempotentResponseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *idempotentResponseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// checkIdempotencyKey is a middleware that makes the requests with an Idempotency-Key header run once. The request hash
+// and the response of the first request are stored, and a repeat within the retention gets the same response without
+// running again. A repeat while the first request is running gets 409, and a different request with the same key 422.
+// Requests skipped by the skipper, or without the header, are let through.
+func checkIdempotencyKey(next echo.HandlerFunc, node *core.DeltaNode, skipper func(c echo.Context) bool) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		key := c.Request().Header.Get(IdempotencyKeyHeader)
+		if key == "" || (skipper != nil && skipper(c)) {
+			return next(c)
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return c.JSON(http.StatusBadRequest, DealResponse{
+				Status:  "error",
+				Message: "The Idempotency-Key header can only be 255 characters or less",
+			})
+		}
+
+		tenantId := callerTenantId(c)
+		param := core.IdempotencyParam{
+			Key:      key,
+			TenantID: tenantId,
+			Method:   c.Request().Method,
+			Path:     c.Request().URL.Path,
+		}
+
+		requestHash, err := idempotentRequestHash(c)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, DealResponse{
+				Status:  "error",
+				Message: "failed to read the request: " + err.Error(),
+			})
+		}
+
+		// a repeat, replay the response of the first request if it is the same request
+		if record := core.GetIdempotencyKey(node.DB, param, time.Now()); record.ID != 0 {
+			if record.Status == model.IDEMPOTENCY_KEY_COMPLETED {
+				if err := core.MatchIdempotentRequest(record, param, requestHash); err != nil {
+					return idempotencyErrorResponse(c, err)
+				}
+				c.Response().Header().Set(IdempotentReplayedHeader, "true")
+				return c.Blob(record.ResponseStatus, record.ResponseContentType, []byte(record.ResponseBody))
+			}
+			return idempotencyErrorResponse(c, core.ErrIdempotencyKeyInProgress)
+		}
+
+		record, err := core.BeginIdempotentRequest(node.DB, param,
+			time.Duration(node.Config.Idempotency.Retention)*time.Hour,
+			time.Duration(node.Config.Idempotency.LockTimeout)*time.Minute)
+		if err != nil {
+			return idempotencyErrorResponse(c, err)
+		}
+
+		// release the key if the request panics, so it can be sent again
+		completed := false
+		defer func() {
+			if !completed {
+				core.AbortIdempotentRequest(node.DB, record)
+			}
+		}()
+
+		// keep a copy of the response
+		recorder := &idempotentResponseRecorder{ResponseWriter: c.Response().Writer}
+		c.Response().Writer = recorder
+
+		if err := next(c); err != nil {
+			c.Error(err)
+		}
+
+		// the server errors and the busy node are not final, the client can retry them with the same key
+		status := c.Response().Status
+		if status >= http.StatusInternalServerError || status == http.StatusTooManyRequests {
+			return nil
+		}
+		err = core.CompleteIdempotentRequest(node.DB, &record, requestHash, status,
+			c.Response().Header().Get(echo.HeaderContentType), recorder.body.Bytes())
+		completed = err == nil
+		return nil
+	}
+}
+
+// idempotentRequestHash returns the hash of the content of a request. A multipart upload is hashed by its form fields
+// and the digests of its files, streamed from the parsed form the handler reads again, so a retry with another
+// boundary is the same request and the upload isn't held in memory. The other bodies are read once and hashed, the
+// handler reads them again from memory.
+func idempotentRequestHash(c echo.Context) (string, error) {
+	hash := sha256.New()
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEMultipartForm) {
+		form, err := c.MultipartForm()
+		if err != nil {
+			return "", err
+		}
+		files := map[string][]string{}
+		for name, fileHeaders := range form.File {
+			for _, fileHeader := range fileHeaders {
+				digest, err := multipartFileDigest(fileHeader)
+				if err != nil {
+					return "", err
+				}
+				files[name] = append(files[name], fileHeader.Filename+":"+digest)
+			}
+		}
+		// the maps are encoded with their keys sorted
+		if err := json.NewEncoder(hash).Encode(map[string]interface{}{"values": form.Value, "files": files}); err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(hash.Sum(nil)), nil
+	}
+
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return "", err
+	}
+	c.Request().Body = io.NopCloser(bytes.NewReader(body))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// multipartFileDigest returns the sha256 of a file of a multipart form.
+func multipartFileDigest(fileHeader *multipart.FileHeader) (string, error) {
+	file, err := fileHeader.Open()
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// idempotencyErrorResponse answers a request whose idempotency key can't be used.
+func idempotencyErrorResponse(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, core.ErrIdempotencyKeyInProgress):
+		return c.JSON(http.StatusConflict, DealResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+	case errors.Is(err, core.ErrIdempotencyKeyReused):
+		return c.JSON(http.StatusUnprocessableEntity, DealResponse{
+			Status:  "error",
+			Message: err.Error(),
+		})
+	}
+	return err
+}
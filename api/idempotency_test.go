@@ -0,0 +1,78 @@
+package api
+
+import (
+	"bytes"
+	"delta/config"
+	"delta/core"
+	model "delta/models"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestCheckIdempotencyKey_MultipartReplay(t *testing.T) {
+	db, err := model.OpenDatabase("file:" + t.Name() + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.DeltaConfig{}
+	cfg.Idempotency.Retention = 24
+	cfg.Idempotency.LockTimeout = 60
+	node := &core.DeltaNode{DB: db, Config: cfg}
+
+	calls := 0
+	handler := checkIdempotencyKey(func(c echo.Context) error {
+		calls++
+		file, err := c.FormFile("data")
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, map[string]interface{}{"size": file.Size, "metadata": c.FormValue("metadata")})
+	}, node, nil)
+
+	upload := func(boundary string, data string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		if err := writer.SetBoundary(boundary); err != nil {
+			t.Fatal(err)
+		}
+		writer.WriteField("metadata", `{"miner": "f01000"}`)
+		part, _ := writer.CreateFormFile("data", "file.car")
+		part.Write([]byte(data))
+		writer.Close()
+
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/deal/end-to-end", &body)
+		req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+		req.Header.Set(IdempotencyKeyHeader, "upload-1")
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+		c.Set(ApiKeyContextKey, model.ApiKey{TenantID: 1})
+		if err := handler(c); err != nil {
+			t.Fatal(err)
+		}
+		return rec
+	}
+
+	first := upload("first-boundary", "content")
+	if first.Code != http.StatusOK || calls != 1 {
+		t.Fatalf("expected the first upload to run, got %d after %d calls", first.Code, calls)
+	}
+
+	// a retry of the same upload picks another boundary
+	replay := upload("retry-boundary", "content")
+	if replay.Code != http.StatusOK || replay.Header().Get(IdempotentReplayedHeader) != "true" || calls != 1 {
+		t.Fatalf("expected the response to be replayed, got %d after %d calls", replay.Code, calls)
+	}
+	if replay.Body.String() != first.Body.String() {
+		t.Fatalf("expected the response %s, got %s", first.Body.String(), replay.Body.String())
+	}
+
+	// another file with the same key is another request
+	if other := upload("other-boundary", "other content"); other.Code != http.StatusUnprocessableEntity || calls != 1 {
+		t.Fatalf("expected 422 for another upload with the same key, got %d after %d calls", other.Code, calls)
+	}
+}
@@ -18,10 +18,10 @@ func ConfigureNodeInfoRouter(e *echo.Group, node *core.DeltaNode) {
 	nodeGroup.GET("/addr", handleNodeAddr(node))
 	nodeGroup.GET("/peers", handleNodePeers(node))
 	nodeGroup.GET("/host", handleNodeHost(node))
-	nodeGroup.GET("/api-key", handleNodeHostApiKey(node))
+	nodeGroup.GET("/api-key", handleNodeHostApiKey(node), Authenticate(node), RequireApiKeyRole(adminApiKeyRole))
 }
 
-// If the node is in standalone mode, return the API key
+// If the node is in standalone mode, return the API key. Only the admins can see it.
 func handleNodeHostApiKey(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
 		_, span := otel.Tracer("handleNodeHostApiKey").Start(context.Background(), "handleNodeHostApiKey")
@@ -6,6 +6,7 @@ import (
 	"delta/utils"
 	model "delta/models"
 	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
 	"strconv"
 )
 
@@ -13,93 +14,108 @@ import (
 // ConfigureOpenStatsCheckRouter It configures the router to handle the following routes:
 // The first two routes are handled by the `handleOpenStatsByMiner` and `handleOpenGetDealsByMiner` functions,
 // respectively. The last route is handled by the `handleOpenGetTotalsInfo` function
+// The stats only show the contents of the tenant of the caller, and of every tenant to an admin. The reputation of a
+// miner isn't data of a tenant, it needs no API key.
 func ConfigureOpenStatsCheckRouter(e *echo.Group, node *core.DeltaNode) {
-	e.GET("/status/miner/:minerId", func(c echo.Context) error {
+	e.GET("/stats/miner/:minerId/reputation", func(c echo.Context) error {
+		return handleOpenGetMinerReputation(c, node)
+	})
+
+	status := e.Group("/status", Authenticate(node), RequireApiKeyRole(readOnlyApiKeyRole))
+	status.GET("/miner/:minerId", func(c echo.Context) error {
 		return handleOpenStatsByMiner(c, node)
 	})
 
-	e.GET("/status/miner/:minerId/deals", func(c echo.Context) error {
+	status.GET("/miner/:minerId/deals", func(c echo.Context) error {
 		return handleOpenGetDealsByMiner(c, node)
 	})
-	e.GET("/status/batch/imports/:batchId", func(c echo.Context) error {
+	status.GET("/batch/imports/:batchId", func(c echo.Context) error {
 		return handleOpenGetStatsByAllContentsFromBatch(c, node)
 	})
 
-	e.GET("/status/content/:contentId", func(c echo.Context) error {
+	status.GET("/content/:contentId", func(c echo.Context) error {
 		return handleOpenGetStatsByContent(c, node)
 	})
-	e.GET("/status/all-contents", func(c echo.Context) error {
+	status.GET("/content/:contentId/history", func(c echo.Context) error {
+		return handleOpenGetContentStatusHistory(c, node)
+	})
+	status.GET("/all-contents", func(c echo.Context) error {
 		return handleOpenGetStatsByAllContents(c, node)
 	})
-	e.POST("/status/contents", func(c echo.Context) error {
+	status.POST("/contents", func(c echo.Context) error {
 		return handleOpenGetStatsByContents(c, node)
 	})
 
 	// get all deals with paging
-	e.GET("/status/deals", func(c echo.Context) error {
+	status.GET("/deals", func(c echo.Context) error {
 		return handleOpenGetDealsWithPaging(c, node)
 	})
 
-	e.GET("/status/totals/info", func(c echo.Context) error {
+	status.GET("/totals/info", func(c echo.Context) error {
 		return handleOpenGetTotalsInfo(c, node)
 	})
 
-	e.GET("/status/deal/by-cid/:cid", func(c echo.Context) error {
+	status.GET("/deal/by-cid/:cid", func(c echo.Context) error {
 		return handleOpenGetDealByCid(c, node)
 	})
 
-	e.GET("/status/deal/by-uuid/:uuid", func(c echo.Context) error {
+	status.GET("/deal/by-uuid/:uuid", func(c echo.Context) error {
 		return handleOpenGetDealByUuid(c, node)
 	})
 
-	e.GET("/status/deal/by-deal-id/:dealId", func(c echo.Context) error {
+	status.GET("/deal/by-deal-id/:dealId", func(c echo.Context) error {
 		return handleOpenGetDealByDealId(c, node)
 	})
 	////////
-	e.GET("/stats/miner/:minerId", func(c echo.Context) error {
+	stats := e.Group("/stats", Authenticate(node), RequireApiKeyRole(readOnlyApiKeyRole))
+	stats.GET("/miner/:minerId", func(c echo.Context) error {
 		return handleOpenStatsByMiner(c, node)
 	})
 
-	e.GET("/stats/miner/:minerId/deals", func(c echo.Context) error {
+	stats.GET("/miner/:minerId/deals", func(c echo.Context) error {
 		return handleOpenGetDealsByMiner(c, node)
 	})
-	e.GET("/stats/batch/imports/:batchId", func(c echo.Context) error {
+	stats.GET("/batch/imports/:batchId", func(c echo.Context) error {
 		return handleOpenGetStatsByAllContentsFromBatch(c, node)
 	})
 
-	e.GET("/stats/content/:contentId", func(c echo.Context) error {
+	stats.GET("/content/:contentId", func(c echo.Context) error {
 		return handleOpenGetStatsByContent(c, node)
 	})
-	e.GET("/stats/all-contents", func(c echo.Context) error {
+	stats.GET("/content/:contentId/history", func(c echo.Context) error {
+		return handleOpenGetContentStatusHistory(c, node)
+	})
+	stats.GET("/all-contents", func(c echo.Context) error {
 		return handleOpenGetStatsByAllContents(c, node)
 	})
-	e.POST("/stats/contents", func(c echo.Context) error {
+	stats.POST("/contents", func(c echo.Context) error {
 		return handleOpenGetStatsByContents(c, node)
 	})
 
 	// get all deals with paging
-	e.GET("/stats/deals", func(c echo.Context) error {
+	stats.GET("/deals", func(c echo.Context) error {
 		return handleOpenGetDealsWithPaging(c, node)
 	})
 
-	e.GET("/stats/totals/info", func(c echo.Context) error {
+	stats.GET("/totals/info", func(c echo.Context) error {
 		return handleOpenGetTotalsInfo(c, node)
 	})
 
-	e.GET("/stats/deal/by-cid/:cid", func(c echo.Context) error {
+	stats.GET("/deal/by-cid/:cid", func(c echo.Context) error {
 		return handleOpenGetDealByCid(c, node)
 	})
 
-	e.GET("/stats/deal/by-uuid/:uuid", func(c echo.Context) error {
+	stats.GET("/deal/by-uuid/:uuid", func(c echo.Context) error {
 		return handleOpenGetDealByUuid(c, node)
 	})
 
-	e.GET("/stats/deal/by-deal-id/:dealId", func(c echo.Context) error {
+	stats.GET("/deal/by-deal-id/:dealId", func(c echo.Context) error {
 		return handleOpenGetDealByDealId(c, node)
 	})
 }
 
 func handleOpenGetDealsWithPaging(c echo.Context, node *core.DeltaNode) error {
+	scope := callerStatsParam(c)
 
 	// get page number
 	page, err := strconv.Atoi(c.QueryParam("page"))
@@ -109,7 +125,7 @@ func handleOpenGetDealsWithPaging(c echo.Context, node *core.DeltaNode) error {
 
 	// total
 	var total int64
-	node.DB.Model(&model.ContentDeal{}).Count(&total)
+	node.DB.Table("content_deals cd").Joins("JOIN contents c ON cd.content = c.id").Where(core.TenantScope, scope.AllTenants, scope.TenantID).Count(&total)
 
 	// get page size
 	pageSize, err := strconv.Atoi(c.QueryParam("page_size"))
@@ -120,9 +136,11 @@ func handleOpenGetDealsWithPaging(c echo.Context, node *core.DeltaNode) error {
 	var deals []model.ContentDeal
 
 	// Subquery to find the latest deal for each content
-	subQuery := node.DB.Table("content_deals").
-		Select("MAX(id) AS max_id, content").
-		Group("content")
+	subQuery := node.DB.Table("content_deals cd").
+		Select("MAX(cd.id) AS max_id, cd.content").
+		Joins("JOIN contents c ON cd.content = c.id").
+		Where(core.TenantScope, scope.AllTenants, scope.TenantID).
+		Group("cd.content")
 
 	// Execute main query with LIMIT and OFFSET clauses for paging
 	err = node.DB.Table("content_deals c1").
@@ -148,10 +166,10 @@ func handleOpenGetDealsWithPaging(c echo.Context, node *core.DeltaNode) error {
 // It gets the content deal, content, content deal proposal, and piece commitment from the database and returns them as
 // JSON
 func handleOpenGetDealByCid(c echo.Context, node *core.DeltaNode) error {
+	scope := callerStatsParam(c)
 
 	var content model.Content
-	node.DB.Raw("select * from contents where cid = ?", c.Param("cid")).Scan(&content)
-	content.RequestingApiKey = ""
+	node.DB.Raw("select c.* from contents c where c.cid = ? and "+core.TenantScope, c.Param("cid"), scope.AllTenants, scope.TenantID).Scan(&content)
 
 	var contentDeal model.ContentDeal
 	node.DB.Raw("select * from content_deals where content = ?", content.ID).Scan(&contentDeal)
@@ -164,9 +182,7 @@ func handleOpenGetDealByCid(c echo.Context, node *core.DeltaNode) error {
 
 	// check the deal status async
 	if content.Status == utils.DEAL_STATUS_TRANSFER_STARTED || content.Status == utils.CONTENT_DEAL_PROPOSAL_SENT || content.Status == utils.DEAL_STATUS_TRANSFER_FINISHED {
-		job := core.CreateNewDispatcher()
-		job.AddJob(jobs.NewDealStatusCheck(node, &content))
-		job.Start(1)
+		node.Dispatcher.AddJob(jobs.NewDealStatusCheck(node, &content))
 	}
 
 	return c.JSON(200, map[string]interface{}{
@@ -180,12 +196,13 @@ func handleOpenGetDealByCid(c echo.Context, node *core.DeltaNode) error {
 // It gets the content deal, content, content deal proposal, and piece commitment from the database and returns them as
 // JSON
 func handleOpenGetDealByDealId(c echo.Context, node *core.DeltaNode) error {
+	scope := callerStatsParam(c)
+
 	var contentDeal model.ContentDeal
-	node.DB.Raw("select * from content_deals where deal_id = ?", c.Param("dealId")).Scan(&contentDeal)
+	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and cd.deal_id = ? and "+core.TenantScope, c.Param("dealId"), scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
 	var content model.Content
 	node.DB.Raw("select * from contents where id = ?", contentDeal.Content).Scan(&content)
-	content.RequestingApiKey = ""
 
 	var contentDealProposal model.ContentDealProposal
 	node.DB.Raw("select * from content_deal_proposals where content = ?", content.ID).Scan(&contentDealProposal)
@@ -195,9 +212,7 @@ func handleOpenGetDealByDealId(c echo.Context, node *core.DeltaNode) error {
 
 	// check the deal status async
 	if content.Status == utils.DEAL_STATUS_TRANSFER_STARTED || content.Status == utils.CONTENT_DEAL_PROPOSAL_SENT || content.Status == utils.DEAL_STATUS_TRANSFER_FINISHED {
-		job := core.CreateNewDispatcher()
-		job.AddJob(jobs.NewDealStatusCheck(node, &content))
-		job.Start(1)
+		node.Dispatcher.AddJob(jobs.NewDealStatusCheck(node, &content))
 	}
 
 	return c.JSON(200, map[string]interface{}{
@@ -209,12 +224,13 @@ func handleOpenGetDealByDealId(c echo.Context, node *core.DeltaNode) error {
 }
 
 func handleOpenGetDealByUuid(c echo.Context, node *core.DeltaNode) error {
+	scope := callerStatsParam(c)
+
 	var contentDeal model.ContentDeal
-	node.DB.Raw("select * from content_deals where deal_uuid = ?", c.Param("uuid")).Scan(&contentDeal)
+	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and cd.deal_uuid = ? and "+core.TenantScope, c.Param("uuid"), scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
 	var content model.Content
 	node.DB.Raw("select * from contents where id = ?", contentDeal.Content).Scan(&content)
-	content.RequestingApiKey = ""
 
 	var contentDealProposal model.ContentDealProposal
 	node.DB.Raw("select * from content_deal_proposals where content = ?", content.ID).Scan(&contentDealProposal)
@@ -224,9 +240,7 @@ func handleOpenGetDealByUuid(c echo.Context, node *core.DeltaNode) error {
 
 	// check the deal status async
 	if content.Status == utils.DEAL_STATUS_TRANSFER_STARTED || content.Status == utils.CONTENT_DEAL_PROPOSAL_SENT || content.Status == utils.DEAL_STATUS_TRANSFER_FINISHED {
-		job := core.CreateNewDispatcher()
-		job.AddJob(jobs.NewDealStatusCheck(node, &content))
-		job.Start(1)
+		node.Dispatcher.AddJob(jobs.NewDealStatusCheck(node, &content))
 	}
 	return c.JSON(200, map[string]interface{}{
 		"content":          content,
@@ -239,27 +253,24 @@ func handleOpenGetDealByUuid(c echo.Context, node *core.DeltaNode) error {
 // Getting the content consumed by a miner, the content deals by a miner, the piece commitments by a miner, and the content
 // deal proposals by a miner.
 func handleOpenStatsByMiner(c echo.Context, node *core.DeltaNode) error {
+	scope := callerStatsParam(c)
 
 	// get content consumed by miner
 	var content []model.Content
-	node.DB.Raw("select c.* from contents c, content_miner cma where c.id = cma.content and cma.miner = ?", c.Param("minerId")).Scan(&content)
-	// TODO: remove api key
-	for i := range content {
-		content[i].RequestingApiKey = ""
-	}
+	node.DB.Raw("select c.* from contents c, content_miners cma where c.id = cma.content and cma.miner = ? and "+core.TenantScope, c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&content)
 
 	// get content deals by miner
 	var contentDeal []model.ContentDeal
-	node.DB.Raw("select cd.* from content_deals cd, content_miners cma where cd.content = cma.content and cma.miner = ?", c.Param("minerId")).Scan(&contentDeal)
+	node.DB.Raw("select cd.* from content_deals cd, content_miners cma, contents c where cd.content = cma.content and cd.content = c.id and cma.miner = ? and "+core.TenantScope, c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
 	var pieceCommitments []model.PieceCommitment
-	node.DB.Raw("select pc.* from piece_commitments pc, content_deals cd, content_miners cma where pc.content_deal = cd.id and cd.content = cma.content and cma.miner = ?", c.Param("minerId")).Scan(&pieceCommitments)
+	node.DB.Raw("select pc.* from piece_commitments pc, content_deals cd, content_miners cma, contents c where pc.content_deal = cd.id and cd.content = cma.content and cd.content = c.id and cma.miner = ? and "+core.TenantScope, c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&pieceCommitments)
 
 	var contentDealProposal []model.ContentDealProposal
-	node.DB.Raw("select cdp.* from content_deal_proposals cdp, content_deals cd, content_miners cma where cdp.content = cd.content and cd.content = cma.content and cma.miner = ?", c.Param("minerId")).Scan(&contentDealProposal)
+	node.DB.Raw("select cdp.* from content_deal_proposals cdp, content_deals cd, content_miners cma, contents c where cdp.content = cd.content and cd.content = cma.content and cd.content = c.id and cma.miner = ? and "+core.TenantScope, c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&contentDealProposal)
 
 	var contentDealProposalParameters []model.ContentDealProposalParameters
-	node.DB.Raw("select cdp.* from content_deal_proposal_parameters cdp, content_deal_proposals cdp2, content_deals cd, content_miners cma where cdp.content_deal_proposal = cdp2.id and cdp2.content = cd.content and cd.content = cma.content and cma.miner = ?", c.Param("minerId")).Scan(&contentDealProposalParameters)
+	node.DB.Raw("select cdp.* from content_deal_proposal_parameters cdp, content_deal_proposals cdp2, content_deals cd, content_miners cma, contents c where cdp.content_deal_proposal = cdp2.id and cdp2.content = cd.content and cd.content = cma.content and cd.content = c.id and cma.miner = ? and "+core.TenantScope, c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&contentDealProposalParameters)
 
 	return c.JSON(200, map[string]interface{}{
 		"content":           content,
@@ -272,9 +283,10 @@ func handleOpenStatsByMiner(c echo.Context, node *core.DeltaNode) error {
 
 // function to get all deals of a given miner
 func handleOpenGetDealsByMiner(c echo.Context, node *core.DeltaNode) error {
+	scope := callerStatsParam(c)
 
 	var contentDeal []model.ContentDeal
-	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and cd.miner = ?", c.Param("minerId")).Scan(&contentDeal)
+	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and cd.miner = ? and "+core.TenantScope, c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
 	return c.JSON(200, map[string]interface{}{
 		"deals": contentDeal,
@@ -282,29 +294,46 @@ func handleOpenGetDealsByMiner(c echo.Context, node *core.DeltaNode) error {
 
 }
 
+// function to get the reputation of a given miner
+func handleOpenGetMinerReputation(c echo.Context, node *core.DeltaNode) error {
+	reputation := core.GetMinerReputation(node.DB, c.Param("minerId"))
+	if reputation.ID == 0 {
+		return c.JSON(404, map[string]interface{}{
+			"message": "no reputation computed for miner " + c.Param("minerId"),
+		})
+	}
+	return c.JSON(200, map[string]interface{}{
+		"reputation": reputation,
+	})
+}
+
 // function to get all totals info
 func handleOpenGetTotalsInfo(c echo.Context, node *core.DeltaNode) error {
+	scope := callerStatsParam(c)
+	tenantScope := func(query string) *gorm.DB {
+		return node.DB.Raw(query+" and "+core.TenantScope, scope.AllTenants, scope.TenantID)
+	}
 
 	var totalContentConsumed int64
-	node.DB.Raw("select count(*) from contents").Scan(&totalContentConsumed)
+	tenantScope("select count(*) from contents c where true").Scan(&totalContentConsumed)
 
 	var totalTransferStarted int64
-	node.DB.Raw("select count(*) from contents where status = 'transfer-started'").Scan(&totalTransferStarted)
+	tenantScope("select count(*) from contents c where c.status = 'transfer-started'").Scan(&totalTransferStarted)
 
 	var totalTransferFinished int64
-	node.DB.Raw("select count(*) from contents where status = 'transfer-finished'").Scan(&totalTransferFinished)
+	tenantScope("select count(*) from contents c where c.status = 'transfer-finished'").Scan(&totalTransferFinished)
 
 	var totalProposalMade int64
-	node.DB.Raw("select count(*) from content_deal_proposals").Scan(&totalProposalMade)
+	tenantScope("select count(*) from content_deal_proposals cdp, contents c where cdp.content = c.id").Scan(&totalProposalMade)
 
 	var totalCommitmentPiece int64
-	node.DB.Raw("select count(*) from piece_commitments").Scan(&totalCommitmentPiece)
+	tenantScope("select count(*) from piece_commitments pc, contents c where c.piece_commitment_id = pc.id").Scan(&totalCommitmentPiece)
 
 	var totalPieceCommitted int64
-	node.DB.Raw("select count(*) from piece_commitments where status = 'committed'").Scan(&totalPieceCommitted)
+	tenantScope("select count(*) from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and pc.status = 'committed'").Scan(&totalPieceCommitted)
 
 	var totalMiners int64
-	rows, err := node.DB.Raw("select distinct(miner) from content_miners").Rows()
+	rows, err := tenantScope("select distinct(cma.miner) from content_miners cma, contents c where cma.content = c.id").Rows()
 	if err != nil {
 		return err
 	}
@@ -316,25 +345,25 @@ func handleOpenGetTotalsInfo(c echo.Context, node *core.DeltaNode) error {
 	}
 
 	var totalStorageAllocated int64
-	node.DB.Raw("select sum(size) from contents").Scan(&totalStorageAllocated)
+	tenantScope("select sum(c.size) from contents c where true").Scan(&totalStorageAllocated)
 
 	var totalProposalSent int64
-	node.DB.Raw("select count(*) from contents where status = 'deal-proposal-sent'").Scan(&totalProposalSent)
+	tenantScope("select count(*) from contents c where c.status = 'deal-proposal-sent'").Scan(&totalProposalSent)
 
 	var totalSealedDealInBytes int64
-	node.DB.Raw("select sum(size) from contents where status in ('transfer-started','transfer-finished','deal-proposal-sent')").Scan(&totalSealedDealInBytes)
+	tenantScope("select sum(c.size) from contents c where c.status in ('transfer-started','transfer-finished','deal-proposal-sent')").Scan(&totalSealedDealInBytes)
 
 	var totalImportDeals int64
-	node.DB.Raw("select count(*) from contents where connection_mode = 'import'").Scan(&totalImportDeals)
+	tenantScope("select count(*) from contents c where c.connection_mode = 'import'").Scan(&totalImportDeals)
 
 	var totalE2EDeals int64
-	node.DB.Raw("select count(*) from contents where connection_mode = 'e2e'").Scan(&totalE2EDeals)
+	tenantScope("select count(*) from contents c where c.connection_mode = 'e2e'").Scan(&totalE2EDeals)
 
 	var totalE2EDealsInBytes int64
-	node.DB.Raw("select sum(size) from contents where connection_mode = 'e2e'").Scan(&totalE2EDealsInBytes)
+	tenantScope("select sum(c.size) from contents c where c.connection_mode = 'e2e'").Scan(&totalE2EDealsInBytes)
 
 	var totalImportDealsInBytes int64
-	node.DB.Raw("select sum(size) from contents where connection_mode = 'import'").Scan(&totalImportDealsInBytes)
+	tenantScope("select sum(c.size) from contents c where c.connection_mode = 'import'").Scan(&totalImportDealsInBytes)
 
 	c.JSON(200, map[string]interface{}{
 		"total_content_consumed":      totalContentConsumed,
@@ -356,35 +385,36 @@ func handleOpenGetTotalsInfo(c echo.Context, node *core.DeltaNode) error {
 }
 
 func handleOpenGetStatsByAllContentsFromBatch(c echo.Context, node *core.DeltaNode) error {
+	scope := callerStatsParam(c)
 
 	batchImportId := c.Param("batchId")
 	var contentIds []int64
-	node.DB.Raw("select content_id from batch_import_contents where batch_import_id = ?", batchImportId).Scan(&contentIds)
+	node.DB.Raw("select bic.content_id from batch_import_contents bic, batch_imports bi where bic.batch_import_id = bi.id and bi.id = ? and (? or bi.tenant_id = ?)", batchImportId, scope.AllTenants, scope.TenantID).Scan(&contentIds)
 
 	var contentResponse []map[string]interface{}
 	for _, contentId := range contentIds {
 
 		var content model.Content
-		node.DB.Raw("select c.* from contents c where c.id = ?", contentId).Scan(&content)
-		content.RequestingApiKey = ""
+		node.DB.Raw("select c.* from contents c where c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&content)
+		if content.ID == 0 {
+			continue
+		}
 
 		var contentDeal []model.ContentDeal
-		node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.id = ?", contentId).Scan(&contentDeal)
+		node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
 		var pieceCommitments []model.PieceCommitment
-		node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.id = ?", contentId).Scan(&pieceCommitments)
+		node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&pieceCommitments)
 
 		var contentDealProposal []model.ContentDealProposal
-		node.DB.Raw("select cdp.* from content_deal_proposals cdp, contents c where cdp.content = c.id and c.id = ?", contentId).Scan(&contentDealProposal)
+		node.DB.Raw("select cdp.* from content_deal_proposals cdp, contents c where cdp.content = c.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&contentDealProposal)
 
 		var contentDealProposalParameters []model.ContentDealProposalParameters
-		node.DB.Raw("select cdp.* from content_deal_proposal_parameters cdp, contents c where cdp.content = c.id and c.id = ?", contentId).Scan(&contentDealProposalParameters)
+		node.DB.Raw("select cdp.* from content_deal_proposal_parameters cdp, contents c where cdp.content = c.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&contentDealProposalParameters)
 
 		// check the deal status async
 		if content.Status == utils.DEAL_STATUS_TRANSFER_STARTED || content.Status == utils.CONTENT_DEAL_PROPOSAL_SENT || content.Status == utils.DEAL_STATUS_TRANSFER_FINISHED {
-			job := core.CreateNewDispatcher()
-			job.AddJob(jobs.NewDealStatusCheck(node, &content))
-			job.Start(1)
+			node.Dispatcher.AddJob(jobs.NewDealStatusCheck(node, &content))
 		}
 
 		contentResponse = append(contentResponse, map[string]interface{}{
@@ -400,34 +430,35 @@ func handleOpenGetStatsByAllContentsFromBatch(c echo.Context, node *core.DeltaNo
 }
 
 func handleOpenGetStatsByAllContents(c echo.Context, node *core.DeltaNode) error {
+	scope := callerStatsParam(c)
 
 	var contentIds []int64
-	node.DB.Raw("select id from contents").Scan(&contentIds)
+	node.DB.Raw("select c.id from contents c where "+core.TenantScope, scope.AllTenants, scope.TenantID).Scan(&contentIds)
 
 	var contentResponse []map[string]interface{}
 	for _, contentId := range contentIds {
 
 		var content model.Content
-		node.DB.Raw("select c.* from contents c where c.id = ?", contentId).Scan(&content)
-		content.RequestingApiKey = ""
+		node.DB.Raw("select c.* from contents c where c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&content)
+		if content.ID == 0 {
+			continue
+		}
 
 		var contentDeal []model.ContentDeal
-		node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.id = ?", contentId).Scan(&contentDeal)
+		node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
 		var pieceCommitments []model.PieceCommitment
-		node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.id = ?", contentId).Scan(&pieceCommitments)
+		node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&pieceCommitments)
 
 		var contentDealProposal []model.ContentDealProposal
-		node.DB.Raw("select cdp.* from content_deal_proposals cdp, contents c where cdp.content = c.id and c.id = ?", contentId).Scan(&contentDealProposal)
+		node.DB.Raw("select cdp.* from content_deal_proposals cdp, contents c where cdp.content = c.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&contentDealProposal)
 
 		var contentDealProposalParameters []model.ContentDealProposalParameters
-		node.DB.Raw("select cdp.* from content_deal_proposal_parameters cdp, contents c where cdp.content = c.id and c.id = ?", contentId).Scan(&contentDealProposalParameters)
+		node.DB.Raw("select cdp.* from content_deal_proposal_parameters cdp, contents c where cdp.content = c.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&contentDealProposalParameters)
 
 		// check the deal status async
 		if content.Status == utils.DEAL_STATUS_TRANSFER_STARTED || content.Status == utils.CONTENT_DEAL_PROPOSAL_SENT || content.Status == utils.DEAL_STATUS_TRANSFER_FINISHED {
-			job := core.CreateNewDispatcher()
-			job.AddJob(jobs.NewDealStatusCheck(node, &content))
-			job.Start(1)
+			node.Dispatcher.AddJob(jobs.NewDealStatusCheck(node, &content))
 		}
 
 		contentResponse = append(contentResponse, map[string]interface{}{
@@ -444,6 +475,7 @@ func handleOpenGetStatsByAllContents(c echo.Context, node *core.DeltaNode) error
 
 // A function that is called when a GET request is made to the /open/get_stats_by_contents endpoint.
 func handleOpenGetStatsByContents(c echo.Context, node *core.DeltaNode) error {
+	scope := callerStatsParam(c)
 
 	var contentIds []int64
 	c.Bind(&contentIds)
@@ -452,26 +484,26 @@ func handleOpenGetStatsByContents(c echo.Context, node *core.DeltaNode) error {
 	for _, contentId := range contentIds {
 
 		var content model.Content
-		node.DB.Raw("select c.* from contents c where c.id = ?", contentId).Scan(&content)
-		content.RequestingApiKey = ""
+		node.DB.Raw("select c.* from contents c where c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&content)
+		if content.ID == 0 {
+			continue
+		}
 
 		var contentDeal []model.ContentDeal
-		node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.id = ?", contentId).Scan(&contentDeal)
+		node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
 		var pieceCommitments []model.PieceCommitment
-		node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.id = ?", contentId).Scan(&pieceCommitments)
+		node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&pieceCommitments)
 
 		var contentDealProposal []model.ContentDealProposal
-		node.DB.Raw("select cdp.* from content_deal_proposals cdp, contents c where cdp.content = c.id and c.id = ?", contentId).Scan(&contentDealProposal)
+		node.DB.Raw("select cdp.* from content_deal_proposals cdp, contents c where cdp.content = c.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&contentDealProposal)
 
 		var contentDealProposalParameters []model.ContentDealProposalParameters
-		node.DB.Raw("select cdp.* from content_deal_proposal_parameters cdp, contents c where cdp.content = c.id and c.id = ?", contentId).Scan(&contentDealProposalParameters)
+		node.DB.Raw("select cdp.* from content_deal_proposal_parameters cdp, contents c where cdp.content = c.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&contentDealProposalParameters)
 
 		// check the deal status async
 		if content.Status == utils.DEAL_STATUS_TRANSFER_STARTED || content.Status == utils.CONTENT_DEAL_PROPOSAL_SENT || content.Status == utils.DEAL_STATUS_TRANSFER_FINISHED {
-			job := core.CreateNewDispatcher()
-			job.AddJob(jobs.NewDealStatusCheck(node, &content))
-			job.Start(1)
+			node.Dispatcher.AddJob(jobs.NewDealStatusCheck(node, &content))
 		}
 
 		contentResponse = append(contentResponse, map[string]interface{}{
@@ -486,29 +518,57 @@ func handleOpenGetStatsByContents(c echo.Context, node *core.DeltaNode) error {
 
 }
 
+// handleOpenGetContentStatusHistory returns every status change of a content, of its deals and of its piece
+// commitment, the oldest first.
+func handleOpenGetContentStatusHistory(c echo.Context, node *core.DeltaNode) error {
+	contentId, err := strconv.ParseInt(c.Param("contentId"), 10, 64)
+	if err != nil {
+		return c.JSON(400, map[string]interface{}{
+			"message": "invalid content id",
+		})
+	}
+	scope := callerStatsParam(c)
+	var content model.Content
+	node.DB.Raw("select c.* from contents c where c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&content)
+	if content.ID == 0 {
+		return c.JSON(404, map[string]interface{}{
+			"message": "content not found",
+		})
+	}
+	history, err := core.GetContentStatusHistory(node.DB, contentId)
+	if err != nil {
+		return c.JSON(404, map[string]interface{}{
+			"message": err.Error(),
+		})
+	}
+	return c.JSON(200, map[string]interface{}{
+		"content_id": contentId,
+		"history":    history,
+	})
+}
+
 // function to get all stats given a content id and user api key
 func handleOpenGetStatsByContent(c echo.Context, node *core.DeltaNode) error {
+	scope := callerStatsParam(c)
+
 	var content model.Content
-	node.DB.Raw("select c.* from contents c where c.id = ?", c.Param("contentId")).Scan(&content)
-	content.RequestingApiKey = ""
+	node.DB.Raw("select c.* from contents c where c.id = ? and "+core.TenantScope, c.Param("contentId"), scope.AllTenants, scope.TenantID).Scan(&content)
 
 	var contentDeal []model.ContentDeal
-	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.id = ?", c.Param("contentId")).Scan(&contentDeal)
+	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.id = ? and "+core.TenantScope, c.Param("contentId"), scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
 	var pieceCommitments []model.PieceCommitment
-	node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.id = ?", c.Param("contentId")).Scan(&pieceCommitments)
+	node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.id = ? and "+core.TenantScope, c.Param("contentId"), scope.AllTenants, scope.TenantID).Scan(&pieceCommitments)
 
 	var contentDealProposal []model.ContentDealProposal
-	node.DB.Raw("select cdp.* from content_deal_proposals cdp, contents c where cdp.content = c.id and c.id = ?", c.Param("contentId")).Scan(&contentDealProposal)
+	node.DB.Raw("select cdp.* from content_deal_proposals cdp, contents c where cdp.content = c.id and c.id = ? and "+core.TenantScope, c.Param("contentId"), scope.AllTenants, scope.TenantID).Scan(&contentDealProposal)
 
 	var contentDealProposalParameters []model.ContentDealProposalParameters
-	node.DB.Raw("select cdp.* from content_deal_proposal_parameters cdp, contents c where cdp.content = c.id and c.id = ?", c.Param("contentId")).Scan(&contentDealProposalParameters)
+	node.DB.Raw("select cdp.* from content_deal_proposal_parameters cdp, contents c where cdp.content = c.id and c.id = ? and "+core.TenantScope, c.Param("contentId"), scope.AllTenants, scope.TenantID).Scan(&contentDealProposalParameters)
 
 	// check the deal status async
 	if content.Status == utils.DEAL_STATUS_TRANSFER_STARTED || content.Status == utils.CONTENT_DEAL_PROPOSAL_SENT || content.Status == utils.DEAL_STATUS_TRANSFER_FINISHED {
-		job := core.CreateNewDispatcher()
-		job.AddJob(jobs.NewDealStatusCheck(node, &content))
-		job.Start(1)
+		node.Dispatcher.AddJob(jobs.NewDealStatusCheck(node, &content))
 	}
 
 	return c.JSON(200, map[string]interface{}{
@@ -7,7 +7,7 @@ import (
 	"encoding/json"
 	model "delta/models"
 	"github.com/labstack/echo/v4"
-	"strings"
+	"gorm.io/gorm"
 	"time"
 )
 
@@ -36,14 +36,24 @@ type ImportRetryResponse struct {
 // It's a function that configures the repair router
 func ConfigureRepairRouter(e *echo.Group, node *core.DeltaNode) {
 
+	// idempotency key middleware, the repairs and retries make deals on GET as well
+	idempotencyKey := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return checkIdempotencyKey(next, node, nil)
+	}
+
+	// tenant quota middleware, the repairs and retries make deals on GET as well
+	tenantQuota := func(next echo.HandlerFunc) echo.HandlerFunc {
+		return checkTenantQuota(next, node, nil)
+	}
+
 	// repair with a different (miner, duration only)
-	repair := e.Group("/repair")
+	repair := e.Group("/repair", idempotencyKey, tenantQuota)
 	repair.GET("/deal/end-to-end/:contentId", handleRepairDealContent(node))
 	repair.GET("/deal/import/:contentId", handleRepairImportContent(node))
 	repair.POST("/deal/imports", handleRepairMultipleImport(node))
 
 	// retry
-	retry := e.Group("/retry")
+	retry := e.Group("/retry", idempotencyKey, tenantQuota)
 	retry.GET("/deal/end-to-end/:contentId", handleRetryDealContent(node))
 	retry.GET("/deal/import/:contentId", handleRetryDealImport(node))
 	retry.POST("/deal/imports", handleRetryMultipleImport(node))
@@ -52,20 +62,21 @@ func ConfigureRepairRouter(e *echo.Group, node *core.DeltaNode) {
 	autoRetry := e.Group("/auto-retry")
 	autoRetry.GET("/deal/disable/:contentId", handleDisableAutoRetry(node))
 	autoRetry.GET("/deal/enable/:contentId", handleEnableAutoRetry(node))
+
+	// top up the failed or slashed copies of a replication group
+	repair.POST("/replication-group/:groupId/top-up", handleTopUpReplicationGroup(node))
 }
 
 func handleDisableAutoRetry(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
 
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
+		tenantId := callerTenantId(c)
 
 		var contentId = c.Param("contentId")
 		var content model.Content
 
-		node.DB.Model(&model.Content{}).Where("id = ? AND requesting_api_key = ?", contentId, authParts[1]).First(&content)
-		content.AutoRetry = false
-		node.DB.Model(&model.Content{}).Save(&content)
+		node.DB.Model(&model.Content{}).Where("id = ? AND tenant_id = ?", contentId, tenantId).First(&content)
+		node.DB.Model(&model.Content{}).Where("id = ?", content.ID).Update("auto_retry", false)
 		return nil
 	}
 }
@@ -74,33 +85,112 @@ func handleDisableAutoRetry(node *core.DeltaNode) func(c echo.Context) error {
 func handleEnableAutoRetry(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
 
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
+		tenantId := callerTenantId(c)
 
 		var contentId = c.Param("contentId")
 		var content model.Content
 
-		node.DB.Model(&model.Content{}).Where("id = ? AND requesting_api_key = ?", contentId, authParts[1]).First(&content)
-		content.AutoRetry = true
-		node.DB.Model(&model.Content{}).Save(&content)
+		node.DB.Model(&model.Content{}).Where("id = ? AND tenant_id = ?", contentId, tenantId).First(&content)
+		node.DB.Model(&model.Content{}).Where("id = ?", content.ID).Update("auto_retry", true)
 		return nil
 	}
 }
 
+// handleTopUpReplicationGroup creates new replicas for the copies of a replication group that failed or were slashed,
+// on storage providers that no copy of the group has used.
+func handleTopUpReplicationGroup(node *core.DeltaNode) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		tenantId := callerTenantId(c)
+
+		var replicationGroup model.ReplicationGroup
+		node.DB.Model(&model.ReplicationGroup{}).Where("id = ? and tenant_id = ?", c.Param("groupId"), tenantId).Find(&replicationGroup)
+		if replicationGroup.ID == 0 {
+			return c.JSON(404, map[string]interface{}{
+				"message": "replication group not found",
+			})
+		}
+
+		status, err := core.GetReplicationGroupStatus(node.DB, replicationGroup.ID)
+		if err != nil {
+			return c.JSON(500, map[string]interface{}{
+				"message": err.Error(),
+			})
+		}
+		missing := status.MissingCopies()
+		if missing == 0 {
+			return c.JSON(200, map[string]interface{}{
+				"message":           "replication group has no failed or slashed copies",
+				"replication_group": status,
+			})
+		}
+
+		var content model.Content
+		node.DB.Model(&model.Content{}).Where("id = ?", replicationGroup.SourceContent).Find(&content)
+		var dealProposalParam model.ContentDealProposalParameters
+		node.DB.Model(&model.ContentDealProposalParameters{}).Where("content = ?", content.ID).Find(&dealProposalParam)
+		if content.ID == 0 || dealProposalParam.ID == 0 {
+			return c.JSON(500, map[string]interface{}{
+				"message": "source content of the replication group not found",
+			})
+		}
+		if content.ConnectionMode != utils.CONNECTION_MODE_E2E {
+			return c.JSON(400, map[string]interface{}{
+				"message": "content is not in end-to-end mode",
+			})
+		}
+
+		// the new copies start over from the piece commitment
+		content.Status = utils.CONTENT_PINNED
+		content.LastMessage = ""
+		content.PieceCommitmentId = 0
+		content.NextRetryAt = time.Time{}
+		content.CreatedAt = time.Now()
+		content.UpdatedAt = time.Now()
+
+		var replicatedContents []ReplicatedContent
+		errTxn := node.DB.Transaction(func(tx *gorm.DB) error {
+			replicatedContents, err = ReplicateContent(node, DealReplication{
+				Content:                      content,
+				ContentDealProposalParameter: dealProposalParam,
+			}, DealRequest{
+				Replication:          missing,
+				ReplicationDiversity: replicationGroup.Diversity,
+			}, tx)
+			return err
+		})
+		if errTxn != nil {
+			return c.JSON(500, map[string]interface{}{
+				"message": "failed to top up the replication group",
+				"error":   errTxn.Error(),
+			})
+		}
+
+		var dealResponses []DealResponse
+		for _, contentRep := range replicatedContents {
+			node.Dispatcher.AddJob(jobs.NewPieceCommpProcessor(node, contentRep.Content))
+			dealResponses = append(dealResponses, contentRep.DealResponse)
+		}
+
+		return c.JSON(200, map[string]interface{}{
+			"message":             "successfully topped up the replication group",
+			"replication_group":   replicationGroup.ID,
+			"replicated_contents": dealResponses,
+		})
+	}
+}
+
 // > This function handles the retry of a deal content
 // This function handles retrying a content deal and returns a JSON response.
 func handleRetryDealContent(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
 
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
+		tenantId := callerTenantId(c)
 
 		paramContentId := c.Param("contentId")
 
 		// if the deal is not in the right state, throw an error.
 		var content model.Content
-		node.DB.Model(&model.Content{}).Where("id = ? AND requesting_api_key = ?", paramContentId, authParts[1]).First(&content)
-		content.RequestingApiKey = ""
+		node.DB.Model(&model.Content{}).Where("id = ? AND tenant_id = ?", paramContentId, tenantId).First(&content)
 
 		if content.ConnectionMode != utils.CONNECTION_MODE_E2E {
 			return c.JSON(200, map[string]interface{}{
@@ -121,7 +211,7 @@ func handleRetryDealContent(node *core.DeltaNode) func(c echo.Context) error {
 
 		// retry it.
 		processor := jobs.NewPieceCommpProcessor(node, content)
-		node.Dispatcher.AddJobAndDispatch(processor, 1)
+		node.Dispatcher.AddJob(processor)
 
 		return c.JSON(200, map[string]interface{}{
 			"message": "retrying deal",
@@ -134,15 +224,13 @@ func handleRetryDealContent(node *core.DeltaNode) func(c echo.Context) error {
 // returns an `error`
 func handleRepairDealContent(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
+		tenantId := callerTenantId(c)
 		paramContentId := c.Param("contentId")
 		meta := c.FormValue("metadata") // only allow miner and durations
 
 		// if the deal is not in the right state, throw an error.
 		var content model.Content
-		node.DB.Model(&model.Content{}).Where("id = ? and requesting_api_key = ?", paramContentId, authParts[1]).First(&content)
-		content.RequestingApiKey = ""
+		node.DB.Model(&model.Content{}).Where("id = ? and tenant_id = ?", paramContentId, tenantId).First(&content)
 
 		if content.ID == 0 {
 			return c.JSON(200, map[string]interface{}{
@@ -163,22 +251,9 @@ func handleRepairDealContent(node *core.DeltaNode) func(c echo.Context) error {
 		}
 
 		// validate the deal request
-		if (DealRequest{} != dealRequest && dealRequest.StartEpochInDays > 14) {
-			return c.JSON(200, map[string]interface{}{
-				"message": "start epoch cannot be more than 14 days",
-			})
-		}
-
-		if (DealRequest{} != dealRequest && dealRequest.DurationInDays > 540) {
-			return c.JSON(200, map[string]interface{}{
-				"message": "duration cannot be more than 540 days",
-			})
-		}
-
-		if dealRequest.StartEpochInDays > dealRequest.DurationInDays {
-			return c.JSON(200, map[string]interface{}{
-				"message": "start epoch cannot be more than duration",
-			})
+		err = ValidateDealRequest(&dealRequest, DealRequestRepair, node)
+		if err != nil {
+			return err
 		}
 
 		// get the content deal entry
@@ -209,22 +284,14 @@ func handleRepairDealContent(node *core.DeltaNode) func(c echo.Context) error {
 		// only change the miner and duration
 		// create new content miner record.
 		dealContentMiner.Miner = dealRequest.Miner
-		if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-			startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-			dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-			dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays - dealRequest.StartEpochInDays))
-			dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-		} else {
-			dealProposalParam.StartEpoch = 0
-			dealProposalParam.Duration = utils.DEFAULT_DURATION
-		}
+		setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestRepair)
 
 		var pieceComm model.PieceCommitment
 		node.DB.Model(&model.PieceCommitment{}).Where("id = ?", content.PieceCommitmentId).First(&pieceComm)
 
 		// retry it.
 		processor := jobs.NewStorageDealMakerProcessor(node, content, pieceComm)
-		node.Dispatcher.AddJobAndDispatch(processor, 1)
+		node.Dispatcher.AddJob(processor)
 
 		return c.JSON(200, map[string]interface{}{
 			"message": "retrying deal",
@@ -236,8 +303,7 @@ func handleRepairDealContent(node *core.DeltaNode) func(c echo.Context) error {
 // This function handles a request to retry multiple failed import deals and returns a JSON response.
 func handleRepairMultipleImport(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
+		tenantId := callerTenantId(c)
 		var multipleImportRequest []MultipleImportRequest
 		err := c.Bind(&multipleImportRequest)
 		if err != nil {
@@ -245,34 +311,25 @@ func handleRepairMultipleImport(node *core.DeltaNode) func(c echo.Context) error
 				"message": "invalid request",
 			})
 		}
+
+		// validate the deal requests
+		var dealRequests []DealRequest
+		for _, request := range multipleImportRequest {
+			dealRequests = append(dealRequests, request.DealRequest)
+		}
+		err = ValidateDealRequests(dealRequests, DealRequestRepair, node)
+		if err != nil {
+			return err
+		}
+
 		var importResponse []ImportRetryResponse
 		for _, request := range multipleImportRequest {
 			paramContentId := request.ContentID
 			dealRequest := request.DealRequest
 
-			// validate the deal request
-			if (DealRequest{} != dealRequest && dealRequest.StartEpochInDays > 14) {
-				importResponse = append(importResponse, ImportRetryResponse{
-					Message: "start epoch cannot be more than 14 days",
-				})
-			}
-
-			if (DealRequest{} != dealRequest && dealRequest.DurationInDays > 540) {
-				return c.JSON(200, map[string]interface{}{
-					"message": "duration cannot be more than 540 days",
-				})
-			}
-
-			if dealRequest.StartEpochInDays > dealRequest.DurationInDays {
-				return c.JSON(200, map[string]interface{}{
-					"message": "start epoch cannot be more than duration",
-				})
-			}
-
 			// if the deal is not in the right state, throw an error.
 			var content model.Content
-			node.DB.Model(&model.Content{}).Where("id = ? and requesting_api_key = ?", paramContentId, authParts[1]).First(&content)
-			content.RequestingApiKey = ""
+			node.DB.Model(&model.Content{}).Where("id = ? and tenant_id = ?", paramContentId, tenantId).First(&content)
 
 			if content.ConnectionMode != utils.CONNECTION_MODE_IMPORT {
 				importResponse = append(importResponse, ImportRetryResponse{
@@ -310,22 +367,14 @@ func handleRepairMultipleImport(node *core.DeltaNode) func(c echo.Context) error
 			// only change the miner and duration
 			// create new content miner record.
 			dealContentMiner.Miner = dealRequest.Miner
-			if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-				startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-				dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-				dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays - dealRequest.StartEpochInDays))
-				dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-			} else {
-				dealProposalParam.StartEpoch = 0
-				dealProposalParam.Duration = utils.DEFAULT_DURATION
-			}
+			setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestRepair)
 
 			var pieceComm model.PieceCommitment
 			node.DB.Model(&model.PieceCommitment{}).Where("id = ?", content.PieceCommitmentId).First(&pieceComm)
 
 			// retry it.
 			processor := jobs.NewStorageDealMakerProcessor(node, content, pieceComm)
-			node.Dispatcher.AddJobAndDispatch(processor, 1)
+			node.Dispatcher.AddJob(processor)
 
 			importResponse = append(importResponse, ImportRetryResponse{
 				Message: "retrying deal",
@@ -340,8 +389,7 @@ func handleRepairMultipleImport(node *core.DeltaNode) func(c echo.Context) error
 // deal.
 func handleRepairImportContent(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
+		tenantId := callerTenantId(c)
 		paramContentId := c.Param("contentId")
 		meta := c.FormValue("metadata") // only allow miner and durations
 
@@ -351,10 +399,15 @@ func handleRepairImportContent(node *core.DeltaNode) func(c echo.Context) error
 			return err
 		}
 
+		// validate the deal request
+		err = ValidateDealRequest(&dealRequest, DealRequestRepair, node)
+		if err != nil {
+			return err
+		}
+
 		// if the deal is not in the right state, throw an error.
 		var content model.Content
-		node.DB.Model(&model.Content{}).Where("id = ? and requesting_api_key = ?", paramContentId, authParts[1]).First(&content)
-		content.RequestingApiKey = ""
+		node.DB.Model(&model.Content{}).Where("id = ? and tenant_id = ?", paramContentId, tenantId).First(&content)
 
 		if content.ConnectionMode != utils.CONNECTION_MODE_IMPORT {
 			return c.JSON(200, map[string]interface{}{
@@ -390,22 +443,14 @@ func handleRepairImportContent(node *core.DeltaNode) func(c echo.Context) error
 		// only change the miner and duration
 		// create new content miner record.
 		dealContentMiner.Miner = dealRequest.Miner
-		if dealRequest.StartEpochInDays != 0 && dealRequest.DurationInDays != 0 {
-			startEpochTime := time.Now().AddDate(0, 0, int(dealRequest.StartEpochInDays))
-			dealProposalParam.StartEpoch = utils.DateToHeight(startEpochTime)
-			dealProposalParam.EndEpoch = dealProposalParam.StartEpoch + (utils.EPOCH_PER_DAY * (dealRequest.DurationInDays - dealRequest.StartEpochInDays))
-			dealProposalParam.Duration = dealProposalParam.EndEpoch - dealProposalParam.StartEpoch
-		} else {
-			dealProposalParam.StartEpoch = 0
-			dealProposalParam.Duration = utils.DEFAULT_DURATION
-		}
+		setDealProposalEpochs(&dealProposalParam, dealRequest, DealRequestRepair)
 
 		var pieceComm model.PieceCommitment
 		node.DB.Model(&model.PieceCommitment{}).Where("id = ?", content.PieceCommitmentId).First(&pieceComm)
 
 		// retry it.
 		processor := jobs.NewStorageDealMakerProcessor(node, content, pieceComm)
-		node.Dispatcher.AddJobAndDispatch(processor, 1)
+		node.Dispatcher.AddJob(processor)
 
 		return c.JSON(200, map[string]interface{}{
 			"message": "retrying deal",
@@ -418,8 +463,7 @@ func handleRepairImportContent(node *core.DeltaNode) func(c echo.Context) error
 // This function handles retrying multiple content deals for import.
 func handleRetryMultipleImport(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
+		tenantId := callerTenantId(c)
 		var importRetryRequest ImportRetryRequest
 		err := c.Bind(&importRetryRequest)
 		if err != nil {
@@ -433,8 +477,7 @@ func handleRetryMultipleImport(node *core.DeltaNode) func(c echo.Context) error
 
 			// if the deal is not in the right state, throw an error.
 			var content model.Content
-			node.DB.Model(&model.Content{}).Where("id = ? and requesting_api_key = ?", paramContentId, authParts[1]).First(&content)
-			content.RequestingApiKey = ""
+			node.DB.Model(&model.Content{}).Where("id = ? and tenant_id = ?", paramContentId, tenantId).First(&content)
 
 			if content.ConnectionMode != utils.CONNECTION_MODE_IMPORT {
 				return c.JSON(200, map[string]interface{}{
@@ -455,7 +498,7 @@ func handleRetryMultipleImport(node *core.DeltaNode) func(c echo.Context) error
 
 			// retry it.
 			processor := jobs.NewPieceCommpProcessor(node, content)
-			node.Dispatcher.AddJobAndDispatch(processor, 1)
+			node.Dispatcher.AddJob(processor)
 
 			importRetryResponse = append(importRetryResponse, ImportRetryResponse{
 				Message: "retrying deal",
@@ -469,13 +512,12 @@ func handleRetryMultipleImport(node *core.DeltaNode) func(c echo.Context) error
 // This function handles retrying a content deal import and returns a JSON response.
 func handleRetryDealImport(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
+		tenantId := callerTenantId(c)
 		paramContentId := c.Param("contentId")
 
 		// get the content deal entry
 		var contentDeal model.ContentDeal
-		node.DB.Model(&model.ContentDeal{}).Where("content = ? and requesting_api_key = ?", paramContentId, authParts[1]).First(&contentDeal)
+		node.DB.Model(&model.ContentDeal{}).Where("content = ? and content in (select id from contents where tenant_id = ?)", paramContentId, tenantId).First(&contentDeal)
 
 		// if not content deal entry, throw an error.
 		if contentDeal.ID == 0 {
@@ -487,7 +529,6 @@ func handleRetryDealImport(node *core.DeltaNode) func(c echo.Context) error {
 		// if the deal is not in the right state, throw an error.
 		var content model.Content
 		node.DB.Model(&model.Content{}).Where("id = ?", paramContentId).First(&content)
-		content.RequestingApiKey = ""
 
 		if content.ConnectionMode != utils.CONNECTION_MODE_IMPORT {
 			return c.JSON(200, map[string]interface{}{
@@ -497,7 +538,7 @@ func handleRetryDealImport(node *core.DeltaNode) func(c echo.Context) error {
 
 		// retry it.
 		processor := jobs.NewPieceCommpProcessor(node, content)
-		node.Dispatcher.AddJobAndDispatch(processor, 1)
+		node.Dispatcher.AddJob(processor)
 
 		return c.JSON(200, map[string]interface{}{
 			"message": "retrying deal",
@@ -4,7 +4,7 @@ import (
 	"delta/config"
 	"delta/core"
 	_ "delta/docs/swagger"
-	"encoding/json"
+	model "delta/models"
 	"fmt"
 	"net/http"
 	"os"
@@ -46,12 +46,6 @@ func (he HttpError) Error() string {
 type HttpErrorResponse struct {
 	Error HttpError `json:"error"`
 }
-type AuthResponse struct {
-	Result struct {
-		Validated bool   `json:"validated"`
-		Details   string `json:"details"`
-	} `json:"result"`
-}
 
 // InitializeEchoRouterConfig Initializing the router.
 // It's initializing the Echo router, and configuring the routes for the API
@@ -106,8 +100,8 @@ func InitializeEchoRouterConfig(ln *core.DeltaNode, config config.DeltaConfig) {
 	ConfigureDebugProfileRouter(debugGroup, ln)
 
 	// Authentication
-	apiGroup.Use(Authenticate(config))
-	adminApiGroup.Use(Authenticate(config))
+	apiGroup.Use(Authenticate(ln), RequireApiKeyRole(apiKeyRole))
+	adminApiGroup.Use(Authenticate(ln), RequireApiKeyRole(adminApiKeyRole))
 
 	// health check
 	ConfigureHealthCheckRouter(healthCheckApiGroup, ln)
@@ -119,6 +113,7 @@ func InitializeEchoRouterConfig(ln *core.DeltaNode, config config.DeltaConfig) {
 	ConfigureDealRouter(apiGroup, ln)
 	ConfigureStatsCheckRouter(apiGroup, ln)
 	ConfigureRepairRouter(apiGroup, ln)
+	ConfigureAccountRouter(apiGroup, ln)
 
 	// open api
 	ConfigureNodeInfoRouter(openApiGroup, ln)
@@ -146,7 +141,12 @@ func InitializeEchoRouterConfig(ln *core.DeltaNode, config config.DeltaConfig) {
 	e.Logger.Fatal(e.Start("0.0.0.0:1414")) // configuration
 }
 
-func Authenticate(config config.DeltaConfig) func(next echo.HandlerFunc) echo.HandlerFunc {
+// ApiKeyContextKey is the key of the authenticated API key in the echo context.
+const ApiKeyContextKey = "api_key"
+
+// Authenticate is a middleware that checks the bearer token of the request with the authenticator of the node, the
+// backend of the config. The authenticated key is set in the context under ApiKeyContextKey.
+func Authenticate(node *core.DeltaNode) func(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
 			// check if the authorization header is present
@@ -180,79 +180,88 @@ func Authenticate(config config.DeltaConfig) func(next echo.HandlerFunc) echo.Ha
 					},
 				})
 			}
-			if config.Common.Mode == "standalone" {
-				// check if the API key is present and is valid
-				if config.Standalone.APIKey != authParts[1] {
-					return c.JSON(http.StatusUnauthorized, HttpErrorResponse{
-						Error: HttpError{
-							Code:    http.StatusUnauthorized,
-							Reason:  http.StatusText(http.StatusUnauthorized),
-							Details: "Invalid API key provided for standalone mode",
-						},
-					})
-				}
-			}
-			// if everything is good. we can check the token against estuary-auth.
-			response, err := http.Post(
-				config.ExternalApis.AuthSvcApi+"/check-api-key",
-				"application/json",
-				strings.NewReader(fmt.Sprintf(`{"token": "%s"}`, authParts[1])),
-			)
-			if err != nil {
-				log.Errorf("handler error: %s", err)
-				return c.JSON(http.StatusInternalServerError, HttpErrorResponse{
-					Error: HttpError{
-						Code:    http.StatusInternalServerError,
-						Reason:  http.StatusText(http.StatusInternalServerError),
-						Details: err.Error(),
-					},
-				})
-			}
-			authResp, err := GetAuthResponse(response)
+
+			apiKey, err := node.Authenticator.Authenticate(c.Request().Context(), authParts[1])
 			if err != nil {
-				log.Errorf("handler error: %s", err)
-				return c.JSON(http.StatusInternalServerError, HttpErrorResponse{
+				code := http.StatusUnauthorized
+				if xerrors.Is(err, core.ErrAuthServiceUnavailable) {
+					log.Errorf("handler error: %s", err)
+					code = http.StatusServiceUnavailable
+				}
+				return c.JSON(code, HttpErrorResponse{
 					Error: HttpError{
-						Code:    http.StatusInternalServerError,
-						Reason:  http.StatusText(http.StatusInternalServerError),
+						Code:    code,
+						Reason:  http.StatusText(code),
 						Details: err.Error(),
 					},
 				})
 			}
-			if authResp.Result.Validated == false {
-				return c.JSON(http.StatusUnauthorized, HttpErrorResponse{
+			c.Set(ApiKeyContextKey, apiKey)
+			return next(c)
+		}
+	}
+}
+
+// callerApiKey returns the API key the request was authenticated with by Authenticate.
+func callerApiKey(c echo.Context) model.ApiKey {
+	apiKey, _ := c.Get(ApiKeyContextKey).(model.ApiKey)
+	return apiKey
+}
+
+// callerTenantId returns the tenant of the caller, it only sees the contents, the wallets and the batch imports of its
+// tenant.
+func callerTenantId(c echo.Context) int64 {
+	return callerApiKey(c).TenantID
+}
+
+// callerStatsParam returns the caller of a stats query, the admins see the contents of every tenant.
+func callerStatsParam(c echo.Context) core.StatsParam {
+	apiKey := callerApiKey(c)
+	return core.StatsParam{
+		TenantID:   apiKey.TenantID,
+		AllTenants: apiKey.HasRole(model.API_KEY_ROLE_ADMIN),
+	}
+}
+
+// RequireApiKeyRole is a middleware that only lets through the API keys with the role of the route, or a role above
+// it. It runs after Authenticate.
+func RequireApiKeyRole(roleOf func(c echo.Context) string) func(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			apiKey := callerApiKey(c)
+			role := roleOf(c)
+			if !apiKey.HasRole(role) {
+				return c.JSON(http.StatusForbidden, HttpErrorResponse{
 					Error: HttpError{
-						Code:    http.StatusUnauthorized,
-						Reason:  http.StatusText(http.StatusUnauthorized),
-						Details: authResp.Result.Details,
+						Code:    http.StatusForbidden,
+						Reason:  http.StatusText(http.StatusForbidden),
+						Details: "The API key doesn't have the " + role + " role",
 					},
 				})
 			}
-			if authResp.Result.Validated == true {
-				return next(c)
-			}
 			return next(c)
 		}
 	}
 }
 
-// GetAuthResponse It's making a request to the auth API to check if the API key is valid.
-func GetAuthResponse(resp *http.Response) (AuthResponse, error) {
-	jsonBody := AuthResponse{}
-	err := json.NewDecoder(resp.Body).Decode(&jsonBody)
-	if err != nil {
-		log.Error("empty json body")
-		return AuthResponse{
-			Result: struct {
-				Validated bool   `json:"validated"`
-				Details   string `json:"details"`
-			}{
-				Validated: false,
-				Details:   "empty json body",
-			},
-		}, nil
+// apiKeyRole returns the role of a route of the /api/v1 group: the stats, the status of the deals and the usage of the
+// account are read-only, everything else makes deals.
+func apiKeyRole(c echo.Context) string {
+	if strings.HasPrefix(c.Path(), "/api/v1/stats") || strings.HasPrefix(c.Path(), "/api/v1/deal/status") ||
+		strings.HasPrefix(c.Path(), "/api/v1/account") {
+		return model.API_KEY_ROLE_READ_ONLY
 	}
-	return jsonBody, nil
+	return model.API_KEY_ROLE_DEALMAKER
+}
+
+// adminApiKeyRole returns the role of the routes of the /admin group and of the sensitive open routes.
+func adminApiKeyRole(c echo.Context) string {
+	return model.API_KEY_ROLE_ADMIN
+}
+
+// readOnlyApiKeyRole returns the role of the stats routes of the /open group.
+func readOnlyApiKeyRole(c echo.Context) string {
+	return model.API_KEY_ROLE_READ_ONLY
 }
 
 // Usage `Echo#Pre(RemoveTrailingSlash())`
@@ -265,6 +274,19 @@ func ValidateRequestBody() echo.MiddlewareFunc {
 // ErrorHandler It's a function that is called when an error occurs.
 func ErrorHandler(err error, c echo.Context) {
 
+	var validationErr *ValidationError
+	if xerrors.As(err, &validationErr) {
+		if err := c.JSON(http.StatusUnprocessableEntity, ValidationErrorResponse{
+			Status:  "error",
+			Message: "The request is invalid, please check the errors of its fields",
+			Version: DealRequestValidationVersion,
+			Errors:  validationErr.Errors,
+		}); err != nil {
+			log.Errorf("handler error: %s", err)
+		}
+		return
+	}
+
 	var httpRespErr *HttpError
 	if xerrors.As(err, &httpRespErr) {
 		log.Errorf("handler error: %s", err)
@@ -3,7 +3,6 @@ package api
 import (
 	"delta/core"
 	model "delta/models"
-	"strings"
 
 	"github.com/labstack/echo/v4"
 )
@@ -62,6 +61,10 @@ func ConfigureStatsCheckRouter(e *echo.Group, node *core.DeltaNode) {
 		return handleGetStatsByContents(c, node)
 	})
 
+	e.GET("/stats/replication-group/:groupId", func(c echo.Context) error {
+		return handleGetStatsByReplicationGroup(c, node)
+	})
+
 	e.GET("/stats/batch/imports/:batchId", func(c echo.Context) error {
 		return handleOpenGetStatsByAllContentsFromBatch(c, node)
 	})
@@ -100,19 +103,18 @@ func ConfigureStatsCheckRouter(e *echo.Group, node *core.DeltaNode) {
 // `error`
 func handleStats(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
+		scope := callerStatsParam(c)
 
-		// select * from content_deals cd, content c where cd.content = c.id and c.requesting_api_key = ?;
+		// select * from content_deals cd, content c where cd.content = c.id and c.tenant_id = ?;
 		var content []model.Content
-		node.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and c.requesting_api_key = ?", authParts[1]).Scan(&content)
+		node.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and "+core.TenantScope, scope.AllTenants, scope.TenantID).Scan(&content)
 
 		var contentDeal []model.ContentDeal
-		node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.requesting_api_key = ?", authParts[1]).Scan(&contentDeal)
+		node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and "+core.TenantScope, scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
-		// select * from piece_commitments pc, content c where c.piece_commitment_id = pc.id and c.requesting_api_key = ?;
+		// select * from piece_commitments pc, content c where c.piece_commitment_id = pc.id and c.tenant_id = ?;
 		var pieceCommitments []model.PieceCommitment
-		node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.requesting_api_key = ?", authParts[1]).Scan(&pieceCommitments)
+		node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and "+core.TenantScope, scope.AllTenants, scope.TenantID).Scan(&pieceCommitments)
 
 		return c.JSON(200, map[string]interface{}{
 			"content":           content,
@@ -127,14 +129,13 @@ func handleStats(node *core.DeltaNode) func(c echo.Context) error {
 func handleMinerStats(node *core.DeltaNode) func(c echo.Context) error {
 	return func(c echo.Context) error {
 
-		authorizationString := c.Request().Header.Get("Authorization")
-		authParts := strings.Split(authorizationString, " ")
+		scope := callerStatsParam(c)
 
 		var contents []model.Content
-		node.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and cd.miner = ? and c.requesting_api_key = ?", c.Param("minerId"), authParts[1]).Scan(&contents)
+		node.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and cd.miner = ? and "+core.TenantScope, c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&contents)
 
 		var contentMinerAssignment []model.ContentMiner
-		node.DB.Raw("select cma.* from content_miners cma, contents c where cma.content = c.id and cma.miner = ? and c.requesting_api_key = ?", c.Param("minerId"), authParts[1]).Scan(&contentMinerAssignment)
+		node.DB.Raw("select cma.* from content_miners cma, contents c where cma.content = c.id and cma.miner = ? and "+core.TenantScope, c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&contentMinerAssignment)
 
 		return c.JSON(200, map[string]interface{}{
 			"content": contents,
@@ -146,12 +147,11 @@ func handleMinerStats(node *core.DeltaNode) func(c echo.Context) error {
 
 // A function that takes in a commitment and a piece number and returns the piece of the commitment.
 func handleGetCommitmentPiece(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
-	// select * from piece_commitments pc, content c where c.piece_commitment_id = pc.id and c.requesting_api_key = ?;
+	// select * from piece_commitments pc, content c where c.piece_commitment_id = pc.id and c.tenant_id = ?;
 	var pieceCommitments []model.PieceCommitment
-	node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = ? and c.requesting_api_key = ?", c.Param("commitmentPieceId"), authParts[1]).Scan(&pieceCommitments)
+	node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and pc.id = ? and "+core.TenantScope, c.Param("commitmentPieceId"), scope.AllTenants, scope.TenantID).Scan(&pieceCommitments)
 
 	return c.JSON(200, map[string]interface{}{
 		"piece_commitments": pieceCommitments,
@@ -161,8 +161,7 @@ func handleGetCommitmentPiece(c echo.Context, node *core.DeltaNode) error {
 
 // > This function handles the `GET /stats/contents` route
 func handleGetStatsByContents(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 	var contentIds []int64
 	err := c.Bind(&contentIds)
 	if err != nil {
@@ -173,19 +172,19 @@ func handleGetStatsByContents(c echo.Context, node *core.DeltaNode) error {
 	for _, contentId := range contentIds {
 
 		var content model.Content
-		node.DB.Raw("select c.* from contents c where c.id = ? and c.requesting_api_key = ?", contentId, authParts[1]).Scan(&content)
+		node.DB.Raw("select c.* from contents c where c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&content)
 
 		var contentDeal []model.ContentDeal
-		node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.id = ? and c.requesting_api_key = ?", contentId, authParts[1]).Scan(&contentDeal)
+		node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
 		var pieceCommitments []model.PieceCommitment
-		node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.id = ? and c.requesting_api_key = ?", contentId, authParts[1]).Scan(&pieceCommitments)
+		node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&pieceCommitments)
 
 		var contentDealProposal []model.ContentDealProposal
-		node.DB.Raw("select cdp.* from content_deal_proposals cdp, contents c where cdp.content = c.id and c.id = ? and c.requesting_api_key = ?", contentId, authParts[1]).Scan(&contentDealProposal)
+		node.DB.Raw("select cdp.* from content_deal_proposals cdp, contents c where cdp.content = c.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&contentDealProposal)
 
 		var contentDealProposalParams []model.ContentDealProposalParameters
-		node.DB.Raw("select cdpp.* from content_deal_proposal_parameters cdpp, contents c where cdpp.content = c.id and c.id = ? and c.requesting_api_key = ?", contentId, authParts[1]).Scan(&contentDealProposalParams)
+		node.DB.Raw("select cdpp.* from content_deal_proposal_parameters cdpp, contents c where cdpp.content = c.id and c.id = ? and "+core.TenantScope, contentId, scope.AllTenants, scope.TenantID).Scan(&contentDealProposalParams)
 
 		contentResponse = append(contentResponse, map[string]interface{}{
 			"content":                  content,
@@ -201,24 +200,22 @@ func handleGetStatsByContents(c echo.Context, node *core.DeltaNode) error {
 
 // function to get all stats given a content id and user api key
 func handleGetStatsByContent(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var content model.Content
-	node.DB.Raw("select c.* from contents c where c.id = ? and c.requesting_api_key = ?", c.Param("contentId"), authParts[1]).Scan(&content)
-	content.RequestingApiKey = ""
+	node.DB.Raw("select c.* from contents c where c.id = ? and "+core.TenantScope, c.Param("contentId"), scope.AllTenants, scope.TenantID).Scan(&content)
 
 	var contentDeal []model.ContentDeal
-	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.id = ? and c.requesting_api_key = ?", c.Param("contentId"), authParts[1]).Scan(&contentDeal)
+	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.id = ? and "+core.TenantScope, c.Param("contentId"), scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
 	var pieceCommitments []model.PieceCommitment
-	node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.id = ? and c.requesting_api_key = ?", c.Param("contentId"), authParts[1]).Scan(&pieceCommitments)
+	node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.id = ? and "+core.TenantScope, c.Param("contentId"), scope.AllTenants, scope.TenantID).Scan(&pieceCommitments)
 
 	var contentDealProposal []model.ContentDealProposal
-	node.DB.Raw("select cdp.* from content_deal_proposals cdp, contents c where cdp.content = c.id and c.id = ? and c.requesting_api_key = ?", c.Param("contentId"), authParts[1]).Scan(&contentDealProposal)
+	node.DB.Raw("select cdp.* from content_deal_proposals cdp, contents c where cdp.content = c.id and c.id = ? and "+core.TenantScope, c.Param("contentId"), scope.AllTenants, scope.TenantID).Scan(&contentDealProposal)
 
 	var contentDealProposalParameters []model.ContentDealProposalParameters
-	node.DB.Raw("select cdp.* from content_deal_proposal_parameters cdp, contents c where cdp.content = c.id and c.id = ? and c.requesting_api_key = ?", c.Param("contentId"), authParts[1]).Scan(&contentDealProposalParameters)
+	node.DB.Raw("select cdp.* from content_deal_proposal_parameters cdp, contents c where cdp.content = c.id and c.id = ? and "+core.TenantScope, c.Param("contentId"), scope.AllTenants, scope.TenantID).Scan(&contentDealProposalParameters)
 
 	return c.JSON(200, map[string]interface{}{
 		"content":                  content,
@@ -229,13 +226,36 @@ func handleGetStatsByContent(c echo.Context, node *core.DeltaNode) error {
 	})
 }
 
+// function to get the aggregate status of the copies of a replication group
+func handleGetStatsByReplicationGroup(c echo.Context, node *core.DeltaNode) error {
+	scope := callerStatsParam(c)
+
+	var replicationGroup model.ReplicationGroup
+	node.DB.Model(&model.ReplicationGroup{}).Where("id = ? and (? or tenant_id = ?)", c.Param("groupId"), scope.AllTenants, scope.TenantID).Find(&replicationGroup)
+	if replicationGroup.ID == 0 {
+		return c.JSON(404, map[string]interface{}{
+			"message": "replication group not found",
+		})
+	}
+
+	status, err := core.GetReplicationGroupStatus(node.DB, replicationGroup.ID)
+	if err != nil {
+		return c.JSON(500, map[string]interface{}{
+			"message": err.Error(),
+		})
+	}
+
+	return c.JSON(200, map[string]interface{}{
+		"replication_group": status,
+	})
+}
+
 // function to get all contents of a given a miner
 func handleGetContentsByMiner(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var contents []model.Content
-	node.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and cd.miner = ? and c.requesting_api_key = ?", c.Param("minerId"), authParts[1]).Scan(&contents)
+	node.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and cd.miner = ? and "+core.TenantScope, c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&contents)
 
 	c.JSON(200, map[string]interface{}{
 		"content": contents,
@@ -246,11 +266,10 @@ func handleGetContentsByMiner(c echo.Context, node *core.DeltaNode) error {
 
 // function to get all piece-commitment of a given miner
 func handleGetCommitmentPiecesByMiner(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var pieceCommitments []model.PieceCommitment
-	node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.requesting_api_key = ?", authParts[1]).Scan(&pieceCommitments)
+	node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and "+core.TenantScope, scope.AllTenants, scope.TenantID).Scan(&pieceCommitments)
 
 	c.JSON(200, map[string]interface{}{
 		"piece_commitments": pieceCommitments,
@@ -261,11 +280,10 @@ func handleGetCommitmentPiecesByMiner(c echo.Context, node *core.DeltaNode) erro
 
 // function to get all deals of a given miner
 func handleGetDealsByMiner(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var contentDeal []model.ContentDeal
-	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.requesting_api_key = ? and cd.miner = ?", authParts[1], c.Param("minerId")).Scan(&contentDeal)
+	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and "+core.TenantScope+" and cd.miner = ?", scope.AllTenants, scope.TenantID, c.Param("minerId")).Scan(&contentDeal)
 
 	c.JSON(200, map[string]interface{}{
 		"deals": contentDeal,
@@ -276,11 +294,10 @@ func handleGetDealsByMiner(c echo.Context, node *core.DeltaNode) error {
 
 // function to get all deal-proposal of a given miner
 func handleGetDealProposalsByMiner(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var contentMinerAssignment []model.ContentMiner
-	node.DB.Raw("select cma.* from content_miners cma, contents c where cma.content = c.id and cma.miner = ? and c.requesting_api_key = ?", c.Param("minerId"), authParts[1]).Scan(&contentMinerAssignment)
+	node.DB.Raw("select cma.* from content_miners cma, contents c where cma.content = c.id and cma.miner = ? and "+core.TenantScope, c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&contentMinerAssignment)
 
 	c.JSON(200, map[string]interface{}{
 		"cmas": contentMinerAssignment,
@@ -291,11 +308,10 @@ func handleGetDealProposalsByMiner(c echo.Context, node *core.DeltaNode) error {
 
 // function to get all content of a given api key
 func handleGetContents(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var content []model.Content
-	node.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and c.requesting_api_key = ?", authParts[1]).Scan(&content)
+	node.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and "+core.TenantScope, scope.AllTenants, scope.TenantID).Scan(&content)
 
 	c.JSON(200, map[string]interface{}{
 		"content": content,
@@ -306,11 +322,10 @@ func handleGetContents(c echo.Context, node *core.DeltaNode) error {
 
 // function to get all piece-commitment of a given api key
 func handleGetCommitmentPieces(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var pieceCommitments []model.PieceCommitment
-	node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.requesting_api_key = ?", authParts[1]).Scan(&pieceCommitments)
+	node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and "+core.TenantScope, scope.AllTenants, scope.TenantID).Scan(&pieceCommitments)
 
 	c.JSON(200, map[string]interface{}{
 		"piece_commitments": pieceCommitments,
@@ -321,11 +336,10 @@ func handleGetCommitmentPieces(c echo.Context, node *core.DeltaNode) error {
 
 // function to get all deals of a given api key
 func handleGetDeals(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var contentDeal []model.ContentDeal
-	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.requesting_api_key = ?", authParts[1]).Scan(&contentDeal)
+	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and "+core.TenantScope, scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
 	c.JSON(200, map[string]interface{}{
 		"deals": contentDeal,
@@ -336,11 +350,10 @@ func handleGetDeals(c echo.Context, node *core.DeltaNode) error {
 
 // function to get all deal-proposal of a given api key
 func handleGetDealProposals(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var contentMinerAssignment []model.ContentMiner
-	node.DB.Raw("select cma.* from content_miners cma, contents c where cma.content = c.id and c.requesting_api_key = ?", authParts[1]).Scan(&contentMinerAssignment)
+	node.DB.Raw("select cma.* from content_miners cma, contents c where cma.content = c.id and "+core.TenantScope, scope.AllTenants, scope.TenantID).Scan(&contentMinerAssignment)
 
 	c.JSON(200, map[string]interface{}{
 		"cmas": contentMinerAssignment,
@@ -351,11 +364,10 @@ func handleGetDealProposals(c echo.Context, node *core.DeltaNode) error {
 
 // function to get a specific content with a given miner
 func handleGetContentByMiner(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var content model.Content
-	node.DB.Raw("select c.* from content_deals cd, contents c where cd.content = ? and cd.miner = ? and c.requesting_api_key = ?", c.Param("contentId"), c.Param("minerId"), authParts[1]).Scan(&content)
+	node.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and cd.content = ? and cd.miner = ? and "+core.TenantScope, c.Param("contentId"), c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&content)
 
 	c.JSON(200, map[string]interface{}{
 		"content": content,
@@ -366,11 +378,10 @@ func handleGetContentByMiner(c echo.Context, node *core.DeltaNode) error {
 
 // function to get a specific piece-commitment with a given miner
 func handleGetCommitmentPieceByMiner(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var pieceCommitment model.PieceCommitment
-	node.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = ? and c.miner = ? and c.id = ? and c.requesting_api_key = ?", c.Param("commitmentPieceId"), c.Param("minerId"), authParts[1]).Scan(&pieceCommitment)
+	node.DB.Raw("select pc.* from piece_commitments pc, contents c, content_deals cd where c.piece_commitment_id = pc.id and cd.content = c.id and pc.id = ? and cd.miner = ? and "+core.TenantScope, c.Param("commitmentPieceId"), c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&pieceCommitment)
 
 	c.JSON(200, map[string]interface{}{
 		"piece_commitment": pieceCommitment,
@@ -381,11 +392,10 @@ func handleGetCommitmentPieceByMiner(c echo.Context, node *core.DeltaNode) error
 
 // function to get a specific deal with a given miner
 func handleGetDealByMiner(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var contentDeal model.ContentDeal
-	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.id = ? and cd.miner = ? and c.requesting_api_key = ?", c.Param("dealId"), c.Param("minerId"), authParts[1]).Scan(&contentDeal)
+	node.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and cd.id = ? and cd.miner = ? and "+core.TenantScope, c.Param("dealId"), c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&contentDeal)
 
 	c.JSON(200, map[string]interface{}{
 		"deal": contentDeal,
@@ -396,11 +406,10 @@ func handleGetDealByMiner(c echo.Context, node *core.DeltaNode) error {
 
 // function to get a specific deal-proposal with a given miner
 func handleGetDealProposalByMiner(c echo.Context, node *core.DeltaNode) error {
-	authorizationString := c.Request().Header.Get("Authorization")
-	authParts := strings.Split(authorizationString, " ")
+	scope := callerStatsParam(c)
 
 	var contentMinerAssignment model.ContentMiner
-	node.DB.Raw("select cma.* from content_deal_proposals cdp, contents c where cdp.id = ? and cdp.miner = ? and c.requesting_api_key = ?", c.Param("dealProposalId"), c.Param("minerId"), authParts[1]).Scan(&contentMinerAssignment)
+	node.DB.Raw("select cma.* from content_deal_proposals cdp, contents c where cdp.content = c.id and cdp.id = ? and cdp.miner = ? and "+core.TenantScope, c.Param("dealProposalId"), c.Param("minerId"), scope.AllTenants, scope.TenantID).Scan(&contentMinerAssignment)
 
 	c.JSON(200, map[string]interface{}{
 		"cmas": contentMinerAssignment,
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"bytes"
+	"delta/core"
+	model "delta/models"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// checkTenantQuota is a middleware that rejects with 429 the requests that would take the tenant of the caller over
+// one of its limits, see core.TenantLimits. What a request adds to the usage is reserved until its handler returns, so
+// the requests made at once are counted together. The requests the skipper returns true for are let through.
+func checkTenantQuota(next echo.HandlerFunc, node *core.DeltaNode, skipper func(c echo.Context) bool) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		if skipper != nil && skipper(c) {
+			return next(c)
+		}
+
+		tenantId := callerTenantId(c)
+		limits := core.GetTenantLimits(node.DB, node.Config, tenantId)
+		if limits == (core.TenantLimits{}) {
+			return next(c)
+		}
+		release, err := core.ReserveTenantQuota(node.DB, limits, tenantId, tenantQuotaRequest(c, node), time.Now())
+		if errors.Is(err, core.ErrTenantQuotaExceeded) {
+			return c.JSON(http.StatusTooManyRequests, DealResponse{
+				Status:  "error",
+				Message: err.Error(),
+			})
+		}
+		if err != nil {
+			return err
+		}
+		defer release()
+		return next(c)
+	}
+}
+
+// tenantQuotaRequest returns what a request adds to the usage of the tenant. A body that can't be parsed adds
+// nothing, the handler rejects it.
+func tenantQuotaRequest(c echo.Context, node *core.DeltaNode) core.TenantQuotaRequest {
+	path := c.Path()
+	switch {
+	case strings.HasSuffix(path, "/top-up"):
+		return topUpQuotaRequest(c, node)
+	case strings.Contains(path, "/repair/") || strings.Contains(path, "/retry/"):
+		return retryQuotaRequest(c)
+	}
+
+	var dealRequests []DealRequest
+	var fileSize int64
+	if strings.HasPrefix(c.Request().Header.Get(echo.HeaderContentType), echo.MIMEApplicationJSON) {
+		body := readQuotaRequestBody(c)
+		if len(body) > 0 && body[0] == '[' {
+			json.Unmarshal(body, &dealRequests)
+		} else {
+			var dealRequest DealRequest
+			if json.Unmarshal(body, &dealRequest) == nil {
+				dealRequests = append(dealRequests, dealRequest)
+			}
+		}
+	} else {
+		// the end-to-end deals, the metadata is a form value next to the file
+		var dealRequest DealRequest
+		if json.Unmarshal([]byte(c.FormValue("metadata")), &dealRequest) == nil {
+			dealRequests = append(dealRequests, dealRequest)
+		}
+		if file, err := c.FormFile("data"); err == nil {
+			fileSize = file.Size
+		}
+	}
+
+	var request core.TenantQuotaRequest
+	for _, dealRequest := range dealRequests {
+		copies := int64(dealRequest.Replication) + 1
+		size := dealRequest.Size
+		if fileSize > 0 {
+			size = fileSize
+		}
+		request.Deals += copies
+		request.Bytes += size * copies
+		if int64(dealRequest.Replication) > request.Replication {
+			request.Replication = int64(dealRequest.Replication)
+		}
+	}
+	return request
+}
+
+// retryQuotaRequest returns what a repair or a retry adds to the usage of the tenant, a deal for each of its contents.
+// Their size was counted when they were made.
+func retryQuotaRequest(c echo.Context) core.TenantQuotaRequest {
+	if c.Request().Method == http.MethodGet {
+		return core.TenantQuotaRequest{Deals: 1}
+	}
+
+	body := readQuotaRequestBody(c)
+	var repairRequests []MultipleImportRequest
+	if json.Unmarshal(body, &repairRequests) == nil {
+		return core.TenantQuotaRequest{Deals: int64(len(repairRequests))}
+	}
+	var retryRequest ImportRetryRequest
+	if json.Unmarshal(body, &retryRequest) == nil {
+		return core.TenantQuotaRequest{Deals: int64(len(retryRequest.ContentIds))}
+	}
+	return core.TenantQuotaRequest{}
+}
+
+// topUpQuotaRequest returns what the top up of a replication group adds to the usage of the tenant, a copy of the
+// source content for each of the missing copies.
+func topUpQuotaRequest(c echo.Context, node *core.DeltaNode) core.TenantQuotaRequest {
+	var replicationGroup model.ReplicationGroup
+	node.DB.Model(&model.ReplicationGroup{}).Where("id = ? and tenant_id = ?", c.Param("groupId"), callerTenantId(c)).Find(&replicationGroup)
+	if replicationGroup.ID == 0 {
+		return core.TenantQuotaRequest{}
+	}
+	status, err := core.GetReplicationGroupStatus(node.DB, replicationGroup.ID)
+	if err != nil {
+		return core.TenantQuotaRequest{}
+	}
+	var content model.Content
+	node.DB.Model(&model.Content{}).Where("id = ?", replicationGroup.SourceContent).Find(&content)
+
+	missing := int64(status.MissingCopies())
+	return core.TenantQuotaRequest{Deals: missing, Bytes: content.Size * missing}
+}
+
+// readQuotaRequestBody reads the body of a request before its handler, so it is put back for it.
+func readQuotaRequestBody(c echo.Context) []byte {
+	body, err := io.ReadAll(c.Request().Body)
+	if err != nil {
+		return nil
+	}
+	c.Request().Body = io.NopCloser(bytes.NewReader(body))
+	return bytes.TrimSpace(body)
+}
@@ -1,26 +1,297 @@
 package cmd
 
 import (
+	"bytes"
 	c "delta/config"
+	"delta/utils"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/urfave/cli/v2"
 )
 
-// TODO: add a command to administer the node
+type ApiKey struct {
+	UUID       string    `json:"uuid"`
+	TenantID   int64     `json:"tenant_id"`
+	Label      string    `json:"label"`
+	Prefix     string    `json:"prefix"`
+	Role       string    `json:"role"`
+	Scopes     string    `json:"scopes"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	RevokedAt  time.Time `json:"revoked_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ApiKeyResponse struct {
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Key     string `json:"key,omitempty"`
+	ApiKey  ApiKey `json:"api_key"`
+}
+
+type ApiKeyListResponse struct {
+	ApiKeys []ApiKey `json:"api_keys"`
+}
+
+type Tenant struct {
+	ID         int64     `json:"ID"`
+	UUID       string    `json:"uuid"`
+	Name       string    `json:"name"`
+	ExternalId string    `json:"external_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type TenantResponse struct {
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Tenant  Tenant `json:"tenant"`
+}
+
+type TenantListResponse struct {
+	Tenants []Tenant `json:"tenants"`
+}
+
+type TenantLimits struct {
+	MaxConcurrentTransfers int64 `json:"max_concurrent_transfers"`
+	MaxBytesPerDay         int64 `json:"max_bytes_per_day"`
+	MaxDealsPerHour        int64 `json:"max_deals_per_hour"`
+	MaxReplication         int64 `json:"max_replication"`
+}
+
+type TenantLimitsResponse struct {
+	Message string       `json:"message,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Limits  TenantLimits `json:"limits"`
+}
+
+// AdminCmd Creating the `admin` commands, they administer the node through the admin API.
 func AdminCmd(cfg *c.DeltaConfig) []*cli.Command {
-	// add a command to run API node
 	var adminCommands []*cli.Command
 
 	adminCmd := &cli.Command{
-		Name:  "wallet",
-		Usage: "Make a delta storage deal",
-		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:  "register-hex",
-				Usage: "content to store",
+		Name:  "admin",
+		Usage: "Run Delta admin commands",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "key",
+				Usage: "Manage the API keys of the node",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "create",
+						Usage: "Create a new API key, it is shown once",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "tenant",
+								Usage: "uuid of the tenant of the key, a new tenant if empty",
+							},
+							&cli.StringFlag{
+								Name:  "label",
+								Usage: "description of the key",
+							},
+							&cli.StringFlag{
+								Name:  "role",
+								Usage: "role of the key: admin, dealmaker or read-only",
+								Value: "dealmaker",
+							},
+							&cli.StringFlag{
+								Name:  "scopes",
+								Usage: "comma separated scopes of the key: deal, read and admin, all the scopes of the role if empty",
+							},
+							&cli.IntFlag{
+								Name:  "expires-in-days",
+								Usage: "number of days before the key expires, never if 0",
+							},
+						},
+						Action: func(context *cli.Context) error {
+							cmd, err := NewDeltaCmdNode(context)
+							if err != nil {
+								return err
+							}
+
+							payload := map[string]interface{}{
+								"tenant":          context.String("tenant"),
+								"label":           context.String("label"),
+								"role":            context.String("role"),
+								"expires_in_days": context.Int("expires-in-days"),
+							}
+							if context.String("scopes") != "" {
+								payload["scopes"] = strings.Split(context.String("scopes"), ",")
+							}
+							var response ApiKeyResponse
+							if err := adminApiRequest(cmd, "POST", "/admin/keys/create", payload, &response); err != nil {
+								return err
+							}
+							return printAdminResponse(response)
+						},
+					},
+					{
+						Name:  "list",
+						Usage: "List the API keys of the node",
+						Action: func(context *cli.Context) error {
+							cmd, err := NewDeltaCmdNode(context)
+							if err != nil {
+								return err
+							}
+
+							var response ApiKeyListResponse
+							if err := adminApiRequest(cmd, "GET", "/admin/keys/list", nil, &response); err != nil {
+								return err
+							}
+							return printAdminResponse(response)
+						},
+					},
+					{
+						Name:  "revoke",
+						Usage: "Revoke an API key",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "uuid",
+								Usage:    "uuid of the key to revoke",
+								Required: true,
+							},
+						},
+						Action: func(context *cli.Context) error {
+							cmd, err := NewDeltaCmdNode(context)
+							if err != nil {
+								return err
+							}
+
+							var response ApiKeyResponse
+							if err := adminApiRequest(cmd, "POST", "/admin/keys/revoke/"+context.String("uuid"), nil, &response); err != nil {
+								return err
+							}
+							return printAdminResponse(response)
+						},
+					},
+				},
 			},
-			&cli.StringFlag{
-				Name:  "api-key",
-				Usage: "The API key to use for the request",
+			{
+				Name:  "tenant",
+				Usage: "Manage the tenants of the node",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "create",
+						Usage: "Create a new tenant, its keys are created with its uuid",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "name",
+								Usage:    "name of the tenant",
+								Required: true,
+							},
+						},
+						Action: func(context *cli.Context) error {
+							cmd, err := NewDeltaCmdNode(context)
+							if err != nil {
+								return err
+							}
+
+							payload := map[string]interface{}{
+								"name": context.String("name"),
+							}
+							var response TenantResponse
+							if err := adminApiRequest(cmd, "POST", "/admin/tenants/create", payload, &response); err != nil {
+								return err
+							}
+							return printAdminResponse(response)
+						},
+					},
+					{
+						Name:  "list",
+						Usage: "List the tenants of the node",
+						Action: func(context *cli.Context) error {
+							cmd, err := NewDeltaCmdNode(context)
+							if err != nil {
+								return err
+							}
+
+							var response TenantListResponse
+							if err := adminApiRequest(cmd, "GET", "/admin/tenants/list", nil, &response); err != nil {
+								return err
+							}
+							return printAdminResponse(response)
+						},
+					},
+					{
+						Name:  "limits",
+						Usage: "Set the limits of the deal requests of a tenant, a zero limit is the one of the config",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "uuid",
+								Usage:    "uuid of the tenant",
+								Required: true,
+							},
+							&cli.Int64Flag{
+								Name:  "max-concurrent-transfers",
+								Usage: "contents being transferred at once",
+							},
+							&cli.Int64Flag{
+								Name:  "max-bytes-per-day",
+								Usage: "bytes of the contents of the last 24 hours",
+							},
+							&cli.Int64Flag{
+								Name:  "max-deals-per-hour",
+								Usage: "deals of the last hour, with the replicas",
+							},
+							&cli.Int64Flag{
+								Name:  "max-replication",
+								Usage: "replication of a deal request",
+							},
+						},
+						Action: func(context *cli.Context) error {
+							cmd, err := NewDeltaCmdNode(context)
+							if err != nil {
+								return err
+							}
+
+							payload := TenantLimits{
+								MaxConcurrentTransfers: context.Int64("max-concurrent-transfers"),
+								MaxBytesPerDay:         context.Int64("max-bytes-per-day"),
+								MaxDealsPerHour:        context.Int64("max-deals-per-hour"),
+								MaxReplication:         context.Int64("max-replication"),
+							}
+							var response TenantLimitsResponse
+							if err := adminApiRequest(cmd, "POST", "/admin/tenants/limits/"+context.String("uuid"), payload, &response); err != nil {
+								return err
+							}
+							return printAdminResponse(response)
+						},
+					},
+					{
+						Name:  "map-remote-token",
+						Usage: "Map a token of estuary-auth onto a tenant, e.g. the new token of a rotated key onto the tenant of the old one",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:     "uuid",
+								Usage:    "uuid of the tenant",
+								Required: true,
+							},
+							&cli.StringFlag{
+								Name:     "token",
+								Usage:    "token of estuary-auth",
+								Required: true,
+							},
+						},
+						Action: func(context *cli.Context) error {
+							cmd, err := NewDeltaCmdNode(context)
+							if err != nil {
+								return err
+							}
+
+							payload := map[string]interface{}{
+								"token": context.String("token"),
+							}
+							var response TenantResponse
+							if err := adminApiRequest(cmd, "POST", "/admin/tenants/remote-token/"+context.String("uuid"), payload, &response); err != nil {
+								return err
+							}
+							return printAdminResponse(response)
+						},
+					},
+				},
 			},
 		},
 	}
@@ -28,3 +299,39 @@ func AdminCmd(cfg *c.DeltaConfig) []*cli.Command {
 
 	return adminCommands
 }
+
+// adminApiRequest sends a request to the admin API of the node and decodes the response.
+func adminApiRequest(cmd *DeltaCmdNode, method string, path string, payload interface{}, response interface{}) error {
+	var body bytes.Buffer
+	if payload != nil {
+		if err := json.NewEncoder(&body).Encode(payload); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequest(method, cmd.DeltaApi+path, &body)
+	if err != nil {
+		return fmt.Errorf("could not construct http request %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+cmd.DeltaAuth)
+	req.Header.Set("Content-Type", "application/json")
+
+	client := &http.Client{}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("could not make http request %s", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden {
+		return fmt.Errorf("the api key is not allowed to administer the node: %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
+
+func printAdminResponse(response interface{}) error {
+	var buffer bytes.Buffer
+	if err := utils.PrettyEncode(response, &buffer); err != nil {
+		return err
+	}
+	fmt.Println(buffer.String())
+	return nil
+}
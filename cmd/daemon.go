@@ -4,6 +4,7 @@ import (
 	"delta/api"
 	c "delta/config"
 	"delta/core"
+	"delta/jobs"
 	_ "delta/models"
 	"delta/utils"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"github.com/jasonlvhit/gocron"
 	"github.com/urfave/cli/v2"
 	"runtime"
+	"time"
 )
 
 // DaemonCmd Creating a new command called `daemon` that will run the API node.
@@ -158,6 +160,16 @@ func DaemonCmd(cfg *c.DeltaConfig) []*cli.Command {
 			core.SetDataTransferEventsSubscribe(ln)
 			fmt.Println(utils.Blue + "Subscribing the event listeners... DONE" + utils.Reset)
 
+			// start the worker pools of the dispatcher
+			fmt.Println(utils.Blue + "Starting the dispatcher workers" + utils.Reset)
+			ln.Dispatcher.Start()
+			fmt.Println(utils.Blue + "Starting the dispatcher workers... DONE" + utils.Reset)
+
+			// resume the jobs that were queued or running before the restart
+			fmt.Println(utils.Blue + "Resuming persisted jobs" + utils.Reset)
+			core.ResumePersistedJobs(ln)
+			fmt.Println(utils.Blue + "Resuming persisted jobs... DONE" + utils.Reset)
+
 			// run the clean up every 30 minutes so we can retry and also remove the unecessary files on the blockstore.
 			fmt.Println(utils.Blue + "Running the atomic cron jobs" + utils.Reset)
 			RunScheduledCleanupAndRetryCron(ln)
@@ -208,17 +220,41 @@ By: Protocol Labs - Outercore Engineering
 // `RunScheduledCleanupAndRetryCron` is a function that runs a cron job on a node
 func RunScheduledCleanupAndRetryCron(ln *core.DeltaNode) {
 	fmt.Println(utils.Purple + "Scheduling dispatchers and scanners..." + utils.Reset)
-	maxCleanUpJobs := ln.Config.Dispatcher.MaxCleanupWorkers
 
 	s := gocron.NewScheduler()
 	s.Every(24).Hour().Do(func() { // let's clean and retry every 30 minutes. It'll only get the old data.
-		dispatcher := core.CreateNewDispatcher()
-		//dispatcher.AddJob(jobs.NewItemContentCleanUpProcessor(ln))
-		//dispatcher.AddJob(jobs.NewRetryProcessor(ln))
-		dispatcher.Start(maxCleanUpJobs)
+		//ln.Dispatcher.AddJob(jobs.NewItemContentCleanUpProcessor(ln))
+		//ln.Dispatcher.AddJob(jobs.NewRetryProcessor(ln))
 
 		core.CleanUpContentAndPieceComm(ln)
 		core.ScanHostComputeResources(ln, ln.Node.Config.Blockstore)
+		core.DeleteExpiredIdempotencyKeys(ln.DB, time.Now())
+	})
+
+	// score the storage providers from their deal outcomes
+	s.Every(uint64(ln.Config.MinerSelection.ReputationInterval)).Minutes().Do(func() {
+		ln.Dispatcher.AddJob(jobs.NewMinerReputationProcessor(ln))
+	})
+
+	// resolve the deal ids of the published deals, then record their activation and slashing
+	s.Every(uint64(ln.Config.DealTracking.ActivationCheckInterval)).Minutes().Do(func() {
+		ln.Dispatcher.AddJob(jobs.NewDealPublishResolverProcessor(ln))
+		ln.Dispatcher.AddJob(jobs.NewDealActivationTrackerProcessor(ln))
+	})
+
+	// check the deal status of the contents in a non-final state
+	s.Every(uint64(ln.Config.DealTracking.StatusCheckInterval)).Minutes().Do(func() {
+		ln.Dispatcher.AddJob(jobs.NewDealStatusReconcilerProcessor(ln))
+	})
+
+	// renew the deals about to expire of the contents that opted in
+	s.Every(uint64(ln.Config.DealRenewal.CheckInterval)).Minutes().Do(func() {
+		ln.Dispatcher.AddJob(jobs.NewDealRenewalProcessor(ln))
+	})
+
+	// detect the slashed, terminated and faulty deals, and replace the slashed deals of the contents that opted in
+	s.Every(uint64(ln.Config.DealFaults.CheckInterval)).Minutes().Do(func() {
+		ln.Dispatcher.AddJob(jobs.NewDealFaultMonitorProcessor(ln))
 	})
 
 	s.Start()
@@ -50,6 +50,20 @@ type StatusResponse struct {
 	} `json:"piece_commitments"`
 }
 
+type StatusHistoryResponse struct {
+	ContentID int64 `json:"content_id"`
+	History   []struct {
+		ObjectType string    `json:"object_type"`
+		ObjectID   int64     `json:"object_id"`
+		OldStatus  string    `json:"old_status"`
+		NewStatus  string    `json:"new_status"`
+		Message    string    `json:"message"`
+		Source     string    `json:"source"`
+		CreatedAt  time.Time `json:"created_at"`
+	} `json:"history"`
+	Message string `json:"message"`
+}
+
 func StatusCmd(cfg *c.DeltaConfig) []*cli.Command {
 	// add a command to run API node
 	var statusCommands []*cli.Command
@@ -67,6 +81,10 @@ func StatusCmd(cfg *c.DeltaConfig) []*cli.Command {
 				Name:  "id",
 				Usage: "the id of the content, deal, or piece-commitment",
 			},
+			&cli.BoolFlag{
+				Name:  "history",
+				Usage: "show every status change of the content, its deals and its piece commitment",
+			},
 		},
 		Action: func(context *cli.Context) error {
 			cmd, err := NewDeltaCmdNode(context)
@@ -81,6 +99,10 @@ func StatusCmd(cfg *c.DeltaConfig) []*cli.Command {
 			fmt.Println(typeParam)
 			fmt.Println(cmd.DeltaAuth)
 			fmt.Println(idParam)
+			if typeParam == "content" && context.Bool("history") {
+				return printStatusHistory(cmd.DeltaApi+"/open/status/content/"+idParam+"/history", cmd.DeltaAuth)
+			}
+
 			var dealStatusResponse StatusResponse
 			url := cmd.DeltaApi + "/open/stats/" + typeParam + "/" + idParam
 			if typeParam == "content" {
@@ -131,3 +153,36 @@ func StatusCmd(cfg *c.DeltaConfig) []*cli.Command {
 
 	return statusCommands
 }
+
+// printStatusHistory prints the status changes of a content, one per line, the oldest first.
+func printStatusHistory(url string, auth string) error {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+auth)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var historyResponse StatusHistoryResponse
+	if err := json.NewDecoder(resp.Body).Decode(&historyResponse); err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", resp.Status, historyResponse.Message)
+	}
+
+	for _, transition := range historyResponse.History {
+		oldStatus := transition.OldStatus
+		if oldStatus == "" {
+			oldStatus = "-"
+		}
+		fmt.Printf("%s  %s %d  %s -> %s  [%s]  %s\n", transition.CreatedAt.Format(time.RFC3339), transition.ObjectType,
+			transition.ObjectID, oldStatus, transition.NewStatus, transition.Source, transition.Message)
+	}
+	return nil
+}
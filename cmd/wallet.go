@@ -26,7 +26,7 @@ type WalletListResponse struct {
 		ID         int       `json:"ID"`
 		UUID       string    `json:"uuid"`
 		Addr       string    `json:"addr"`
-		Owner      string    `json:"owner"`
+		TenantID   int64     `json:"tenant_id"`
 		KeyType    string    `json:"key_type"`
 		PrivateKey string    `json:"private_key"`
 		CreatedAt  time.Time `json:"created_at"`
@@ -106,6 +106,10 @@ func WalletCmd(cfg *c.DeltaConfig) []*cli.Command {
 						Name:  "hex",
 						Usage: "Hexed wallet from LOTUS/BOOSTD export",
 					},
+					&cli.StringFlag{
+						Name:  "tenant",
+						Usage: "uuid of the tenant of the wallet, the tenant of the API key if empty",
+					},
 				},
 				Action: func(context *cli.Context) error {
 					cmd, err := NewDeltaCmdNode(context)
@@ -117,6 +121,7 @@ func WalletCmd(cfg *c.DeltaConfig) []*cli.Command {
 					url := cmd.DeltaApi + "/admin/wallet/register-hex"
 					payload := map[string]string{
 						"hex_key": hexParam,
+						"tenant":  context.String("tenant"),
 					}
 					data, err := json.Marshal(payload)
 					if err != nil {
@@ -150,6 +155,12 @@ func WalletCmd(cfg *c.DeltaConfig) []*cli.Command {
 			{
 				Name:  "list",
 				Usage: "List all wallets associated with the API key",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "tenant",
+						Usage: "uuid of the tenant of the wallets, the tenant of the API key if empty",
+					},
+				},
 				Action: func(context *cli.Context) error {
 					cmd, err := NewDeltaCmdNode(context)
 					if err != nil {
@@ -157,6 +168,9 @@ func WalletCmd(cfg *c.DeltaConfig) []*cli.Command {
 					}
 
 					url := cmd.DeltaApi + "/admin/wallet/list"
+					if tenant := context.String("tenant"); tenant != "" {
+						url += "?tenant=" + tenant
+					}
 					req, err := http.NewRequest("GET", url, nil)
 					if err != nil {
 						panic(err)
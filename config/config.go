@@ -24,7 +24,99 @@ type DeltaConfig struct {
 	}
 
 	Dispatcher struct {
-		MaxCleanupWorkers int `env:"MAX_CLEANUP_WORKERS" envDefault:"1500"`
+		MaxCleanupWorkers     int `env:"MAX_CLEANUP_WORKERS" envDefault:"1500"`
+		MaxCommpWorkers       int `env:"MAX_COMMP_WORKERS" envDefault:"5"`
+		MaxDealMakingWorkers  int `env:"MAX_DEAL_MAKING_WORKERS" envDefault:"20"`
+		MaxStatusCheckWorkers int `env:"MAX_STATUS_CHECK_WORKERS" envDefault:"10"`
+		JobQueueCapacity      int `env:"JOB_QUEUE_CAPACITY" envDefault:"1000"` // per queue
+		JobPollInterval       int `env:"JOB_POLL_INTERVAL" envDefault:"10"`    // seconds
+		JobLeaseDuration      int `env:"JOB_LEASE_DURATION" envDefault:"60"`   // minutes
+		MaxJobResumeAttempts  int `env:"MAX_JOB_RESUME_ATTEMPTS" envDefault:"3"`
+		CommpJobTimeout       int `env:"COMMP_JOB_TIMEOUT" envDefault:"30"`       // minutes
+		DealMakingJobTimeout  int `env:"DEAL_MAKING_JOB_TIMEOUT" envDefault:"20"` // minutes
+		StatusCheckJobTimeout int `env:"STATUS_CHECK_JOB_TIMEOUT" envDefault:"5"` // minutes
+		CleanupJobTimeout     int `env:"CLEANUP_JOB_TIMEOUT" envDefault:"30"`     // minutes
+	}
+
+	// storage provider selection, the strategy of a deal request overrides the default one
+	MinerSelection struct {
+		DefaultStrategy    string   `env:"MINER_SELECTION_STRATEGY" envDefault:"remote-api"`
+		StaticMiners       []string `env:"MINER_SELECTION_STATIC_MINERS" envSeparator:","`
+		WeightedMiners     string   `env:"MINER_SELECTION_WEIGHTED_MINERS"`                     // f01234:3,f05678:1
+		MinReputationScore float64  `env:"MINER_SELECTION_MIN_REPUTATION_SCORE" envDefault:"0"` // 0 to 1, 0 disables
+		MinReputationDeals int64    `env:"MINER_SELECTION_MIN_REPUTATION_DEALS" envDefault:"10"`
+		ReputationInterval int      `env:"MINER_REPUTATION_INTERVAL" envDefault:"60"`          // minutes
+		RemoteApiTimeout   int      `env:"MINER_SELECTION_REMOTE_API_TIMEOUT" envDefault:"10"` // seconds
+	}
+
+	// on-chain tracking of the published deals
+	DealTracking struct {
+		ActivationCheckInterval int `env:"DEAL_ACTIVATION_CHECK_INTERVAL" envDefault:"30"` // minutes
+		PublishConfirmations    int `env:"DEAL_PUBLISH_CONFIRMATIONS" envDefault:"10"`     // epochs
+		StatusCheckInterval     int `env:"DEAL_STATUS_CHECK_INTERVAL" envDefault:"15"`     // minutes
+		StatusCheckBatchSize    int `env:"DEAL_STATUS_CHECK_BATCH_SIZE" envDefault:"100"`
+		StatusCheckMinerSpacing int `env:"DEAL_STATUS_CHECK_MINER_SPACING" envDefault:"5"` // seconds between two checks of the same storage provider
+	}
+
+	// renewal of the active deals of the contents that opted in, before they expire
+	DealRenewal struct {
+		CheckInterval   int  `env:"DEAL_RENEWAL_CHECK_INTERVAL" envDefault:"360"`     // minutes
+		Window          int  `env:"DEAL_RENEWAL_WINDOW" envDefault:"40320"`           // epochs before the end of a deal, 14 days
+		StartEpochDelay int  `env:"DEAL_RENEWAL_START_EPOCH_DELAY" envDefault:"8640"` // epochs, 3 days
+		SwitchProvider  bool `env:"DEAL_RENEWAL_SWITCH_PROVIDER" envDefault:"false"`  // renew with another storage provider
+		MaxAttempts     int  `env:"DEAL_RENEWAL_MAX_ATTEMPTS" envDefault:"3"`         // failed renewals before giving up
+	}
+
+	// detection of the slashed, terminated and faulty deals, and their replacement for the contents that opted in
+	DealFaults struct {
+		CheckInterval          int `env:"DEAL_FAULT_CHECK_INTERVAL" envDefault:"60"`          // minutes
+		MaxReplacementAttempts int `env:"DEAL_FAULT_MAX_REPLACEMENT_ATTEMPTS" envDefault:"3"` // failed replacements before giving up
+	}
+
+	// retry policy of each deal error category, backoff is in seconds
+	DealRetry struct {
+		BackoffBase                     int  `env:"RETRY_BACKOFF_BASE" envDefault:"30"`
+		BackoffCap                      int  `env:"RETRY_BACKOFF_CAP" envDefault:"3600"`
+		TransientNetworkMaxAttempts     int  `env:"RETRY_TRANSIENT_NETWORK_MAX_ATTEMPTS" envDefault:"3"`
+		TransientNetworkBackoff         int  `env:"RETRY_TRANSIENT_NETWORK_BACKOFF" envDefault:"60"`
+		TransientNetworkSwitchProvider  bool `env:"RETRY_TRANSIENT_NETWORK_SWITCH_PROVIDER" envDefault:"true"`
+		PriceRejectedMaxAttempts        int  `env:"RETRY_PRICE_REJECTED_MAX_ATTEMPTS" envDefault:"3"`
+		PriceRejectedBackoff            int  `env:"RETRY_PRICE_REJECTED_BACKOFF" envDefault:"0"`
+		PriceRejectedSwitchProvider     bool `env:"RETRY_PRICE_REJECTED_SWITCH_PROVIDER" envDefault:"true"`
+		DealTypeRejectedMaxAttempts     int  `env:"RETRY_DEAL_TYPE_REJECTED_MAX_ATTEMPTS" envDefault:"3"`
+		DealTypeRejectedBackoff         int  `env:"RETRY_DEAL_TYPE_REJECTED_BACKOFF" envDefault:"0"`
+		DealTypeRejectedSwitchProvider  bool `env:"RETRY_DEAL_TYPE_REJECTED_SWITCH_PROVIDER" envDefault:"true"`
+		PieceTooSmallMaxAttempts        int  `env:"RETRY_PIECE_TOO_SMALL_MAX_ATTEMPTS" envDefault:"2"`
+		PieceTooSmallBackoff            int  `env:"RETRY_PIECE_TOO_SMALL_BACKOFF" envDefault:"0"`
+		PieceTooSmallSwitchProvider     bool `env:"RETRY_PIECE_TOO_SMALL_SWITCH_PROVIDER" envDefault:"true"`
+		InsufficientFundsMaxAttempts    int  `env:"RETRY_INSUFFICIENT_FUNDS_MAX_ATTEMPTS" envDefault:"2"`
+		InsufficientFundsBackoff        int  `env:"RETRY_INSUFFICIENT_FUNDS_BACKOFF" envDefault:"300"`
+		InsufficientFundsSwitchProvider bool `env:"RETRY_INSUFFICIENT_FUNDS_SWITCH_PROVIDER" envDefault:"true"`
+		UnknownMaxAttempts              int  `env:"RETRY_UNKNOWN_MAX_ATTEMPTS" envDefault:"0"`
+		UnknownBackoff                  int  `env:"RETRY_UNKNOWN_BACKOFF" envDefault:"0"`
+		UnknownSwitchProvider           bool `env:"RETRY_UNKNOWN_SWITCH_PROVIDER" envDefault:"false"`
+	}
+
+	// limits of the deal requests, the maximum replication is MaxReplicationFactor
+	DealLimits struct {
+		MaxStartEpochInDays int64 `env:"DEAL_MAX_START_EPOCH_IN_DAYS" envDefault:"14"`
+		MaxDurationInDays   int64 `env:"DEAL_MAX_DURATION_IN_DAYS" envDefault:"540"`
+		MaxLabelLength      int   `env:"DEAL_MAX_LABEL_LENGTH" envDefault:"100"`
+		MinVerifiedDealSize int64 `env:"DEAL_MIN_VERIFIED_SIZE" envDefault:"1048576"` // bytes, lotus rejects smaller verified deals
+	}
+
+	// limits of the deal requests of each tenant, 0 is unlimited. The limits of a tenant set by the admins override them
+	TenantLimits struct {
+		MaxConcurrentTransfers int64 `env:"TENANT_MAX_CONCURRENT_TRANSFERS" envDefault:"0"`
+		MaxBytesPerDay         int64 `env:"TENANT_MAX_BYTES_PER_DAY" envDefault:"0"`
+		MaxDealsPerHour        int64 `env:"TENANT_MAX_DEALS_PER_HOUR" envDefault:"0"`
+		MaxReplication         int64 `env:"TENANT_MAX_REPLICATION" envDefault:"0"` // at most MAX_REPLICATION_FACTOR
+	}
+
+	// idempotency keys of the deal requests
+	Idempotency struct {
+		Retention   int `env:"IDEMPOTENCY_KEY_RETENTION" envDefault:"24"`    // hours a response is replayed
+		LockTimeout int `env:"IDEMPOTENCY_KEY_LOCK_TIMEOUT" envDefault:"60"` // minutes before an unfinished request can run again
 	}
 
 	Common struct {
@@ -50,6 +142,22 @@ type DeltaConfig struct {
 		DealStatusApi  string `env:"DEAL_STATUS_API" envDefault:"https://deal-status.estuary.tech"`
 	}
 
+	// authentication of the requests, local in standalone mode and remote in cluster mode if the backend is empty
+	Auth struct {
+		Backend                string `env:"AUTH_BACKEND" envDefault:""`                   // local, remote or jwt
+		RemoteTimeout          int    `env:"AUTH_REMOTE_TIMEOUT" envDefault:"5"`           // seconds
+		RemoteCacheTTL         int    `env:"AUTH_REMOTE_CACHE_TTL" envDefault:"300"`       // seconds a validated key is cached
+		RemoteFailureThreshold int    `env:"AUTH_REMOTE_FAILURE_THRESHOLD" envDefault:"5"` // failures in a row that open the circuit
+		RemoteBreakerCooldown  int    `env:"AUTH_REMOTE_BREAKER_COOLDOWN" envDefault:"30"` // seconds the circuit stays open
+		RemoteRole             string `env:"AUTH_REMOTE_ROLE" envDefault:"dealmaker"`      // role of the keys of the auth service
+		JwksFile               string `env:"AUTH_JWKS_FILE"`
+		JwtIssuer              string `env:"AUTH_JWT_ISSUER"`   // not checked if empty
+		JwtAudience            string `env:"AUTH_JWT_AUDIENCE"` // not checked if empty
+		JwtScopesClaim         string `env:"AUTH_JWT_SCOPES_CLAIM" envDefault:"scope"`
+		JwtRoleClaim           string `env:"AUTH_JWT_ROLE_CLAIM" envDefault:"role"` // read-only if the token has no role
+	}
+
+	// the admin key of a standalone node, it creates the other keys with `delta admin key create`
 	Standalone struct {
 		APIKey string `env:"DELTA_AUTH" envDefault:""`
 	}
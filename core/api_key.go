@@ -0,0 +1,168 @@
+package core
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	model "delta/models"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// the keys issued by the node start with the prefix, so they are told apart from the keys of estuary-auth
+const apiKeyPrefix = "DEL"
+
+// the last use of a key is only recorded once a minute, not on every request
+const apiKeyLastUsedResolution = time.Minute
+
+var (
+	// ErrApiKeyNotFound is returned for a key the node didn't issue.
+	ErrApiKeyNotFound = errors.New("api key not found")
+	// ErrApiKeyRevoked is returned for a revoked key.
+	ErrApiKeyRevoked = errors.New("api key was revoked")
+	// ErrApiKeyExpired is returned for a key past its expiry.
+	ErrApiKeyExpired = errors.New("api key is expired")
+)
+
+// CreateApiKeyParam is the API key to issue.
+// @property TenantID - The tenant of the key, a new tenant named after the label if zero.
+// @property Label - The description of the key.
+// @property Role - The role of the key: admin, dealmaker or read-only, dealmaker if empty.
+// @property Scopes - The scopes of the key, all the scopes of its role if empty.
+// @property ExpiresAt - When the key expires, never if zero.
+type CreateApiKeyParam struct {
+	TenantID  int64
+	Label     string
+	Role      string
+	Scopes    []string
+	ExpiresAt time.Time
+}
+
+// HashApiKey returns the sha256 of an API key, the only form it is stored in.
+func HashApiKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// CreateApiKey issues a new API key. It returns the record of the key and the key itself, which can't be retrieved
+// afterwards.
+func CreateApiKey(db *gorm.DB, param CreateApiKeyParam) (model.ApiKey, string, error) {
+	role := param.Role
+	if role == "" {
+		role = model.API_KEY_ROLE_DEALMAKER
+	}
+	if _, ok := model.API_KEY_ROLE_SCOPES[role]; !ok {
+		return model.ApiKey{}, "", fmt.Errorf("invalid role %s, the roles are %s, %s and %s", role, model.API_KEY_ROLE_ADMIN, model.API_KEY_ROLE_DEALMAKER, model.API_KEY_ROLE_READ_ONLY)
+	}
+	scopes := param.Scopes
+	if len(scopes) == 0 {
+		scopes = model.API_KEY_ROLE_SCOPES[role]
+	}
+	for _, scope := range scopes {
+		if !isApiKeyScope(scope) {
+			return model.ApiKey{}, "", fmt.Errorf("invalid scope %s, the scopes are %s", scope, strings.Join(model.API_KEY_SCOPES, ", "))
+		}
+		if !model.RoleAllowsScope(role, scope) {
+			return model.ApiKey{}, "", fmt.Errorf("the %s role doesn't allow the %s scope", role, scope)
+		}
+	}
+	if !param.ExpiresAt.IsZero() && param.ExpiresAt.Before(time.Now()) {
+		return model.ApiKey{}, "", errors.New("the expiry of the key is in the past")
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return model.ApiKey{}, "", err
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+	keyUuid, err := uuid.NewUUID()
+	if err != nil {
+		return model.ApiKey{}, "", err
+	}
+	tenantId := param.TenantID
+	if tenantId == 0 {
+		tenant, err := CreateTenant(db, param.Label)
+		if err != nil {
+			return model.ApiKey{}, "", err
+		}
+		tenantId = tenant.ID
+	}
+	apiKey := model.ApiKey{
+		UuId:      keyUuid.String(),
+		TenantID:  tenantId,
+		Label:     param.Label,
+		Prefix:    key[:len(apiKeyPrefix)+8],
+		KeyHash:   HashApiKey(key),
+		Role:      role,
+		Scopes:    strings.Join(scopes, ","),
+		ExpiresAt: param.ExpiresAt,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.Create(&apiKey).Error; err != nil {
+		return model.ApiKey{}, "", err
+	}
+	return apiKey, key, nil
+}
+
+// ListApiKeys returns all the API keys issued by the node, the revoked and expired ones included.
+func ListApiKeys(db *gorm.DB) ([]model.ApiKey, error) {
+	var apiKeys []model.ApiKey
+	err := db.Model(&model.ApiKey{}).Order("id asc").Find(&apiKeys).Error
+	return apiKeys, err
+}
+
+// RevokeApiKey revokes the API key with the uuid, it can't authenticate anymore.
+func RevokeApiKey(db *gorm.DB, keyUuid string) (model.ApiKey, error) {
+	var apiKey model.ApiKey
+	db.Model(&model.ApiKey{}).Where("uu_id = ?", keyUuid).Find(&apiKey)
+	if apiKey.ID == 0 {
+		return model.ApiKey{}, ErrApiKeyNotFound
+	}
+	if !apiKey.RevokedAt.IsZero() {
+		return apiKey, nil
+	}
+	apiKey.RevokedAt = time.Now()
+	apiKey.UpdatedAt = time.Now()
+	return apiKey, db.Save(&apiKey).Error
+}
+
+// AuthenticateApiKey returns the record of an API key issued by the node, or an error if the key is unknown, revoked
+// or expired. It is only a database lookup, no external service is called.
+func AuthenticateApiKey(db *gorm.DB, key string, now time.Time) (model.ApiKey, error) {
+	var apiKey model.ApiKey
+	db.Model(&model.ApiKey{}).Where("key_hash = ?", HashApiKey(key)).Find(&apiKey)
+	if apiKey.ID == 0 {
+		return model.ApiKey{}, ErrApiKeyNotFound
+	}
+	if !apiKey.RevokedAt.IsZero() {
+		return model.ApiKey{}, ErrApiKeyRevoked
+	}
+	if !apiKey.ExpiresAt.IsZero() && !now.Before(apiKey.ExpiresAt) {
+		return model.ApiKey{}, ErrApiKeyExpired
+	}
+	if now.Sub(apiKey.LastUsedAt) >= apiKeyLastUsedResolution {
+		apiKey.LastUsedAt = now
+		db.Model(&model.ApiKey{}).Where("id = ?", apiKey.ID).Update("last_used_at", now)
+	}
+	return apiKey, nil
+}
+
+// IsNodeApiKey returns true if the key has the format of the keys issued by the node.
+func IsNodeApiKey(key string) bool {
+	return strings.HasPrefix(key, apiKeyPrefix) && len(key) == len(apiKeyPrefix)+64
+}
+
+func isApiKeyScope(scope string) bool {
+	for _, s := range model.API_KEY_SCOPES {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
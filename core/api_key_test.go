@@ -0,0 +1,126 @@
+package core
+
+import (
+	model "delta/models"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCreateApiKey(t *testing.T) {
+	db := newTestDB(t)
+
+	apiKey, key, err := CreateApiKey(db, CreateApiKeyParam{Label: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsNodeApiKey(key) {
+		t.Fatalf("expected a key issued by the node, got %s", key)
+	}
+	if apiKey.KeyHash == key || apiKey.KeyHash != HashApiKey(key) {
+		t.Fatal("expected only the hash of the key to be stored")
+	}
+	if apiKey.Role != model.API_KEY_ROLE_DEALMAKER || apiKey.Scopes != "deal,read" {
+		t.Fatalf("expected the dealmaker role and its scopes by default, got %s %s", apiKey.Role, apiKey.Scopes)
+	}
+
+	tests := []struct {
+		param   CreateApiKeyParam
+		wantErr bool
+	}{
+		{CreateApiKeyParam{Role: model.API_KEY_ROLE_ADMIN, Scopes: []string{model.API_KEY_SCOPE_ADMIN}}, false},
+		{CreateApiKeyParam{Scopes: []string{model.API_KEY_SCOPE_ADMIN}}, true}, // not allowed to a dealmaker
+		{CreateApiKeyParam{Scopes: []string{"owner"}}, true},
+		{CreateApiKeyParam{Role: "owner"}, true},
+		{CreateApiKeyParam{ExpiresAt: time.Now().Add(time.Hour)}, false},
+		{CreateApiKeyParam{ExpiresAt: time.Now().Add(-time.Hour)}, true},
+	}
+	for i, tt := range tests {
+		if _, _, err := CreateApiKey(db, tt.param); (err != nil) != tt.wantErr {
+			t.Errorf("key %d: expected error %v, got %v", i, tt.wantErr, err)
+		}
+	}
+
+	apiKeys, err := ListApiKeys(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(apiKeys) != 3 {
+		t.Fatalf("expected 3 api keys, got %d", len(apiKeys))
+	}
+}
+
+func TestApiKeyHasRole(t *testing.T) {
+	tests := []struct {
+		apiKey model.ApiKey
+		role   string
+		want   bool
+	}{
+		{model.ApiKey{Role: model.API_KEY_ROLE_ADMIN, Scopes: "deal,read,admin"}, model.API_KEY_ROLE_ADMIN, true},
+		{model.ApiKey{Role: model.API_KEY_ROLE_ADMIN, Scopes: "deal,read,admin"}, model.API_KEY_ROLE_READ_ONLY, true},
+		{model.ApiKey{Role: model.API_KEY_ROLE_ADMIN, Scopes: "read,admin"}, model.API_KEY_ROLE_DEALMAKER, false}, // narrowed by its scopes
+		{model.ApiKey{Role: model.API_KEY_ROLE_DEALMAKER, Scopes: "deal,read"}, model.API_KEY_ROLE_DEALMAKER, true},
+		{model.ApiKey{Role: model.API_KEY_ROLE_DEALMAKER, Scopes: "deal,read,admin"}, model.API_KEY_ROLE_ADMIN, false},
+		{model.ApiKey{Role: model.API_KEY_ROLE_READ_ONLY, Scopes: "deal,read"}, model.API_KEY_ROLE_DEALMAKER, false},
+		{model.ApiKey{Role: model.API_KEY_ROLE_READ_ONLY, Scopes: "read"}, model.API_KEY_ROLE_READ_ONLY, true},
+		{model.ApiKey{Scopes: "deal,read,admin"}, model.API_KEY_ROLE_READ_ONLY, false},
+	}
+	for i, tt := range tests {
+		if got := tt.apiKey.HasRole(tt.role); got != tt.want {
+			t.Errorf("key %d: expected HasRole(%s) %v, got %v", i, tt.role, tt.want, got)
+		}
+	}
+}
+
+func TestAuthenticateApiKey(t *testing.T) {
+	db := newTestDB(t)
+	_, key, err := CreateApiKey(db, CreateApiKeyParam{Label: "valid"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	revoked, revokedKey, err := CreateApiKey(db, CreateApiKeyParam{Label: "revoked"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RevokeApiKey(db, revoked.UuId); err != nil {
+		t.Fatal(err)
+	}
+	_, expiringKey, err := CreateApiKey(db, CreateApiKeyParam{Label: "expiring", ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	tests := []struct {
+		key  string
+		now  time.Time
+		want error
+	}{
+		{key, now, nil},
+		{revokedKey, now, ErrApiKeyRevoked},
+		{expiringKey, now, nil},
+		{expiringKey, now.Add(2 * time.Hour), ErrApiKeyExpired},
+		{"DEL" + strings.Repeat("0", 64), now, ErrApiKeyNotFound},
+	}
+	for i, tt := range tests {
+		if _, err := AuthenticateApiKey(db, tt.key, tt.now); !errors.Is(err, tt.want) {
+			t.Errorf("key %d: expected %v, got %v", i, tt.want, err)
+		}
+	}
+
+	// the use of the key is recorded
+	apiKey, err := AuthenticateApiKey(db, key, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored model.ApiKey
+	db.First(&stored, apiKey.ID)
+	if stored.LastUsedAt.IsZero() {
+		t.Fatal("expected the last use of the key to be recorded")
+	}
+
+	if _, err := RevokeApiKey(db, "unknown"); !errors.Is(err, ErrApiKeyNotFound) {
+		t.Fatalf("expected ErrApiKeyNotFound, got %v", err)
+	}
+}
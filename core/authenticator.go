@@ -0,0 +1,290 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	c "delta/config"
+	model "delta/models"
+	"delta/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrInvalidToken is returned for a token the authenticator rejects.
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrAuthServiceUnavailable is returned when the remote auth service can't be reached, or its circuit is open.
+	ErrAuthServiceUnavailable = errors.New("auth service unavailable")
+)
+
+// IAuthenticator checks the bearer token of a request and returns the API key it stands for, with its scopes. A key
+// that isn't stored by the node has the Subject of its tenant instead of a TenantID.
+type IAuthenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, token string) (model.ApiKey, error)
+}
+
+// NewAuthenticator creates the authenticator of the backend of the node configuration. The keys issued by the node are
+// always checked against the local database, whatever the backend, and every authenticated key gets a tenant.
+func NewAuthenticator(db *gorm.DB, config *c.DeltaConfig) (IAuthenticator, error) {
+	authenticator, err := newBackendAuthenticator(db, config)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantAuthenticator{DB: db, Backend: authenticator}, nil
+}
+
+func newBackendAuthenticator(db *gorm.DB, config *c.DeltaConfig) (IAuthenticator, error) {
+	local := NewLocalAuthenticator(db, config.Standalone.APIKey)
+	backend := config.Auth.Backend
+	if backend == "" {
+		backend = utils.AUTH_BACKEND_REMOTE
+		if config.Common.Mode == "standalone" {
+			backend = utils.AUTH_BACKEND_LOCAL
+		}
+	}
+
+	switch backend {
+	case utils.AUTH_BACKEND_LOCAL:
+		return local, nil
+	case utils.AUTH_BACKEND_REMOTE:
+		if _, ok := model.API_KEY_ROLE_SCOPES[config.Auth.RemoteRole]; !ok {
+			return nil, fmt.Errorf("invalid role %s of the remote auth backend", config.Auth.RemoteRole)
+		}
+		return &nodeApiKeyAuthenticator{
+			Local: local,
+			Backend: NewRemoteAuthenticator(config.ExternalApis.AuthSvcApi,
+				time.Duration(config.Auth.RemoteTimeout)*time.Second,
+				time.Duration(config.Auth.RemoteCacheTTL)*time.Second,
+				config.Auth.RemoteFailureThreshold,
+				time.Duration(config.Auth.RemoteBreakerCooldown)*time.Second,
+				config.Auth.RemoteRole),
+		}, nil
+	case utils.AUTH_BACKEND_JWT:
+		jwtAuthenticator, err := NewJwtAuthenticatorFromFile(config.Auth.JwksFile, config.Auth.JwtIssuer, config.Auth.JwtAudience, config.Auth.JwtScopesClaim, config.Auth.JwtRoleClaim)
+		if err != nil {
+			return nil, err
+		}
+		return &nodeApiKeyAuthenticator{Local: local, Backend: jwtAuthenticator}, nil
+	}
+	return nil, fmt.Errorf("unknown auth backend %s, the backends are %s, %s and %s", backend, utils.AUTH_BACKEND_LOCAL, utils.AUTH_BACKEND_REMOTE, utils.AUTH_BACKEND_JWT)
+}
+
+// LocalAuthenticator checks the API keys issued by the node against the database, and the DELTA_AUTH key, the admin
+// key of the node. It never leaves the node.
+type LocalAuthenticator struct {
+	DB        *gorm.DB
+	StaticKey string
+}
+
+// NewLocalAuthenticator creates an authenticator of the keys of the database and of the static DELTA_AUTH key
+func NewLocalAuthenticator(db *gorm.DB, staticKey string) *LocalAuthenticator {
+	return &LocalAuthenticator{
+		DB:        db,
+		StaticKey: staticKey,
+	}
+}
+
+func (a *LocalAuthenticator) Name() string {
+	return utils.AUTH_BACKEND_LOCAL
+}
+
+func (a *LocalAuthenticator) Authenticate(ctx context.Context, token string) (model.ApiKey, error) {
+	if a.StaticKey != "" && token == a.StaticKey {
+		return model.ApiKey{Label: "DELTA_AUTH", Role: model.API_KEY_ROLE_ADMIN, Scopes: strings.Join(model.API_KEY_SCOPES, ","), Subject: staticKeyTenantSubject}, nil
+	}
+	apiKey, err := AuthenticateApiKey(a.DB, token, time.Now())
+	if err != nil {
+		return model.ApiKey{}, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+	return apiKey, nil
+}
+
+// RemoteAuthenticator checks the tokens against the estuary-auth service. The validated tokens are cached, and after
+// too many failures in a row the service isn't called until the cooldown is over, only the cached tokens are accepted.
+// The validated tokens all get the same role.
+type RemoteAuthenticator struct {
+	ApiUrl           string
+	Client           *http.Client
+	CacheTTL         time.Duration
+	FailureThreshold int
+	Cooldown         time.Duration
+	Role             string
+
+	lk        sync.Mutex
+	cache     map[string]remoteAuthCacheEntry
+	failures  int
+	openUntil time.Time
+}
+
+// the expired tokens are removed from the cache once it holds that many
+const remoteAuthCacheSweepSize = 10000
+
+type remoteAuthCacheEntry struct {
+	apiKey    model.ApiKey
+	expiresAt time.Time
+}
+
+// NewRemoteAuthenticator creates an authenticator that calls the check-api-key endpoint of the given auth api
+func NewRemoteAuthenticator(apiUrl string, timeout time.Duration, cacheTTL time.Duration, failureThreshold int, cooldown time.Duration, role string) *RemoteAuthenticator {
+	return &RemoteAuthenticator{
+		ApiUrl:           apiUrl,
+		Client:           &http.Client{Timeout: timeout},
+		CacheTTL:         cacheTTL,
+		FailureThreshold: failureThreshold,
+		Cooldown:         cooldown,
+		Role:             role,
+		cache:            map[string]remoteAuthCacheEntry{},
+	}
+}
+
+func (a *RemoteAuthenticator) Name() string {
+	return utils.AUTH_BACKEND_REMOTE
+}
+
+func (a *RemoteAuthenticator) Authenticate(ctx context.Context, token string) (model.ApiKey, error) {
+	// the tokens are cached by their hash, like the keys of the database
+	tokenHash := HashApiKey(token)
+	now := time.Now()
+	a.lk.Lock()
+	if entry, ok := a.cache[tokenHash]; ok {
+		if now.Before(entry.expiresAt) {
+			a.lk.Unlock()
+			return entry.apiKey, nil
+		}
+		delete(a.cache, tokenHash)
+	}
+	if now.Before(a.openUntil) {
+		a.lk.Unlock()
+		return model.ApiKey{}, fmt.Errorf("%w: too many failures, retrying after %s", ErrAuthServiceUnavailable, a.openUntil.Format(time.RFC3339))
+	}
+	a.lk.Unlock()
+
+	validated, details, err := a.checkApiKey(ctx, token)
+
+	a.lk.Lock()
+	defer a.lk.Unlock()
+	if err != nil && ctx.Err() != nil {
+		// the request was cancelled, the service isn't to blame
+		return model.ApiKey{}, err
+	}
+	if err != nil {
+		a.failures++
+		if a.FailureThreshold > 0 && a.failures >= a.FailureThreshold {
+			a.openUntil = time.Now().Add(a.Cooldown)
+			a.failures = 0
+		}
+		return model.ApiKey{}, fmt.Errorf("%w: %s", ErrAuthServiceUnavailable, err)
+	}
+	a.failures = 0
+	if !validated {
+		return model.ApiKey{}, fmt.Errorf("%w: %s", ErrInvalidToken, details)
+	}
+	apiKey := model.ApiKey{Label: "estuary-auth", Role: a.Role, Scopes: strings.Join(model.API_KEY_ROLE_SCOPES[a.Role], ","), Subject: remoteTenantSubject(token)}
+	if a.CacheTTL > 0 {
+		if len(a.cache) >= remoteAuthCacheSweepSize {
+			a.sweepCache(time.Now())
+		}
+		a.cache[tokenHash] = remoteAuthCacheEntry{apiKey: apiKey, expiresAt: time.Now().Add(a.CacheTTL)}
+	}
+	return apiKey, nil
+}
+
+func (a *RemoteAuthenticator) sweepCache(now time.Time) {
+	for tokenHash, entry := range a.cache {
+		if !now.Before(entry.expiresAt) {
+			delete(a.cache, tokenHash)
+		}
+	}
+}
+
+// checkApiKey calls the auth service. An error means the service didn't answer, not that the token is invalid.
+func (a *RemoteAuthenticator) checkApiKey(ctx context.Context, token string) (bool, string, error) {
+	payload, err := json.Marshal(map[string]string{"token": token})
+	if err != nil {
+		return false, "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.ApiUrl+"/check-api-key", bytes.NewReader(payload))
+	if err != nil {
+		return false, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := a.Client.Do(req)
+	if err != nil {
+		return false, "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return false, "", fmt.Errorf("auth api returned %s", resp.Status)
+	}
+
+	var authResp struct {
+		Result struct {
+			Validated bool   `json:"validated"`
+			Details   string `json:"details"`
+		} `json:"result"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&authResp); err != nil {
+		return false, "empty json body", nil
+	}
+	return authResp.Result.Validated, authResp.Result.Details, nil
+}
+
+// nodeApiKeyAuthenticator checks the keys issued by the node and the DELTA_AUTH key locally, and the other tokens with
+// the backend.
+type nodeApiKeyAuthenticator struct {
+	Local   *LocalAuthenticator
+	Backend IAuthenticator
+}
+
+func (a *nodeApiKeyAuthenticator) Name() string {
+	return a.Backend.Name()
+}
+
+func (a *nodeApiKeyAuthenticator) Authenticate(ctx context.Context, token string) (model.ApiKey, error) {
+	if IsNodeApiKey(token) || (a.Local.StaticKey != "" && token == a.Local.StaticKey) {
+		return a.Local.Authenticate(ctx, token)
+	}
+	return a.Backend.Authenticate(ctx, token)
+}
+
+// tenantAuthenticator resolves the tenant of the keys the backend authenticated without one, the tenant of an identity
+// never changes so it is only looked up once.
+type tenantAuthenticator struct {
+	DB      *gorm.DB
+	Backend IAuthenticator
+
+	tenants sync.Map // tenant id by subject
+}
+
+func (a *tenantAuthenticator) Name() string {
+	return a.Backend.Name()
+}
+
+func (a *tenantAuthenticator) Authenticate(ctx context.Context, token string) (model.ApiKey, error) {
+	apiKey, err := a.Backend.Authenticate(ctx, token)
+	if err != nil || apiKey.TenantID != 0 {
+		return apiKey, err
+	}
+	if apiKey.Subject == "" {
+		return model.ApiKey{}, fmt.Errorf("%w: the caller has no identity", ErrInvalidToken)
+	}
+	if tenantId, ok := a.tenants.Load(apiKey.Subject); ok {
+		apiKey.TenantID = tenantId.(int64)
+		return apiKey, nil
+	}
+	tenant, err := TenantOfSubject(a.DB, apiKey.Subject, apiKey.Label)
+	if err != nil {
+		return model.ApiKey{}, fmt.Errorf("%w: resolving the tenant: %s", ErrAuthServiceUnavailable, err)
+	}
+	a.tenants.Store(apiKey.Subject, tenant.ID)
+	apiKey.TenantID = tenant.ID
+	return apiKey, nil
+}
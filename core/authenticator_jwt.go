@@ -0,0 +1,248 @@
+package core
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"delta/utils"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	model "delta/models"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// the clock skew allowed on the expiry and the not before of a token
+const jwtLeeway = time.Minute
+
+// the algorithms of the tokens, the ones of the RSA and EC keys of a jwks
+var jwtSigningMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+
+// JwtAuthenticator checks json web tokens signed with a key of a jwks file. The role of the token is read from the role
+// claim, read-only if missing, and its scopes from the scopes claim as a space separated string or as an array, all
+// the scopes of the role if missing. The subject of the token, at its issuer, is its tenant.
+type JwtAuthenticator struct {
+	Keys        map[string]crypto.PublicKey // by key id
+	Issuer      string
+	Audience    string
+	ScopesClaim string
+	RoleClaim   string
+}
+
+// jsonWebKey is a key of a jwks file, only the RSA and EC public keys are used.
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// NewJwtAuthenticatorFromFile creates an authenticator of the tokens signed with the keys of the jwks file
+func NewJwtAuthenticatorFromFile(jwksFile string, issuer string, audience string, scopesClaim string, roleClaim string) (*JwtAuthenticator, error) {
+	if jwksFile == "" {
+		return nil, errors.New("the jwt auth backend needs a jwks file, set AUTH_JWKS_FILE")
+	}
+	jwks, err := os.ReadFile(jwksFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading the jwks file %s: %w", jwksFile, err)
+	}
+	return NewJwtAuthenticator(jwks, issuer, audience, scopesClaim, roleClaim)
+}
+
+// NewJwtAuthenticator creates an authenticator of the tokens signed with the keys of the jwks document
+func NewJwtAuthenticator(jwks []byte, issuer string, audience string, scopesClaim string, roleClaim string) (*JwtAuthenticator, error) {
+	var keySet struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := json.Unmarshal(jwks, &keySet); err != nil {
+		return nil, fmt.Errorf("invalid jwks: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range keySet.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		publicKey, err := jwk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s of the jwks: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = publicKey
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("the jwks has no signing key")
+	}
+	if scopesClaim == "" {
+		scopesClaim = "scope"
+	}
+	if roleClaim == "" {
+		roleClaim = "role"
+	}
+	return &JwtAuthenticator{
+		Keys:        keys,
+		Issuer:      issuer,
+		Audience:    audience,
+		ScopesClaim: scopesClaim,
+		RoleClaim:   roleClaim,
+	}, nil
+}
+
+func (a *JwtAuthenticator) Name() string {
+	return utils.AUTH_BACKEND_JWT
+}
+
+func (a *JwtAuthenticator) Authenticate(ctx context.Context, token string) (model.ApiKey, error) {
+	claims, err := a.verify(token, time.Now())
+	if err != nil {
+		return model.ApiKey{}, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	role, _ := claims[a.RoleClaim].(string)
+	if role == "" {
+		role = model.API_KEY_ROLE_READ_ONLY
+	}
+	if _, ok := model.API_KEY_ROLE_SCOPES[role]; !ok {
+		return model.ApiKey{}, fmt.Errorf("%w: unknown role %s", ErrInvalidToken, role)
+	}
+
+	var scopes []string
+	switch claim := claims[a.ScopesClaim].(type) {
+	case string:
+		scopes = strings.Fields(claim)
+	case []interface{}:
+		for _, scope := range claim {
+			if s, ok := scope.(string); ok {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	if _, ok := claims[a.ScopesClaim]; !ok {
+		scopes = model.API_KEY_ROLE_SCOPES[role]
+	}
+	// the subject is the tenant, its tokens can be rotated
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return model.ApiKey{}, fmt.Errorf("%w: the token has no subject", ErrInvalidToken)
+	}
+	issuer, _ := claims["iss"].(string)
+	return model.ApiKey{Label: subject, Role: role, Scopes: strings.Join(scopes, ","), Subject: jwtTenantSubject(issuer, subject)}, nil
+}
+
+// verify checks the signature, the expiry, the issuer and the audience of a token, and returns its claims.
+func (a *JwtAuthenticator) verify(token string, now time.Time) (jwt.MapClaims, error) {
+	// the claims are checked below, with the leeway
+	parser := &jwt.Parser{ValidMethods: jwtSigningMethods, SkipClaimsValidation: true}
+	claims := jwt.MapClaims{}
+	if _, err := parser.ParseWithClaims(token, claims, a.signingKey); err != nil {
+		return nil, err
+	}
+
+	if _, ok := claims["exp"]; !ok {
+		return nil, errors.New("the token has no expiry")
+	}
+	if !claims.VerifyExpiresAt(now.Add(-jwtLeeway).Unix(), true) {
+		return nil, errors.New("the token is expired")
+	}
+	if !claims.VerifyNotBefore(now.Add(jwtLeeway).Unix(), false) {
+		return nil, errors.New("the token is not valid yet")
+	}
+	if a.Issuer != "" && !claims.VerifyIssuer(a.Issuer, true) {
+		return nil, fmt.Errorf("unexpected issuer %v", claims["iss"])
+	}
+	if a.Audience != "" && !claims.VerifyAudience(a.Audience, true) {
+		return nil, fmt.Errorf("unexpected audience %v", claims["aud"])
+	}
+	return claims, nil
+}
+
+// signingKey returns the key of the jwks that signed a token, the algorithm of the token must match the type of the
+// key, and the curve of an EC key.
+func (a *JwtAuthenticator) signingKey(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+	publicKey, ok := a.Keys[kid]
+	if !ok && kid == "" && len(a.Keys) == 1 {
+		for _, key := range a.Keys {
+			publicKey, ok = key, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key %q", kid)
+	}
+
+	switch method := token.Method.(type) {
+	case *jwt.SigningMethodRSA:
+		if _, ok := publicKey.(*rsa.PublicKey); !ok {
+			return nil, fmt.Errorf("algorithm %s doesn't match the key %q", method.Alg(), kid)
+		}
+	case *jwt.SigningMethodECDSA:
+		ecKey, ok := publicKey.(*ecdsa.PublicKey)
+		if !ok || ecKey.Curve.Params().BitSize != method.CurveBits {
+			return nil, fmt.Errorf("algorithm %s doesn't match the key %q", method.Alg(), kid)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %s", token.Method.Alg())
+	}
+	return publicKey, nil
+}
+
+func (jwk jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	switch jwk.Kty {
+	case "RSA":
+		n, err := decodeJwkInt(jwk.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJwkInt(jwk.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch jwk.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", jwk.Crv)
+		}
+		x, err := decodeJwkInt(jwk.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJwkInt(jwk.Y)
+		if err != nil {
+			return nil, err
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, errors.New("the point is not on the curve")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	}
+	return nil, fmt.Errorf("unsupported key type %s", jwk.Kty)
+}
+
+func decodeJwkInt(value string) (*big.Int, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(decoded) == 0 {
+		return nil, errors.New("invalid key parameter")
+	}
+	return new(big.Int).SetBytes(decoded), nil
+}
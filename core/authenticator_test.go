@@ -0,0 +1,258 @@
+package core
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	c "delta/config"
+	model "delta/models"
+	"delta/utils"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewAuthenticator(t *testing.T) {
+	db := newTestDB(t)
+	tests := []struct {
+		mode    string
+		backend string
+		want    string
+		wantErr bool
+	}{
+		{"standalone", "", utils.AUTH_BACKEND_LOCAL, false},
+		{"cluster", "", utils.AUTH_BACKEND_REMOTE, false},
+		{"standalone", utils.AUTH_BACKEND_REMOTE, utils.AUTH_BACKEND_REMOTE, false},
+		{"cluster", utils.AUTH_BACKEND_JWT, "", true}, // no jwks file
+		{"cluster", "ldap", "", true},
+	}
+	for i, tt := range tests {
+		var config c.DeltaConfig
+		config.Common.Mode = tt.mode
+		config.Auth.Backend = tt.backend
+		config.Auth.RemoteRole = model.API_KEY_ROLE_DEALMAKER
+		authenticator, err := NewAuthenticator(db, &config)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("config %d: expected error %v, got %v", i, tt.wantErr, err)
+			continue
+		}
+		if err == nil && authenticator.Name() != tt.want {
+			t.Errorf("config %d: expected backend %s, got %s", i, tt.want, authenticator.Name())
+		}
+	}
+}
+
+func TestLocalAuthenticator(t *testing.T) {
+	db := newTestDB(t)
+	_, key, err := CreateApiKey(db, CreateApiKeyParam{Label: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator := NewLocalAuthenticator(db, "static-key")
+
+	tests := []struct {
+		token     string
+		wantScope string
+		wantErr   error
+	}{
+		{"static-key", model.API_KEY_SCOPE_ADMIN, nil},
+		{key, model.API_KEY_SCOPE_DEAL, nil},
+		{"unknown", "", ErrInvalidToken},
+	}
+	for i, tt := range tests {
+		apiKey, err := authenticator.Authenticate(context.Background(), tt.token)
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("token %d: expected %v, got %v", i, tt.wantErr, err)
+			continue
+		}
+		if err == nil && !apiKey.HasScope(tt.wantScope) {
+			t.Errorf("token %d: expected the %s scope, got %s", i, tt.wantScope, apiKey.Scopes)
+		}
+	}
+
+	// an empty static key doesn't match an empty token
+	if _, err := NewLocalAuthenticator(db, "").Authenticate(context.Background(), ""); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestRemoteAuthenticator(t *testing.T) {
+	var calls int32
+	var down atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		if down.Load() {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var body struct {
+			Token string `json:"token"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		fmt.Fprintf(w, `{"result": {"validated": %t, "details": "checked"}}`, body.Token == "EST-valid")
+	}))
+	defer server.Close()
+
+	authenticator := NewRemoteAuthenticator(server.URL, time.Second, time.Minute, 2, time.Minute, model.API_KEY_ROLE_DEALMAKER)
+	ctx := context.Background()
+
+	apiKey, err := authenticator.Authenticate(ctx, "EST-valid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !apiKey.HasRole(model.API_KEY_ROLE_DEALMAKER) || apiKey.HasRole(model.API_KEY_ROLE_ADMIN) {
+		t.Fatalf("expected the dealmaker role, got %+v", apiKey)
+	}
+	if _, err := authenticator.Authenticate(ctx, "EST-invalid"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+	// the validated token is cached
+	if _, err := authenticator.Authenticate(ctx, "EST-valid"); err != nil {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&calls) != 2 {
+		t.Fatalf("expected 2 calls to the auth service, got %d", calls)
+	}
+
+	// the circuit opens after 2 failures, then only the cached tokens are accepted
+	down.Store(true)
+	for i := 0; i < 2; i++ {
+		if _, err := authenticator.Authenticate(ctx, "EST-other"); !errors.Is(err, ErrAuthServiceUnavailable) {
+			t.Fatalf("expected ErrAuthServiceUnavailable, got %v", err)
+		}
+	}
+	if _, err := authenticator.Authenticate(ctx, "EST-other"); !errors.Is(err, ErrAuthServiceUnavailable) {
+		t.Fatalf("expected ErrAuthServiceUnavailable, got %v", err)
+	}
+	if atomic.LoadInt32(&calls) != 4 {
+		t.Fatalf("expected the open circuit not to call the auth service, got %d calls", calls)
+	}
+	if _, err := authenticator.Authenticate(ctx, "EST-valid"); err != nil {
+		t.Fatalf("expected the cached token to be accepted, got %v", err)
+	}
+
+	// after the cooldown the service is called again
+	down.Store(false)
+	authenticator.openUntil = time.Now()
+	if _, err := authenticator.Authenticate(ctx, "EST-invalid"); !errors.Is(err, ErrInvalidToken) {
+		t.Fatalf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestJwtAuthenticator(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec384Key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks := fmt.Sprintf(`{"keys": [
+		{"kty": "RSA", "kid": "rsa", "use": "sig", "n": "%s", "e": "%s"},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": "%s", "y": "%s"},
+		{"kty": "EC", "kid": "ec384", "crv": "P-384", "x": "%s", "y": "%s"}
+	]}`, b64(rsaKey.N.Bytes()), b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		b64(ecKey.X.FillBytes(make([]byte, 32))), b64(ecKey.Y.FillBytes(make([]byte, 32))),
+		b64(ec384Key.X.FillBytes(make([]byte, 48))), b64(ec384Key.Y.FillBytes(make([]byte, 48))))
+	authenticator, err := NewJwtAuthenticator([]byte(jwks), "https://issuer", "delta", "scope", "role")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(alg string, kid string, key crypto.Signer, claims map[string]interface{}) string {
+		header, _ := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+		payload, _ := json.Marshal(claims)
+		signingInput := b64(header) + "." + b64(payload)
+		hash := map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}[alg[2:]]
+		hasher := hash.New()
+		hasher.Write([]byte(signingInput))
+		digest := hasher.Sum(nil)
+		var signature []byte
+		switch key := key.(type) {
+		case *rsa.PrivateKey:
+			signature, _ = rsa.SignPKCS1v15(rand.Reader, key, hash, digest)
+		case *ecdsa.PrivateKey:
+			size := (key.Curve.Params().BitSize + 7) / 8
+			r, s, _ := ecdsa.Sign(rand.Reader, key, digest)
+			signature = append(r.FillBytes(make([]byte, size)), s.FillBytes(make([]byte, size))...)
+		}
+		return signingInput + "." + b64(signature)
+	}
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{
+			"sub":   "client-1",
+			"iss":   "https://issuer",
+			"aud":   []string{"delta"},
+			"exp":   time.Now().Add(time.Hour).Unix(),
+			"scope": "deal read",
+			"role":  "dealmaker",
+		}
+		for k, v := range overrides {
+			claims[k] = v
+		}
+		return claims
+	}
+
+	tests := []struct {
+		token   string
+		wantErr bool
+	}{
+		{sign("RS256", "rsa", rsaKey, claims(nil)), false},
+		{sign("ES256", "ec", ecKey, claims(nil)), false},
+		{sign("ES384", "ec384", ec384Key, claims(nil)), false},
+		{sign("RS256", "rsa", otherKey, claims(nil)), true},                                                            // wrong key
+		{sign("ES256", "rsa", ecKey, claims(nil)), true},                                                               // algorithm of another key type
+		{sign("ES256", "ec384", ec384Key, claims(nil)), true},                                                          // algorithm of another curve
+		{sign("ES384", "ec", ecKey, claims(nil)), true},                                                                // algorithm of another curve
+		{sign("RS256", "unknown", rsaKey, claims(nil)), true},                                                          // unknown key id
+		{sign("RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": time.Now().Add(-time.Hour).Unix()})), true}, // expired
+		{sign("RS256", "rsa", rsaKey, claims(map[string]interface{}{"iss": "https://other"})), true},                   // other issuer
+		{sign("RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": "other"})), true},                           // other audience
+		{sign("RS256", "rsa", rsaKey, claims(map[string]interface{}{"role": "owner"})), true},                          // unknown role
+		{"not.a.token", true},
+	}
+	for i, tt := range tests {
+		if _, err := authenticator.Authenticate(context.Background(), tt.token); (err != nil) != tt.wantErr {
+			t.Errorf("token %d: expected error %v, got %v", i, tt.wantErr, err)
+		}
+	}
+
+	apiKey, err := authenticator.Authenticate(context.Background(), sign("RS256", "rsa", rsaKey, claims(nil)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if apiKey.Label != "client-1" || !apiKey.HasRole(model.API_KEY_ROLE_DEALMAKER) || apiKey.HasRole(model.API_KEY_ROLE_ADMIN) {
+		t.Fatalf("expected the subject and the role of the token, got %+v", apiKey)
+	}
+
+	// a token without a role is read-only, even with more scopes
+	apiKey, err = authenticator.Authenticate(context.Background(), sign("RS256", "rsa", rsaKey, claims(map[string]interface{}{"role": nil, "scope": "deal read admin"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !apiKey.HasRole(model.API_KEY_ROLE_READ_ONLY) || apiKey.HasRole(model.API_KEY_ROLE_DEALMAKER) {
+		t.Fatalf("expected the read-only role, got %+v", apiKey)
+	}
+}
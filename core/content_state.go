@@ -0,0 +1,251 @@
+package core
+
+import (
+	model "delta/models"
+	"delta/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"gorm.io/gorm"
+)
+
+// ErrIllegalStatusTransition is returned when a content can't move from its status to the requested one.
+var ErrIllegalStatusTransition = errors.New("illegal content status transition")
+
+// marketDealStates are the statuses reported by the storage provider for a deal in progress, see jobs.DealStatusCheck
+var marketDealStates = func() []string {
+	var states []string
+	for _, state := range storagemarket.DealStates {
+		states = append(states, state)
+	}
+	return states
+}()
+
+// contentTransitions lists the statuses a content can move to from each status. A content can always stay in its
+// status, and a new content starts as pinned, or with a deal proposal for the imports.
+var contentTransitions = map[string][]string{
+	"": {
+		utils.CONTENT_PINNED,
+		utils.CONTENT_DEAL_MAKING_PROPOSAL,
+		utils.CONTENT_FAILED_TO_PIN,
+	},
+	utils.CONTENT_PINNED: {
+		utils.CONTENT_PIECE_COMPUTING,
+		utils.CONTENT_PIECE_ASSIGNED,
+		utils.CONTENT_FAILED_TO_PIN,
+		utils.CONTENT_FAILED_TO_PROCESS,
+		utils.CONTENT_CANCELLED,
+	},
+	utils.CONTENT_FAILED_TO_PIN: {
+		utils.CONTENT_PINNED,
+		utils.CONTENT_CANCELLED,
+	},
+	utils.CONTENT_PIECE_COMPUTING: {
+		utils.CONTENT_PIECE_COMPUTED,
+		utils.CONTENT_PIECE_ASSIGNED,
+		utils.CONTENT_PIECE_COMPUTING_FAILED,
+		utils.CONTENT_FAILED_TO_PROCESS,
+		utils.CONTENT_CANCELLED,
+	},
+	utils.CONTENT_PIECE_COMPUTED: {
+		utils.CONTENT_PIECE_ASSIGNED,
+		utils.CONTENT_DEAL_MAKING_PROPOSAL,
+		utils.CONTENT_FAILED_TO_PROCESS,
+		utils.CONTENT_CANCELLED,
+	},
+	utils.CONTENT_PIECE_COMPUTING_FAILED: {
+		utils.CONTENT_PINNED,
+		utils.CONTENT_PIECE_COMPUTING,
+		utils.CONTENT_PIECE_ASSIGNED,
+		utils.CONTENT_FAILED_TO_PROCESS,
+		utils.CONTENT_CANCELLED,
+	},
+	utils.CONTENT_PIECE_ASSIGNED: {
+		utils.CONTENT_PIECE_COMPUTING,
+		utils.CONTENT_DEAL_MAKING_PROPOSAL,
+		utils.CONTENT_DEAL_PROPOSAL_FAILED,
+		utils.CONTENT_FAILED_TO_PROCESS,
+		utils.CONTENT_CANCELLED,
+	},
+	utils.CONTENT_DEAL_MAKING_PROPOSAL: {
+		utils.CONTENT_DEAL_SENDING_PROPOSAL,
+		utils.CONTENT_DEAL_PROPOSAL_FAILED,
+		utils.CONTENT_FAILED_TO_PROCESS,
+		utils.CONTENT_CANCELLED,
+	},
+	utils.CONTENT_DEAL_SENDING_PROPOSAL: {
+		utils.CONTENT_DEAL_MAKING_PROPOSAL,
+		utils.CONTENT_DEAL_PROPOSAL_SENT,
+		utils.CONTENT_DEAL_PROPOSAL_FAILED,
+		utils.DEAL_STATUS_TRANSFER_STARTED,
+		utils.CONTENT_FAILED_TO_PROCESS,
+		utils.CONTENT_CANCELLED,
+	},
+	utils.CONTENT_DEAL_PROPOSAL_SENT: append([]string{
+		utils.DEAL_STATUS_TRANSFER_STARTED,
+		utils.DEAL_STATUS_TRANSFER_FINISHED,
+		utils.DEAL_STATUS_TRANSFER_FAILED,
+		utils.CONTENT_DEAL_PROPOSAL_FAILED,
+		utils.CONTENT_DEAL_ACTIVE,
+		utils.CONTENT_DEAL_SLASHED,
+		utils.CONTENT_FAILED_TO_PROCESS,
+		utils.CONTENT_CANCELLED,
+	}, marketDealStates...),
+	utils.CONTENT_DEAL_PROPOSAL_FAILED: {
+		utils.CONTENT_PINNED,
+		utils.CONTENT_PIECE_COMPUTING,
+		utils.CONTENT_PIECE_ASSIGNED,
+		utils.CONTENT_DEAL_MAKING_PROPOSAL,
+		utils.CONTENT_FAILED_TO_PROCESS,
+		utils.CONTENT_CANCELLED,
+	},
+	utils.DEAL_STATUS_TRANSFER_STARTED: append([]string{
+		utils.DEAL_STATUS_TRANSFER_FINISHED,
+		utils.DEAL_STATUS_TRANSFER_FAILED,
+		utils.CONTENT_DEAL_ACTIVE,
+		utils.CONTENT_DEAL_SLASHED,
+		utils.CONTENT_FAILED_TO_PROCESS,
+		utils.CONTENT_CANCELLED,
+	}, marketDealStates...),
+	utils.DEAL_STATUS_TRANSFER_FINISHED: append([]string{
+		utils.CONTENT_DEAL_ACTIVE,
+		utils.CONTENT_DEAL_SLASHED,
+	}, marketDealStates...),
+	utils.DEAL_STATUS_TRANSFER_FAILED: {
+		utils.CONTENT_PINNED,
+		utils.CONTENT_PIECE_COMPUTING,
+		utils.CONTENT_DEAL_MAKING_PROPOSAL,
+		utils.DEAL_STATUS_TRANSFER_STARTED,
+		utils.CONTENT_FAILED_TO_PROCESS,
+		utils.CONTENT_CANCELLED,
+	},
+	utils.CONTENT_FAILED_TO_PROCESS: {
+		utils.CONTENT_PINNED,
+		utils.CONTENT_PIECE_COMPUTING,
+		utils.CONTENT_PIECE_ASSIGNED,
+		utils.CONTENT_DEAL_MAKING_PROPOSAL,
+		utils.CONTENT_CANCELLED,
+	},
+	utils.CONTENT_DEAL_ACTIVE: {
+		utils.CONTENT_DEAL_SLASHED,
+		storagemarket.DealStates[storagemarket.StorageDealActive],
+		storagemarket.DealStates[storagemarket.StorageDealExpired],
+		storagemarket.DealStates[storagemarket.StorageDealSlashed],
+	},
+	utils.CONTENT_DEAL_SLASHED: {},
+	utils.CONTENT_CANCELLED:    {},
+}
+
+func init() {
+	// the storage provider can report any state of a deal in progress, or the transfer or the activation of the deal
+	for _, state := range marketDealStates {
+		contentTransitions[state] = append([]string{
+			utils.DEAL_STATUS_TRANSFER_FINISHED,
+			utils.DEAL_STATUS_TRANSFER_FAILED,
+			utils.CONTENT_DEAL_ACTIVE,
+			utils.CONTENT_DEAL_SLASHED,
+			utils.CONTENT_CANCELLED,
+		}, marketDealStates...)
+	}
+	// except for the final states of the storage provider
+	contentTransitions[storagemarket.DealStates[storagemarket.StorageDealActive]] = []string{
+		utils.CONTENT_DEAL_ACTIVE,
+		utils.CONTENT_DEAL_SLASHED,
+		storagemarket.DealStates[storagemarket.StorageDealExpired],
+		storagemarket.DealStates[storagemarket.StorageDealSlashed],
+	}
+	contentTransitions[storagemarket.DealStates[storagemarket.StorageDealExpired]] = []string{}
+	contentTransitions[storagemarket.DealStates[storagemarket.StorageDealSlashed]] = []string{
+		utils.CONTENT_DEAL_SLASHED,
+	}
+	contentTransitions[storagemarket.DealStates[storagemarket.StorageDealError]] = contentTransitions[utils.CONTENT_DEAL_PROPOSAL_FAILED]
+}
+
+// CanTransitionContentStatus returns true if a content can move from one status to the other.
+func CanTransitionContentStatus(from string, to string) bool {
+	if from == to {
+		return true
+	}
+	for _, next := range contentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionContentStatus is the only way to change the status of a content. It moves the content to the status if
+// the transition is legal, with the message as its last message, and records the transition.
+func TransitionContentStatus(db *gorm.DB, content *model.Content, status string, message string, source string) error {
+	var current model.Content
+	if err := db.Model(&model.Content{}).Where("id = ?", content.ID).First(&current).Error; err != nil {
+		return err
+	}
+	if !CanTransitionContentStatus(current.Status, status) {
+		return fmt.Errorf("%w: content %d from %q to %q", ErrIllegalStatusTransition, content.ID, current.Status, status)
+	}
+
+	now := time.Now()
+	result := db.Model(&model.Content{}).Where("id = ? and status = ?", content.ID, current.Status).Updates(map[string]interface{}{
+		"status":       status,
+		"last_message": message,
+		"updated_at":   now,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("status of content %d changed while moving it to %q", content.ID, status)
+	}
+	content.Status = status
+	content.LastMessage = message
+	content.UpdatedAt = now
+
+	return model.RecordStatusTransition(db, utils.STATUS_OBJECT_CONTENT, content.ID, current.Status, status, message, source)
+}
+
+// staleContentFailures lists the failed status of the contents the clean up gives up on, the contents that are still
+// transferring, or that never got to their transfer. The contents with a deal, or already failed, are left alone.
+var staleContentFailures = map[string]string{
+	utils.CONTENT_PINNED:                 utils.CONTENT_FAILED_TO_PROCESS,
+	utils.CONTENT_PIECE_COMPUTING:        utils.CONTENT_FAILED_TO_PROCESS,
+	utils.CONTENT_PIECE_COMPUTED:         utils.CONTENT_FAILED_TO_PROCESS,
+	utils.CONTENT_PIECE_COMPUTING_FAILED: utils.CONTENT_FAILED_TO_PROCESS,
+	utils.CONTENT_PIECE_ASSIGNED:         utils.CONTENT_FAILED_TO_PROCESS,
+	utils.CONTENT_DEAL_MAKING_PROPOSAL:   utils.CONTENT_FAILED_TO_PROCESS,
+	utils.CONTENT_DEAL_SENDING_PROPOSAL:  utils.CONTENT_FAILED_TO_PROCESS,
+	utils.DEAL_STATUS_TRANSFER_STARTED:   utils.DEAL_STATUS_TRANSFER_FAILED,
+}
+
+// FailStaleContents moves the contents older than the max age that are stuck before or in their transfer to their
+// failed status. The data of a content is only released, by the release callback, once its status has moved. It
+// returns the number of contents failed.
+func FailStaleContents(db *gorm.DB, now time.Time, maxAge time.Duration, release func(content model.Content)) (int, error) {
+	var statuses []string
+	for status := range staleContentFailures {
+		statuses = append(statuses, status)
+	}
+	var contents []model.Content
+	if err := db.Model(&model.Content{}).Where("status in ? and created_at < ?", statuses, now.Add(-maxAge)).Find(&contents).Error; err != nil {
+		return 0, err
+	}
+
+	days := int(maxAge.Hours() / 24)
+	failed := 0
+	for _, content := range contents {
+		status := staleContentFailures[content.Status]
+		message := fmt.Sprintf("Failed to process. Record is older than %d days.", days)
+		if status == utils.DEAL_STATUS_TRANSFER_FAILED {
+			message = fmt.Sprintf("Transfer failed. Record is older than %d days.", days)
+		}
+		if err := TransitionContentStatus(db, &content, status, message, utils.STATUS_SOURCE_CLEAN_UP); err != nil {
+			// it moved on since it was read, its data may still be needed
+			continue
+		}
+		failed++
+		release(content)
+	}
+	return failed, nil
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	model "delta/models"
+	"delta/utils"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+)
+
+func TestCanTransitionContentStatus(t *testing.T) {
+	sealing := storagemarket.DealStates[storagemarket.StorageDealSealing]
+	active := storagemarket.DealStates[storagemarket.StorageDealActive]
+	tests := []struct {
+		from string
+		to   string
+		want bool
+	}{
+		{"", utils.CONTENT_PINNED, true},
+		{"", utils.CONTENT_DEAL_MAKING_PROPOSAL, true},
+		{utils.CONTENT_PINNED, utils.CONTENT_PIECE_COMPUTING, true},
+		{utils.CONTENT_PINNED, utils.CONTENT_PINNED, true},
+		{utils.CONTENT_PIECE_COMPUTING, utils.CONTENT_PIECE_ASSIGNED, true},
+		{utils.CONTENT_PIECE_ASSIGNED, utils.CONTENT_DEAL_MAKING_PROPOSAL, true},
+		{utils.CONTENT_DEAL_MAKING_PROPOSAL, utils.CONTENT_DEAL_SENDING_PROPOSAL, true},
+		{utils.CONTENT_DEAL_SENDING_PROPOSAL, utils.CONTENT_DEAL_PROPOSAL_SENT, true},
+		{utils.CONTENT_DEAL_PROPOSAL_SENT, utils.DEAL_STATUS_TRANSFER_STARTED, true},
+		{utils.DEAL_STATUS_TRANSFER_STARTED, utils.DEAL_STATUS_TRANSFER_FINISHED, true},
+		{utils.DEAL_STATUS_TRANSFER_FINISHED, sealing, true},
+		{sealing, active, true},
+		{active, utils.CONTENT_DEAL_ACTIVE, true},
+		{utils.DEAL_STATUS_TRANSFER_FINISHED, utils.CONTENT_DEAL_ACTIVE, true},
+		{utils.CONTENT_DEAL_ACTIVE, utils.CONTENT_DEAL_SLASHED, true},
+		{utils.CONTENT_DEAL_PROPOSAL_FAILED, utils.CONTENT_DEAL_MAKING_PROPOSAL, true},
+		{utils.DEAL_STATUS_TRANSFER_FAILED, utils.CONTENT_DEAL_MAKING_PROPOSAL, true},
+		{utils.CONTENT_PINNED, utils.CONTENT_CANCELLED, true},
+
+		{utils.DEAL_STATUS_TRANSFER_FINISHED, utils.CONTENT_DEAL_PROPOSAL_FAILED, false},
+		{utils.DEAL_STATUS_TRANSFER_FINISHED, utils.DEAL_STATUS_TRANSFER_STARTED, false},
+		{utils.CONTENT_PINNED, utils.DEAL_STATUS_TRANSFER_FINISHED, false},
+		{utils.CONTENT_DEAL_PROPOSAL_SENT, utils.CONTENT_DEAL_MAKING_PROPOSAL, false},
+		{sealing, utils.CONTENT_DEAL_PROPOSAL_FAILED, false},
+		{active, sealing, false},
+		{utils.CONTENT_DEAL_ACTIVE, utils.CONTENT_FAILED_TO_PROCESS, false},
+		{utils.CONTENT_DEAL_ACTIVE, sealing, false},
+		{utils.CONTENT_DEAL_SLASHED, utils.CONTENT_DEAL_ACTIVE, false},
+		{utils.CONTENT_CANCELLED, utils.CONTENT_PINNED, false},
+		{utils.DEAL_STATUS_TRANSFER_FINISHED, utils.CONTENT_CANCELLED, false},
+		{"unknown", utils.CONTENT_PINNED, false},
+	}
+	for _, tt := range tests {
+		if got := CanTransitionContentStatus(tt.from, tt.to); got != tt.want {
+			t.Errorf("CanTransitionContentStatus(%q, %q) = %v, expected %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestTransitionContentStatus(t *testing.T) {
+	db := newTestDB(t)
+	content := model.Content{Status: utils.DEAL_STATUS_TRANSFER_STARTED}
+	db.Create(&content)
+
+	tests := []struct {
+		status  string
+		wantErr bool
+		want    string
+	}{
+		{utils.DEAL_STATUS_TRANSFER_FINISHED, false, utils.DEAL_STATUS_TRANSFER_FINISHED},
+		{utils.CONTENT_DEAL_PROPOSAL_FAILED, true, utils.DEAL_STATUS_TRANSFER_FINISHED},
+		{utils.DEAL_STATUS_TRANSFER_FINISHED, false, utils.DEAL_STATUS_TRANSFER_FINISHED},
+		{utils.CONTENT_DEAL_ACTIVE, false, utils.CONTENT_DEAL_ACTIVE},
+		{utils.DEAL_STATUS_TRANSFER_STARTED, true, utils.CONTENT_DEAL_ACTIVE},
+	}
+	for _, tt := range tests {
+		err := TransitionContentStatus(db, &content, tt.status, "message for "+tt.status, "test")
+		if tt.wantErr != errors.Is(err, ErrIllegalStatusTransition) {
+			t.Errorf("moving to %q: unexpected error %v", tt.status, err)
+		}
+		var stored model.Content
+		db.Model(&model.Content{}).Where("id = ?", content.ID).First(&stored)
+		if stored.Status != tt.want || content.Status != tt.want {
+			t.Errorf("moving to %q: expected status %q, got %q stored and %q in memory", tt.status, tt.want, stored.Status, content.Status)
+		}
+	}
+
+	// only the legal changes of status are recorded
+	var transitions []model.StatusTransition
+	db.Model(&model.StatusTransition{}).Where("object_type = ? and object_id = ?", utils.STATUS_OBJECT_CONTENT, content.ID).Order("id asc").Find(&transitions)
+	if len(transitions) != 2 || transitions[0].NewStatus != utils.DEAL_STATUS_TRANSFER_FINISHED || transitions[1].OldStatus != utils.DEAL_STATUS_TRANSFER_FINISHED || transitions[1].NewStatus != utils.CONTENT_DEAL_ACTIVE {
+		t.Errorf("expected the 2 legal transitions, got %+v", transitions)
+	}
+}
+
+func TestFailStaleContents(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	old := now.Add(-4 * 24 * time.Hour)
+	contents := []model.Content{
+		{Name: "pinned", Status: utils.CONTENT_PINNED, CreatedAt: old},
+		{Name: "transferring", Status: utils.DEAL_STATUS_TRANSFER_STARTED, CreatedAt: old},
+		{Name: "active", Status: utils.CONTENT_DEAL_ACTIVE, CreatedAt: old},
+		{Name: "cancelled", Status: utils.CONTENT_CANCELLED, CreatedAt: old},
+		{Name: "recent", Status: utils.CONTENT_PINNED, CreatedAt: now},
+	}
+	if err := db.Create(&contents).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var released []string
+	failed, err := FailStaleContents(db, now, 3*24*time.Hour, func(content model.Content) {
+		released = append(released, content.Name)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed != 2 || len(released) != 2 {
+		t.Fatalf("expected 2 contents failed and released, got %d failed and %v released", failed, released)
+	}
+
+	want := map[string]string{
+		"pinned":       utils.CONTENT_FAILED_TO_PROCESS,
+		"transferring": utils.DEAL_STATUS_TRANSFER_FAILED,
+		"active":       utils.CONTENT_DEAL_ACTIVE,
+		"cancelled":    utils.CONTENT_CANCELLED,
+		"recent":       utils.CONTENT_PINNED,
+	}
+	for name, status := range want {
+		var content model.Content
+		db.Model(&model.Content{}).Where("name = ?", name).First(&content)
+		if content.Status != status {
+			t.Errorf("%s: expected status %q, got %q", name, status, content.Status)
+		}
+	}
+
+	// every stale status can move to its failed status
+	for from, to := range staleContentFailures {
+		if !CanTransitionContentStatus(from, to) {
+			t.Errorf("expected %q to move to %q", from, to)
+		}
+	}
+}
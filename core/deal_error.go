@@ -0,0 +1,124 @@
+package core
+
+import (
+	"context"
+	c "delta/config"
+	"delta/utils"
+	"errors"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// dealErrorPatterns maps the errors returned by the SPs and the transport to a deal error category. The categories
+// are checked in order, the first match wins.
+var dealErrorPatterns = []struct {
+	category string
+	patterns []string
+}{
+	{utils.DEAL_ERROR_INSUFFICIENT_FUNDS, []string{
+		"insufficient funds",
+		"Error 2 (Worker balance too low)",
+	}},
+	{utils.DEAL_ERROR_PIECE_TOO_SMALL, []string{
+		"proposal piece size is invalid",
+		"piece size less than minimum required size",
+	}},
+	{utils.DEAL_ERROR_PRICE_REJECTED, []string{
+		"storage price per epoch less than asking price",
+		"Deal rejected | Price below acceptance for such deal",
+	}},
+	{utils.DEAL_ERROR_DEAL_TYPE_REJECTED, []string{
+		"miner is not considering online storage deals",
+		"miner is not accepting unverified storage deals",
+		"Deal rejected | Such deal is not accepted",
+		"Deal rejected | Error | like a spam",
+		"deal proposal is identical to deal",
+		"does not support any deal making protocol",
+		"protocol not supported",
+	}},
+	{utils.DEAL_ERROR_TRANSIENT_NETWORK, []string{
+		"failed to dial",
+		"failed to send request: stream reset",
+		"connection limited. rate: Wait(n=3) would exceed context deadline",
+		"error getting deal protocol for miner connecting",
+		"failed validation: server error: getting chain head",
+		"Deal rejected | Under maintenance, retry later",
+		"send proposal rpc:",
+		"i/o timeout",
+		"connection refused",
+	}},
+}
+
+// ClassifyDealError returns the deal error category of an error returned while making a deal.
+func ClassifyDealError(err error) string {
+	if err == nil {
+		return ""
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return utils.DEAL_ERROR_TRANSIENT_NETWORK
+	}
+	message := err.Error()
+	for _, entry := range dealErrorPatterns {
+		for _, pattern := range entry.patterns {
+			if strings.Contains(message, pattern) {
+				return entry.category
+			}
+		}
+	}
+	return utils.DEAL_ERROR_UNKNOWN
+}
+
+// DealRetryPolicy is how a failed deal of a given error category is retried.
+// @property MaxAttempts - The number of failed deals of the category after which the content is not retried anymore.
+// @property Backoff - How long to wait before the deal is retried.
+// @property SwitchProvider - Whether the retry goes to another storage provider.
+type DealRetryPolicy struct {
+	MaxAttempts    int
+	Backoff        time.Duration
+	SwitchProvider bool
+}
+
+// GetDealRetryPolicy returns the configured retry policy of a deal error category.
+func GetDealRetryPolicy(config *c.DeltaConfig, category string) DealRetryPolicy {
+	retry := config.DealRetry
+	switch category {
+	case utils.DEAL_ERROR_TRANSIENT_NETWORK:
+		return newDealRetryPolicy(retry.TransientNetworkMaxAttempts, retry.TransientNetworkBackoff, retry.TransientNetworkSwitchProvider)
+	case utils.DEAL_ERROR_PRICE_REJECTED:
+		return newDealRetryPolicy(retry.PriceRejectedMaxAttempts, retry.PriceRejectedBackoff, retry.PriceRejectedSwitchProvider)
+	case utils.DEAL_ERROR_DEAL_TYPE_REJECTED:
+		return newDealRetryPolicy(retry.DealTypeRejectedMaxAttempts, retry.DealTypeRejectedBackoff, retry.DealTypeRejectedSwitchProvider)
+	case utils.DEAL_ERROR_PIECE_TOO_SMALL:
+		return newDealRetryPolicy(retry.PieceTooSmallMaxAttempts, retry.PieceTooSmallBackoff, retry.PieceTooSmallSwitchProvider)
+	case utils.DEAL_ERROR_INSUFFICIENT_FUNDS:
+		return newDealRetryPolicy(retry.InsufficientFundsMaxAttempts, retry.InsufficientFundsBackoff, retry.InsufficientFundsSwitchProvider)
+	default:
+		return newDealRetryPolicy(retry.UnknownMaxAttempts, retry.UnknownBackoff, retry.UnknownSwitchProvider)
+	}
+}
+
+// DealRetryBackoff returns how long to wait before the given retry attempt. The wait doubles with every attempt from
+// base up to maxBackoff, and half of it is randomized so that deals failing together are not retried together.
+func DealRetryBackoff(base time.Duration, maxBackoff time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	backoff := base
+	for i := 1; i < attempt && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if maxBackoff > 0 && backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+func newDealRetryPolicy(maxAttempts int, backoff int, switchProvider bool) DealRetryPolicy {
+	return DealRetryPolicy{
+		MaxAttempts:    maxAttempts,
+		Backoff:        time.Duration(backoff) * time.Second,
+		SwitchProvider: switchProvider,
+	}
+}
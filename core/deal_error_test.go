@@ -0,0 +1,79 @@
+package core
+
+import (
+	"context"
+	c "delta/config"
+	"delta/utils"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestClassifyDealError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"nil", nil, ""},
+		{"dial", errors.New("miner connection failed: failed to dial 12D3KooW"), utils.DEAL_ERROR_TRANSIENT_NETWORK},
+		{"stream reset", errors.New("send proposal rpc: failed to send request: stream reset"), utils.DEAL_ERROR_TRANSIENT_NETWORK},
+		{"deadline", fmt.Errorf("making deal: %w", context.DeadlineExceeded), utils.DEAL_ERROR_TRANSIENT_NETWORK},
+		{"asking price", errors.New("storage price per epoch less than asking price: 0 < 500000"), utils.DEAL_ERROR_PRICE_REJECTED},
+		{"price below acceptance", errors.New("send proposal rpc: Deal rejected | Price below acceptance for such deal"), utils.DEAL_ERROR_PRICE_REJECTED},
+		{"unverified", errors.New("miner is not accepting unverified storage deals"), utils.DEAL_ERROR_DEAL_TYPE_REJECTED},
+		{"protocol", errors.New("opening stream to miner: failed to open stream to peer: protocol not supported"), utils.DEAL_ERROR_DEAL_TYPE_REJECTED},
+		{"piece size", errors.New("piece size less than minimum required size: 256 < 1048576"), utils.DEAL_ERROR_PIECE_TOO_SMALL},
+		{"provider funds", errors.New("provider has insufficient funds to accept deal"), utils.DEAL_ERROR_INSUFFICIENT_FUNDS},
+		{"worker balance", errors.New("Error 2 (Worker balance too low)"), utils.DEAL_ERROR_INSUFFICIENT_FUNDS},
+		{"unknown", errors.New("something else went wrong"), utils.DEAL_ERROR_UNKNOWN},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClassifyDealError(tt.err); got != tt.want {
+				t.Errorf("ClassifyDealError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetDealRetryPolicy(t *testing.T) {
+	cfg := &c.DeltaConfig{}
+	cfg.DealRetry.PriceRejectedMaxAttempts = 4
+	cfg.DealRetry.PriceRejectedBackoff = 30
+	cfg.DealRetry.PriceRejectedSwitchProvider = true
+
+	policy := GetDealRetryPolicy(cfg, utils.DEAL_ERROR_PRICE_REJECTED)
+	if policy.MaxAttempts != 4 || policy.Backoff != 30*time.Second || !policy.SwitchProvider {
+		t.Errorf("unexpected price rejected policy: %+v", policy)
+	}
+	if policy := GetDealRetryPolicy(cfg, utils.DEAL_ERROR_UNKNOWN); policy.MaxAttempts != 0 {
+		t.Errorf("expected unknown errors not to be retried, got %+v", policy)
+	}
+}
+
+func TestDealRetryBackoff(t *testing.T) {
+	base := 30 * time.Second
+	maxBackoff := 10 * time.Minute
+	tests := []struct {
+		attempt int
+		max     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{6, 10 * time.Minute},
+		{20, 10 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.attempt), func(t *testing.T) {
+			for i := 0; i < 100; i++ {
+				got := DealRetryBackoff(base, maxBackoff, tt.attempt)
+				if got < tt.max/2 || got > tt.max {
+					t.Fatalf("DealRetryBackoff() = %v, want between %v and %v", got, tt.max/2, tt.max)
+				}
+			}
+		})
+	}
+}
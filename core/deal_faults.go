@@ -0,0 +1,228 @@
+package core
+
+import (
+	"context"
+	model "delta/models"
+	"delta/utils"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"gorm.io/gorm"
+)
+
+// DealFault is a change of the on-chain health of an active deal.
+// @property Deal - The content deal, with the change recorded.
+// @property Kind - slashed, terminated, faulty, or active when the sector of the deal recovered.
+type DealFault struct {
+	Deal model.ContentDeal
+	Kind string
+}
+
+// DealFaultService detects the slashed, terminated and faulty active deals, and replaces the ones of the contents that
+// opted in.
+type DealFaultService struct {
+	DeltaNode DeltaNode
+}
+
+// NewDealFaultService creates a new DealFaultService.
+func NewDealFaultService(dn DeltaNode) *DealFaultService {
+	return &DealFaultService{
+		DeltaNode: dn,
+	}
+}
+
+// GetActiveDealsByMiner returns the active deals that haven't ended at the head and aren't slashed, by storage
+// provider.
+func GetActiveDealsByMiner(db *gorm.DB, head int64) (map[string][]model.ContentDeal, error) {
+	var deals []model.ContentDeal
+	err := db.Model(&model.ContentDeal{}).
+		Where("deal_id > 0 and sector_start_epoch > 0 and slashed = ? and failed = ? and deal_end_epoch > ?", false, false, head).
+		Order("id asc").Find(&deals).Error
+	if err != nil {
+		return nil, err
+	}
+	byMiner := make(map[string][]model.ContentDeal)
+	for _, deal := range deals {
+		byMiner[deal.Miner] = append(byMiner[deal.Miner], deal)
+	}
+	return byMiner, nil
+}
+
+// CheckMinerDeals checks the active deals of a storage provider against the chain. A deal slashed in the storage market
+// actor, or removed from it before its end epoch, is flagged as slashed and its content moves to slashed. A deal whose
+// sector is faulty is flagged as faulty until the sector recovers. It returns the changes it recorded.
+func (d DealFaultService) CheckMinerDeals(ctx context.Context, miner string, deals []model.ContentDeal, head int64) ([]DealFault, error) {
+	minerAddr, err := address.NewFromString(miner)
+	if err != nil {
+		return nil, err
+	}
+	faultySectors, err := d.DeltaNode.LotusApiNode.StateMinerFaults(ctx, minerAddr, types.EmptyTSK)
+	if err != nil {
+		return nil, err
+	}
+
+	var dealSectors map[int64]int64
+	var faults []DealFault
+	for _, deal := range deals {
+		if err := ctx.Err(); err != nil {
+			return faults, err
+		}
+
+		// the sector of a deal is only known from the active sectors of the provider, they are read once when needed
+		if deal.SectorNumber == 0 && dealSectors == nil {
+			if dealSectors, err = d.getDealSectors(ctx, minerAddr); err != nil {
+				return faults, err
+			}
+		}
+
+		fault, err := d.checkDeal(ctx, deal, dealSectors, faultySectors, head)
+		if err != nil {
+			fmt.Println("Error checking deal", deal.DealID, "of content", deal.Content, err)
+			continue
+		}
+		if fault.Kind == "" {
+			continue
+		}
+		faults = append(faults, fault)
+	}
+	return faults, nil
+}
+
+func (d DealFaultService) getDealSectors(ctx context.Context, minerAddr address.Address) (map[int64]int64, error) {
+	sectors, err := d.DeltaNode.LotusApiNode.StateMinerActiveSectors(ctx, minerAddr, types.EmptyTSK)
+	if err != nil {
+		return nil, err
+	}
+	dealSectors := make(map[int64]int64)
+	for _, sector := range sectors {
+		for _, dealID := range sector.DealIDs {
+			dealSectors[int64(dealID)] = int64(sector.SectorNumber)
+		}
+	}
+	return dealSectors, nil
+}
+
+func (d DealFaultService) checkDeal(ctx context.Context, deal model.ContentDeal, dealSectors map[int64]int64, faultySectors bitfield.BitField, head int64) (DealFault, error) {
+	fault := DealFault{Deal: deal}
+	marketDeal, err := d.DeltaNode.LotusApiNode.StateMarketStorageDeal(ctx, abi.DealID(deal.DealID), types.EmptyTSK)
+	switch {
+	case err != nil && !isDealNotFound(err):
+		return fault, err
+	case err != nil:
+		// an active deal leaves the storage market actor before its end epoch only when its sector was terminated
+		if deal.DealEndEpoch <= head {
+			return fault, nil
+		}
+		fault.Kind = utils.DEAL_STATE_TERMINATED
+		fault.Deal.Slashed = true
+		fault.Deal.LastMessage = fmt.Sprintf("deal was terminated before its end epoch %d", deal.DealEndEpoch)
+	case marketDeal.State.SlashEpoch > 0:
+		fault.Kind = utils.DEAL_STATE_SLASHED
+		fault.Deal.Slashed = true
+		fault.Deal.SlashEpoch = int64(marketDeal.State.SlashEpoch)
+		fault.Deal.LastMessage = fmt.Sprintf("deal was slashed at epoch %d", marketDeal.State.SlashEpoch)
+	default:
+		if sectorNumber, ok := dealSectors[deal.DealID]; ok {
+			fault.Deal.SectorNumber = sectorNumber
+		}
+		faulty := false
+		if fault.Deal.SectorNumber > 0 {
+			if faulty, err = faultySectors.IsSet(uint64(fault.Deal.SectorNumber)); err != nil {
+				return fault, err
+			}
+		}
+		switch {
+		case faulty && !deal.Faulty:
+			fault.Kind = utils.DEAL_STATUS_FAULTY
+			fault.Deal.Faulty = true
+			fault.Deal.LastMessage = fmt.Sprintf("sector %d of the deal is faulty", fault.Deal.SectorNumber)
+		case !faulty && deal.Faulty:
+			fault.Kind = utils.DEAL_STATE_ACTIVE
+			fault.Deal.Faulty = false
+			fault.Deal.LastMessage = fmt.Sprintf("sector %d of the deal recovered", fault.Deal.SectorNumber)
+		case fault.Deal.SectorNumber != deal.SectorNumber:
+			// only the sector number was found, nothing to report
+			fault.Deal.UpdatedAt = time.Now()
+			return DealFault{}, SaveContentDeal(d.DeltaNode.DB, &fault.Deal, utils.STATUS_SOURCE_DEAL_FAULT)
+		default:
+			return fault, nil
+		}
+	}
+
+	fault.Deal.UpdatedAt = time.Now()
+	if err := SaveContentDeal(d.DeltaNode.DB, &fault.Deal, utils.STATUS_SOURCE_DEAL_FAULT); err != nil {
+		return fault, err
+	}
+	if fault.Deal.Slashed {
+		content := model.Content{ID: deal.Content}
+		if err := TransitionContentStatus(d.DeltaNode.DB, &content, utils.CONTENT_DEAL_SLASHED, fault.Deal.LastMessage, utils.STATUS_SOURCE_DEAL_FAULT); err != nil && !errors.Is(err, ErrIllegalStatusTransition) {
+			return fault, err
+		}
+	}
+	return fault, nil
+}
+
+// isDealNotFound tells if the error of StateMarketStorageDeal is a deal missing from the storage market actor.
+func isDealNotFound(err error) bool {
+	return strings.Contains(err.Error(), "not found")
+}
+
+// GetDealsToReplace returns the slashed deals of the contents that opted in for replacement. A content with a live
+// replacement or one in progress is skipped, and so is a content whose replacements failed maxAttempts times.
+func GetDealsToReplace(db *gorm.DB, maxAttempts int) ([]DealToRenew, error) {
+	var deals []model.ContentDeal
+	err := db.Model(&model.ContentDeal{}).
+		Joins("join contents c on c.id = content_deals.content").
+		Where("c.auto_replace = ? and content_deals.slashed = ?", true, true).
+		Order("content_deals.id asc").Find(&deals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var toReplace []DealToRenew
+	seen := make(map[int64]bool)
+	for _, deal := range deals {
+		if seen[deal.Content] {
+			continue
+		}
+		seen[deal.Content] = true
+
+		failed, inProgress := countDealCopies(db, "replacement_of", deal.Content)
+		if inProgress || failed >= maxAttempts {
+			continue
+		}
+
+		var content model.Content
+		db.Model(&model.Content{}).Where("id = ?", deal.Content).Find(&content)
+		toReplace = append(toReplace, DealToRenew{Content: content, Deal: deal})
+	}
+	return toReplace, nil
+}
+
+// SkipDealReplacement turns the replacement of a content off when its slashed deal can't be replaced, and says why on
+// the content and on the deal, so the skip shows in their status.
+func SkipDealReplacement(db *gorm.DB, toReplace DealToRenew, reason string) error {
+	return skipDealCopy(db, toReplace, "auto_replace", fmt.Sprintf("slashed deal %d can't be replaced: %s", toReplace.Deal.DealID, reason))
+}
+
+// ReplaceDeal creates the replacement of a slashed or terminated deal: a new content linked to the original one that
+// reuses its piece commitment, its wallet and its deal parameters with new epochs. The replacement stays in the
+// replication group of the original content, and is assigned to a storage provider that doesn't hold a copy of the
+// group yet. The replacement is ready for the deal maker.
+func (d DealFaultService) ReplaceDeal(toReplace DealToRenew, head int64) (model.Content, model.PieceCommitment, error) {
+	excludedMiners := []string{toReplace.Deal.Miner}
+	if toReplace.Content.ReplicationGroup > 0 {
+		excludedMiners = append(excludedMiners, GetReplicationGroupMiners(d.DeltaNode.DB, toReplace.Content.ReplicationGroup)...)
+	}
+	return NewDealRenewalService(d.DeltaNode).copyDealContent(toReplace.Content, head, "", excludedMiners, func(replacement *model.Content) {
+		replacement.LastMessage = fmt.Sprintf("replacement of slashed deal %d of content %d", toReplace.Deal.DealID, toReplace.Content.ID)
+		replacement.ReplacementOf = toReplace.Content.ID
+		replacement.RenewalOf = 0
+	})
+}
@@ -0,0 +1,177 @@
+package core
+
+import (
+	"context"
+	"delta/config"
+	model "delta/models"
+	"delta/utils"
+	"testing"
+
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin/v9/miner"
+	lapi "github.com/filecoin-project/lotus/api"
+)
+
+func TestDealFaultService_CheckMinerDeals(t *testing.T) {
+	db := newTestDB(t)
+	const head = 2500000
+	fullNode := &mockFullNode{
+		deals: map[abi.DealID]*lapi.MarketDeal{
+			1: newMarketDeal(1990000, -1),
+			2: newMarketDeal(1990000, 2100000),
+			4: newMarketDeal(1990000, -1),
+			5: newMarketDeal(1990000, -1),
+		},
+		sectors: []*miner.SectorOnChainInfo{
+			{SectorNumber: 11, DealIDs: []abi.DealID{1}},
+			{SectorNumber: 14, DealIDs: []abi.DealID{4}},
+		},
+		faults: bitfield.NewFromSet([]uint64{14, 15}),
+	}
+	service := NewDealFaultService(DeltaNode{DB: db, LotusApiNode: fullNode})
+
+	tests := []struct {
+		deal        model.ContentDeal
+		wantKind    string
+		wantStatus  string
+		wantSector  int64
+		wantSlashed bool
+		wantFaulty  bool
+	}{
+		{model.ContentDeal{DealID: 1}, "", utils.CONTENT_DEAL_ACTIVE, 11, false, false},                                                      // healthy
+		{model.ContentDeal{DealID: 2}, utils.DEAL_STATE_SLASHED, utils.CONTENT_DEAL_SLASHED, 0, true, false},                                 // slashed
+		{model.ContentDeal{DealID: 3}, utils.DEAL_STATE_TERMINATED, utils.CONTENT_DEAL_SLASHED, 0, true, false},                              // terminated
+		{model.ContentDeal{DealID: 4}, utils.DEAL_STATUS_FAULTY, utils.CONTENT_DEAL_ACTIVE, 14, false, true},                                 // faulty sector
+		{model.ContentDeal{DealID: 5, SectorNumber: 16, Faulty: true}, utils.DEAL_STATE_ACTIVE, utils.CONTENT_DEAL_ACTIVE, 16, false, false}, // recovered sector
+		{model.ContentDeal{DealID: 6, DealEndEpoch: head - 10}, "", utils.CONTENT_DEAL_ACTIVE, 0, false, false},                              // ended
+	}
+	var deals []model.ContentDeal
+	for i := range tests {
+		content := model.Content{Status: utils.CONTENT_DEAL_ACTIVE}
+		db.Create(&content)
+		tests[i].deal.Content = content.ID
+		tests[i].deal.Miner = "f01000"
+		tests[i].deal.SectorStartEpoch = 1990000
+		if tests[i].deal.DealEndEpoch == 0 {
+			tests[i].deal.DealEndEpoch = 3000000
+		}
+		db.Create(&tests[i].deal)
+		deals = append(deals, tests[i].deal)
+	}
+
+	faults, err := service.CheckMinerDeals(context.Background(), "f01000", deals, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kinds := make(map[int64]string)
+	for _, fault := range faults {
+		kinds[fault.Deal.DealID] = fault.Kind
+	}
+	for _, tt := range tests {
+		if kinds[tt.deal.DealID] != tt.wantKind {
+			t.Errorf("deal %d: expected fault %q, got %q", tt.deal.DealID, tt.wantKind, kinds[tt.deal.DealID])
+		}
+		var deal model.ContentDeal
+		db.First(&deal, tt.deal.ID)
+		if deal.SectorNumber != tt.wantSector || deal.Slashed != tt.wantSlashed || deal.Faulty != tt.wantFaulty {
+			t.Errorf("deal %d: expected sector %d, slashed %v and faulty %v, got %+v", tt.deal.DealID, tt.wantSector, tt.wantSlashed, tt.wantFaulty, deal)
+		}
+		var content model.Content
+		db.First(&content, tt.deal.Content)
+		if content.Status != tt.wantStatus {
+			t.Errorf("deal %d: expected content status %q, got %q", tt.deal.DealID, tt.wantStatus, content.Status)
+		}
+	}
+
+	// the monitor doesn't check the slashed deals again
+	byMiner, err := GetActiveDealsByMiner(db, head)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byMiner["f01000"]) != 3 {
+		t.Errorf("expected 3 active deals, got %+v", byMiner)
+	}
+}
+
+func TestDealFaultService_ReplaceDeal(t *testing.T) {
+	db := newTestDB(t)
+	cfg := &config.DeltaConfig{}
+	cfg.DealRenewal.StartEpochDelay = 100
+	strategies := map[string]IMinerSelectionStrategy{
+		utils.MINER_SELECTION_STATIC_ALLOWLIST: NewStaticAllowlistStrategy([]string{"f01000", "f02000", "f03000"}),
+	}
+
+	pieceComm := model.PieceCommitment{Piece: "piece", Status: utils.COMMP_STATUS_COMITTED}
+	db.Create(&pieceComm)
+	content := model.Content{Cid: "cid", PieceCommitmentId: pieceComm.ID, AutoReplace: true, ReplicationGroup: 3, Status: utils.CONTENT_DEAL_SLASHED}
+	db.Create(&content)
+	db.Create(&model.Content{Cid: "cid", ReplicationGroup: 3, Status: utils.CONTENT_DEAL_ACTIVE})
+	db.Create(&model.ContentMiner{Content: content.ID + 1, Miner: "f02000"})
+	db.Create(&model.ContentDealProposalParameters{Content: content.ID, Duration: 518400, MinerSelectionStrategy: utils.MINER_SELECTION_STATIC_ALLOWLIST})
+	db.Create(&model.ContentDeal{Content: content.ID, Miner: "f01000", DealID: 42, SectorStartEpoch: 10, Slashed: true})
+	notOptedIn := model.Content{Status: utils.CONTENT_DEAL_SLASHED}
+	db.Create(&notOptedIn)
+	db.Create(&model.ContentDeal{Content: notOptedIn.ID, Miner: "f01000", DealID: 43, SectorStartEpoch: 10, Slashed: true})
+
+	toReplace, err := GetDealsToReplace(db, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toReplace) != 1 || toReplace[0].Content.ID != content.ID {
+		t.Fatalf("expected the deal of content %d, got %+v", content.ID, toReplace)
+	}
+
+	service := NewDealFaultService(DeltaNode{DB: db, Config: cfg, MinerSelectionStrategies: strategies})
+	replacement, replacementPieceComm, err := service.ReplaceDeal(toReplace[0], 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replacement.ReplacementOf != content.ID || replacement.ReplicationGroup != 3 || replacement.Status != utils.CONTENT_DEAL_MAKING_PROPOSAL || replacementPieceComm.ID != pieceComm.ID {
+		t.Errorf("unexpected replacement %+v", replacement)
+	}
+	var contentMiner model.ContentMiner
+	db.Model(&model.ContentMiner{}).Where("content = ?", replacement.ID).Find(&contentMiner)
+	if contentMiner.Miner != "f03000" {
+		t.Errorf("expected the replacement with a provider outside of the group, got %q", contentMiner.Miner)
+	}
+
+	// a replaced deal isn't replaced twice
+	if toReplace, _ := GetDealsToReplace(db, 2); len(toReplace) != 0 {
+		t.Errorf("expected no deal to replace, got %+v", toReplace)
+	}
+
+	// a failed replacement doesn't keep the deal from being replaced again, until the attempts run out
+	db.Model(&model.Content{}).Where("id = ?", replacement.ID).Update("status", utils.CONTENT_DEAL_PROPOSAL_FAILED)
+	if toReplace, _ := GetDealsToReplace(db, 2); len(toReplace) != 1 || toReplace[0].Content.ID != content.ID {
+		t.Errorf("expected the deal of content %d after a failed replacement, got %+v", content.ID, toReplace)
+	}
+	db.Create(&model.Content{ReplacementOf: content.ID, Status: utils.DEAL_STATUS_TRANSFER_FAILED})
+	if toReplace, _ := GetDealsToReplace(db, 2); len(toReplace) != 0 {
+		t.Errorf("expected no deal to replace after 2 failed replacements, got %+v", toReplace)
+	}
+}
+
+func TestSkipDealReplacement(t *testing.T) {
+	db := newTestDB(t)
+	content := model.Content{AutoReplace: true, Status: utils.CONTENT_DEAL_SLASHED}
+	db.Create(&content)
+	deal := model.ContentDeal{Content: content.ID, DealID: 42, SectorStartEpoch: 10, Slashed: true}
+	db.Create(&deal)
+
+	if err := SkipDealReplacement(db, DealToRenew{Content: content, Deal: deal}, "the data is gone"); err != nil {
+		t.Fatal(err)
+	}
+	var stored model.Content
+	db.Model(&model.Content{}).Where("id = ?", content.ID).First(&stored)
+	var storedDeal model.ContentDeal
+	db.Model(&model.ContentDeal{}).Where("id = ?", deal.ID).First(&storedDeal)
+	if stored.AutoReplace || stored.LastMessage != "slashed deal 42 can't be replaced: the data is gone" || storedDeal.LastMessage != stored.LastMessage {
+		t.Fatalf("expected the replacement to be turned off with its reason, got %+v and %+v", stored, storedDeal)
+	}
+
+	// the content isn't picked again
+	if toReplace, err := GetDealsToReplace(db, 2); err != nil || len(toReplace) != 0 {
+		t.Fatalf("expected no deal to replace, got %+v (%v)", toReplace, err)
+	}
+}
@@ -0,0 +1,129 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	model "delta/models"
+	"delta/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	cborutil "github.com/filecoin-project/go-cbor-util"
+	"github.com/filecoin-project/go-state-types/builtin/v9/market"
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+)
+
+// ErrPublishNotConfirmed is returned while the publish message of a deal is not on chain or doesn't have enough
+// confirmations yet.
+var ErrPublishNotConfirmed = errors.New("publish message is not confirmed yet")
+
+// ResolveDealID finds the on-chain deal id of a deal from the PublishStorageDeals message of its storage provider.
+// The message must be on chain for the given number of confirmations so a re-org can't change the deal id. It returns
+// the deal id and the publish message cid, which can differ from the given one if the message was replaced.
+func (d DealStatusService) ResolveDealID(ctx context.Context, contentDeal model.ContentDeal, confirmations int64) (int64, cid.Cid, error) {
+	publishCid, err := cid.Decode(contentDeal.PublishMessageCid)
+	if err != nil {
+		return 0, cid.Undef, err
+	}
+
+	api := d.DeltaNode.LotusApiNode
+	lookup, err := api.StateSearchMsg(ctx, types.EmptyTSK, publishCid, lapi.LookbackNoLimit, true)
+	if err != nil {
+		return 0, cid.Undef, err
+	}
+	if lookup == nil {
+		return 0, cid.Undef, ErrPublishNotConfirmed
+	}
+	head, err := api.ChainHead(ctx)
+	if err != nil {
+		return 0, cid.Undef, err
+	}
+	if int64(head.Height()-lookup.Height) < confirmations {
+		return 0, cid.Undef, ErrPublishNotConfirmed
+	}
+	if lookup.Receipt.ExitCode.IsError() {
+		return 0, cid.Undef, fmt.Errorf("publish message %s failed with exit code %d", lookup.Message, lookup.Receipt.ExitCode)
+	}
+
+	msg, err := api.ChainGetMessage(ctx, lookup.Message)
+	if err != nil {
+		return 0, cid.Undef, err
+	}
+	var params market.PublishStorageDealsParams
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return 0, cid.Undef, fmt.Errorf("decoding publish message params: %w", err)
+	}
+	var ret market.PublishStorageDealsReturn
+	if err := ret.UnmarshalCBOR(bytes.NewReader(lookup.Receipt.Return)); err != nil {
+		return 0, cid.Undef, fmt.Errorf("decoding publish message return: %w", err)
+	}
+
+	dealIndex := -1
+	for i, deal := range params.Deals {
+		nd, err := cborutil.AsIpld(&deal)
+		if err != nil {
+			return 0, cid.Undef, err
+		}
+		if nd.Cid().String() == contentDeal.PropCid {
+			dealIndex = i
+			break
+		}
+	}
+	if dealIndex < 0 {
+		return 0, cid.Undef, fmt.Errorf("deal proposal %s is not in publish message %s", contentDeal.PropCid, lookup.Message)
+	}
+
+	// the return only has the ids of the valid deals of the message, in order
+	valid, err := ret.ValidDeals.IsSet(uint64(dealIndex))
+	if err != nil {
+		return 0, cid.Undef, err
+	}
+	if !valid {
+		return 0, cid.Undef, fmt.Errorf("deal proposal %s was rejected by the market actor", contentDeal.PropCid)
+	}
+	validBefore := 0
+	for i := 0; i < dealIndex; i++ {
+		if isSet, _ := ret.ValidDeals.IsSet(uint64(i)); isSet {
+			validBefore++
+		}
+	}
+	if validBefore >= len(ret.IDs) {
+		return 0, cid.Undef, fmt.Errorf("publish message %s has %d deal ids, no id for the deal at %d", lookup.Message, len(ret.IDs), dealIndex)
+	}
+	return int64(ret.IDs[validBefore]), lookup.Message, nil
+}
+
+// ResolvePublishedDeals records the on-chain deal id of every deal whose publish message is known but whose deal id
+// isn't. It returns how many deals were resolved.
+func (d DealStatusService) ResolvePublishedDeals(ctx context.Context) (int, error) {
+	confirmations := int64(d.DeltaNode.Config.DealTracking.PublishConfirmations)
+
+	var contentDeals []model.ContentDeal
+	d.DeltaNode.DB.Model(&model.ContentDeal{}).Where("publish_message_cid <> '' and deal_id = 0 and failed = ?", false).Find(&contentDeals)
+
+	resolved := 0
+	for _, contentDeal := range contentDeals {
+		if err := ctx.Err(); err != nil {
+			return resolved, err
+		}
+		dealID, publishCid, err := d.ResolveDealID(ctx, contentDeal, confirmations)
+		if errors.Is(err, ErrPublishNotConfirmed) {
+			continue
+		}
+		if err != nil {
+			fmt.Println("Error resolving the deal id of content deal", contentDeal.ID, err)
+			continue
+		}
+		UpdateContentDeal(d.DeltaNode.DB, contentDeal.ID, model.ContentDeal{
+			DealID:            dealID,
+			PublishMessageCid: publishCid.String(),
+			LastMessage:       fmt.Sprintf("deal published with id %d", dealID),
+			UpdatedAt:         time.Now(),
+		}, utils.STATUS_SOURCE_DEAL_PUBLISH)
+		resolved++
+	}
+	return resolved, nil
+}
@@ -0,0 +1,136 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	c "delta/config"
+	model "delta/models"
+	"errors"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	cborutil "github.com/filecoin-project/go-cbor-util"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/builtin/v9/market"
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+)
+
+// newPublishMessage builds a publish message of three deals where the second one is invalid
+func newPublishMessage(t *testing.T, fullNode *mockFullNode, publishCid cid.Cid, height abi.ChainEpoch) []string {
+	client, _ := address.NewIDAddress(1000)
+	provider, _ := address.NewIDAddress(2000)
+	var params market.PublishStorageDealsParams
+	var propCids []string
+	for i := 0; i < 3; i++ {
+		label, _ := market.NewLabelFromString("deal")
+		deal := market.ClientDealProposal{
+			Proposal: market.DealProposal{
+				PieceCID:             publishCid,
+				PieceSize:            abi.PaddedPieceSize(2048 << i),
+				Client:               client,
+				Provider:             provider,
+				Label:                label,
+				StoragePricePerEpoch: big.Zero(),
+				ProviderCollateral:   big.Zero(),
+				ClientCollateral:     big.Zero(),
+			},
+			ClientSignature: crypto.Signature{Type: crypto.SigTypeBLS, Data: []byte("signature")},
+		}
+		nd, err := cborutil.AsIpld(&deal)
+		if err != nil {
+			t.Fatal(err)
+		}
+		propCids = append(propCids, nd.Cid().String())
+		params.Deals = append(params.Deals, deal)
+	}
+	paramsBuf := new(bytes.Buffer)
+	if err := params.MarshalCBOR(paramsBuf); err != nil {
+		t.Fatal(err)
+	}
+	ret := market.PublishStorageDealsReturn{IDs: []abi.DealID{100, 102}, ValidDeals: bitfield.NewFromSet([]uint64{0, 2})}
+	retBuf := new(bytes.Buffer)
+	if err := ret.MarshalCBOR(retBuf); err != nil {
+		t.Fatal(err)
+	}
+
+	fullNode.messages[publishCid] = &types.Message{Params: paramsBuf.Bytes()}
+	fullNode.lookups[publishCid] = &lapi.MsgLookup{
+		Message: publishCid,
+		Receipt: types.MessageReceipt{ExitCode: exitcode.Ok, Return: retBuf.Bytes()},
+		Height:  height,
+	}
+	return propCids
+}
+
+func TestDealStatusService_ResolveDealID(t *testing.T) {
+	publishCid, _ := cid.Decode("bafy2bzacecnamqgqmifpluoeldx7zzglxcljo6oja4vrmtj7432rphldpdmm2")
+	fullNode := &mockFullNode{lookups: map[cid.Cid]*lapi.MsgLookup{}, messages: map[cid.Cid]*types.Message{}, height: 1005}
+	propCids := newPublishMessage(t, fullNode, publishCid, 1000)
+	service := NewDealStatusService(DeltaNode{LotusApiNode: fullNode})
+
+	tests := []struct {
+		name          string
+		propCid       string
+		confirmations int64
+		dealID        int64
+		wantErr       error
+	}{
+		{"first deal", propCids[0], 5, 100, nil},
+		{"deal after an invalid one", propCids[2], 5, 102, nil},
+		{"invalid deal", propCids[1], 5, 0, errors.New("rejected")},
+		{"not enough confirmations", propCids[0], 10, 0, ErrPublishNotConfirmed},
+		{"deal not in the message", "bafyreicmaj5hhoy5mgqvamfhgexxyergw7hdeshizghodwkjg6qmpoco7i", 5, 0, errors.New("not in message")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dealID, msgCid, err := service.ResolveDealID(context.Background(), model.ContentDeal{PropCid: tt.propCid, PublishMessageCid: publishCid.String()}, tt.confirmations)
+			if tt.wantErr != nil {
+				if err == nil {
+					t.Fatalf("expected an error, got deal id %d", dealID)
+				}
+				if errors.Is(tt.wantErr, ErrPublishNotConfirmed) && !errors.Is(err, ErrPublishNotConfirmed) {
+					t.Fatalf("expected ErrPublishNotConfirmed, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dealID != tt.dealID || msgCid != publishCid {
+				t.Errorf("expected deal id %d of %s, got %d of %s", tt.dealID, publishCid, dealID, msgCid)
+			}
+		})
+	}
+}
+
+func TestDealStatusService_ResolvePublishedDeals(t *testing.T) {
+	db := newTestDB(t)
+	publishCid, _ := cid.Decode("bafy2bzacecnamqgqmifpluoeldx7zzglxcljo6oja4vrmtj7432rphldpdmm2")
+	fullNode := &mockFullNode{lookups: map[cid.Cid]*lapi.MsgLookup{}, messages: map[cid.Cid]*types.Message{}, height: 1020}
+	propCids := newPublishMessage(t, fullNode, publishCid, 1000)
+	cfg := &c.DeltaConfig{}
+	cfg.DealTracking.PublishConfirmations = 10
+	service := NewDealStatusService(DeltaNode{DB: db, Config: cfg, LotusApiNode: fullNode})
+
+	published := model.ContentDeal{Content: 1, PropCid: propCids[2], PublishMessageCid: publishCid.String()}
+	db.Create(&published)
+	db.Create(&model.ContentDeal{Content: 2, PropCid: propCids[0]}) // not published yet
+
+	resolved, err := service.ResolvePublishedDeals(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved != 1 {
+		t.Errorf("expected 1 resolved deal, got %d", resolved)
+	}
+	db.First(&published, published.ID)
+	if published.DealID != 102 {
+		t.Errorf("expected deal id 102, got %d", published.DealID)
+	}
+}
@@ -0,0 +1,211 @@
+package core
+
+import (
+	"context"
+	model "delta/models"
+	"delta/utils"
+	"fmt"
+	"math/bits"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// filclient gives the storage provider a week to seal the sector when a deal has no start epoch
+const quoteDefaultStartEpochDelay = utils.EPOCH_PER_DAY * 7
+
+// DealQuoteParam is a deal request resolved to what the deal maker would use.
+// @property Miner - The storage provider of the request, selected with the strategy if empty.
+// @property Replication - The number of copies besides the first one.
+// @property ReplicationDiversity - What must differ between the providers of the copies.
+// @property Size - The size of the content in bytes.
+// @property Piece - The piece cid of the content, if known.
+// @property PaddedPieceSize - The padded piece size of the content, estimated from the size if zero.
+// @property Wallet - The address of the client wallet of the deals.
+// @property ProposalParameters - The deal proposal parameters the request would be saved with.
+type DealQuoteParam struct {
+	Miner                string
+	Replication          int
+	ReplicationDiversity string
+	Size                 int64
+	Piece                string
+	PaddedPieceSize      uint64
+	Wallet               string
+	ProposalParameters   model.ContentDealProposalParameters
+}
+
+// DealQuoteProposal is the deal proposal that would be sent to one storage provider.
+type DealQuoteProposal struct {
+	Provider             string `json:"provider"`
+	Client               string `json:"client"`
+	PieceCid             string `json:"piece_cid,omitempty"`
+	PieceSize            uint64 `json:"piece_size"`
+	VerifiedDeal         bool   `json:"verified_deal"`
+	Label                string `json:"label"`
+	StartEpoch           int64  `json:"start_epoch"`
+	EndEpoch             int64  `json:"end_epoch"`
+	Duration             int64  `json:"duration"`
+	StoragePricePerEpoch string `json:"storage_price_per_epoch"`
+	TotalPrice           string `json:"total_price"`
+	RemoveUnsealedCopy   bool   `json:"remove_unsealed_copy"`
+	SkipIPNIAnnounce     bool   `json:"skip_ipni_announce"`
+}
+
+// DealQuote is what a deal request would do, without making it. Prices and balances are in attoFIL.
+type DealQuote struct {
+	Proposals         []DealQuoteProposal `json:"proposals"`
+	Wallet            string              `json:"wallet"`
+	TotalPrice        string              `json:"total_price"`
+	WalletBalance     string              `json:"wallet_balance"`
+	MarketBalance     string              `json:"market_balance"`
+	SufficientFunds   bool                `json:"sufficient_funds"`
+	DataCap           string              `json:"datacap,omitempty"`
+	RequiredDataCap   uint64              `json:"required_datacap,omitempty"`
+	SufficientDataCap bool                `json:"sufficient_datacap"`
+}
+
+// DealQuoteService tells what a deal request would do without writing anything or dispatching any job.
+type DealQuoteService struct {
+	DeltaNode DeltaNode
+}
+
+// NewDealQuoteService creates a new DealQuoteService.
+func NewDealQuoteService(dn DeltaNode) *DealQuoteService {
+	return &DealQuoteService{
+		DeltaNode: dn,
+	}
+}
+
+// Quote selects the storage providers of a deal request and builds the proposal each one would get, with the epochs
+// and the price the deal maker would use. It checks the wallet has enough funds for the unverified deals, or enough
+// DataCap for the verified ones.
+func (d DealQuoteService) Quote(ctx context.Context, param DealQuoteParam) (DealQuote, error) {
+	api := d.DeltaNode.LotusApiNode
+	walletAddr, err := address.NewFromString(param.Wallet)
+	if err != nil {
+		return DealQuote{}, fmt.Errorf("invalid wallet address %s: %w", param.Wallet, err)
+	}
+	head, err := api.ChainHead(ctx)
+	if err != nil {
+		return DealQuote{}, err
+	}
+
+	// the providers, like the deal request handlers select them
+	proposalParam := param.ProposalParameters
+	minerAssignService := NewMinerAssignmentService(d.DeltaNode)
+	miners := []string{param.Miner}
+	if param.Miner == "" {
+		provider, err := minerAssignService.GetSPWithStrategy(proposalParam.MinerSelectionStrategy, MinerSelectionParam{Size: param.Size})
+		if err != nil {
+			return DealQuote{}, err
+		}
+		miners[0] = provider.Address
+	}
+	if param.Replication > 0 {
+		providers, err := minerAssignService.SelectDistinctProviders(proposalParam.MinerSelectionStrategy, MinerSelectionParam{
+			Size:           param.Size,
+			ExcludedMiners: []string{miners[0]},
+		}, param.Replication, param.ReplicationDiversity)
+		if err != nil {
+			return DealQuote{}, err
+		}
+		for _, provider := range providers {
+			miners = append(miners, provider.Address)
+		}
+	}
+
+	// the epochs and the price, like the deal maker and filclient compute them
+	startEpoch, endEpoch := QuoteEpochs(proposalParam, int64(head.Height()))
+	pricePerEpoch := big.Zero()
+	if !proposalParam.VerifiedDeal {
+		pricePerEpoch, err = types.BigFromString(proposalParam.UnverifiedDealMaxPrice)
+		if err != nil {
+			return DealQuote{}, fmt.Errorf("invalid unverified deal price %s: %w", proposalParam.UnverifiedDealMaxPrice, err)
+		}
+	}
+	dealPrice := big.Mul(pricePerEpoch, big.NewInt(endEpoch-startEpoch))
+	pieceSize := param.PaddedPieceSize
+	if pieceSize == 0 {
+		pieceSize = EstimatePaddedPieceSize(uint64(param.Size))
+	}
+
+	quote := DealQuote{Wallet: walletAddr.String()}
+	for _, miner := range miners {
+		quote.Proposals = append(quote.Proposals, DealQuoteProposal{
+			Provider:             miner,
+			Client:               walletAddr.String(),
+			PieceCid:             param.Piece,
+			PieceSize:            pieceSize,
+			VerifiedDeal:         proposalParam.VerifiedDeal,
+			Label:                proposalParam.Label,
+			StartEpoch:           startEpoch,
+			EndEpoch:             endEpoch,
+			Duration:             endEpoch - startEpoch,
+			StoragePricePerEpoch: pricePerEpoch.String(),
+			TotalPrice:           dealPrice.String(),
+			RemoveUnsealedCopy:   proposalParam.RemoveUnsealedCopy,
+			SkipIPNIAnnounce:     proposalParam.SkipIPNIAnnounce,
+		})
+	}
+	totalPrice := big.Mul(dealPrice, big.NewInt(int64(len(miners))))
+	quote.TotalPrice = totalPrice.String()
+
+	// the funds, the market escrow that isn't locked can pay for the deals as well as the wallet
+	walletBalance, err := api.WalletBalance(ctx, walletAddr)
+	if err != nil {
+		return DealQuote{}, err
+	}
+	marketBalance, err := api.StateMarketBalance(ctx, walletAddr, types.EmptyTSK)
+	if err != nil {
+		return DealQuote{}, err
+	}
+	availableMarketBalance := big.Sub(marketBalance.Escrow, marketBalance.Locked)
+	quote.WalletBalance = walletBalance.String()
+	quote.MarketBalance = availableMarketBalance.String()
+	quote.SufficientFunds = big.Add(walletBalance, availableMarketBalance).GreaterThanEqual(totalPrice)
+
+	quote.SufficientDataCap = true
+	if proposalParam.VerifiedDeal {
+		quote.RequiredDataCap = pieceSize * uint64(len(miners))
+		dataCap, err := api.StateVerifiedClientStatus(ctx, walletAddr, types.EmptyTSK)
+		if err != nil {
+			return DealQuote{}, err
+		}
+		quote.DataCap = "0"
+		quote.SufficientDataCap = false
+		if dataCap != nil {
+			quote.DataCap = dataCap.String()
+			quote.SufficientDataCap = dataCap.GreaterThanEqual(big.NewIntUnsigned(quote.RequiredDataCap))
+		}
+	}
+	return quote, nil
+}
+
+// QuoteEpochs returns the start and end epochs of a deal made with the given parameters. Without a start epoch the
+// deal starts a week after the head, and without an end epoch it lasts its duration.
+func QuoteEpochs(param model.ContentDealProposalParameters, head int64) (int64, int64) {
+	startEpoch := param.StartEpoch
+	if startEpoch == 0 {
+		startEpoch = head + quoteDefaultStartEpochDelay
+	}
+	endEpoch := param.EndEpoch
+	if endEpoch == 0 {
+		duration := param.Duration
+		if duration <= 0 {
+			duration = utils.DEFAULT_DURATION
+		}
+		endEpoch = startEpoch + duration
+	}
+	return startEpoch, endEpoch
+}
+
+// EstimatePaddedPieceSize returns the padded size of the piece of a payload of the given size: the payload grows by
+// 128/127 with the fr32 padding, then up to the next power of two.
+func EstimatePaddedPieceSize(size uint64) uint64 {
+	padded := (size*128 + 126) / 127
+	if padded <= 128 {
+		return 128
+	}
+	return 1 << bits.Len64(padded-1)
+}
@@ -0,0 +1,116 @@
+package core
+
+import (
+	"context"
+	"delta/config"
+	model "delta/models"
+	"delta/utils"
+	"testing"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func TestQuoteEpochs(t *testing.T) {
+	tests := []struct {
+		param     model.ContentDealProposalParameters
+		wantStart int64
+		wantEnd   int64
+	}{
+		{model.ContentDealProposalParameters{StartEpoch: 5000, EndEpoch: 9000}, 5000, 9000},
+		{model.ContentDealProposalParameters{Duration: 518400}, 1000 + utils.EPOCH_PER_DAY*7, 1000 + utils.EPOCH_PER_DAY*7 + 518400},
+		{model.ContentDealProposalParameters{}, 1000 + utils.EPOCH_PER_DAY*7, 1000 + utils.EPOCH_PER_DAY*7 + utils.DEFAULT_DURATION},
+	}
+	for i, tt := range tests {
+		start, end := QuoteEpochs(tt.param, 1000)
+		if start != tt.wantStart || end != tt.wantEnd {
+			t.Errorf("param %d: expected %d-%d, got %d-%d", i, tt.wantStart, tt.wantEnd, start, end)
+		}
+	}
+}
+
+func TestEstimatePaddedPieceSize(t *testing.T) {
+	tests := []struct {
+		size uint64
+		want uint64
+	}{
+		{0, 128},
+		{127, 128},
+		{128, 256},
+		{1 << 20, 2 << 20},
+		{1016 << 20, 1 << 30},
+		{1017 << 20, 2 << 30},
+	}
+	for _, tt := range tests {
+		if got := EstimatePaddedPieceSize(tt.size); got != tt.want {
+			t.Errorf("size %d: expected %d, got %d", tt.size, tt.want, got)
+		}
+	}
+}
+
+func TestDealQuoteService_Quote(t *testing.T) {
+	db := newTestDB(t)
+	datacap := abi.NewStoragePower(3 << 30)
+	fullNode := &mockFullNode{
+		height:  1000,
+		balance: types.NewInt(1000),
+		market:  lapi.MarketBalance{Escrow: big.NewInt(500), Locked: big.NewInt(100)},
+		datacap: &datacap,
+	}
+	strategies := map[string]IMinerSelectionStrategy{
+		utils.MINER_SELECTION_STATIC_ALLOWLIST: NewStaticAllowlistStrategy([]string{"f01000", "f02000", "f03000"}),
+	}
+	service := NewDealQuoteService(DeltaNode{DB: db, Config: &config.DeltaConfig{}, LotusApiNode: fullNode, MinerSelectionStrategies: strategies})
+
+	tests := []struct {
+		name           string
+		param          DealQuoteParam
+		wantMiners     int
+		wantTotalPrice string
+		wantFunds      bool
+		wantDataCap    bool
+	}{
+		{"unverified", DealQuoteParam{Miner: "f01000", Size: 1 << 20, ProposalParameters: model.ContentDealProposalParameters{
+			StartEpoch: 2000, EndEpoch: 2100, UnverifiedDealMaxPrice: "2",
+		}}, 1, "200", true, true},
+		{"unverified without funds", DealQuoteParam{Miner: "f01000", Replication: 2, Size: 1 << 20, ProposalParameters: model.ContentDealProposalParameters{
+			StartEpoch: 2000, EndEpoch: 2100, UnverifiedDealMaxPrice: "5", MinerSelectionStrategy: utils.MINER_SELECTION_STATIC_ALLOWLIST,
+		}}, 3, "1500", false, true},
+		{"verified", DealQuoteParam{Replication: 2, PaddedPieceSize: 1 << 30, ProposalParameters: model.ContentDealProposalParameters{
+			VerifiedDeal: true, MinerSelectionStrategy: utils.MINER_SELECTION_STATIC_ALLOWLIST,
+		}}, 3, "0", true, true},
+		{"verified without datacap", DealQuoteParam{Miner: "f01000", PaddedPieceSize: 4 << 30, ProposalParameters: model.ContentDealProposalParameters{
+			VerifiedDeal: true,
+		}}, 1, "0", true, false},
+	}
+	for _, tt := range tests {
+		tt.param.Wallet = "f01234"
+		quote, err := service.Quote(context.Background(), tt.param)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if len(quote.Proposals) != tt.wantMiners || quote.TotalPrice != tt.wantTotalPrice || quote.SufficientFunds != tt.wantFunds || quote.SufficientDataCap != tt.wantDataCap {
+			t.Errorf("%s: unexpected quote %+v", tt.name, quote)
+		}
+		miners := map[string]bool{}
+		for _, proposal := range quote.Proposals {
+			miners[proposal.Provider] = true
+		}
+		if len(miners) != tt.wantMiners {
+			t.Errorf("%s: expected %d distinct providers, got %+v", tt.name, tt.wantMiners, quote.Proposals)
+		}
+	}
+
+	if _, err := service.Quote(context.Background(), DealQuoteParam{Miner: "f01000", Wallet: "f01234", ProposalParameters: model.ContentDealProposalParameters{UnverifiedDealMaxPrice: "0.5"}}); err == nil {
+		t.Error("expected an error for a price that isn't in attoFIL")
+	}
+
+	var count int64
+	db.Model(&model.Content{}).Count(&count)
+	if count != 0 {
+		t.Errorf("expected nothing to be saved, got %d contents", count)
+	}
+}
@@ -0,0 +1,76 @@
+package core
+
+import (
+	model "delta/models"
+	"delta/utils"
+	"time"
+
+	"github.com/filecoin-project/go-fil-markets/storagemarket"
+	"gorm.io/gorm"
+)
+
+// ContentToReconcile is a content whose deal status is checked again.
+// @property Content - The content in a non-final state.
+// @property Miner - The storage provider of the latest deal of the content.
+type ContentToReconcile struct {
+	Content model.Content
+	Miner   string
+}
+
+// finalContentStatuses are the statuses a deal status check can't change anymore
+var finalContentStatuses = func() []string {
+	statuses := []string{
+		utils.CONTENT_DEAL_ACTIVE,
+		utils.CONTENT_DEAL_SLASHED,
+		storagemarket.DealStates[storagemarket.StorageDealActive],
+		storagemarket.DealStates[storagemarket.StorageDealExpired],
+		storagemarket.DealStates[storagemarket.StorageDealSlashed],
+		storagemarket.DealStates[storagemarket.StorageDealError],
+	}
+	for status := range failedContentStatuses {
+		statuses = append(statuses, status)
+	}
+	return statuses
+}()
+
+// IsFinalContentStatus returns true if the deal status of a content in this status doesn't need to be checked anymore.
+func IsFinalContentStatus(status string) bool {
+	for _, final := range finalContentStatuses {
+		if final == status {
+			return true
+		}
+	}
+	return false
+}
+
+// GetContentsToReconcile returns up to batchSize contents in a non-final state that have a deal in progress, the
+// least recently updated first.
+func GetContentsToReconcile(db *gorm.DB, batchSize int) ([]ContentToReconcile, error) {
+	var contents []model.Content
+	err := db.Model(&model.Content{}).
+		Where("status not in ? and exists (select 1 from content_deals cd where cd.content = contents.id and cd.deal_uuid <> '' and cd.failed = ?)", finalContentStatuses, false).
+		Order("updated_at asc").Limit(batchSize).Find(&contents).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var toReconcile []ContentToReconcile
+	for _, content := range contents {
+		var contentDeal model.ContentDeal
+		db.Model(&model.ContentDeal{}).Where("content = ? and deal_uuid <> ''", content.ID).Order("created_at desc").Limit(1).Find(&contentDeal)
+		toReconcile = append(toReconcile, ContentToReconcile{Content: content, Miner: contentDeal.Miner})
+	}
+	return toReconcile, nil
+}
+
+// ReconciliationDelays returns when to check each content so two checks of the same storage provider are at least
+// spacing apart.
+func ReconciliationDelays(contents []ContentToReconcile, spacing time.Duration) []time.Duration {
+	perMiner := map[string]int{}
+	delays := make([]time.Duration, len(contents))
+	for i, content := range contents {
+		delays[i] = time.Duration(perMiner[content.Miner]) * spacing
+		perMiner[content.Miner]++
+	}
+	return delays
+}
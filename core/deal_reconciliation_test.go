@@ -0,0 +1,64 @@
+package core
+
+import (
+	model "delta/models"
+	"delta/utils"
+	"testing"
+	"time"
+)
+
+func TestGetContentsToReconcile(t *testing.T) {
+	db := newTestDB(t)
+	contents := []struct {
+		status   string
+		dealUuid string
+		miner    string
+	}{
+		{utils.CONTENT_DEAL_PROPOSAL_SENT, "uuid-1", "f01"},
+		{utils.DEAL_STATUS_TRANSFER_FINISHED, "uuid-2", "f02"},
+		{utils.CONTENT_DEAL_ACTIVE, "uuid-3", "f01"},          // final
+		{utils.CONTENT_DEAL_PROPOSAL_FAILED, "uuid-4", "f01"}, // final
+		{utils.CONTENT_PIECE_COMPUTING, "", ""},               // no deal yet
+	}
+	for i, c := range contents {
+		content := model.Content{Status: c.status, UpdatedAt: time.Now().Add(time.Duration(i) * time.Minute)}
+		db.Create(&content)
+		if c.dealUuid != "" {
+			db.Create(&model.ContentDeal{Content: content.ID, DealUUID: c.dealUuid, Miner: c.miner})
+		}
+	}
+
+	toReconcile, err := GetContentsToReconcile(db, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toReconcile) != 2 || toReconcile[0].Miner != "f01" || toReconcile[1].Miner != "f02" {
+		t.Fatalf("expected the 2 contents in a non-final state, got %+v", toReconcile)
+	}
+	if limited, _ := GetContentsToReconcile(db, 1); len(limited) != 1 || limited[0].Content.Status != utils.CONTENT_DEAL_PROPOSAL_SENT {
+		t.Errorf("expected the least recently updated content, got %+v", limited)
+	}
+}
+
+func TestReconciliationDelays(t *testing.T) {
+	contents := []ContentToReconcile{{Miner: "f01"}, {Miner: "f02"}, {Miner: "f01"}, {Miner: "f01"}}
+	delays := ReconciliationDelays(contents, 5*time.Second)
+	expected := []time.Duration{0, 0, 5 * time.Second, 10 * time.Second}
+	for i := range expected {
+		if delays[i] != expected[i] {
+			t.Errorf("content %d: expected a delay of %s, got %s", i, expected[i], delays[i])
+		}
+	}
+}
+
+func TestRecordStatusTransition(t *testing.T) {
+	db := newTestDB(t)
+	model.RecordStatusTransition(db, utils.STATUS_OBJECT_CONTENT, 1, utils.CONTENT_PINNED, utils.CONTENT_PINNED, "", "test")
+	model.RecordStatusTransition(db, utils.STATUS_OBJECT_CONTENT, 1, utils.CONTENT_PINNED, utils.CONTENT_PIECE_COMPUTING, "", "test")
+
+	var transitions []model.StatusTransition
+	db.Where("object_type = ? and object_id = ?", utils.STATUS_OBJECT_CONTENT, 1).Find(&transitions)
+	if len(transitions) != 1 || transitions[0].NewStatus != utils.CONTENT_PIECE_COMPUTING {
+		t.Errorf("expected only the status change to be recorded, got %+v", transitions)
+	}
+}
@@ -0,0 +1,210 @@
+package core
+
+import (
+	model "delta/models"
+	"delta/utils"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DealToRenew is an active deal close to its end epoch, of a content that opted in for renewal.
+// @property Content - The content of the deal.
+// @property Deal - The active deal to renew.
+type DealToRenew struct {
+	Content model.Content
+	Deal    model.ContentDeal
+}
+
+// DealRenewalService creates the renewals of the deals that are about to expire.
+type DealRenewalService struct {
+	DeltaNode DeltaNode
+}
+
+// NewDealRenewalService creates a new DealRenewalService.
+func NewDealRenewalService(dn DeltaNode) *DealRenewalService {
+	return &DealRenewalService{
+		DeltaNode: dn,
+	}
+}
+
+// GetDealsToRenew returns the active deals that end within the window after the head, of the contents that opted in
+// for renewal. A content with a renewal in progress is skipped, and so is a content whose renewals failed maxAttempts
+// times.
+func GetDealsToRenew(db *gorm.DB, head int64, window int64, maxAttempts int) ([]DealToRenew, error) {
+	var deals []model.ContentDeal
+	err := db.Model(&model.ContentDeal{}).
+		Joins("join contents c on c.id = content_deals.content").
+		Where("c.auto_renew = ? and content_deals.sector_start_epoch > 0 and content_deals.slashed = ? and content_deals.failed = ? and content_deals.deal_end_epoch > ? and content_deals.deal_end_epoch <= ?",
+			true, false, false, head, head+window).
+		Order("content_deals.deal_end_epoch asc").Find(&deals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var toRenew []DealToRenew
+	seen := make(map[int64]bool)
+	for _, deal := range deals {
+		if seen[deal.Content] {
+			continue
+		}
+		seen[deal.Content] = true
+
+		failed, inProgress := countDealCopies(db, "renewal_of", deal.Content)
+		if inProgress || failed >= maxAttempts {
+			continue
+		}
+
+		var content model.Content
+		db.Model(&model.Content{}).Where("id = ?", deal.Content).Find(&content)
+		toRenew = append(toRenew, DealToRenew{Content: content, Deal: deal})
+	}
+	return toRenew, nil
+}
+
+// countDealCopies counts the failed renewals or replacements of a content, by the column that links them to it, and
+// tells if one of them is live or in progress. A failed one that is scheduled for an auto retry is in progress.
+func countDealCopies(db *gorm.DB, column string, contentId int64) (int, bool) {
+	var copies []model.Content
+	db.Model(&model.Content{}).Where(column+" = ?", contentId).Find(&copies)
+	failed, inProgress := 0, false
+	for _, dealCopy := range copies {
+		if failedContentStatuses[dealCopy.Status] && !(dealCopy.AutoRetry && !dealCopy.NextRetryAt.IsZero()) {
+			failed++
+		} else {
+			inProgress = true
+		}
+	}
+	return failed, inProgress
+}
+
+// SkipDealRenewal turns the renewal of a content off when its deal can't be renewed, and says why on the content and
+// on the deal, so the skip shows in their status.
+func SkipDealRenewal(db *gorm.DB, toRenew DealToRenew, reason string) error {
+	return skipDealCopy(db, toRenew, "auto_renew", fmt.Sprintf("deal %d can't be renewed: %s", toRenew.Deal.DealID, reason))
+}
+
+// skipDealCopy turns the opt-in column of the content of a deal off, with the message on the content and on the deal.
+func skipDealCopy(db *gorm.DB, deal DealToRenew, optInColumn string, message string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Content{}).Where("id = ?", deal.Content.ID).Updates(map[string]interface{}{
+			optInColumn:    false,
+			"last_message": message,
+			"updated_at":   time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&model.ContentDeal{}).Where("id = ?", deal.Deal.ID).Updates(map[string]interface{}{
+			"last_message": message,
+			"updated_at":   time.Now(),
+		}).Error
+	})
+}
+
+// RenewalEpochs returns the start epoch, the end epoch and the duration of the renewal of a deal made with the given
+// parameters. The renewal keeps the duration of the original deal and starts after the delay.
+func RenewalEpochs(param model.ContentDealProposalParameters, head int64, startEpochDelay int64) (int64, int64, int64) {
+	duration := param.Duration
+	if duration <= 0 {
+		duration = utils.DEFAULT_DURATION
+	}
+	startEpoch := head + startEpochDelay
+	return startEpoch, startEpoch + duration, duration
+}
+
+// RenewDeal creates the renewal of a deal: a new content linked to the original one that reuses its piece commitment,
+// its wallet and its deal parameters with new epochs. The renewal is assigned to the same storage provider, or to
+// another one if switching is configured or the provider isn't allowed anymore. The renewal is ready for the deal
+// maker.
+func (r DealRenewalService) RenewDeal(toRenew DealToRenew, head int64) (model.Content, model.PieceCommitment, error) {
+	miner := toRenew.Deal.Miner
+	if r.DeltaNode.Config.DealRenewal.SwitchProvider || model.CheckMinerAccess(r.DeltaNode.DB, miner) != nil {
+		miner = ""
+	}
+	return r.copyDealContent(toRenew.Content, head, miner, []string{toRenew.Deal.Miner}, func(renewal *model.Content) {
+		renewal.LastMessage = fmt.Sprintf("renewal of deal %d of content %d", toRenew.Deal.DealID, toRenew.Content.ID)
+		renewal.RenewalOf = toRenew.Content.ID
+		renewal.ReplicationGroup = 0
+	})
+}
+
+// copyDealContent creates a new content from the content of a deal, ready for the deal maker. It reuses the piece
+// commitment, the wallet and the deal parameters of the content with new epochs, and is assigned to the given storage
+// provider, or to one selected with the strategy of the content outside the excluded ones if miner is empty. prepare
+// sets the fields that link the new content to the original one.
+func (r DealRenewalService) copyDealContent(source model.Content, head int64, miner string, excludedMiners []string, prepare func(*model.Content)) (model.Content, model.PieceCommitment, error) {
+	db := r.DeltaNode.DB
+
+	var pieceComm model.PieceCommitment
+	db.Model(&model.PieceCommitment{}).Where("id = ?", source.PieceCommitmentId).Find(&pieceComm)
+	if pieceComm.ID == 0 {
+		return model.Content{}, model.PieceCommitment{}, fmt.Errorf("content %d has no piece commitment", source.ID)
+	}
+	var param model.ContentDealProposalParameters
+	db.Model(&model.ContentDealProposalParameters{}).Where("content = ?", source.ID).Find(&param)
+	if param.ID == 0 {
+		return model.Content{}, model.PieceCommitment{}, fmt.Errorf("content %d has no deal proposal parameters", source.ID)
+	}
+
+	if miner == "" {
+		provider, err := NewMinerAssignmentService(r.DeltaNode).GetSPWithStrategy(param.MinerSelectionStrategy, MinerSelectionParam{
+			Size:           source.Size,
+			ExcludedMiners: excludedMiners,
+		})
+		if err != nil {
+			return model.Content{}, model.PieceCommitment{}, err
+		}
+		miner = provider.Address
+	}
+
+	content := source
+	content.ID = 0
+	content.Status = utils.CONTENT_DEAL_MAKING_PROPOSAL
+	content.NextRetryAt = time.Time{}
+	content.CreatedAt = time.Now()
+	content.UpdatedAt = time.Now()
+	prepare(&content)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&content).Error; err != nil {
+			return err
+		}
+
+		contentParam := param
+		contentParam.ID = 0
+		contentParam.Content = content.ID
+		contentParam.StartEpoch, contentParam.EndEpoch, contentParam.Duration = RenewalEpochs(param, head, int64(r.DeltaNode.Config.DealRenewal.StartEpochDelay))
+		contentParam.CreatedAt = time.Now()
+		contentParam.UpdatedAt = time.Now()
+		if err := tx.Create(&contentParam).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Create(&model.ContentMiner{
+			Content:   content.ID,
+			Miner:     miner,
+			CreatedAt: time.Now(),
+			UpdatedAt: time.Now(),
+		}).Error; err != nil {
+			return err
+		}
+
+		var contentWallets []model.ContentWallet
+		tx.Model(&model.ContentWallet{}).Where("content = ?", source.ID).Find(&contentWallets)
+		for _, contentWallet := range contentWallets {
+			contentWallet.ID = 0
+			contentWallet.Content = content.ID
+			contentWallet.CreatedAt = time.Now()
+			contentWallet.UpdatedAt = time.Now()
+			if err := tx.Create(&contentWallet).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return model.Content{}, model.PieceCommitment{}, err
+	}
+	return content, pieceComm, nil
+}
@@ -0,0 +1,138 @@
+package core
+
+import (
+	"delta/config"
+	model "delta/models"
+	"delta/utils"
+	"testing"
+)
+
+func TestGetDealsToRenew(t *testing.T) {
+	db := newTestDB(t)
+	const head = 1000
+	deals := []struct {
+		autoRenew bool
+		deal      model.ContentDeal
+		renewals  []string
+	}{
+		{true, model.ContentDeal{SectorStartEpoch: 10, DealEndEpoch: head + 50}, nil},                                                                             // renewed
+		{true, model.ContentDeal{SectorStartEpoch: 10, DealEndEpoch: head + 500}, nil},                                                                            // outside of the window
+		{false, model.ContentDeal{SectorStartEpoch: 10, DealEndEpoch: head + 50}, nil},                                                                            // not opted in
+		{true, model.ContentDeal{SectorStartEpoch: 10, DealEndEpoch: head - 1}, nil},                                                                              // already expired
+		{true, model.ContentDeal{SectorStartEpoch: 10, DealEndEpoch: head + 50, Slashed: true}, nil},                                                              // slashed
+		{true, model.ContentDeal{DealEndEpoch: head + 50}, nil},                                                                                                   // not active
+		{true, model.ContentDeal{SectorStartEpoch: 10, DealEndEpoch: head + 50}, []string{utils.CONTENT_DEAL_MAKING_PROPOSAL}},                                    // renewal in progress
+		{true, model.ContentDeal{SectorStartEpoch: 10, DealEndEpoch: head + 40}, []string{utils.CONTENT_DEAL_PROPOSAL_FAILED}},                                    // renewed again
+		{true, model.ContentDeal{SectorStartEpoch: 10, DealEndEpoch: head + 50}, []string{utils.CONTENT_DEAL_PROPOSAL_FAILED, utils.DEAL_STATUS_TRANSFER_FAILED}}, // too many failures
+	}
+	var contentIds []int64
+	for _, d := range deals {
+		content := model.Content{AutoRenew: d.autoRenew, Status: utils.CONTENT_DEAL_ACTIVE}
+		db.Create(&content)
+		d.deal.Content = content.ID
+		db.Create(&d.deal)
+		for _, status := range d.renewals {
+			db.Create(&model.Content{Status: status, RenewalOf: content.ID})
+		}
+		contentIds = append(contentIds, content.ID)
+	}
+
+	toRenew, err := GetDealsToRenew(db, head, 100, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(toRenew) != 2 || toRenew[0].Content.ID != contentIds[7] || toRenew[1].Content.ID != contentIds[0] {
+		t.Fatalf("expected the deals of contents %d and %d, got %+v", contentIds[7], contentIds[0], toRenew)
+	}
+}
+
+func TestRenewalEpochs(t *testing.T) {
+	tests := []struct {
+		duration     int64
+		wantStart    int64
+		wantEnd      int64
+		wantDuration int64
+	}{
+		{518400, 1100, 1100 + 518400, 518400},
+		{0, 1100, 1100 + utils.DEFAULT_DURATION, utils.DEFAULT_DURATION},
+	}
+	for _, tt := range tests {
+		start, end, duration := RenewalEpochs(model.ContentDealProposalParameters{Duration: tt.duration}, 1000, 100)
+		if start != tt.wantStart || end != tt.wantEnd || duration != tt.wantDuration {
+			t.Errorf("duration %d: expected %d-%d (%d), got %d-%d (%d)", tt.duration, tt.wantStart, tt.wantEnd, tt.wantDuration, start, end, duration)
+		}
+	}
+}
+
+func TestDealRenewalService_RenewDeal(t *testing.T) {
+	db := newTestDB(t)
+	cfg := &config.DeltaConfig{}
+	cfg.DealRenewal.StartEpochDelay = 100
+
+	pieceComm := model.PieceCommitment{Piece: "piece", Status: utils.COMMP_STATUS_COMITTED}
+	db.Create(&pieceComm)
+	content := model.Content{Cid: "cid", PieceCommitmentId: pieceComm.ID, AutoRenew: true, ReplicationGroup: 3, Status: utils.CONTENT_DEAL_ACTIVE}
+	db.Create(&content)
+	db.Create(&model.ContentDealProposalParameters{Content: content.ID, Duration: 518400, StartEpoch: 10, EndEpoch: 10 + 518400, Label: "label"})
+	db.Create(&model.ContentWallet{Content: content.ID, WalletId: 7})
+	deal := model.ContentDeal{Content: content.ID, Miner: "f01000", DealID: 42, SectorStartEpoch: 10, DealEndEpoch: 10 + 518400}
+	db.Create(&deal)
+
+	service := NewDealRenewalService(DeltaNode{DB: db, Config: cfg})
+	renewal, renewalPieceComm, err := service.RenewDeal(DealToRenew{Content: content, Deal: deal}, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if renewal.ID == content.ID || renewal.RenewalOf != content.ID || renewal.Status != utils.CONTENT_DEAL_MAKING_PROPOSAL || renewal.ReplicationGroup != 0 || !renewal.AutoRenew {
+		t.Errorf("unexpected renewal %+v", renewal)
+	}
+	if renewalPieceComm.ID != pieceComm.ID || renewal.PieceCommitmentId != pieceComm.ID {
+		t.Errorf("expected the piece commitment %d to be reused, got %d", pieceComm.ID, renewalPieceComm.ID)
+	}
+
+	var param model.ContentDealProposalParameters
+	db.Model(&model.ContentDealProposalParameters{}).Where("content = ?", renewal.ID).Find(&param)
+	if param.StartEpoch != 1100 || param.EndEpoch != 1100+518400 || param.Label != "label" {
+		t.Errorf("unexpected deal proposal parameters %+v", param)
+	}
+	var contentMiner model.ContentMiner
+	db.Model(&model.ContentMiner{}).Where("content = ?", renewal.ID).Find(&contentMiner)
+	if contentMiner.Miner != "f01000" {
+		t.Errorf("expected the renewal with the same storage provider, got %q", contentMiner.Miner)
+	}
+	var contentWallet model.ContentWallet
+	db.Model(&model.ContentWallet{}).Where("content = ?", renewal.ID).Find(&contentWallet)
+	if contentWallet.WalletId != 7 {
+		t.Errorf("expected the wallet of the original content, got %+v", contentWallet)
+	}
+
+	// a renewal in progress is not renewed twice
+	db.Model(&model.ContentDeal{}).Where("id = ?", deal.ID).Update("deal_end_epoch", 1050)
+	if toRenew, _ := GetDealsToRenew(db, 1000, 100, 3); len(toRenew) != 0 {
+		t.Errorf("expected no deal to renew, got %+v", toRenew)
+	}
+}
+
+func TestSkipDealRenewal(t *testing.T) {
+	db := newTestDB(t)
+	content := model.Content{AutoRenew: true, Status: utils.CONTENT_DEAL_ACTIVE}
+	db.Create(&content)
+	deal := model.ContentDeal{Content: content.ID, DealID: 42, SectorStartEpoch: 10, DealEndEpoch: 1050}
+	db.Create(&deal)
+
+	if err := SkipDealRenewal(db, DealToRenew{Content: content, Deal: deal}, "the data is gone"); err != nil {
+		t.Fatal(err)
+	}
+	var stored model.Content
+	db.Model(&model.Content{}).Where("id = ?", content.ID).First(&stored)
+	var storedDeal model.ContentDeal
+	db.Model(&model.ContentDeal{}).Where("id = ?", deal.ID).First(&storedDeal)
+	if stored.AutoRenew || stored.Status != utils.CONTENT_DEAL_ACTIVE || stored.LastMessage != "deal 42 can't be renewed: the data is gone" || storedDeal.LastMessage != stored.LastMessage {
+		t.Fatalf("expected the renewal to be turned off with its reason, got %+v and %+v", stored, storedDeal)
+	}
+
+	// the content isn't picked again
+	if toRenew, err := GetDealsToRenew(db, 1000, 100, 2); err != nil || len(toRenew) != 0 {
+		t.Fatalf("expected no deal to renew, got %+v (%v)", toRenew, err)
+	}
+}
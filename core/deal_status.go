@@ -1,11 +1,40 @@
 package core
 
+import (
+	"context"
+	model "delta/models"
+	"delta/utils"
+	"fmt"
+	"time"
+
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+// DealStatusParam identifies a published deal.
+// @property DealUuid - The boost deal uuid.
+// @property MinerAddr - The storage provider of the deal.
+// @property DealID - The on-chain deal id.
 type DealStatusParam struct {
 	DealUuid  string
 	MinerAddr string
+	DealID    int64
 }
 
+// DealStatusResult is the state of a deal in the storage market actor.
+// @property DealID - The on-chain deal id.
+// @property State - published, active or slashed.
+// @property SectorStartEpoch - The epoch the sector of the deal was activated, -1 if it isn't yet.
+// @property SlashEpoch - The epoch the deal was slashed, -1 if it never was.
+// @property StartEpoch - The start epoch of the deal proposal.
+// @property EndEpoch - The end epoch of the deal proposal.
 type DealStatusResult struct {
+	DealID           int64  `json:"deal_id"`
+	State            string `json:"state"`
+	SectorStartEpoch int64  `json:"sector_start_epoch"`
+	SlashEpoch       int64  `json:"slash_epoch"`
+	StartEpoch       int64  `json:"start_epoch"`
+	EndEpoch         int64  `json:"end_epoch"`
 }
 
 type DealStatusService struct {
@@ -19,8 +48,103 @@ func NewDealStatusService(dn DeltaNode) *DealStatusService {
 	}
 }
 
-func (d DealStatusService) GetDealStatus(param DealStatusParam) (DealStatusResult, error) {
-	return DealStatusResult{}, nil
+// GetDealStatus reads the state of a published deal from the storage market actor with StateMarketStorageDeal.
+func (d DealStatusService) GetDealStatus(ctx context.Context, param DealStatusParam) (DealStatusResult, error) {
+	if param.DealID == 0 {
+		return DealStatusResult{}, fmt.Errorf("deal of %s with %s has no deal id yet", param.DealUuid, param.MinerAddr)
+	}
+	marketDeal, err := d.DeltaNode.LotusApiNode.StateMarketStorageDeal(ctx, abi.DealID(param.DealID), types.EmptyTSK)
+	if err != nil {
+		return DealStatusResult{}, err
+	}
+
+	result := DealStatusResult{
+		DealID:           param.DealID,
+		State:            utils.DEAL_STATE_PUBLISHED,
+		SectorStartEpoch: int64(marketDeal.State.SectorStartEpoch),
+		SlashEpoch:       int64(marketDeal.State.SlashEpoch),
+		StartEpoch:       int64(marketDeal.Proposal.StartEpoch),
+		EndEpoch:         int64(marketDeal.Proposal.EndEpoch),
+	}
+	switch {
+	case result.SlashEpoch > 0:
+		result.State = utils.DEAL_STATE_SLASHED
+	case result.SectorStartEpoch > 0:
+		result.State = utils.DEAL_STATE_ACTIVE
+	}
+	return result, nil
+}
+
+// TrackDealActivation records the on-chain state of a published deal, and moves its content to active or slashed.
+func (d DealStatusService) TrackDealActivation(ctx context.Context, contentDeal model.ContentDeal) (DealStatusResult, error) {
+	result, err := d.GetDealStatus(ctx, DealStatusParam{
+		DealUuid:  contentDeal.DealUUID,
+		MinerAddr: contentDeal.Miner,
+		DealID:    contentDeal.DealID,
+	})
+	if err != nil {
+		return result, err
+	}
+
+	wasActive := contentDeal.SectorStartEpoch > 0
+	contentDeal.SectorStartEpoch = result.SectorStartEpoch
+	contentDeal.SlashEpoch = result.SlashEpoch
+	contentDeal.DealStartEpoch = result.StartEpoch
+	contentDeal.DealEndEpoch = result.EndEpoch
+	contentDeal.UpdatedAt = time.Now()
+
+	var contentStatus string
+	switch result.State {
+	case utils.DEAL_STATE_ACTIVE:
+		if !wasActive {
+			contentDeal.OnChainAt = utils.HeightToDate(result.SectorStartEpoch)
+			contentDeal.SealedAt = contentDeal.OnChainAt
+			contentDeal.LastMessage = "deal is active on chain"
+			contentStatus = utils.CONTENT_DEAL_ACTIVE
+		}
+	case utils.DEAL_STATE_SLASHED:
+		contentDeal.Slashed = true
+		contentDeal.LastMessage = fmt.Sprintf("deal was slashed at epoch %d", result.SlashEpoch)
+		contentStatus = utils.CONTENT_DEAL_SLASHED
+	}
+	if err := SaveContentDeal(d.DeltaNode.DB, &contentDeal, utils.STATUS_SOURCE_DEAL_TRACKER); err != nil {
+		return result, err
+	}
+
+	if contentStatus != "" {
+		content := model.Content{ID: contentDeal.Content}
+		if err := TransitionContentStatus(d.DeltaNode.DB, &content, contentStatus, contentDeal.LastMessage, utils.STATUS_SOURCE_DEAL_TRACKER); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// TrackPublishedDeals records the on-chain state of every published deal that isn't failed, slashed or active yet. The
+// active deals are watched by the fault monitor, and the deals past their end epoch are no longer in the market actor.
+// It returns how many deals were checked.
+func (d DealStatusService) TrackPublishedDeals(ctx context.Context) (int, error) {
+	head, err := d.DeltaNode.LotusApiNode.ChainHead(ctx)
+	if err != nil {
+		return 0, err
+	}
+	var contentDeals []model.ContentDeal
+	d.DeltaNode.DB.Model(&model.ContentDeal{}).
+		Where("deal_id > 0 and failed = ? and slashed = ? and sector_start_epoch <= 0 and (deal_end_epoch <= 0 or deal_end_epoch > ?)", false, false, int64(head.Height())).
+		Order("updated_at asc").Find(&contentDeals)
+
+	checked := 0
+	for _, contentDeal := range contentDeals {
+		if err := ctx.Err(); err != nil {
+			return checked, err
+		}
+		if _, err := d.TrackDealActivation(ctx, contentDeal); err != nil {
+			fmt.Println("Error tracking deal", contentDeal.DealID, "of content", contentDeal.Content, err)
+			continue
+		}
+		checked++
+	}
+	return checked, nil
 }
 
 // get dealid
@@ -0,0 +1,182 @@
+package core
+
+import (
+	"context"
+	model "delta/models"
+	"delta/utils"
+	"errors"
+	"testing"
+
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-bitfield"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/builtin/v9/market"
+	"github.com/filecoin-project/go-state-types/builtin/v9/miner"
+	lapi "github.com/filecoin-project/lotus/api"
+	"github.com/filecoin-project/lotus/api/v1api"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+)
+
+// mockFullNode answers the chain and state calls from fixed data, any other call panics
+type mockFullNode struct {
+	v1api.FullNode
+	deals    map[abi.DealID]*lapi.MarketDeal
+	lookups  map[cid.Cid]*lapi.MsgLookup
+	messages map[cid.Cid]*types.Message
+	height   abi.ChainEpoch
+	sectors  []*miner.SectorOnChainInfo
+	faults   bitfield.BitField
+	balance  types.BigInt
+	market   lapi.MarketBalance
+	datacap  *abi.StoragePower
+}
+
+func (m *mockFullNode) ChainHead(ctx context.Context) (*types.TipSet, error) {
+	root, _ := cid.Decode("bafyreicmaj5hhoy5mgqvamfhgexxyergw7hdeshizghodwkjg6qmpoco7i")
+	miner, _ := address.NewIDAddress(1000)
+	return types.NewTipSet([]*types.BlockHeader{{
+		Miner:                 miner,
+		Ticket:                &types.Ticket{VRFProof: []byte("ticket")},
+		Height:                m.height,
+		ParentStateRoot:       root,
+		ParentMessageReceipts: root,
+		Messages:              root,
+		ParentWeight:          types.NewInt(0),
+		ParentBaseFee:         types.NewInt(0),
+	}})
+}
+
+func (m *mockFullNode) StateSearchMsg(ctx context.Context, from types.TipSetKey, msg cid.Cid, limit abi.ChainEpoch, allowReplaced bool) (*lapi.MsgLookup, error) {
+	return m.lookups[msg], nil
+}
+
+func (m *mockFullNode) ChainGetMessage(ctx context.Context, msg cid.Cid) (*types.Message, error) {
+	message, ok := m.messages[msg]
+	if !ok {
+		return nil, errors.New("message not found")
+	}
+	return message, nil
+}
+
+func (m *mockFullNode) StateMarketStorageDeal(ctx context.Context, dealID abi.DealID, tsk types.TipSetKey) (*lapi.MarketDeal, error) {
+	deal, ok := m.deals[dealID]
+	if !ok {
+		return nil, errors.New("deal not found")
+	}
+	return deal, nil
+}
+
+func (m *mockFullNode) StateMinerActiveSectors(ctx context.Context, addr address.Address, tsk types.TipSetKey) ([]*miner.SectorOnChainInfo, error) {
+	return m.sectors, nil
+}
+
+func (m *mockFullNode) StateMinerFaults(ctx context.Context, addr address.Address, tsk types.TipSetKey) (bitfield.BitField, error) {
+	return m.faults, nil
+}
+
+func (m *mockFullNode) WalletBalance(ctx context.Context, addr address.Address) (types.BigInt, error) {
+	return m.balance, nil
+}
+
+func (m *mockFullNode) StateMarketBalance(ctx context.Context, addr address.Address, tsk types.TipSetKey) (lapi.MarketBalance, error) {
+	return m.market, nil
+}
+
+func (m *mockFullNode) StateVerifiedClientStatus(ctx context.Context, addr address.Address, tsk types.TipSetKey) (*abi.StoragePower, error) {
+	return m.datacap, nil
+}
+
+func newMarketDeal(sectorStart, slash abi.ChainEpoch) *lapi.MarketDeal {
+	return &lapi.MarketDeal{
+		Proposal: market.DealProposal{StartEpoch: 2000000, EndEpoch: 3000000},
+		State:    market.DealState{SectorStartEpoch: sectorStart, LastUpdatedEpoch: -1, SlashEpoch: slash},
+	}
+}
+
+func TestDealStatusService_GetDealStatus(t *testing.T) {
+	fullNode := &mockFullNode{deals: map[abi.DealID]*lapi.MarketDeal{
+		1: newMarketDeal(-1, -1),
+		2: newMarketDeal(1990000, -1),
+		3: newMarketDeal(1990000, 2100000),
+	}}
+	service := NewDealStatusService(DeltaNode{LotusApiNode: fullNode})
+
+	tests := []struct {
+		dealID  int64
+		state   string
+		wantErr bool
+	}{
+		{1, utils.DEAL_STATE_PUBLISHED, false},
+		{2, utils.DEAL_STATE_ACTIVE, false},
+		{3, utils.DEAL_STATE_SLASHED, false},
+		{4, "", true},
+		{0, "", true},
+	}
+	for _, tt := range tests {
+		result, err := service.GetDealStatus(context.Background(), DealStatusParam{DealID: tt.dealID})
+		if (err != nil) != tt.wantErr {
+			t.Errorf("deal %d: expected error %v, got %v", tt.dealID, tt.wantErr, err)
+			continue
+		}
+		if result.State != tt.state {
+			t.Errorf("deal %d: expected state %q, got %q", tt.dealID, tt.state, result.State)
+		}
+	}
+}
+
+func TestDealStatusService_TrackPublishedDeals(t *testing.T) {
+	db := newTestDB(t)
+	fullNode := &mockFullNode{deals: map[abi.DealID]*lapi.MarketDeal{
+		1: newMarketDeal(-1, -1),
+		2: newMarketDeal(1990000, -1),
+		3: newMarketDeal(1990000, 2100000),
+	}}
+	service := NewDealStatusService(DeltaNode{DB: db, LotusApiNode: fullNode})
+
+	var contents []model.Content
+	for dealID := int64(1); dealID <= 4; dealID++ {
+		content := model.Content{Status: utils.DEAL_STATUS_TRANSFER_FINISHED}
+		db.Create(&content)
+		db.Create(&model.ContentDeal{Content: content.ID, DealID: dealID})
+		contents = append(contents, content)
+	}
+
+	checked, err := service.TrackPublishedDeals(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checked != 3 {
+		t.Errorf("expected 3 deals to be checked, got %d", checked)
+	}
+
+	wantStatuses := []string{utils.DEAL_STATUS_TRANSFER_FINISHED, utils.CONTENT_DEAL_ACTIVE, utils.CONTENT_DEAL_SLASHED, utils.DEAL_STATUS_TRANSFER_FINISHED}
+	for i, content := range contents {
+		db.First(&content, content.ID)
+		if content.Status != wantStatuses[i] {
+			t.Errorf("content %d: expected status %q, got %q", content.ID, wantStatuses[i], content.Status)
+		}
+	}
+
+	var active model.ContentDeal
+	db.Where("deal_id = ?", 2).First(&active)
+	if active.SectorStartEpoch != 1990000 || active.DealEndEpoch != 3000000 || !active.OnChainAt.Equal(utils.HeightToDate(1990000)) {
+		t.Errorf("expected the activation to be recorded, got %+v", active)
+	}
+	var slashed model.ContentDeal
+	db.Where("deal_id = ?", 3).First(&slashed)
+	if !slashed.Slashed || slashed.SlashEpoch != 2100000 {
+		t.Errorf("expected the slashing to be recorded, got %+v", slashed)
+	}
+
+	// active and slashed deals are no longer tracked
+	if checked, _ := service.TrackPublishedDeals(context.Background()); checked != 1 {
+		t.Errorf("expected 1 deal to be checked again, got %d", checked)
+	}
+
+	// nor the deals past their end epoch
+	fullNode.height = 3000000
+	if checked, _ := service.TrackPublishedDeals(context.Background()); checked != 0 {
+		t.Errorf("expected no deal to be checked after the end epoch, got %d", checked)
+	}
+}
@@ -0,0 +1,104 @@
+package core
+
+import (
+	model "delta/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrIdempotencyKeyInProgress is returned when a request with the same idempotency key is still running.
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still in progress")
+	// ErrIdempotencyKeyReused is returned when an idempotency key comes back with a different request.
+	ErrIdempotencyKeyReused = errors.New("this idempotency key was already used with a different request")
+)
+
+// IdempotencyParam identifies a request made with an idempotency key.
+// @property Key - The value of the Idempotency-Key header.
+// @property TenantID - The tenant of the caller, the keys of different tenants never collide.
+// @property Method - The http method of the request.
+// @property Path - The path of the request.
+type IdempotencyParam struct {
+	Key      string
+	TenantID int64
+	Method   string
+	Path     string
+}
+
+// GetIdempotencyKey returns the unexpired record of an idempotency key of the caller, or an empty record.
+func GetIdempotencyKey(db *gorm.DB, param IdempotencyParam, now time.Time) model.IdempotencyKey {
+	var record model.IdempotencyKey
+	db.Model(&model.IdempotencyKey{}).
+		Where("key = ? and tenant_id = ? and expires_at > ?", param.Key, param.TenantID, now).
+		Find(&record)
+	return record
+}
+
+// BeginIdempotentRequest records that a request with an idempotency key is running. An expired record of the key, or
+// an unfinished one older than the lock timeout, is replaced. It returns ErrIdempotencyKeyInProgress if the key is held
+// by another request.
+func BeginIdempotentRequest(db *gorm.DB, param IdempotencyParam, retention time.Duration, lockTimeout time.Duration) (model.IdempotencyKey, error) {
+	now := time.Now()
+	err := db.Where("key = ? and tenant_id = ? and (expires_at <= ? or (status = ? and updated_at <= ?))",
+		param.Key, param.TenantID, now, model.IDEMPOTENCY_KEY_IN_PROGRESS, now.Add(-lockTimeout)).
+		Delete(&model.IdempotencyKey{}).Error
+	if err != nil {
+		return model.IdempotencyKey{}, err
+	}
+
+	record := model.IdempotencyKey{
+		Key:       param.Key,
+		TenantID:  param.TenantID,
+		Method:    param.Method,
+		Path:      param.Path,
+		Status:    model.IDEMPOTENCY_KEY_IN_PROGRESS,
+		ExpiresAt: now.Add(retention),
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	// the unique index on the key and the tenant lets only one request through
+	if err := db.Create(&record).Error; err != nil {
+		if existing := GetIdempotencyKey(db, param, now); existing.ID != 0 {
+			return existing, ErrIdempotencyKeyInProgress
+		}
+		return model.IdempotencyKey{}, err
+	}
+	return record, nil
+}
+
+// CompleteIdempotentRequest records the response of a request with an idempotency key, it is replayed to the repeats
+// of the request.
+func CompleteIdempotentRequest(db *gorm.DB, record *model.IdempotencyKey, requestHash string, status int, contentType string, body []byte) error {
+	record.RequestHash = requestHash
+	record.Status = model.IDEMPOTENCY_KEY_COMPLETED
+	record.ResponseStatus = status
+	record.ResponseContentType = contentType
+	record.ResponseBody = string(body)
+	record.UpdatedAt = time.Now()
+	return db.Save(record).Error
+}
+
+// AbortIdempotentRequest releases the idempotency key of a request that didn't complete, so it can be sent again.
+func AbortIdempotentRequest(db *gorm.DB, record model.IdempotencyKey) error {
+	return db.Where("id = ? and status = ?", record.ID, model.IDEMPOTENCY_KEY_IN_PROGRESS).Delete(&model.IdempotencyKey{}).Error
+}
+
+// MatchIdempotentRequest checks a repeat is the same request as the one recorded with its idempotency key. It returns
+// ErrIdempotencyKeyInProgress if the recorded request isn't completed yet.
+func MatchIdempotentRequest(record model.IdempotencyKey, param IdempotencyParam, requestHash string) error {
+	if record.Status != model.IDEMPOTENCY_KEY_COMPLETED {
+		return ErrIdempotencyKeyInProgress
+	}
+	if record.Method != param.Method || record.Path != param.Path || record.RequestHash != requestHash {
+		return ErrIdempotencyKeyReused
+	}
+	return nil
+}
+
+// DeleteExpiredIdempotencyKeys deletes the records of the idempotency keys past their retention.
+func DeleteExpiredIdempotencyKeys(db *gorm.DB, now time.Time) (int64, error) {
+	result := db.Where("expires_at <= ?", now).Delete(&model.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
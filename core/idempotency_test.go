@@ -0,0 +1,119 @@
+package core
+
+import (
+	model "delta/models"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBeginIdempotentRequest(t *testing.T) {
+	db := newTestDB(t)
+	param := IdempotencyParam{Key: "key-1", TenantID: 1, Method: "POST", Path: "/api/v1/deal/end-to-end"}
+
+	record, err := BeginIdempotentRequest(db, param, time.Hour, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.ID == 0 || record.Status != model.IDEMPOTENCY_KEY_IN_PROGRESS {
+		t.Fatalf("expected an in-progress record, got %+v", record)
+	}
+
+	// the same key while the first request runs
+	if _, err := BeginIdempotentRequest(db, param, time.Hour, time.Minute); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Fatalf("expected ErrIdempotencyKeyInProgress, got %v", err)
+	}
+
+	// the same key of another caller
+	other := param
+	other.TenantID = 2
+	if _, err := BeginIdempotentRequest(db, other, time.Hour, time.Minute); err != nil {
+		t.Fatalf("expected the key of another caller to be free, got %v", err)
+	}
+
+	// a request that didn't complete releases its key
+	if err := AbortIdempotentRequest(db, record); err != nil {
+		t.Fatal(err)
+	}
+	if record, err = BeginIdempotentRequest(db, param, time.Hour, time.Minute); err != nil {
+		t.Fatalf("expected an aborted key to be free, got %v", err)
+	}
+
+	// an unfinished request older than the lock timeout releases its key
+	db.Model(&model.IdempotencyKey{}).Where("id = ?", record.ID).Update("updated_at", time.Now().Add(-2*time.Minute))
+	if _, err := BeginIdempotentRequest(db, param, time.Hour, time.Minute); err != nil {
+		t.Fatalf("expected a stale key to be free, got %v", err)
+	}
+}
+
+func TestMatchIdempotentRequest(t *testing.T) {
+	db := newTestDB(t)
+	param := IdempotencyParam{Key: "key-1", TenantID: 1, Method: "POST", Path: "/api/v1/deal/end-to-end"}
+	record, err := BeginIdempotentRequest(db, param, time.Hour, time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := MatchIdempotentRequest(GetIdempotencyKey(db, param, time.Now()), param, "hash"); !errors.Is(err, ErrIdempotencyKeyInProgress) {
+		t.Fatalf("expected ErrIdempotencyKeyInProgress before completion, got %v", err)
+	}
+	if err := CompleteIdempotentRequest(db, &record, "hash", 200, "application/json", []byte(`{"status":"success"}`)); err != nil {
+		t.Fatal(err)
+	}
+	stored := GetIdempotencyKey(db, param, time.Now())
+	if stored.ResponseStatus != 200 || stored.ResponseBody != `{"status":"success"}` {
+		t.Fatalf("expected the response to be stored, got %+v", stored)
+	}
+
+	otherPath := param
+	otherPath.Path = "/api/v1/deal/imports"
+	tests := []struct {
+		param IdempotencyParam
+		hash  string
+		want  error
+	}{
+		{param, "hash", nil},
+		{param, "other-hash", ErrIdempotencyKeyReused},
+		{otherPath, "hash", ErrIdempotencyKeyReused},
+	}
+	for i, tt := range tests {
+		if err := MatchIdempotentRequest(stored, tt.param, tt.hash); !errors.Is(err, tt.want) {
+			t.Errorf("request %d: expected %v, got %v", i, tt.want, err)
+		}
+	}
+
+	// a completed key isn't released by an abort
+	if err := AbortIdempotentRequest(db, record); err != nil {
+		t.Fatal(err)
+	}
+	if GetIdempotencyKey(db, param, time.Now()).ID == 0 {
+		t.Fatal("expected the completed key to be kept")
+	}
+}
+
+func TestDeleteExpiredIdempotencyKeys(t *testing.T) {
+	db := newTestDB(t)
+	param := IdempotencyParam{Key: "key-1", TenantID: 1, Method: "POST", Path: "/api/v1/deal/end-to-end"}
+	if _, err := BeginIdempotentRequest(db, param, time.Hour, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	kept := param
+	kept.Key = "key-2"
+	if _, err := BeginIdempotentRequest(db, kept, 3*time.Hour, time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	later := time.Now().Add(2 * time.Hour)
+	if GetIdempotencyKey(db, param, later).ID != 0 {
+		t.Fatal("expected an expired key not to be found")
+	}
+	deleted, err := DeleteExpiredIdempotencyKeys(db, later)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deleted != 1 {
+		t.Fatalf("expected 1 expired key deleted, got %d", deleted)
+	}
+	if GetIdempotencyKey(db, kept, later).ID == 0 {
+		t.Fatal("expected the unexpired key to be kept")
+	}
+}
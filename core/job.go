@@ -1,62 +1,106 @@
-// Package core Create a dispatcher, add jobs to it, and then start it with a number of workers
+// Package core Create a dispatcher, add jobs to it, and then start it with a fixed pool of workers per job queue
 package core
 
 import (
+	"context"
+	c "delta/config"
+	model "delta/models"
+	"delta/utils"
+	"errors"
 	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
 type JobExecutable func() error
 
 // IProcessor is an interface that has a Run method that returns an error.
 // @property {error} Run - This is the main function of the processor. It will be called by the processor manager.
+// The context is cancelled when the job times out or is cancelled.
 type IProcessor interface {
-	Run() error
+	Run(ctx context.Context) error
 }
 
+// IQueuedProcessor is an IProcessor that names the queue it runs on. Processors that don't implement it run on
+// the cleanup queue.
+type IQueuedProcessor interface {
+	IProcessor
+	JobQueue() string
+}
+
+// IContentProcessor is an IProcessor that works on a single content, its jobs can be cancelled by content id.
+type IContentProcessor interface {
+	IProcessor
+	JobContent() int64
+}
+
+// ErrQueueFull is returned when a job can't be added because its queue is at capacity.
+var ErrQueueFull = errors.New("job queue is full")
+
 // A Job is a struct that has an ID and a Processor.
 // @property {int} ID - The ID of the job.
+// @property {int64} QueueID - The ID of the job table entry, 0 if the job is not persisted.
+// @property {int64} ContentID - The content the job works on, 0 if the processor doesn't work on a content.
 // @property {IProcessor} Processor - This is the interface that the job will use to process itself.
 type Job struct {
 	ID        int
+	QueueID   int64
+	ContentID int64
 	Processor IProcessor
+	cancelled int32
+	cancel    context.CancelFunc
+	lk        sync.Mutex
+}
+
+// Cancel stops the job. A queued job is skipped by the worker, a running job gets its context cancelled.
+func (j *Job) Cancel() {
+	atomic.StoreInt32(&j.cancelled, 1)
+	j.lk.Lock()
+	defer j.lk.Unlock()
+	if j.cancel != nil {
+		j.cancel()
+	}
+}
+
+// Cancelled returns true if the job was cancelled.
+func (j *Job) Cancelled() bool {
+	return atomic.LoadInt32(&j.cancelled) == 1
 }
 
-// A Worker is a struct that has an ID, a jobs channel, a dispatchStatus channel, and a Quit channel.
+// A Worker is a struct that has an ID, the queue it works on, a jobs channel, and a Quit channel.
 // @property {int} ID - The ID of the worker.
-// @property jobs - This is the channel that the worker will use to receive jobs from the dispatcher.
-// @property dispatchStatus - This is a channel that will be used to send a message to the worker when it's done processing
-// a job.
+// @property queue - This is the queue the worker takes its jobs from.
 // @property Quit - This is a channel that will be used to tell the worker to stop working.
+// @property store - The job store used to record the outcome of persisted jobs.
+// @property dispatcher - The dispatcher that keeps track of the active jobs.
 type Worker struct {
-	ID             int
-	jobs           chan *Job
-	dispatchStatus chan *DispatchStatus
-	Quit           chan bool
+	ID         int
+	queue      *JobQueue
+	Quit       chan bool
+	store      *JobStore
+	dispatcher *Dispatcher
 }
 
-// CreateNewWorker Create a new worker, add it to the worker queue, and return it
-func CreateNewWorker(id int, workerQueue chan *Worker, jobQueue chan *Job, dStatus chan *DispatchStatus) *Worker {
-	w := &Worker{
-		ID:             id,
-		jobs:           jobQueue,
-		dispatchStatus: dStatus,
+// CreateNewWorker Create a new worker for the given queue and return it
+func CreateNewWorker(id int, queue *JobQueue, store *JobStore, dispatcher *Dispatcher) *Worker {
+	return &Worker{
+		ID:         id,
+		queue:      queue,
+		Quit:       make(chan bool),
+		store:      store,
+		dispatcher: dispatcher,
 	}
-
-	go func() { workerQueue <- w }()
-	return w
 }
 
-// Start It's a goroutine that is waiting for a job to be added to the worker's job channel.
-// When a job is added, it is executed and then the worker sends a quit message to the dispatcher.
+// Start It's a goroutine that is waiting for a job to be added to the worker's queue.
+// When a job is added, it is executed and the worker waits for the next one until it is told to quit.
 func (w *Worker) Start() {
 	go func() {
 		for {
 			select {
-			case job := <-w.jobs:
-				fmt.Printf("Worker[%d] executing job[%d].\n", w.ID, job.ID)
-				job.Processor.Run()
-				w.dispatchStatus <- &DispatchStatus{Type: "worker", ID: w.ID, Status: "quit"}
-				w.Quit <- true
+			case job := <-w.queue.jobs:
+				w.run(job)
 			case <-w.Quit:
 				return
 			}
@@ -64,106 +108,396 @@ func (w *Worker) Start() {
 	}()
 }
 
-// DispatchStatus is a struct with three fields, Type, ID, and Status.
-//
-// The first field, Type, is a string. The second field, ID, is an int. The third field, Status, is a string.
-//
-// The DispatchStatus type is a struct type. A struct type is a collection of fields.
-//
-// The DispatchStatus type has three fields. Each field has a name and a type.
-//
-// The DispatchStatus type has three fields: Type, ID, and Status.
-//
-// The Type field has a name and a type. The name is Type
-// @property {string} Type - The type of the dispatch. This can be either "order" or "quote".
-// @property {int} ID - The ID of the dispatch
-// @property {string} Status - The status of the dispatch. This can be one of the following:
-type DispatchStatus struct {
-	Type   string
-	ID     int
-	Status string
-}
-
-// A Dispatcher is a struct that contains a jobCounter, jobQueue, dispatchStatus, workQueue, and workerQueue.
+// run executes the job with the deadline of its queue and records the outcome.
+func (w *Worker) run(job *Job) {
+	defer w.dispatcher.untrack(job)
+	defer atomic.AddInt64(&w.queue.pending, -1)
+
+	if job.Cancelled() {
+		fmt.Printf("Worker[%s-%d] skipping cancelled job[%d].\n", w.queue.Name, w.ID, job.ID)
+		if w.store != nil && job.QueueID != 0 {
+			w.store.MarkFinished(job.QueueID, context.Canceled)
+		}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if w.queue.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(context.Background(), w.queue.Timeout)
+	}
+	defer cancel()
+	job.lk.Lock()
+	job.cancel = cancel
+	job.lk.Unlock()
+	if job.Cancelled() { // cancelled between the check and the context creation
+		cancel()
+	}
+
+	fmt.Printf("Worker[%s-%d] executing job[%d].\n", w.queue.Name, w.ID, job.ID)
+	atomic.AddInt64(&w.queue.running, 1)
+	defer atomic.AddInt64(&w.queue.running, -1)
+	if w.store != nil && job.QueueID != 0 {
+		w.store.MarkRunning(job.QueueID)
+		stopLease := w.store.KeepLease(job.QueueID)
+		defer stopLease()
+	}
+	err := job.Processor.Run(ctx)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		fmt.Printf("Worker[%s-%d] job[%d] timed out after %s.\n", w.queue.Name, w.ID, job.ID, w.queue.Timeout)
+	}
+	if w.store != nil && job.QueueID != 0 {
+		w.store.MarkFinished(job.QueueID, err)
+	}
+}
+
+// JobQueue is a bounded queue of jobs of one type with its own number of workers.
+// @property Name - The name of the queue (commp, deal-making, status-check, cleanup).
+// @property Workers - The number of jobs of this queue that can run at the same time.
+// @property Capacity - The number of jobs that can wait in the queue.
+// @property Timeout - The deadline of a single job of this queue, no deadline when 0.
+type JobQueue struct {
+	Name     string
+	Workers  int
+	Capacity int
+	Timeout  time.Duration
+	jobs     chan *Job
+	pending  int64 // queued and running
+	running  int64
+}
+
+// JobQueueStats is a snapshot of a job queue.
+type JobQueueStats struct {
+	Name     string `json:"name"`
+	Workers  int    `json:"workers"`
+	Capacity int    `json:"capacity"`
+	Queued   int    `json:"queued"`
+	Running  int64  `json:"running"`
+}
+
+// A Dispatcher is a struct that holds a fixed set of job queues, each with its own worker pool.
 // @property {int} jobCounter - an internal counter for the number of jobs submitted
-// @property jobQueue - This is the channel that jobs are submitted to.
-// @property dispatchStatus - This is a channel that will be used to report the status of jobs and workers.
-// @property workQueue - This is the channel that the workers will use to send back the results of their work.
-// @property workerQueue - a channel of workers
+// @property queues - the job queues by name
+// @property workers - the workers of all the queues
+// @property active - the queued and running jobs by id, used to cancel them
+// @property Store - The job store that persists the queue. Jobs are kept in memory only when it is nil.
 type Dispatcher struct {
-	jobCounter     int                  // internal counter for number of jobs
-	jobQueue       chan *Job            // channel of jobs submitted
-	dispatchStatus chan *DispatchStatus // channel for job/worker status reports
-	workQueue      chan *Job            // channel of work dispatched
-	workerQueue    chan *Worker         // channel of workers
+	jobCounter int64
+	queues     map[string]*JobQueue
+	workers    []*Worker
+	active     map[int]*Job
+	activeLk   sync.Mutex
+	started    bool
+	lk         sync.Mutex
+	Store      *JobStore // durable job table
 }
 
-// CreateNewDispatcher Create a new dispatcher, and return a pointer to it
+const defaultJobQueueCapacity = 100
+
+// CreateNewDispatcher Create a new dispatcher with one worker per queue, and return a pointer to it
 func CreateNewDispatcher() *Dispatcher {
 	d := &Dispatcher{
-		jobCounter:     0,
-		jobQueue:       make(chan *Job),
-		dispatchStatus: make(chan *DispatchStatus),
-		workQueue:      make(chan *Job),
-		workerQueue:    make(chan *Worker),
+		queues: map[string]*JobQueue{},
+		active: map[int]*Job{},
+	}
+	for _, name := range []string{utils.JOB_QUEUE_COMMP, utils.JOB_QUEUE_DEAL_MAKING, utils.JOB_QUEUE_STATUS_CHECK, utils.JOB_QUEUE_CLEANUP} {
+		d.SetQueueLimit(name, 1, defaultJobQueueCapacity)
 	}
 	return d
 }
 
-// Start Creating a number of workers and then waiting for jobs to be added to the jobQueue.
-// When a job is added, it is sent to the workQueue, which is the channel that the workers are listening to.
-func (d *Dispatcher) Start(numWorkers int) {
-	// Create numWorkers:
-	for i := 0; i < numWorkers; i++ {
-		worker := CreateNewWorker(i, d.workerQueue, d.workQueue, d.dispatchStatus)
-		worker.Start()
+// ConfigureQueues sets the concurrency limit of each queue from the node configuration.
+func (d *Dispatcher) ConfigureQueues(config *c.DeltaConfig) {
+	capacity := config.Dispatcher.JobQueueCapacity
+	d.SetQueueLimit(utils.JOB_QUEUE_COMMP, config.Dispatcher.MaxCommpWorkers, capacity)
+	d.SetQueueLimit(utils.JOB_QUEUE_DEAL_MAKING, config.Dispatcher.MaxDealMakingWorkers, capacity)
+	d.SetQueueLimit(utils.JOB_QUEUE_STATUS_CHECK, config.Dispatcher.MaxStatusCheckWorkers, capacity)
+	d.SetQueueLimit(utils.JOB_QUEUE_CLEANUP, config.Dispatcher.MaxCleanupWorkers, capacity)
+
+	d.SetQueueTimeout(utils.JOB_QUEUE_COMMP, time.Duration(config.Dispatcher.CommpJobTimeout)*time.Minute)
+	d.SetQueueTimeout(utils.JOB_QUEUE_DEAL_MAKING, time.Duration(config.Dispatcher.DealMakingJobTimeout)*time.Minute)
+	d.SetQueueTimeout(utils.JOB_QUEUE_STATUS_CHECK, time.Duration(config.Dispatcher.StatusCheckJobTimeout)*time.Minute)
+	d.SetQueueTimeout(utils.JOB_QUEUE_CLEANUP, time.Duration(config.Dispatcher.CleanupJobTimeout)*time.Minute)
+}
+
+// ValidateJobTimeouts checks that the jobs of every queue time out before their lease expires. The lease of a running
+// job is renewed, but a job outliving its lease would be dispatched again by the poller of another node if the renewal
+// fails. A queue without a deadline relies on the renewal alone.
+func ValidateJobTimeouts(config *c.DeltaConfig) error {
+	lease := config.Dispatcher.JobLeaseDuration
+	timeouts := []struct {
+		queue   string
+		timeout int
+	}{
+		{utils.JOB_QUEUE_COMMP, config.Dispatcher.CommpJobTimeout},
+		{utils.JOB_QUEUE_DEAL_MAKING, config.Dispatcher.DealMakingJobTimeout},
+		{utils.JOB_QUEUE_STATUS_CHECK, config.Dispatcher.StatusCheckJobTimeout},
+		{utils.JOB_QUEUE_CLEANUP, config.Dispatcher.CleanupJobTimeout},
+	}
+	for _, queue := range timeouts {
+		if queue.timeout > 0 && queue.timeout >= lease {
+			return fmt.Errorf("the timeout of the %s jobs (%d minutes) must be shorter than the job lease (%d minutes)", queue.queue, queue.timeout, lease)
+		}
 	}
+	return nil
+}
 
-	// wait for work to be added then pass it off.
-	go func() {
-		for {
-			select {
-			case job := <-d.jobQueue:
-				fmt.Printf("Got a job in the queue to dispatch: %d\n", job.ID)
-				// Sending it off;
-				d.workQueue <- job
-			case ds := <-d.dispatchStatus:
-				fmt.Printf("Got a dispatch status: Type[%s] - ID[%d] - Status[%s]", ds.Type, ds.ID, ds.Status)
-				if ds.Type == "worker" {
-					if ds.Status == "quit" {
-						d.jobCounter--
-					}
-				}
+// SetQueueLimit sets the number of workers and the capacity of a queue. It has to be called before Start.
+func (d *Dispatcher) SetQueueLimit(name string, workers int, capacity int) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if d.started {
+		return
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if capacity < 1 {
+		capacity = defaultJobQueueCapacity
+	}
+	d.queues[name] = &JobQueue{
+		Name:     name,
+		Workers:  workers,
+		Capacity: capacity,
+		jobs:     make(chan *Job, capacity),
+	}
+}
+
+// SetQueueTimeout sets the deadline of the jobs of a queue. It has to be called before Start.
+func (d *Dispatcher) SetQueueTimeout(name string, timeout time.Duration) {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if queue, ok := d.queues[name]; ok && !d.started {
+		queue.Timeout = timeout
+	}
+}
+
+// Start Creating the workers of every queue. The pool is only created once, calling Start again does nothing.
+func (d *Dispatcher) Start() {
+	d.lk.Lock()
+	defer d.lk.Unlock()
+	if d.started {
+		return
+	}
+	d.started = true
+	for _, queue := range d.queues {
+		for i := 0; i < queue.Workers; i++ {
+			worker := CreateNewWorker(i, queue, d.Store, d)
+			worker.Start()
+			d.workers = append(d.workers, worker)
+		}
+	}
+}
+
+// AddJob Adding a job to the queue of its type without blocking.
+// A persisted job that doesn't fit stays in the job table and is dispatched by the poller once there is room,
+// any other job is dropped and ErrQueueFull is returned.
+func (d *Dispatcher) AddJob(je IProcessor) error {
+	j := d.newJob(je)
+	if d.enqueue(j) {
+		return nil
+	}
+	if j.QueueID != 0 {
+		d.Store.Release(j.QueueID)
+		return nil
+	}
+	fmt.Printf("Job queue %s is full, dropping job[%d]\n", queueOf(je), j.ID)
+	return ErrQueueFull
+}
+
+// ScheduleJob Adding a job that should not run before runAt. A persisted job waits in the job table until the poller
+// picks it up, any other job waits in memory.
+func (d *Dispatcher) ScheduleJob(je IProcessor, runAt time.Time) error {
+	if !runAt.After(time.Now()) {
+		return d.AddJob(je)
+	}
+	if persistent, ok := je.(IPersistentProcessor); ok && d.Store != nil {
+		_, err := d.Store.Enqueue(persistent, runAt)
+		return err
+	}
+	time.AfterFunc(time.Until(runAt), func() {
+		d.AddJob(je)
+	})
+	return nil
+}
+
+// IsFull returns true if the named queue can't take any more jobs.
+func (d *Dispatcher) IsFull(name string) bool {
+	queue, ok := d.queues[name]
+	if !ok {
+		return false
+	}
+	return len(queue.jobs) >= queue.Capacity
+}
+
+// Stats returns a snapshot of every queue.
+func (d *Dispatcher) Stats() []JobQueueStats {
+	var stats []JobQueueStats
+	for _, queue := range d.queues {
+		stats = append(stats, JobQueueStats{
+			Name:     queue.Name,
+			Workers:  queue.Workers,
+			Capacity: queue.Capacity,
+			Queued:   len(queue.jobs),
+			Running:  atomic.LoadInt64(&queue.running),
+		})
+	}
+	return stats
+}
+
+// queueOf returns the name of the queue the processor runs on.
+func queueOf(je IProcessor) string {
+	if queued, ok := je.(IQueuedProcessor); ok {
+		return queued.JobQueue()
+	}
+	return utils.JOB_QUEUE_CLEANUP
+}
+
+// contentOf returns the content the processor works on, 0 if it doesn't work on a content.
+func contentOf(je IProcessor) int64 {
+	if processor, ok := je.(IContentProcessor); ok {
+		return processor.JobContent()
+	}
+	return 0
+}
+
+func (d *Dispatcher) enqueue(j *Job) bool {
+	queue, ok := d.queues[queueOf(j.Processor)]
+	if !ok {
+		queue = d.queues[utils.JOB_QUEUE_CLEANUP]
+	}
+	atomic.AddInt64(&queue.pending, 1)
+	d.track(j)
+	select {
+	case queue.jobs <- j:
+		return true
+	default:
+		d.untrack(j)
+		atomic.AddInt64(&queue.pending, -1)
+		return false
+	}
+}
+
+func (d *Dispatcher) track(j *Job) {
+	d.activeLk.Lock()
+	defer d.activeLk.Unlock()
+	d.active[j.ID] = j
+}
+
+func (d *Dispatcher) untrack(j *Job) {
+	d.activeLk.Lock()
+	defer d.activeLk.Unlock()
+	delete(d.active, j.ID)
+}
+
+// CancelContentJobs cancels the queued and running jobs of a content, including the persisted jobs that are not
+// loaded yet. It returns the number of jobs that were cancelled.
+func (d *Dispatcher) CancelContentJobs(contentId int64) int {
+	cancelled := 0
+	d.activeLk.Lock()
+	for _, j := range d.active {
+		if j.ContentID == contentId && !j.Cancelled() {
+			j.Cancel()
+			// persisted jobs are counted by the job store
+			if j.QueueID == 0 || d.Store == nil {
+				cancelled++
 			}
 		}
+	}
+	d.activeLk.Unlock()
+
+	if d.Store != nil {
+		stored, err := d.Store.CancelContentJobs(contentId)
+		if err != nil {
+			fmt.Println("error cancelling persisted jobs", err)
+		}
+		cancelled += int(stored)
+	}
+	return cancelled
+}
+
+// newJob wraps the processor in a job and writes it to the job store if the processor can be persisted.
+func (d *Dispatcher) newJob(je IProcessor) *Job {
+	j := &Job{ID: int(atomic.AddInt64(&d.jobCounter, 1)), ContentID: contentOf(je), Processor: je}
+	if persistent, ok := je.(IPersistentProcessor); ok && d.Store != nil {
+		record, err := d.Store.Enqueue(persistent, time.Now())
+		if err != nil {
+			fmt.Println("error persisting job", err)
+			return j
+		}
+		j.QueueID = record.ID
+	}
+	return j
+}
+
+// StartPoller It's a goroutine that periodically leases the due jobs from the job store and dispatches them.
+// This picks up the jobs left behind by a restart, the jobs that didn't fit in their queue and the jobs that
+// were scheduled to run later.
+func (d *Dispatcher) StartPoller(ln *DeltaNode, interval time.Duration) {
+	go func() {
+		d.dispatchDueJobs(ln)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			d.dispatchDueJobs(ln)
+		}
 	}()
 }
 
-// AddJob Adding a job to the jobQueue.
-// It's adding a job to the jobQueue.
-func (d *Dispatcher) AddJob(je IProcessor) {
-	j := &Job{ID: d.jobCounter, Processor: je}
-	go func() { d.jobQueue <- j }()
-	d.jobCounter++
-	fmt.Printf("Number Of Jobs: %d\n", d.jobCounter)
+// freeCapacity is the number of jobs the queues can still take.
+func (d *Dispatcher) freeCapacity() int {
+	free := 0
+	for _, queue := range d.queues {
+		free += queue.Capacity - len(queue.jobs)
+	}
+	return free
 }
 
-// AddJobAndDispatch Adding a job to the jobQueue, and then starting the dispatcher with a number of workers.
-// It's adding a job to the jobQueue, and then starting the dispatcher with a number of workers.
-func (d *Dispatcher) AddJobAndDispatch(je IProcessor, numWorkers int) {
-	j := &Job{ID: d.jobCounter, Processor: je}
-	go func() { d.jobQueue <- j }()
-	d.jobCounter++
-	fmt.Printf("Number Of Jobs: %d\n", d.jobCounter)
-	d.Start(numWorkers)
+func (d *Dispatcher) dispatchDueJobs(ln *DeltaNode) {
+	limit := d.freeCapacity()
+	if limit == 0 {
+		return
+	}
+	leased, err := d.Store.LeaseDueJobs(limit)
+	if err != nil {
+		fmt.Println("error leasing jobs", err)
+		return
+	}
+	for _, record := range leased {
+		// a job that keeps getting interrupted should not be resumed forever
+		if record.Attempts >= ln.Config.Dispatcher.MaxJobResumeAttempts {
+			d.Store.MarkFinished(record.ID, errors.New("job was interrupted too many times"))
+			var content model.Content
+			ln.DB.Model(&model.Content{}).Where("id = ?", record.Content).Find(&content)
+			failedStatus, ok := restartFailedStatuses[content.Status]
+			if !ok {
+				failedStatus = utils.CONTENT_FAILED_TO_PROCESS
+			}
+			if err := TransitionContentStatus(ln.DB, &content, failedStatus, "Failed due to node restart", utils.STATUS_SOURCE_CLEAN_UP); err != nil {
+				fmt.Println("error failing the content of job", record.ID, err)
+			}
+			continue
+		}
+		processor, err := RebuildProcessor(ln, record)
+		if err != nil {
+			d.Store.MarkFinished(record.ID, err)
+			continue
+		}
+		j := &Job{ID: int(atomic.AddInt64(&d.jobCounter, 1)), QueueID: record.ID, ContentID: record.Content, Processor: processor}
+		if !d.enqueue(j) {
+			d.Store.Release(record.ID)
+		}
+	}
 }
 
-// Finished It's a method that returns true if the jobCounter is less than 1.
-// It's a method that returns true if the jobCounter is less than 1.
+// Finished It's a method that returns true if there are no queued or running jobs left.
 func (d *Dispatcher) Finished() bool {
-	if d.jobCounter < 1 {
-		return true
-	} else {
-		return false
+	for _, queue := range d.queues {
+		if atomic.LoadInt64(&queue.pending) > 0 {
+			return false
+		}
 	}
+	return true
 }
@@ -0,0 +1,260 @@
+package core
+
+import (
+	"context"
+	c "delta/config"
+	model "delta/models"
+	"delta/utils"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"sync"
+	"time"
+)
+
+// IPersistentProcessor is an IProcessor that can be written to the job table and rebuilt from its payload after a
+// restart.
+type IPersistentProcessor interface {
+	IProcessor
+	JobType() string
+	JobContent() int64
+	JobPayload() (string, error)
+}
+
+// JobFactory rebuilds a processor from the payload that was stored in the job table.
+type JobFactory func(ln *DeltaNode, payload string) (IProcessor, error)
+
+var (
+	jobFactories   = map[string]JobFactory{}
+	jobFactoriesLk sync.RWMutex
+)
+
+// RegisterJobFactory registers the factory used to rebuild persisted jobs of the given type.
+func RegisterJobFactory(jobType string, factory JobFactory) {
+	jobFactoriesLk.Lock()
+	defer jobFactoriesLk.Unlock()
+	jobFactories[jobType] = factory
+}
+
+func getJobFactory(jobType string) (JobFactory, bool) {
+	jobFactoriesLk.RLock()
+	defer jobFactoriesLk.RUnlock()
+	factory, ok := jobFactories[jobType]
+	return factory, ok
+}
+
+// JobStore keeps the dispatcher queue in the database so that queued and running jobs can be picked up again after
+// a restart.
+// @property DB - The database connection
+// @property Config - The node configuration. The instance uuid is used as the lease owner.
+type JobStore struct {
+	DB     *gorm.DB
+	Config *c.DeltaConfig
+}
+
+// NewJobStore creates a new job store
+func NewJobStore(db *gorm.DB, config *c.DeltaConfig) *JobStore {
+	return &JobStore{
+		DB:     db,
+		Config: config,
+	}
+}
+
+func (s *JobStore) owner() string {
+	return s.Config.Node.InstanceUuid
+}
+
+func (s *JobStore) leaseDuration() time.Duration {
+	return time.Duration(s.Config.Dispatcher.JobLeaseDuration) * time.Minute
+}
+
+// Enqueue writes the processor to the job table. The job is leased to this node right away when runAt is due,
+// so the caller can hand it to a worker without waiting for the poller.
+func (s *JobStore) Enqueue(processor IPersistentProcessor, runAt time.Time) (*model.Job, error) {
+	payload, err := processor.JobPayload()
+	if err != nil {
+		return nil, err
+	}
+
+	job := &model.Job{
+		Type:      processor.JobType(),
+		Content:   processor.JobContent(),
+		Payload:   payload,
+		Status:    utils.JOB_STATUS_QUEUED,
+		NextRunAt: runAt,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if !runAt.After(time.Now()) {
+		job.LeaseOwner = s.owner()
+		job.LeaseExpiresAt = time.Now().Add(s.leaseDuration())
+	}
+	if err := s.DB.Create(job).Error; err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+// MarkRunning flags the job as picked up by a worker and counts the attempt.
+func (s *JobStore) MarkRunning(jobId int64) error {
+	return s.DB.Model(&model.Job{}).Where("id = ?", jobId).Updates(map[string]interface{}{
+		"status":           utils.JOB_STATUS_RUNNING,
+		"attempts":         gorm.Expr("attempts + 1"),
+		"lease_owner":      s.owner(),
+		"lease_expires_at": time.Now().Add(s.leaseDuration()),
+		"updated_at":       time.Now(),
+	}).Error
+}
+
+// RenewLease extends the lease of a job this node is running, so no poller takes it while it runs.
+func (s *JobStore) RenewLease(jobId int64) error {
+	return s.DB.Model(&model.Job{}).Where("id = ? and status = ? and lease_owner = ?", jobId, utils.JOB_STATUS_RUNNING, s.owner()).Updates(map[string]interface{}{
+		"lease_expires_at": time.Now().Add(s.leaseDuration()),
+		"updated_at":       time.Now(),
+	}).Error
+}
+
+// KeepLease renews the lease of a running job every third of the lease duration until the returned function is
+// called, a job can run longer than its lease.
+func (s *JobStore) KeepLease(jobId int64) func() {
+	interval := s.leaseDuration() / 3
+	if interval <= 0 {
+		return func() {}
+	}
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.RenewLease(jobId); err != nil {
+					fmt.Println("error renewing job lease", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// MarkFinished records the outcome of a job run. A failed run is not requeued here, the processors already decide
+// themselves whether to retry.
+func (s *JobStore) MarkFinished(jobId int64, runErr error) error {
+	status := utils.JOB_STATUS_COMPLETED
+	lastMessage := ""
+	if runErr != nil {
+		status = utils.JOB_STATUS_FAILED
+		lastMessage = runErr.Error()
+	}
+	if errors.Is(runErr, context.Canceled) {
+		status = utils.JOB_STATUS_CANCELLED
+	}
+	return s.DB.Model(&model.Job{}).Where("id = ?", jobId).Updates(map[string]interface{}{
+		"status":       status,
+		"last_message": lastMessage,
+		"lease_owner":  "",
+		"updated_at":   time.Now(),
+	}).Error
+}
+
+// Release gives up the lease of a queued job so the poller can dispatch it later.
+func (s *JobStore) Release(jobId int64) error {
+	return s.DB.Model(&model.Job{}).Where("id = ? and status = ?", jobId, utils.JOB_STATUS_QUEUED).Updates(map[string]interface{}{
+		"lease_owner": "",
+		"updated_at":  time.Now(),
+	}).Error
+}
+
+// ReleaseOwnedLeases puts every unfinished job leased by this node back in the queue. It is called on startup, when
+// nothing can be running yet, so those leases belong to the previous run of this node.
+func (s *JobStore) ReleaseOwnedLeases() (int64, error) {
+	tx := s.DB.Model(&model.Job{}).
+		Where("lease_owner = ? and status in (?,?)", s.owner(), utils.JOB_STATUS_QUEUED, utils.JOB_STATUS_RUNNING).
+		Updates(map[string]interface{}{
+			"status":      utils.JOB_STATUS_QUEUED,
+			"lease_owner": "",
+			"updated_at":  time.Now(),
+		})
+	return tx.RowsAffected, tx.Error
+}
+
+// leasableJobs is the condition of the jobs whose lease can be taken: not leased, or leased by a node that stopped
+// renewing it. A job running on this node is never taken again, even if its lease expired.
+const leasableJobs = "(lease_owner = '' or (lease_expires_at < ? and not (status = ? and lease_owner = ?)))"
+
+// LeaseDueJobs claims up to limit queued jobs that are due and not leased by a live node.
+func (s *JobStore) LeaseDueJobs(limit int) ([]model.Job, error) {
+	var candidates []model.Job
+	err := s.DB.Model(&model.Job{}).
+		Where("status in (?,?) and next_run_at <= ? and "+leasableJobs, utils.JOB_STATUS_QUEUED, utils.JOB_STATUS_RUNNING, time.Now(),
+			time.Now(), utils.JOB_STATUS_RUNNING, s.owner()).
+		Order("next_run_at asc").
+		Limit(limit).
+		Find(&candidates).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var leased []model.Job
+	for _, job := range candidates {
+		// only one node can win the lease
+		tx := s.DB.Model(&model.Job{}).
+			Where("id = ? and "+leasableJobs, job.ID, time.Now(), utils.JOB_STATUS_RUNNING, s.owner()).
+			Updates(map[string]interface{}{
+				"status":           utils.JOB_STATUS_QUEUED,
+				"lease_owner":      s.owner(),
+				"lease_expires_at": time.Now().Add(s.leaseDuration()),
+				"updated_at":       time.Now(),
+			})
+		if tx.Error != nil || tx.RowsAffected != 1 {
+			continue
+		}
+		leased = append(leased, job)
+	}
+	return leased, nil
+}
+
+// CancelContentJobs marks the queued and running jobs of a content as cancelled so they are not dispatched again.
+func (s *JobStore) CancelContentJobs(contentId int64) (int64, error) {
+	tx := s.DB.Model(&model.Job{}).
+		Where("content = ? and status in (?,?)", contentId, utils.JOB_STATUS_QUEUED, utils.JOB_STATUS_RUNNING).
+		Updates(map[string]interface{}{
+			"status":       utils.JOB_STATUS_CANCELLED,
+			"last_message": "job was cancelled",
+			"lease_owner":  "",
+			"updated_at":   time.Now(),
+		})
+	return tx.RowsAffected, tx.Error
+}
+
+// HasPendingJobs returns true if the content has queued or running jobs.
+func (s *JobStore) HasPendingJobs(contentId int64) bool {
+	var count int64
+	s.DB.Model(&model.Job{}).Where("content = ? and status in (?,?)", contentId, utils.JOB_STATUS_QUEUED, utils.JOB_STATUS_RUNNING).Count(&count)
+	return count > 0
+}
+
+// RebuildProcessor creates the processor of a persisted job using the registered factory of its type.
+func RebuildProcessor(ln *DeltaNode, job model.Job) (IProcessor, error) {
+	factory, ok := getJobFactory(job.Type)
+	if !ok {
+		return nil, fmt.Errorf("no job factory registered for job type %s", job.Type)
+	}
+	return factory(ln, job.Payload)
+}
+
+// ResumePersistedJobs releases the leases left behind by the previous run of this node and starts the poller that
+// dispatches queued jobs again, so in-flight content resumes from its last status.
+func ResumePersistedJobs(ln *DeltaNode) {
+	if ln.Dispatcher.Store == nil {
+		return
+	}
+	released, err := ln.Dispatcher.Store.ReleaseOwnedLeases()
+	if err != nil {
+		fmt.Println("error releasing job leases", err)
+	}
+	fmt.Println("Number of jobs resumed: " + fmt.Sprint(released))
+	ln.Dispatcher.StartPoller(ln, time.Duration(ln.Config.Dispatcher.JobPollInterval)*time.Second)
+}
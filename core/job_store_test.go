@@ -0,0 +1,175 @@
+package core
+
+import (
+	"context"
+	c "delta/config"
+	model "delta/models"
+	"delta/utils"
+	"errors"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type TestPersistentProcessor struct {
+	ContentID int64
+}
+
+func (tp *TestPersistentProcessor) Run(ctx context.Context) error {
+	return nil
+}
+
+func (tp *TestPersistentProcessor) JobType() string {
+	return "test-job"
+}
+
+func (tp *TestPersistentProcessor) JobContent() int64 {
+	return tp.ContentID
+}
+
+func (tp *TestPersistentProcessor) JobPayload() (string, error) {
+	return `{"content_id": 1}`, nil
+}
+
+// newTestDB opens an in-memory database of its own for a test, with the migrated tables.
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := model.OpenDatabase("file:" + t.Name() + "?mode=memory&cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func newTestJobStore(t *testing.T) *JobStore {
+	db := newTestDB(t)
+	cfg := &c.DeltaConfig{}
+	cfg.Node.InstanceUuid = "test-instance"
+	cfg.Dispatcher.JobLeaseDuration = 60
+	return NewJobStore(db, cfg)
+}
+
+func TestJobStore_EnqueueAndFinish(t *testing.T) {
+	store := newTestJobStore(t)
+
+	job, err := store.Enqueue(&TestPersistentProcessor{ContentID: 1}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.LeaseOwner != "test-instance" {
+		t.Errorf("expected the job to be leased to the node, got %q", job.LeaseOwner)
+	}
+	if !store.HasPendingJobs(1) {
+		t.Errorf("expected content 1 to have a pending job")
+	}
+
+	store.MarkRunning(job.ID)
+	store.MarkFinished(job.ID, errors.New("boom"))
+
+	var record model.Job
+	store.DB.First(&record, job.ID)
+	if record.Status != utils.JOB_STATUS_FAILED || record.Attempts != 1 || record.LastMessage != "boom" {
+		t.Errorf("unexpected job record after failure: %+v", record)
+	}
+	if store.HasPendingJobs(1) {
+		t.Errorf("expected content 1 to have no pending job")
+	}
+}
+
+func TestJobStore_ResumeAfterRestart(t *testing.T) {
+	store := newTestJobStore(t)
+
+	job, err := store.Enqueue(&TestPersistentProcessor{ContentID: 2}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.MarkRunning(job.ID)
+
+	// nothing is due while this node holds the lease
+	leased, err := store.LeaseDueJobs(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leased) != 0 {
+		t.Fatalf("expected no leasable jobs, got %d", len(leased))
+	}
+
+	// a restart releases the lease and the job can be picked up again
+	released, err := store.ReleaseOwnedLeases()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if released != 1 {
+		t.Errorf("expected 1 released job, got %d", released)
+	}
+	leased, err = store.LeaseDueJobs(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leased) != 1 || leased[0].ID != job.ID {
+		t.Fatalf("expected job %d to be leased again, got %+v", job.ID, leased)
+	}
+}
+
+func TestJobStore_ScheduledJobIsNotDue(t *testing.T) {
+	store := newTestJobStore(t)
+
+	if _, err := store.Enqueue(&TestPersistentProcessor{ContentID: 3}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	leased, err := store.LeaseDueJobs(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leased) != 0 {
+		t.Errorf("expected the scheduled job not to be due, got %d", len(leased))
+	}
+}
+
+func TestJobStore_RunningJobIsNotLeasedAgain(t *testing.T) {
+	store := newTestJobStore(t)
+	other := NewJobStore(store.DB, &c.DeltaConfig{})
+	other.Config.Node.InstanceUuid = "other-instance"
+	other.Config.Dispatcher.JobLeaseDuration = 60
+
+	job, err := store.Enqueue(&TestPersistentProcessor{ContentID: 4}, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.MarkRunning(job.ID)
+	expireLease := func() {
+		store.DB.Model(&model.Job{}).Where("id = ?", job.ID).Update("lease_expires_at", time.Now().Add(-time.Minute))
+	}
+
+	// a job running longer than its lease stays with this node
+	expireLease()
+	leased, err := store.LeaseDueJobs(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leased) != 0 {
+		t.Fatalf("expected the running job not to be leased again, got %d", len(leased))
+	}
+
+	// a renewed lease keeps the other nodes away
+	if err := store.RenewLease(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	leased, err = other.LeaseDueJobs(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leased) != 0 {
+		t.Fatalf("expected the renewed job not to be leased by another node, got %d", len(leased))
+	}
+
+	// a lease that isn't renewed is taken by another node
+	expireLease()
+	leased, err = other.LeaseDueJobs(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(leased) != 1 || leased[0].ID != job.ID {
+		t.Fatalf("expected job %d to be leased by the other node, got %+v", job.ID, leased)
+	}
+}
@@ -1,8 +1,12 @@
 package core
 
 import (
+	"context"
+	c "delta/config"
+	"delta/utils"
 	"errors"
 	"testing"
+	"time"
 )
 
 type TestProcessor struct {
@@ -10,7 +14,7 @@ type TestProcessor struct {
 	Name string
 }
 
-func (tp *TestProcessor) Run() error {
+func (tp *TestProcessor) Run(ctx context.Context) error {
 	if tp.ID == 0 {
 		return errors.New("Invalid Test Processor ID")
 	}
@@ -25,8 +29,8 @@ func TestDispatcher(t *testing.T) {
 	testProcessor := &TestProcessor{ID: 1, Name: "Test Processor 1"}
 	dispatcher.AddJob(testProcessor)
 
-	// start the dispatcher with one worker
-	dispatcher.Start(1)
+	// start the worker pools of the dispatcher
+	dispatcher.Start()
 
 	// wait until the dispatcher has finished processing all jobs
 	for !dispatcher.Finished() {
@@ -37,3 +41,109 @@ func TestDispatcher(t *testing.T) {
 		t.Errorf("Test Processor did not run successfully. Expected ID: 1, Actual ID: %d", testProcessor.ID)
 	}
 }
+
+func TestDispatcher_QueueFull(t *testing.T) {
+	dispatcher := CreateNewDispatcher()
+	dispatcher.SetQueueLimit(utils.JOB_QUEUE_CLEANUP, 1, 1)
+
+	// the queue only holds one job until the workers are started
+	if err := dispatcher.AddJob(&TestProcessor{ID: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if !dispatcher.IsFull(utils.JOB_QUEUE_CLEANUP) {
+		t.Errorf("expected the cleanup queue to be full")
+	}
+	if err := dispatcher.AddJob(&TestProcessor{ID: 2}); err != ErrQueueFull {
+		t.Errorf("expected ErrQueueFull, got %v", err)
+	}
+
+	dispatcher.Start()
+	for !dispatcher.Finished() {
+	}
+	if dispatcher.IsFull(utils.JOB_QUEUE_CLEANUP) {
+		t.Errorf("expected the cleanup queue to be drained")
+	}
+}
+
+type TestBlockingProcessor struct {
+	ContentID int64
+	started   chan struct{}
+	err       error
+}
+
+func (tp *TestBlockingProcessor) Run(ctx context.Context) error {
+	close(tp.started)
+	<-ctx.Done()
+	tp.err = ctx.Err()
+	return tp.err
+}
+
+func (tp *TestBlockingProcessor) JobContent() int64 {
+	return tp.ContentID
+}
+
+func TestDispatcher_JobTimeout(t *testing.T) {
+	dispatcher := CreateNewDispatcher()
+	dispatcher.SetQueueTimeout(utils.JOB_QUEUE_CLEANUP, 50*time.Millisecond)
+
+	testProcessor := &TestBlockingProcessor{started: make(chan struct{})}
+	dispatcher.AddJob(testProcessor)
+	dispatcher.Start()
+	for !dispatcher.Finished() {
+	}
+
+	if testProcessor.err != context.DeadlineExceeded {
+		t.Errorf("expected the job to time out, got %v", testProcessor.err)
+	}
+}
+
+func TestDispatcher_CancelContentJobs(t *testing.T) {
+	dispatcher := CreateNewDispatcher()
+
+	running := &TestBlockingProcessor{ContentID: 7, started: make(chan struct{})}
+	queued := &TestBlockingProcessor{ContentID: 7, started: make(chan struct{})}
+	other := &TestProcessor{ID: 1}
+	dispatcher.AddJob(running)
+	dispatcher.AddJob(queued)
+	dispatcher.AddJob(other)
+	dispatcher.Start()
+	<-running.started
+
+	if cancelled := dispatcher.CancelContentJobs(7); cancelled != 2 {
+		t.Errorf("expected 2 cancelled jobs, got %d", cancelled)
+	}
+	for !dispatcher.Finished() {
+	}
+
+	if running.err != context.Canceled {
+		t.Errorf("expected the running job to be cancelled, got %v", running.err)
+	}
+	select {
+	case <-queued.started:
+		t.Errorf("expected the queued job to be skipped")
+	default:
+	}
+}
+
+func TestValidateJobTimeouts(t *testing.T) {
+	tests := []struct {
+		lease   int
+		commp   int
+		cleanup int
+		wantErr bool
+	}{
+		{60, 30, 30, false},
+		{60, 0, 30, false}, // no deadline, the lease is renewed
+		{60, 60, 30, true},
+		{20, 10, 30, true},
+	}
+	for i, tt := range tests {
+		var config c.DeltaConfig
+		config.Dispatcher.JobLeaseDuration = tt.lease
+		config.Dispatcher.CommpJobTimeout = tt.commp
+		config.Dispatcher.CleanupJobTimeout = tt.cleanup
+		if err := ValidateJobTimeouts(&config); (err != nil) != tt.wantErr {
+			t.Errorf("config %d: expected error %v, got %v", i, tt.wantErr, err)
+		}
+	}
+}
@@ -45,7 +45,7 @@ func SetLibp2pManagerSubscribe(i *DeltaNode) {
 			contentDeal.TransferStarted = time.Now()
 			contentDeal.UpdatedAt = time.Now()
 			contentDeal.LastMessage = utils.DEAL_STATUS_TRANSFER_STARTED
-			i.DB.Save(&contentDeal)
+			SaveContentDeal(i.DB, &contentDeal, utils.STATUS_SOURCE_DATA_TRANSFER)
 
 		case datatransfer.TransferFinished, datatransfer.Completed:
 			fmt.Println("Transfer status: ", fst.Status, " for transfer id: ", fst.TransferID, " for db id: ", dbid)
@@ -58,19 +58,16 @@ func SetLibp2pManagerSubscribe(i *DeltaNode) {
 			var contentDeal model.ContentDeal
 			i.DB.Model(&model.ContentDeal{}).Where("id = ?", dbid).Find(&contentDeal)
 			contentDeal.TransferFinished = time.Now()
-			contentDeal.SealedAt = time.Now()
 			contentDeal.UpdatedAt = time.Now()
-			contentDeal.OnChainAt = time.Now()
 			contentDeal.LastMessage = utils.DEAL_STATUS_TRANSFER_FINISHED
-			i.DB.Save(&contentDeal)
+			SaveContentDeal(i.DB, &contentDeal, utils.STATUS_SOURCE_DATA_TRANSFER)
 
 			// save the content status
 			var content model.Content
 			i.DB.Model(&model.Content{}).Where("id in (select cd.content from content_deals cd where cd.id = ?)", dbid).Find(&content)
-			content.Status = utils.DEAL_STATUS_TRANSFER_FINISHED
-			content.LastMessage = utils.DEAL_STATUS_TRANSFER_FINISHED
-			content.UpdatedAt = time.Now()
-			i.DB.Save(&content)
+			if err := TransitionContentStatus(i.DB, &content, utils.DEAL_STATUS_TRANSFER_FINISHED, utils.DEAL_STATUS_TRANSFER_FINISHED, utils.STATUS_SOURCE_DATA_TRANSFER); err != nil {
+				fmt.Println(err)
+			}
 
 			// remove from the blockstore
 			cidToDelete, err := cid.Decode(content.Cid)
@@ -89,14 +86,13 @@ func SetLibp2pManagerSubscribe(i *DeltaNode) {
 			contentDeal.LastMessage = fst.Message
 			contentDeal.UpdatedAt = time.Now()
 			contentDeal.FailedAt = time.Now()
-			i.DB.Save(&contentDeal)
+			SaveContentDeal(i.DB, &contentDeal, utils.STATUS_SOURCE_DATA_TRANSFER)
 
 			var content model.Content
 			i.DB.Model(&model.Content{}).Where("id in (select cd.content from content_deals cd where cd.id = ?)", dbid).Find(&content)
-			content.Status = utils.DEAL_STATUS_TRANSFER_FAILED
-			content.LastMessage = fst.Message
-			content.UpdatedAt = time.Now()
-			i.DB.Save(&content)
+			if err := TransitionContentStatus(i.DB, &content, utils.DEAL_STATUS_TRANSFER_FAILED, fst.Message, utils.STATUS_SOURCE_DATA_TRANSFER); err != nil {
+				fmt.Println(err)
+			}
 
 			// remove from the blockstore
 			cidToDelete, err := cid.Decode(content.Cid)
@@ -1,9 +1,12 @@
 package core
 
 import (
-	"encoding/json"
+	model "delta/models"
+	"delta/utils"
 	"fmt"
-	"net/http"
+	"github.com/multiformats/go-multiaddr"
+	"net"
+	"strconv"
 	"time"
 )
 
@@ -58,44 +61,166 @@ func NewMinerAssignmentService(node DeltaNode) *MinerAssignmentService {
 }
 
 // A function that takes in a parameter, byteSize, and returns a Provider and an error.
+// The provider is picked by the default selection strategy of the node.
 func (m MinerAssignmentService) GetSPWithGivenBytes(byteSize int64) (Provider, error) {
-	bytSizeStr := fmt.Sprintf("%d", byteSize)
-	fmt.Println("Getting SP with given bytes: ", m.DeltaNode.Config.ExternalApis.SpSelectionApi+"?size_bytes="+bytSizeStr)
-	resp, err := http.Get(m.DeltaNode.Config.ExternalApis.SpSelectionApi + "?size_bytes=" + bytSizeStr)
+	return m.GetSPWithStrategy("", MinerSelectionParam{Size: byteSize})
+}
+
+// A function that takes in two parameters, byteSize and sourceIp, and returns a Provider and an error.
+func (m MinerAssignmentService) GetSPWithGivenBytesAndIp(byteSize string, sourceIp string) (Provider, error) {
+	size, err := strconv.ParseInt(byteSize, 10, 64)
 	if err != nil {
-		// handle error
-		fmt.Println("Error making HTTP request:", err)
 		return Provider{}, err
 	}
-	defer resp.Body.Close()
-	var provider Provider
-	err = json.NewDecoder(resp.Body).Decode(&provider)
+	return m.GetSPWithStrategy("", MinerSelectionParam{Size: size, SourceIp: sourceIp})
+}
+
+// maxMinerSelectionAttempts is how many providers a strategy can offer before the selection gives up
+const maxMinerSelectionAttempts = 5
+
+// GetSPWithStrategy picks a provider with the named selection strategy, or with the default strategy of the node when
+// the name is empty. Providers that can't be used are excluded and the strategy is asked again.
+func (m MinerAssignmentService) GetSPWithStrategy(strategyName string, param MinerSelectionParam) (Provider, error) {
+	strategy, err := m.GetStrategy(strategyName)
 	if err != nil {
-		// handle error
-		fmt.Println("Error decoding JSON:", err)
 		return Provider{}, err
 	}
+	fmt.Println("Getting SP with strategy", strategy.Name(), "for size", param.Size)
+	for attempt := 0; attempt < maxMinerSelectionAttempts; attempt++ {
+		provider, err := strategy.SelectMiner(param)
+		if err != nil {
+			return Provider{}, err
+		}
+		reason := m.RejectMiner(provider.Address)
+		if param.IsExcluded(provider.Address) {
+			reason = "already excluded"
+		}
+		if reason == "" {
+			return provider, nil
+		}
+		fmt.Println("Skipping SP", provider.Address+":", reason)
+		param.ExcludedMiners = append(param.ExcludedMiners, provider.Address)
+	}
+	return Provider{}, ErrNoMinerAvailable
+}
 
-	return provider, nil
+// SwitchContentMiner assigns a content to another provider of its selection strategy after a failed deal. The failed
+// provider and the ones the content was already assigned to or made deals with are excluded.
+func (m MinerAssignmentService) SwitchContentMiner(content model.Content, failedMiner string) (Provider, error) {
+	db := m.DeltaNode.DB
+	var dealProposal model.ContentDealProposalParameters
+	db.Model(&model.ContentDealProposalParameters{}).Where("content = ?", content.ID).Find(&dealProposal)
 
-}
+	var assignedMiners, dealMiners []string
+	db.Model(&model.ContentMiner{}).Where("content = ?", content.ID).Pluck("miner", &assignedMiners)
+	db.Model(&model.ContentDeal{}).Where("content = ? and miner <> ''", content.ID).Pluck("miner", &dealMiners)
+	param := MinerSelectionParam{Size: content.Size}
+	for _, miner := range append(append([]string{failedMiner}, assignedMiners...), dealMiners...) {
+		if miner != "" && !param.IsExcluded(miner) {
+			param.ExcludedMiners = append(param.ExcludedMiners, miner)
+		}
+	}
 
-// A function that takes in two parameters, byteSize and sourceIp, and returns a Provider and an error.
-func (m MinerAssignmentService) GetSPWithGivenBytesAndIp(byteSize string, sourceIp string) (Provider, error) {
-	resp, err := http.Get(m.DeltaNode.Config.ExternalApis.SpSelectionApi + "?size_bytes=" + byteSize + "&source_ip=" + sourceIp)
+	provider, err := m.GetSPWithStrategy(dealProposal.MinerSelectionStrategy, param)
 	if err != nil {
-		// handle error
-		fmt.Println("Error making HTTP request:", err)
 		return Provider{}, err
 	}
-	defer resp.Body.Close()
-	var provider Provider
-	err = json.NewDecoder(resp.Body).Decode(&provider)
-	if err != nil {
-		// handle error
-		fmt.Println("Error decoding JSON:", err)
-		return Provider{}, err
+	contentMiner := &model.ContentMiner{
+		Content:   content.ID,
+		Miner:     provider.Address,
+		UpdatedAt: time.Now(),
+		CreatedAt: time.Now(),
+	}
+	if err := db.Create(contentMiner).Error; err != nil {
+		return Provider{}, fmt.Errorf("failed to create database entry for content miner: %w", err)
 	}
-
 	return provider, nil
 }
+
+// SelectDistinctProviders picks count providers that differ from each other and from the excluded providers of the
+// param. With a diversity, the providers must also have different owner addresses or be in different ip networks.
+func (m MinerAssignmentService) SelectDistinctProviders(strategyName string, param MinerSelectionParam, count int, diversity string) ([]Provider, error) {
+	var providers []Provider
+	usedKeys := map[string]bool{}
+	for attempt := 0; len(providers) < count && attempt < count*maxMinerSelectionAttempts; attempt++ {
+		provider, err := m.GetSPWithStrategy(strategyName, param)
+		if err != nil {
+			return nil, fmt.Errorf("not enough distinct storage providers for %d copies, found %d: %w", count, len(providers), err)
+		}
+		param.ExcludedMiners = append(param.ExcludedMiners, provider.Address)
+
+		if diversity != "" {
+			key := ProviderDiversityKey(provider, diversity)
+			if key == "" || usedKeys[key] {
+				fmt.Println("Skipping SP", provider.Address+": no", diversity, "diversity")
+				continue
+			}
+			usedKeys[key] = true
+		}
+		providers = append(providers, provider)
+	}
+	if len(providers) < count {
+		return nil, fmt.Errorf("not enough distinct storage providers for %d copies, found %d", count, len(providers))
+	}
+	return providers, nil
+}
+
+// ProviderDiversityKey returns what must differ between the providers of the copies of a content, or an empty string
+// if the provider doesn't tell. The network of a provider is the /16 (ip4) or /32 (ip6) of its first address.
+func ProviderDiversityKey(provider Provider, diversity string) string {
+	switch diversity {
+	case utils.REPLICATION_DIVERSITY_OWNER:
+		return provider.AddressOfOwner
+	case utils.REPLICATION_DIVERSITY_NETWORK:
+		for _, addr := range provider.Multiaddrs.Addresses {
+			ma, err := multiaddr.NewMultiaddr(addr)
+			if err != nil {
+				continue
+			}
+			if ip4, err := ma.ValueForProtocol(multiaddr.P_IP4); err == nil {
+				return net.ParseIP(ip4).Mask(net.CIDRMask(16, 32)).String()
+			}
+			if ip6, err := ma.ValueForProtocol(multiaddr.P_IP6); err == nil {
+				return net.ParseIP(ip6).Mask(net.CIDRMask(32, 128)).String()
+			}
+		}
+	}
+	return ""
+}
+
+// RejectMiner returns why the provider can't be used, or an empty string if it can.
+func (m MinerAssignmentService) RejectMiner(miner string) string {
+	if m.DeltaNode.DB != nil {
+		if err := model.CheckMinerAccess(m.DeltaNode.DB, miner); err != nil {
+			return err.Error()
+		}
+	}
+	selection := m.DeltaNode.Config.MinerSelection
+	if selection.MinReputationScore > 0 && m.DeltaNode.DB != nil {
+		reputation := GetMinerReputation(m.DeltaNode.DB, miner)
+		// a provider without enough deals has no reputation yet
+		if reputation.ID != 0 && reputation.SucceededDeals+reputation.FailedDeals >= selection.MinReputationDeals && reputation.Score < selection.MinReputationScore {
+			return fmt.Sprintf("reputation score %.2f is below %.2f", reputation.Score, selection.MinReputationScore)
+		}
+	}
+	return ""
+}
+
+// GetStrategy returns the named selection strategy, or the default strategy of the node when the name is empty.
+func (m MinerAssignmentService) GetStrategy(strategyName string) (IMinerSelectionStrategy, error) {
+	if strategyName == "" {
+		strategyName = m.DeltaNode.Config.MinerSelection.DefaultStrategy
+	}
+	if strategyName == "" {
+		strategyName = utils.MINER_SELECTION_REMOTE_API
+	}
+	strategies := m.DeltaNode.MinerSelectionStrategies
+	if strategies == nil {
+		strategies = NewMinerSelectionStrategies(m.DeltaNode.DB, m.DeltaNode.Config)
+	}
+	strategy, ok := strategies[strategyName]
+	if !ok {
+		return nil, fmt.Errorf("unknown miner selection strategy %s", strategyName)
+	}
+	return strategy, nil
+}
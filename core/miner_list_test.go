@@ -0,0 +1,60 @@
+package core
+
+import (
+	c "delta/config"
+	model "delta/models"
+	"delta/utils"
+	"testing"
+)
+
+func TestMinerAssignmentService_SkipsBlockedMiners(t *testing.T) {
+	db := newTestDB(t)
+	db.Create(&model.MinerListEntry{Miner: "f01", ListType: model.MINER_LIST_BLOCKED, Reason: "faulty sectors"})
+
+	cfg := &c.DeltaConfig{}
+	cfg.MinerSelection.StaticMiners = []string{"f01", "f02", "f03"}
+	service := NewMinerAssignmentService(DeltaNode{DB: db, Config: cfg, MinerSelectionStrategies: NewMinerSelectionStrategies(db, cfg)})
+
+	for i := 0; i < 4; i++ {
+		provider, err := service.GetSPWithStrategy(utils.MINER_SELECTION_STATIC_ALLOWLIST, MinerSelectionParam{Size: 1024})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if provider.Address == "f01" {
+			t.Errorf("expected the blocked miner to be skipped")
+		}
+	}
+
+	// only the allowed miners can be assigned once the allowlist has entries
+	db.Create(&model.MinerListEntry{Miner: "f03", ListType: model.MINER_LIST_ALLOWED})
+	for i := 0; i < 4; i++ {
+		provider, err := service.GetSPWithStrategy(utils.MINER_SELECTION_STATIC_ALLOWLIST, MinerSelectionParam{Size: 1024})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if provider.Address != "f03" {
+			t.Errorf("expected the allowed miner, got %s", provider.Address)
+		}
+	}
+}
+
+func TestContentMiner_RejectsBlockedMiner(t *testing.T) {
+	db := newTestDB(t)
+	db.Create(&model.MinerListEntry{Miner: "f01", ListType: model.MINER_LIST_BLOCKED})
+	db.Create(&model.MinerInfo{Addr: "f02", Suspended: true, SuspendedReason: "slashed"})
+
+	tests := []struct {
+		miner   string
+		wantErr bool
+	}{
+		{"f01", true},
+		{"f02", true},
+		{"f03", false},
+	}
+	for _, tt := range tests {
+		err := db.Create(&model.ContentMiner{Content: 1, Miner: tt.miner}).Error
+		if (err != nil) != tt.wantErr {
+			t.Errorf("miner %s: expected error %v, got %v", tt.miner, tt.wantErr, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package core
+
+import (
+	model "delta/models"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// minerDealOutcome is the part of a content deal that tells how it went.
+type minerDealOutcome struct {
+	Miner            string
+	Failed           bool
+	DealID           int64
+	CreatedAt        time.Time
+	TransferStarted  time.Time
+	TransferFinished time.Time
+	OnChainAt        time.Time
+}
+
+// ComputeMinerReputations computes the reputation of every storage provider that has content deals. A deal counts as
+// succeeded once its transfer finished or it is on chain, deals that are still in flight are left out of the success
+// rate. The score is the success rate.
+func ComputeMinerReputations(db *gorm.DB) ([]model.MinerReputation, error) {
+	var outcomes []minerDealOutcome
+	err := db.Model(&model.ContentDeal{}).
+		Select("miner, failed, deal_id, created_at, transfer_started, transfer_finished, on_chain_at").
+		Where("miner <> ''").
+		Scan(&outcomes).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byMiner := map[string][]minerDealOutcome{}
+	for _, outcome := range outcomes {
+		byMiner[outcome.Miner] = append(byMiner[outcome.Miner], outcome)
+	}
+
+	var reputations []model.MinerReputation
+	for miner, deals := range byMiner {
+		reputation := model.MinerReputation{
+			Miner:      miner,
+			TotalDeals: int64(len(deals)),
+		}
+		var timesToTransfer, timesToChain []int64
+		for _, deal := range deals {
+			switch {
+			case deal.Failed:
+				reputation.FailedDeals++
+			case !deal.TransferFinished.IsZero() || !deal.OnChainAt.IsZero() || deal.DealID != 0:
+				reputation.SucceededDeals++
+			}
+			if !deal.Failed && !deal.TransferFinished.IsZero() {
+				started := deal.TransferStarted
+				if started.IsZero() {
+					started = deal.CreatedAt
+				}
+				timesToTransfer = append(timesToTransfer, int64(deal.TransferFinished.Sub(started).Seconds()))
+			}
+			if !deal.Failed && !deal.OnChainAt.IsZero() {
+				timesToChain = append(timesToChain, int64(deal.OnChainAt.Sub(deal.CreatedAt).Seconds()))
+			}
+		}
+		if finished := reputation.SucceededDeals + reputation.FailedDeals; finished > 0 {
+			reputation.SuccessRate = float64(reputation.SucceededDeals) / float64(finished)
+		}
+		reputation.MedianTimeToTransfer = median(timesToTransfer)
+		reputation.MedianTimeToChain = median(timesToChain)
+		reputation.Score = reputation.SuccessRate
+		reputations = append(reputations, reputation)
+	}
+	sort.Slice(reputations, func(i, j int) bool {
+		return reputations[i].Miner < reputations[j].Miner
+	})
+	return reputations, nil
+}
+
+// UpdateMinerReputations computes the reputation of every storage provider and stores it in the miner reputation
+// table.
+func UpdateMinerReputations(db *gorm.DB) error {
+	reputations, err := ComputeMinerReputations(db)
+	if err != nil {
+		return err
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, reputation := range reputations {
+			var existing model.MinerReputation
+			tx.Model(&model.MinerReputation{}).Where("miner = ?", reputation.Miner).Find(&existing)
+			reputation.ID = existing.ID
+			reputation.CreatedAt = existing.CreatedAt
+			if reputation.ID == 0 {
+				reputation.CreatedAt = time.Now()
+			}
+			reputation.UpdatedAt = time.Now()
+			if err := tx.Save(&reputation).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// GetMinerReputation returns the stored reputation of a storage provider, the ID is 0 if it has none yet.
+func GetMinerReputation(db *gorm.DB, miner string) model.MinerReputation {
+	var reputation model.MinerReputation
+	db.Model(&model.MinerReputation{}).Where("miner = ?", miner).Find(&reputation)
+	return reputation
+}
+
+func median(values []int64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	middle := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[middle-1] + sorted[middle]) / 2
+	}
+	return sorted[middle]
+}
@@ -0,0 +1,75 @@
+package core
+
+import (
+	c "delta/config"
+	model "delta/models"
+	"delta/utils"
+	"testing"
+	"time"
+)
+
+func TestComputeMinerReputations(t *testing.T) {
+	db := newTestDB(t)
+	created := time.Now().Add(-10 * time.Hour)
+	for _, deal := range []model.ContentDeal{
+		{Miner: "f01", CreatedAt: created, TransferStarted: created, TransferFinished: created.Add(time.Hour), OnChainAt: created.Add(2 * time.Hour)},
+		{Miner: "f01", CreatedAt: created, TransferStarted: created, TransferFinished: created.Add(3 * time.Hour), OnChainAt: created.Add(4 * time.Hour)},
+		{Miner: "f01", CreatedAt: created, Failed: true},
+		{Miner: "f01", CreatedAt: created}, // still in flight
+		{Miner: "f02", CreatedAt: created, Failed: true},
+	} {
+		db.Create(&deal)
+	}
+
+	if err := UpdateMinerReputations(db); err != nil {
+		t.Fatal(err)
+	}
+	// updating again must not duplicate the rows
+	if err := UpdateMinerReputations(db); err != nil {
+		t.Fatal(err)
+	}
+	var count int64
+	db.Model(&model.MinerReputation{}).Count(&count)
+	if count != 2 {
+		t.Fatalf("expected 2 reputations, got %d", count)
+	}
+
+	f01 := GetMinerReputation(db, "f01")
+	if f01.TotalDeals != 4 || f01.SucceededDeals != 2 || f01.FailedDeals != 1 {
+		t.Errorf("unexpected deal counts: %+v", f01)
+	}
+	if f01.SuccessRate < 0.66 || f01.SuccessRate > 0.67 {
+		t.Errorf("expected a success rate of 2/3, got %f", f01.SuccessRate)
+	}
+	if f01.MedianTimeToTransfer != int64((2 * time.Hour).Seconds()) {
+		t.Errorf("expected a median time to transfer of 2h, got %ds", f01.MedianTimeToTransfer)
+	}
+	if f01.MedianTimeToChain != int64((3 * time.Hour).Seconds()) {
+		t.Errorf("expected a median time to chain of 3h, got %ds", f01.MedianTimeToChain)
+	}
+	if f02 := GetMinerReputation(db, "f02"); f02.Score != 0 {
+		t.Errorf("expected f02 to have a score of 0, got %f", f02.Score)
+	}
+}
+
+func TestMinerAssignmentService_SkipsLowReputation(t *testing.T) {
+	db := newTestDB(t)
+	db.Create(&model.MinerReputation{Miner: "f01", SucceededDeals: 1, FailedDeals: 19, Score: 0.05})
+	db.Create(&model.MinerReputation{Miner: "f02", SucceededDeals: 0, FailedDeals: 2, Score: 0}) // not enough deals to judge
+
+	cfg := &c.DeltaConfig{}
+	cfg.MinerSelection.StaticMiners = []string{"f01", "f02"}
+	cfg.MinerSelection.MinReputationScore = 0.5
+	cfg.MinerSelection.MinReputationDeals = 10
+	service := NewMinerAssignmentService(DeltaNode{DB: db, Config: cfg, MinerSelectionStrategies: NewMinerSelectionStrategies(db, cfg)})
+
+	for i := 0; i < 3; i++ {
+		provider, err := service.GetSPWithStrategy(utils.MINER_SELECTION_STATIC_ALLOWLIST, MinerSelectionParam{Size: 1024})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if provider.Address != "f02" {
+			t.Errorf("expected f01 to be skipped, got %s", provider.Address)
+		}
+	}
+}
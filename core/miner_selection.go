@@ -0,0 +1,254 @@
+package core
+
+import (
+	c "delta/config"
+	model "delta/models"
+	"delta/utils"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MinerSelectionParam is what a strategy knows about the deal it picks a storage provider for.
+// @property Size - The size of the content in bytes.
+// @property SourceIp - The ip of the requester, used by the remote api to pick a nearby provider.
+// @property ExcludedMiners - The providers that must not be picked.
+type MinerSelectionParam struct {
+	Size           int64
+	SourceIp       string
+	ExcludedMiners []string
+}
+
+// IsExcluded returns true if the provider must not be picked.
+func (p MinerSelectionParam) IsExcluded(miner string) bool {
+	for _, excluded := range p.ExcludedMiners {
+		if excluded == miner {
+			return true
+		}
+	}
+	return false
+}
+
+// IMinerSelectionStrategy picks the storage provider of a deal.
+type IMinerSelectionStrategy interface {
+	Name() string
+	SelectMiner(param MinerSelectionParam) (Provider, error)
+}
+
+// ErrNoMinerAvailable is returned when a strategy has no storage provider to offer.
+var ErrNoMinerAvailable = errors.New("no storage provider available")
+
+// NewMinerSelectionStrategies creates the built-in strategies from the node configuration, by name.
+func NewMinerSelectionStrategies(db *gorm.DB, config *c.DeltaConfig) map[string]IMinerSelectionStrategy {
+	strategies := []IMinerSelectionStrategy{
+		NewRemoteApiStrategy(config.ExternalApis.SpSelectionApi, time.Duration(config.MinerSelection.RemoteApiTimeout)*time.Second),
+		NewStaticAllowlistStrategy(config.MinerSelection.StaticMiners),
+		NewWeightedRoundRobinStrategy(ParseMinerWeights(config.MinerSelection.WeightedMiners)),
+		NewLowestPriceStrategy(db),
+	}
+	byName := map[string]IMinerSelectionStrategy{}
+	for _, strategy := range strategies {
+		byName[strategy.Name()] = strategy
+	}
+	return byName
+}
+
+// RemoteApiStrategy asks the sp selection service for a provider.
+type RemoteApiStrategy struct {
+	ApiUrl string
+	Client *http.Client
+}
+
+// NewRemoteApiStrategy creates a strategy that calls the given sp selection api, a call that takes longer than the
+// timeout fails
+func NewRemoteApiStrategy(apiUrl string, timeout time.Duration) *RemoteApiStrategy {
+	return &RemoteApiStrategy{
+		ApiUrl: apiUrl,
+		Client: &http.Client{Timeout: timeout},
+	}
+}
+
+func (s *RemoteApiStrategy) Name() string {
+	return utils.MINER_SELECTION_REMOTE_API
+}
+
+func (s *RemoteApiStrategy) SelectMiner(param MinerSelectionParam) (Provider, error) {
+	query := url.Values{}
+	query.Set("size_bytes", strconv.FormatInt(param.Size, 10))
+	if param.SourceIp != "" {
+		query.Set("source_ip", param.SourceIp)
+	}
+	resp, err := s.Client.Get(s.ApiUrl + "?" + query.Encode())
+	if err != nil {
+		fmt.Println("Error making HTTP request:", err)
+		return Provider{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Provider{}, fmt.Errorf("sp selection api returned %s", resp.Status)
+	}
+	var provider Provider
+	if err := json.NewDecoder(resp.Body).Decode(&provider); err != nil {
+		fmt.Println("Error decoding JSON:", err)
+		return Provider{}, err
+	}
+	if provider.Address == "" {
+		return Provider{}, ErrNoMinerAvailable
+	}
+	return provider, nil
+}
+
+// StaticAllowlistStrategy hands out the providers of a fixed list in turn.
+type StaticAllowlistStrategy struct {
+	Miners []string
+	next   int
+	lk     sync.Mutex
+}
+
+// NewStaticAllowlistStrategy creates a strategy that only uses the given providers
+func NewStaticAllowlistStrategy(miners []string) *StaticAllowlistStrategy {
+	return &StaticAllowlistStrategy{
+		Miners: miners,
+	}
+}
+
+func (s *StaticAllowlistStrategy) Name() string {
+	return utils.MINER_SELECTION_STATIC_ALLOWLIST
+}
+
+func (s *StaticAllowlistStrategy) SelectMiner(param MinerSelectionParam) (Provider, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	for range s.Miners {
+		miner := s.Miners[s.next%len(s.Miners)]
+		s.next++
+		if !param.IsExcluded(miner) {
+			return Provider{Address: miner}, nil
+		}
+	}
+	return Provider{}, ErrNoMinerAvailable
+}
+
+// MinerWeight is a provider of the weighted round-robin strategy with its share of the deals.
+type MinerWeight struct {
+	Miner  string
+	Weight int
+}
+
+// ParseMinerWeights parses a list of weighted providers in the form "f01234:3,f05678:1". A provider without a weight
+// gets a weight of 1.
+func ParseMinerWeights(value string) []MinerWeight {
+	var weights []MinerWeight
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.SplitN(entry, ":", 2)
+		weight := 1
+		if len(parts) == 2 {
+			if w, err := strconv.Atoi(strings.TrimSpace(parts[1])); err == nil && w > 0 {
+				weight = w
+			}
+		}
+		weights = append(weights, MinerWeight{Miner: strings.TrimSpace(parts[0]), Weight: weight})
+	}
+	return weights
+}
+
+// WeightedRoundRobinStrategy spreads the deals over the providers in proportion to their weight, using the smooth
+// weighted round-robin so that a heavy provider doesn't get all its deals in a row.
+type WeightedRoundRobinStrategy struct {
+	Miners  []MinerWeight
+	current []int
+	lk      sync.Mutex
+}
+
+// NewWeightedRoundRobinStrategy creates a strategy over the given weighted providers
+func NewWeightedRoundRobinStrategy(miners []MinerWeight) *WeightedRoundRobinStrategy {
+	return &WeightedRoundRobinStrategy{
+		Miners:  miners,
+		current: make([]int, len(miners)),
+	}
+}
+
+func (s *WeightedRoundRobinStrategy) Name() string {
+	return utils.MINER_SELECTION_WEIGHTED_ROUND_ROBIN
+}
+
+func (s *WeightedRoundRobinStrategy) SelectMiner(param MinerSelectionParam) (Provider, error) {
+	s.lk.Lock()
+	defer s.lk.Unlock()
+	total, best := 0, -1
+	for i, miner := range s.Miners {
+		if param.IsExcluded(miner.Miner) {
+			continue
+		}
+		s.current[i] += miner.Weight
+		total += miner.Weight
+		if best == -1 || s.current[i] > s.current[best] {
+			best = i
+		}
+	}
+	if best == -1 {
+		return Provider{}, ErrNoMinerAvailable
+	}
+	s.current[best] -= total
+	return Provider{Address: s.Miners[best].Miner}, nil
+}
+
+// LowestPriceStrategy picks the cheapest provider of the miner price table that accepts the size of the content.
+type LowestPriceStrategy struct {
+	DB *gorm.DB
+}
+
+// NewLowestPriceStrategy creates a strategy that reads the miner price table
+func NewLowestPriceStrategy(db *gorm.DB) *LowestPriceStrategy {
+	return &LowestPriceStrategy{
+		DB: db,
+	}
+}
+
+func (s *LowestPriceStrategy) Name() string {
+	return utils.MINER_SELECTION_LOWEST_PRICE
+}
+
+func (s *LowestPriceStrategy) SelectMiner(param MinerSelectionParam) (Provider, error) {
+	var minerPrices []model.MinerPrice
+	err := s.DB.Model(&model.MinerPrice{}).
+		Where("min_piece_size <= ? and (max_piece_size = 0 or max_piece_size >= ?)", param.Size, param.Size).
+		Find(&minerPrices).Error
+	if err != nil {
+		return Provider{}, err
+	}
+
+	// prices are attoFIL strings, compare them as numbers
+	var cheapest *model.MinerPrice
+	var cheapestPrice *big.Int
+	for i := range minerPrices {
+		if param.IsExcluded(minerPrices[i].Miner) {
+			continue
+		}
+		price, ok := new(big.Int).SetString(minerPrices[i].Price, 10)
+		if !ok {
+			continue
+		}
+		if cheapest == nil || price.Cmp(cheapestPrice) < 0 {
+			cheapest = &minerPrices[i]
+			cheapestPrice = price
+		}
+	}
+	if cheapest == nil {
+		return Provider{}, ErrNoMinerAvailable
+	}
+	return Provider{Address: cheapest.Miner, PriceAttofil: cheapest.Price}, nil
+}
@@ -0,0 +1,250 @@
+package core
+
+import (
+	c "delta/config"
+	model "delta/models"
+	"delta/utils"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRemoteApiStrategy_SelectMiner(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("size_bytes") != "1024" {
+			http.Error(w, "bad size", http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(Provider{Address: "f01234"})
+	}))
+	defer server.Close()
+
+	strategy := NewRemoteApiStrategy(server.URL, time.Second)
+	provider, err := strategy.SelectMiner(MinerSelectionParam{Size: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.Address != "f01234" {
+		t.Errorf("expected f01234, got %s", provider.Address)
+	}
+
+	if _, err := strategy.SelectMiner(MinerSelectionParam{Size: 1}); err == nil {
+		t.Errorf("expected an error when the api fails")
+	}
+}
+
+func TestRemoteApiStrategy_NoProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	if _, err := NewRemoteApiStrategy(server.URL, time.Second).SelectMiner(MinerSelectionParam{Size: 1024}); err != ErrNoMinerAvailable {
+		t.Errorf("expected ErrNoMinerAvailable, got %v", err)
+	}
+}
+
+func TestRemoteApiStrategy_Timeout(t *testing.T) {
+	done := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer server.Close()
+	defer close(done)
+
+	if _, err := NewRemoteApiStrategy(server.URL, 50*time.Millisecond).SelectMiner(MinerSelectionParam{Size: 1024}); err == nil {
+		t.Errorf("expected an error when the api hangs")
+	}
+}
+
+func TestStaticAllowlistStrategy_SelectMiner(t *testing.T) {
+	strategy := NewStaticAllowlistStrategy([]string{"f01", "f02"})
+	var got []string
+	for i := 0; i < 3; i++ {
+		provider, err := strategy.SelectMiner(MinerSelectionParam{Size: 1024})
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, provider.Address)
+	}
+	if got[0] != "f01" || got[1] != "f02" || got[2] != "f01" {
+		t.Errorf("unexpected selection order %v", got)
+	}
+
+	if _, err := NewStaticAllowlistStrategy(nil).SelectMiner(MinerSelectionParam{}); err != ErrNoMinerAvailable {
+		t.Errorf("expected ErrNoMinerAvailable, got %v", err)
+	}
+}
+
+func TestWeightedRoundRobinStrategy_SelectMiner(t *testing.T) {
+	strategy := NewWeightedRoundRobinStrategy(ParseMinerWeights("f01:3, f02:1,f03"))
+	counts := map[string]int{}
+	for i := 0; i < 50; i++ {
+		provider, err := strategy.SelectMiner(MinerSelectionParam{Size: 1024})
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[provider.Address]++
+	}
+	if counts["f01"] != 30 || counts["f02"] != 10 || counts["f03"] != 10 {
+		t.Errorf("unexpected distribution %v", counts)
+	}
+}
+
+func TestLowestPriceStrategy_SelectMiner(t *testing.T) {
+	db := newTestDB(t)
+	for _, price := range []model.MinerPrice{
+		{Miner: "f01", Price: "500000000", MinPieceSize: 0, MaxPieceSize: 1 << 30},
+		{Miner: "f02", Price: "20000000", MinPieceSize: 1 << 20, MaxPieceSize: 1 << 30},
+		{Miner: "f03", Price: "100000000", MinPieceSize: 0, MaxPieceSize: 1 << 30},
+	} {
+		price.CreatedAt = time.Now()
+		price.UpdatedAt = time.Now()
+		db.Create(&price)
+	}
+	strategy := NewLowestPriceStrategy(db)
+
+	tests := []struct {
+		name string
+		size int64
+		want string
+	}{
+		{"cheapest accepts the size", 1 << 21, "f02"},
+		{"cheapest piece minimum is too big", 1024, "f03"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := strategy.SelectMiner(MinerSelectionParam{Size: tt.size})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if provider.Address != tt.want {
+				t.Errorf("expected %s, got %s", tt.want, provider.Address)
+			}
+		})
+	}
+
+	if _, err := strategy.SelectMiner(MinerSelectionParam{Size: 1 << 40}); err != ErrNoMinerAvailable {
+		t.Errorf("expected ErrNoMinerAvailable, got %v", err)
+	}
+}
+
+func TestMinerAssignmentService_GetStrategy(t *testing.T) {
+	cfg := &c.DeltaConfig{}
+	cfg.MinerSelection.DefaultStrategy = utils.MINER_SELECTION_STATIC_ALLOWLIST
+	cfg.MinerSelection.StaticMiners = []string{"f01"}
+	service := NewMinerAssignmentService(DeltaNode{Config: cfg, MinerSelectionStrategies: NewMinerSelectionStrategies(nil, cfg)})
+
+	provider, err := service.GetSPWithStrategy("", MinerSelectionParam{Size: 1024})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.Address != "f01" {
+		t.Errorf("expected the default strategy to pick f01, got %s", provider.Address)
+	}
+	if _, err := service.GetStrategy("unknown"); err == nil {
+		t.Errorf("expected an error for an unknown strategy")
+	}
+}
+
+func TestMinerAssignmentService_SelectDistinctProviders(t *testing.T) {
+	cfg := &c.DeltaConfig{}
+	cfg.MinerSelection.StaticMiners = []string{"f01", "f02", "f03"}
+	service := NewMinerAssignmentService(DeltaNode{Config: cfg, MinerSelectionStrategies: NewMinerSelectionStrategies(nil, cfg)})
+
+	providers, err := service.SelectDistinctProviders(utils.MINER_SELECTION_STATIC_ALLOWLIST, MinerSelectionParam{Size: 1024, ExcludedMiners: []string{"f01"}}, 2, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(providers) != 2 || providers[0].Address == providers[1].Address {
+		t.Fatalf("expected 2 distinct providers, got %+v", providers)
+	}
+	for _, provider := range providers {
+		if provider.Address == "f01" {
+			t.Errorf("expected the excluded provider to be skipped")
+		}
+	}
+
+	if _, err := service.SelectDistinctProviders(utils.MINER_SELECTION_STATIC_ALLOWLIST, MinerSelectionParam{Size: 1024}, 4, ""); err == nil {
+		t.Errorf("expected an error when there are not enough providers")
+	}
+}
+
+func TestMinerAssignmentService_SwitchContentMiner(t *testing.T) {
+	db := newTestDB(t)
+	cfg := &c.DeltaConfig{}
+	strategies := map[string]IMinerSelectionStrategy{
+		utils.MINER_SELECTION_STATIC_ALLOWLIST: NewStaticAllowlistStrategy([]string{"f01", "f02", "f03"}),
+	}
+	service := NewMinerAssignmentService(DeltaNode{DB: db, Config: cfg, MinerSelectionStrategies: strategies})
+
+	content := model.Content{Size: 1024}
+	db.Create(&content)
+	db.Create(&model.ContentDealProposalParameters{Content: content.ID, MinerSelectionStrategy: utils.MINER_SELECTION_STATIC_ALLOWLIST})
+	db.Create(&model.ContentMiner{Content: content.ID, Miner: "f01"})
+	db.Create(&model.ContentDeal{Content: content.ID, Miner: "f01", Failed: true})
+	db.Create(&model.ContentMiner{Content: content.ID, Miner: "f02"})
+
+	// f01 was tried before and f02 just failed, f03 is the only other provider
+	provider, err := service.SwitchContentMiner(content, "f02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if provider.Address != "f03" {
+		t.Fatalf("expected the provider that wasn't tried, got %s", provider.Address)
+	}
+	var contentMiner model.ContentMiner
+	db.Model(&model.ContentMiner{}).Where("content = ?", content.ID).Order("id desc").First(&contentMiner)
+	if contentMiner.Miner != "f03" {
+		t.Errorf("expected the content to be assigned to f03, got %s", contentMiner.Miner)
+	}
+
+	// every provider was tried
+	if _, err := service.SwitchContentMiner(content, "f03"); err == nil {
+		t.Errorf("expected an error when every provider was tried")
+	}
+}
+
+func TestMinerAssignmentService_SelectDistinctProvidersWithDiversity(t *testing.T) {
+	// the api keeps offering the same providers, two of them have the same owner
+	owners := map[string]string{"f01": "f0100", "f02": "f0100", "f03": "f0300"}
+	next := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		miner := []string{"f01", "f02", "f03"}[next%3]
+		next++
+		json.NewEncoder(w).Encode(Provider{Address: miner, AddressOfOwner: owners[miner]})
+	}))
+	defer server.Close()
+
+	cfg := &c.DeltaConfig{}
+	cfg.ExternalApis.SpSelectionApi = server.URL
+	service := NewMinerAssignmentService(DeltaNode{Config: cfg, MinerSelectionStrategies: NewMinerSelectionStrategies(nil, cfg)})
+
+	providers, err := service.SelectDistinctProviders(utils.MINER_SELECTION_REMOTE_API, MinerSelectionParam{Size: 1024}, 2, utils.REPLICATION_DIVERSITY_OWNER)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if providers[0].AddressOfOwner == providers[1].AddressOfOwner {
+		t.Errorf("expected providers with different owners, got %+v", providers)
+	}
+	if _, err := service.SelectDistinctProviders(utils.MINER_SELECTION_REMOTE_API, MinerSelectionParam{Size: 1024}, 3, utils.REPLICATION_DIVERSITY_OWNER); err == nil {
+		t.Errorf("expected an error when there are not enough owners")
+	}
+}
+
+func TestProviderDiversityKey(t *testing.T) {
+	provider := Provider{AddressOfOwner: "f0100"}
+	provider.Multiaddrs.Addresses = []string{"/ip4/147.75.49.71/tcp/6745"}
+
+	if key := ProviderDiversityKey(provider, utils.REPLICATION_DIVERSITY_OWNER); key != "f0100" {
+		t.Errorf("expected the owner address, got %s", key)
+	}
+	if key := ProviderDiversityKey(provider, utils.REPLICATION_DIVERSITY_NETWORK); key != "147.75.0.0" {
+		t.Errorf("expected the /16 network, got %s", key)
+	}
+	if key := ProviderDiversityKey(Provider{}, utils.REPLICATION_DIVERSITY_NETWORK); key != "" {
+		t.Errorf("expected no network for a provider without addresses, got %s", key)
+	}
+}
@@ -73,6 +73,9 @@ type DeltaNode struct {
 	Dispatcher   *Dispatcher
 	MetaInfo     *model.InstanceMeta
 
+	MinerSelectionStrategies map[string]IMinerSelectionStrategy
+	Authenticator            IAuthenticator
+
 	DeltaEventEmitter *DeltaEventEmitter
 }
 
@@ -237,7 +240,6 @@ func NewLightNode(repo NewLightNodeParams) (*DeltaNode, error) {
 		walletToDb := &model.Wallet{
 			UuId:       uuid.String(),
 			Addr:       walletAddr.String(),
-			Owner:      "genesis",
 			KeyType:    walletFromKi.Type,
 			PrivateKey: pkey,
 			CreatedAt:  time.Time{},
@@ -256,11 +258,32 @@ func NewLightNode(repo NewLightNodeParams) (*DeltaNode, error) {
 	}
 
 	// job dispatcher
+	if err := ValidateJobTimeouts(repo.Config); err != nil {
+		return nil, err
+	}
 	dispatcher := CreateNewDispatcher()
+	dispatcher.ConfigureQueues(repo.Config)
+	dispatcher.Store = NewJobStore(db, repo.Config)
 
 	openTelemetryTracerProvider := trace.NewTracerProvider(trace.WithSampler(trace.AlwaysSample()))
 	defer openTelemetryTracerProvider.Shutdown(context.Background())
 
+	// the authentication backend of the api
+	authenticator, err := NewAuthenticator(db, repo.Config)
+	if err != nil {
+		return nil, err
+	}
+
+	// the records owned by a bearer token before the tenants
+	if err := MigrateTenants(db, repo.Config.Standalone.APIKey); err != nil {
+		return nil, err
+	}
+
+	// the deal requests that were being made when the node stopped don't hold their quota anymore
+	if err := ResetTenantQuotaReservations(db); err != nil {
+		return nil, err
+	}
+
 	// Register the tracer provider with the global tracer
 	otel.SetTracerProvider(openTelemetryTracerProvider)
 
@@ -274,6 +297,9 @@ func NewLightNode(repo NewLightNodeParams) (*DeltaNode, error) {
 		Dispatcher:   dispatcher,
 		LotusApiNode: api,
 		Config:       repo.Config,
+
+		MinerSelectionStrategies: NewMinerSelectionStrategies(db, repo.Config),
+		Authenticator:            authenticator,
 	}, nil
 }
 
@@ -471,20 +497,33 @@ func ScanHostComputeResources(ln *DeltaNode, repo string) *model.InstanceMeta {
 }
 
 // CleanUpContentAndPieceComm It updates the status of all the content and piece commitments that were in the process of being transferred or computed
-// to failed
+// to failed. Contents that still have a queued or running job are left alone, the job queue resumes them.
 func CleanUpContentAndPieceComm(ln *DeltaNode) {
 
 	// if the transfer was started upon restart, then we need to update the status to failed
 	ln.DB.Transaction(func(tx *gorm.DB) error {
 
-		rowsAffected := tx.Model(&model.Content{}).Where("status in (?,?,?,?,?)", utils.DEAL_STATUS_TRANSFER_STARTED, utils.CONTENT_PIECE_COMPUTING, utils.CONTENT_DEAL_MAKING_PROPOSAL, utils.CONTENT_PIECE_COMPUTING, utils.CONTENT_DEAL_SENDING_PROPOSAL).Updates(
-			model.Content{
-				Status:      utils.DEAL_STATUS_TRANSFER_FAILED,
-				UpdatedAt:   time.Now(),
-				LastMessage: "Transfer failed due to node restart",
-			}).RowsAffected
+		var contents []model.Content
+		tx.Model(&model.Content{}).Where("status in (?,?,?,?) and id not in (select j.content from jobs j where j.status in (?,?))", utils.DEAL_STATUS_TRANSFER_STARTED, utils.CONTENT_PIECE_COMPUTING, utils.CONTENT_DEAL_MAKING_PROPOSAL, utils.CONTENT_DEAL_SENDING_PROPOSAL, utils.JOB_STATUS_QUEUED, utils.JOB_STATUS_RUNNING).Find(&contents)
+
+		rowsAffected := 0
+		for _, content := range contents {
+			if err := TransitionContentStatus(tx, &content, restartFailedStatuses[content.Status], "Failed due to node restart", utils.STATUS_SOURCE_CLEAN_UP); err != nil {
+				fmt.Println(err)
+				continue
+			}
+			rowsAffected++
+		}
 
 		fmt.Println("Number of rows cleaned up: " + fmt.Sprint(rowsAffected))
 		return nil
 	})
 }
+
+// restartFailedStatuses are the failed statuses of the contents that were in progress when the node stopped
+var restartFailedStatuses = map[string]string{
+	utils.DEAL_STATUS_TRANSFER_STARTED:  utils.DEAL_STATUS_TRANSFER_FAILED,
+	utils.CONTENT_PIECE_COMPUTING:       utils.CONTENT_PIECE_COMPUTING_FAILED,
+	utils.CONTENT_DEAL_MAKING_PROPOSAL:  utils.CONTENT_DEAL_PROPOSAL_FAILED,
+	utils.CONTENT_DEAL_SENDING_PROPOSAL: utils.CONTENT_DEAL_PROPOSAL_FAILED,
+}
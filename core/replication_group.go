@@ -0,0 +1,121 @@
+package core
+
+import (
+	model "delta/models"
+	"delta/utils"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ReplicaStatus is the state of one copy of a replication group.
+// @property ContentID - The content of the copy.
+// @property Miner - The storage provider of the latest deal, or the assigned one if there is no deal yet.
+// @property Status - The status of the content.
+// @property OnChain - The copy has an active deal on chain.
+// @property Slashed - The deal of the copy was slashed.
+// @property Failed - The copy failed and won't be retried.
+type ReplicaStatus struct {
+	ContentID int64  `json:"content_id"`
+	Miner     string `json:"miner"`
+	Status    string `json:"status"`
+	OnChain   bool   `json:"on_chain"`
+	Slashed   bool   `json:"slashed"`
+	Failed    bool   `json:"failed"`
+}
+
+// ReplicationGroupStatus is the aggregate status of the copies of a replication group.
+type ReplicationGroupStatus struct {
+	GroupID       int64           `json:"group_id"`
+	SourceContent int64           `json:"source_content"`
+	Copies        int             `json:"copies"`
+	OnChain       int             `json:"on_chain"`
+	Pending       int             `json:"pending"`
+	Failed        int             `json:"failed"`
+	Slashed       int             `json:"slashed"`
+	Summary       string          `json:"summary"`
+	Replicas      []ReplicaStatus `json:"replicas"`
+}
+
+// MissingCopies returns how many copies have to be created again to reach the copies of the group.
+func (s ReplicationGroupStatus) MissingCopies() int {
+	missing := s.Copies - s.OnChain - s.Pending
+	if missing < 0 {
+		return 0
+	}
+	return missing
+}
+
+// failedContentStatuses are the statuses a content can't leave on its own
+var failedContentStatuses = map[string]bool{
+	utils.CONTENT_FAILED_TO_PIN:          true,
+	utils.CONTENT_FAILED_TO_PROCESS:      true,
+	utils.CONTENT_PIECE_COMPUTING_FAILED: true,
+	utils.CONTENT_DEAL_PROPOSAL_FAILED:   true,
+	utils.DEAL_STATUS_TRANSFER_FAILED:    true,
+	utils.CONTENT_CANCELLED:              true,
+}
+
+// GetReplicationGroupStatus computes the state of every copy of the group. A failed copy that has an auto retry
+// scheduled is still pending.
+func GetReplicationGroupStatus(db *gorm.DB, groupId int64) (ReplicationGroupStatus, error) {
+	var group model.ReplicationGroup
+	db.Model(&model.ReplicationGroup{}).Where("id = ?", groupId).Find(&group)
+	if group.ID == 0 {
+		return ReplicationGroupStatus{}, fmt.Errorf("replication group %d not found", groupId)
+	}
+
+	var contents []model.Content
+	db.Model(&model.Content{}).Where("replication_group = ?", group.ID).Order("id asc").Find(&contents)
+
+	status := ReplicationGroupStatus{
+		GroupID:       group.ID,
+		SourceContent: group.SourceContent,
+		Copies:        group.Copies,
+	}
+	for _, content := range contents {
+		replica := ReplicaStatus{
+			ContentID: content.ID,
+			Status:    content.Status,
+		}
+
+		var contentMiner model.ContentMiner
+		db.Model(&model.ContentMiner{}).Where("content = ?", content.ID).Order("id desc").Limit(1).Find(&contentMiner)
+		replica.Miner = contentMiner.Miner
+
+		var deals []model.ContentDeal
+		db.Model(&model.ContentDeal{}).Where("content = ?", content.ID).Order("id asc").Find(&deals)
+		for _, deal := range deals {
+			replica.Miner = deal.Miner
+			switch {
+			case deal.Slashed:
+				replica.Slashed = true
+			case !deal.Failed && !deal.OnChainAt.IsZero():
+				replica.OnChain = true
+			}
+		}
+
+		switch {
+		case replica.OnChain:
+			status.OnChain++
+		case replica.Slashed:
+			status.Slashed++
+		case failedContentStatuses[content.Status] && !(content.AutoRetry && !content.NextRetryAt.IsZero()):
+			replica.Failed = true
+			status.Failed++
+		default:
+			status.Pending++
+		}
+		status.Replicas = append(status.Replicas, replica)
+	}
+	status.Summary = fmt.Sprintf("%d/%d copies on chain", status.OnChain, status.Copies)
+	return status, nil
+}
+
+// GetReplicationGroupMiners returns every storage provider that was assigned to or made a deal for a copy of the group.
+func GetReplicationGroupMiners(db *gorm.DB, groupId int64) []string {
+	var miners []string
+	db.Raw("select cm.miner from content_miners cm, contents c where cm.content = c.id and c.replication_group = ? "+
+		"union select cd.miner from content_deals cd, contents c where cd.content = c.id and c.replication_group = ?", groupId, groupId).Scan(&miners)
+	return miners
+}
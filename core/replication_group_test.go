@@ -0,0 +1,58 @@
+package core
+
+import (
+	model "delta/models"
+	"delta/utils"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestGetReplicationGroupStatus(t *testing.T) {
+	db := newTestDB(t)
+	group := model.ReplicationGroup{Copies: 5}
+	db.Create(&group)
+
+	contents := []model.Content{
+		{Status: utils.DEAL_STATUS_TRANSFER_FINISHED},
+		{Status: utils.DEAL_STATUS_TRANSFER_FINISHED},
+		{Status: utils.CONTENT_DEAL_PROPOSAL_FAILED},
+		{Status: utils.CONTENT_DEAL_PROPOSAL_FAILED, AutoRetry: true, NextRetryAt: time.Now().Add(time.Minute)},
+		{Status: utils.CONTENT_PIECE_COMPUTING},
+	}
+	for i := range contents {
+		contents[i].ReplicationGroup = group.ID
+		db.Create(&contents[i])
+		db.Create(&model.ContentMiner{Content: contents[i].ID, Miner: fmt.Sprintf("f0%d", i+1)})
+	}
+	db.Create(&model.ContentDeal{Content: contents[0].ID, Miner: "f01", OnChainAt: time.Now()})
+	db.Create(&model.ContentDeal{Content: contents[1].ID, Miner: "f06", OnChainAt: time.Now(), Slashed: true})
+
+	status, err := GetReplicationGroupStatus(db, group.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.OnChain != 1 || status.Slashed != 1 || status.Failed != 1 || status.Pending != 2 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if status.Summary != "1/5 copies on chain" {
+		t.Errorf("unexpected summary %q", status.Summary)
+	}
+	if status.MissingCopies() != 2 {
+		t.Errorf("expected 2 missing copies, got %d", status.MissingCopies())
+	}
+	if status.Replicas[1].Miner != "f06" {
+		t.Errorf("expected the miner of the deal, got %s", status.Replicas[1].Miner)
+	}
+
+	miners := GetReplicationGroupMiners(db, group.ID)
+	sort.Strings(miners)
+	if len(miners) != 6 || miners[5] != "f06" {
+		t.Errorf("expected the assigned and the dealing miners, got %v", miners)
+	}
+
+	if _, err := GetReplicationGroupStatus(db, group.ID+1); err == nil {
+		t.Errorf("expected an error for an unknown group")
+	}
+}
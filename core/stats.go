@@ -6,8 +6,16 @@ type StatsService struct {
 	DeltaNode *DeltaNode
 }
 
+// TenantScope is the condition of the stats queries on the tenant of the contents, aliased c. Its arguments are
+// whether the caller sees every tenant, an admin, and the tenant of the caller.
+const TenantScope = "(? or c.tenant_id = ?)"
+
+// StatsParam is the caller of a stats query.
+// @property TenantID - The tenant of the caller, only its contents are returned.
+// @property AllTenants - The caller is an admin, the contents of every tenant are returned.
 type StatsParam struct {
-	RequestingApiKey string `json:"requesting_api_key"`
+	TenantID   int64 `json:"tenant_id"`
+	AllTenants bool  `json:"all_tenants"`
 }
 type PieceCommitmentStatsParam struct {
 	StatsParam
@@ -49,14 +57,14 @@ func NewStatsStatsService(deltaNode *DeltaNode) *StatsService {
 // Status A function that returns a StatsResult and an error.
 func (s *StatsService) Status(param StatsParam) (StatsResult, error) {
 	var content []model.Content
-	s.DeltaNode.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and c.requesting_api_key = ?", param.RequestingApiKey).Scan(&content)
+	s.DeltaNode.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and "+TenantScope, param.AllTenants, param.TenantID).Scan(&content)
 
 	var contentDeal []model.ContentDeal
-	s.DeltaNode.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.requesting_api_key = ?", param.RequestingApiKey).Scan(&contentDeal)
+	s.DeltaNode.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and "+TenantScope, param.AllTenants, param.TenantID).Scan(&contentDeal)
 
-	// select * from piece_commitments pc, content c where c.piece_commitment_id = pc.id and c.requesting_api_key = ?;
+	// select * from piece_commitments pc, content c where c.piece_commitment_id = pc.id and c.tenant_id = ?;
 	var pieceCommitments []model.PieceCommitment
-	s.DeltaNode.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.requesting_api_key = ?", param.RequestingApiKey).Scan(&pieceCommitments)
+	s.DeltaNode.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and "+TenantScope, param.AllTenants, param.TenantID).Scan(&pieceCommitments)
 
 	return StatsResult{
 		Content:          content,
@@ -66,9 +74,9 @@ func (s *StatsService) Status(param StatsParam) (StatsResult, error) {
 
 // PieceCommitmentStatus A function that returns a StatsPieceCommitmentResult and an error.
 func (s *StatsService) PieceCommitmentStatus(param PieceCommitmentStatsParam) (StatsPieceCommitmentResult, error) {
-	// select * from piece_commitments pc, content c where c.piece_commitment_id = pc.id and c.requesting_api_key = ?;
+	// select * from piece_commitments pc, content c where c.piece_commitment_id = pc.id and c.tenant_id = ?;
 	var pieceCommitment model.PieceCommitment
-	s.DeltaNode.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and c.requesting_api_key = ? and pc.id = ?", param.RequestingApiKey, param.PieceCommpId).Scan(&pieceCommitment)
+	s.DeltaNode.DB.Raw("select pc.* from piece_commitments pc, contents c where c.piece_commitment_id = pc.id and "+TenantScope+" and pc.id = ?", param.AllTenants, param.TenantID, param.PieceCommpId).Scan(&pieceCommitment)
 
 	return StatsPieceCommitmentResult{
 		PieceCommitments: pieceCommitment}, nil
@@ -77,7 +85,7 @@ func (s *StatsService) PieceCommitmentStatus(param PieceCommitmentStatsParam) (S
 // ContentStatus A function that returns a StatsContentResult and an error.
 func (s *StatsService) ContentStatus(param ContentStatsParam) (StatsContentResult, error) {
 	var content model.Content
-	s.DeltaNode.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and c.requesting_api_key = ? and c.id = ?", param.RequestingApiKey, param.ContentId).Scan(&content)
+	s.DeltaNode.DB.Raw("select c.* from content_deals cd, contents c where cd.content = c.id and "+TenantScope+" and c.id = ?", param.AllTenants, param.TenantID, param.ContentId).Scan(&content)
 
 	return StatsContentResult{Content: content}, nil
 }
@@ -85,7 +93,7 @@ func (s *StatsService) ContentStatus(param ContentStatsParam) (StatsContentResul
 // DealStatus A function that returns a StatsDealResult and an error.
 func (s *StatsService) DealStatus(param DealStatsParam) (StatsDealResult, error) {
 	var contentDeal model.ContentDeal
-	s.DeltaNode.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and c.requesting_api_key = ? and cd.id = ?", param.RequestingApiKey, param.DealId).Scan(&contentDeal)
+	s.DeltaNode.DB.Raw("select cd.* from content_deals cd, contents c where cd.content = c.id and "+TenantScope+" and cd.id = ?", param.AllTenants, param.TenantID, param.DealId).Scan(&contentDeal)
 
 	return StatsDealResult{Deals: contentDeal}, nil
 }
@@ -0,0 +1,98 @@
+package core
+
+import (
+	model "delta/models"
+	"delta/utils"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// ContentDealStatus derives the status of a content deal from its fields, a content deal has no status of its own.
+func ContentDealStatus(deal model.ContentDeal) string {
+	switch {
+	case deal.Slashed:
+		return utils.DEAL_STATE_SLASHED
+	case deal.Failed:
+		return utils.DEAL_STATUS_FAILED
+	case deal.Faulty:
+		return utils.DEAL_STATUS_FAULTY
+	case deal.SectorStartEpoch > 0 || !deal.OnChainAt.IsZero():
+		return utils.DEAL_STATE_ACTIVE
+	case deal.DealID > 0:
+		return utils.DEAL_STATE_PUBLISHED
+	case !deal.TransferFinished.IsZero():
+		return utils.DEAL_STATUS_TRANSFER_FINISHED
+	case !deal.TransferStarted.IsZero():
+		return utils.DEAL_STATUS_TRANSFER_STARTED
+	default:
+		return utils.DEAL_STATUS_PROPOSED
+	}
+}
+
+// SaveContentDeal saves a content deal and records the change of its status, a new content deal records its initial
+// status.
+func SaveContentDeal(db *gorm.DB, deal *model.ContentDeal, source string) error {
+	var previous model.ContentDeal
+	if deal.ID != 0 {
+		db.Model(&model.ContentDeal{}).Where("id = ?", deal.ID).Find(&previous)
+	}
+	if err := db.Save(deal).Error; err != nil {
+		return err
+	}
+	return recordContentDealTransition(db, previous, *deal, source)
+}
+
+// UpdateContentDeal updates the non-zero fields of a content deal and records the change of its status.
+func UpdateContentDeal(db *gorm.DB, dealId int64, updates model.ContentDeal, source string) error {
+	var previous model.ContentDeal
+	db.Model(&model.ContentDeal{}).Where("id = ?", dealId).Find(&previous)
+	if previous.ID == 0 {
+		return fmt.Errorf("content deal %d not found", dealId)
+	}
+	if err := db.Model(&model.ContentDeal{}).Where("id = ?", dealId).Updates(updates).Error; err != nil {
+		return err
+	}
+	var current model.ContentDeal
+	db.Model(&model.ContentDeal{}).Where("id = ?", dealId).Find(&current)
+	return recordContentDealTransition(db, previous, current, source)
+}
+
+func recordContentDealTransition(db *gorm.DB, previous model.ContentDeal, current model.ContentDeal, source string) error {
+	oldStatus := ""
+	if previous.ID != 0 {
+		oldStatus = ContentDealStatus(previous)
+	}
+	return model.RecordStatusTransition(db, utils.STATUS_OBJECT_CONTENT_DEAL, current.ID, oldStatus, ContentDealStatus(current), current.LastMessage, source)
+}
+
+// SavePieceCommitment saves a piece commitment and records the change of its status.
+func SavePieceCommitment(db *gorm.DB, pieceComm *model.PieceCommitment, source string) error {
+	var previous model.PieceCommitment
+	if pieceComm.ID != 0 {
+		db.Model(&model.PieceCommitment{}).Where("id = ?", pieceComm.ID).Find(&previous)
+	}
+	if err := db.Save(pieceComm).Error; err != nil {
+		return err
+	}
+	return model.RecordStatusTransition(db, utils.STATUS_OBJECT_PIECE_COMMITMENT, pieceComm.ID, previous.Status, pieceComm.Status, pieceComm.LastMessage, source)
+}
+
+// GetContentStatusHistory returns every status change of a content, of its deals and of its piece commitment, the
+// oldest first.
+func GetContentStatusHistory(db *gorm.DB, contentId int64) ([]model.StatusTransition, error) {
+	var content model.Content
+	db.Model(&model.Content{}).Where("id = ?", contentId).Find(&content)
+	if content.ID == 0 {
+		return nil, fmt.Errorf("content %d not found", contentId)
+	}
+
+	var history []model.StatusTransition
+	err := db.Model(&model.StatusTransition{}).
+		Where("(object_type = ? and object_id = ?) or (object_type = ? and object_id in (select id from content_deals where content = ?)) or (object_type = ? and object_id = ?)",
+			utils.STATUS_OBJECT_CONTENT, content.ID,
+			utils.STATUS_OBJECT_CONTENT_DEAL, content.ID,
+			utils.STATUS_OBJECT_PIECE_COMMITMENT, content.PieceCommitmentId).
+		Order("created_at asc, id asc").Find(&history).Error
+	return history, err
+}
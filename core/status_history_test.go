@@ -0,0 +1,86 @@
+package core
+
+import (
+	model "delta/models"
+	"delta/utils"
+	"testing"
+	"time"
+)
+
+func TestContentDealStatus(t *testing.T) {
+	tests := []struct {
+		deal model.ContentDeal
+		want string
+	}{
+		{model.ContentDeal{}, utils.DEAL_STATUS_PROPOSED},
+		{model.ContentDeal{TransferStarted: time.Now()}, utils.DEAL_STATUS_TRANSFER_STARTED},
+		{model.ContentDeal{TransferStarted: time.Now(), TransferFinished: time.Now()}, utils.DEAL_STATUS_TRANSFER_FINISHED},
+		{model.ContentDeal{TransferFinished: time.Now(), DealID: 10}, utils.DEAL_STATE_PUBLISHED},
+		{model.ContentDeal{DealID: 10, SectorStartEpoch: 100}, utils.DEAL_STATE_ACTIVE},
+		{model.ContentDeal{DealID: 10, SectorStartEpoch: 100, Slashed: true}, utils.DEAL_STATE_SLASHED},
+		{model.ContentDeal{TransferStarted: time.Now(), Failed: true}, utils.DEAL_STATUS_FAILED},
+		{model.ContentDeal{DealID: 10, SectorStartEpoch: 100, Faulty: true}, utils.DEAL_STATUS_FAULTY},
+	}
+	for i, tt := range tests {
+		if got := ContentDealStatus(tt.deal); got != tt.want {
+			t.Errorf("deal %d: expected status %q, got %q", i, tt.want, got)
+		}
+	}
+}
+
+func TestGetContentStatusHistory(t *testing.T) {
+	db := newTestDB(t)
+	pieceComm := model.PieceCommitment{Status: utils.COMMP_STATUS_OPEN}
+	db.Create(&pieceComm)
+	content := model.Content{Status: utils.CONTENT_PIECE_ASSIGNED, PieceCommitmentId: pieceComm.ID}
+	db.Create(&content)
+	other := model.Content{Status: utils.CONTENT_PINNED}
+	db.Create(&other)
+
+	if err := TransitionContentStatus(db, &content, utils.CONTENT_DEAL_MAKING_PROPOSAL, "", "test"); err != nil {
+		t.Fatal(err)
+	}
+	deal := model.ContentDeal{Content: content.ID, Miner: "f01000"}
+	if err := SaveContentDeal(db, &deal, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := UpdateContentDeal(db, deal.ID, model.ContentDeal{TransferStarted: time.Now()}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	// no change of status, nothing recorded
+	if err := UpdateContentDeal(db, deal.ID, model.ContentDeal{LastMessage: "still transferring"}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	pieceComm.Status = utils.COMMP_STATUS_COMITTED
+	if err := SavePieceCommitment(db, &pieceComm, "test"); err != nil {
+		t.Fatal(err)
+	}
+	TransitionContentStatus(db, &other, utils.CONTENT_PIECE_COMPUTING, "", "test")
+
+	history, err := GetContentStatusHistory(db, content.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		objectType string
+		oldStatus  string
+		newStatus  string
+	}{
+		{utils.STATUS_OBJECT_CONTENT, utils.CONTENT_PIECE_ASSIGNED, utils.CONTENT_DEAL_MAKING_PROPOSAL},
+		{utils.STATUS_OBJECT_CONTENT_DEAL, "", utils.DEAL_STATUS_PROPOSED},
+		{utils.STATUS_OBJECT_CONTENT_DEAL, utils.DEAL_STATUS_PROPOSED, utils.DEAL_STATUS_TRANSFER_STARTED},
+		{utils.STATUS_OBJECT_PIECE_COMMITMENT, utils.COMMP_STATUS_OPEN, utils.COMMP_STATUS_COMITTED},
+	}
+	if len(history) != len(expected) {
+		t.Fatalf("expected %d status changes, got %+v", len(expected), history)
+	}
+	for i, e := range expected {
+		if history[i].ObjectType != e.objectType || history[i].OldStatus != e.oldStatus || history[i].NewStatus != e.newStatus {
+			t.Errorf("change %d: expected %s %q -> %q, got %+v", i, e.objectType, e.oldStatus, e.newStatus, history[i])
+		}
+	}
+
+	if _, err := GetContentStatusHistory(db, other.ID+1); err == nil {
+		t.Error("expected an error for a missing content")
+	}
+}
@@ -1,6 +1,9 @@
 package core
 
-import model "delta/models"
+import (
+	model "delta/models"
+	"delta/utils"
+)
 
 // StatusLogger is used to change the status of each system objects in a async manner.
 // Each state content, commp and transfers needs to be updated and logged in the database.
@@ -15,22 +18,15 @@ func NewStatusLogger(node DeltaNode) *StatusLogger {
 	}
 }
 
-// UpdateContentStatus updates the status of a content object.
+// UpdateContentStatus updates the status of a content object, see TransitionContentStatus.
 func (s *StatusLogger) UpdateContentStatus(content model.Content, status string) error {
-	tx := s.LightNode.DB.Model(&content).Update("status", status)
-	if tx.Error != nil {
-		return tx.Error
-	}
-	return nil
+	return TransitionContentStatus(s.LightNode.DB, &content, status, content.LastMessage, utils.STATUS_SOURCE_STATUS_LOGGER)
 }
 
 // UpdatePieceCommStatus Updating the status of a piece commitment object.
 func (s *StatusLogger) UpdatePieceCommStatus(pieceCommp model.PieceCommitment, status string) error {
-	tx := s.LightNode.DB.Model(&pieceCommp).Update("status", status)
-	if tx.Error != nil {
-		return tx.Error
-	}
-	return nil
+	pieceCommp.Status = status
+	return SavePieceCommitment(s.LightNode.DB, &pieceCommp, utils.STATUS_SOURCE_STATUS_LOGGER)
 }
 
 // UpdateContentDealStatus Updating the status of a content deal object.
@@ -0,0 +1,219 @@
+package core
+
+import (
+	model "delta/models"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// the identity of the tenant of the DELTA_AUTH key in the auth backend
+const staticKeyTenantSubject = "delta-auth"
+
+// ErrTenantNotFound is returned for a tenant the node doesn't know.
+var ErrTenantNotFound = errors.New("tenant not found")
+
+// CreateTenant creates a tenant of the node, its API keys are issued by the node.
+func CreateTenant(db *gorm.DB, name string) (model.Tenant, error) {
+	tenantUuid, err := uuid.NewUUID()
+	if err != nil {
+		return model.Tenant{}, err
+	}
+	tenant := model.Tenant{
+		UuId:       tenantUuid.String(),
+		Name:       name,
+		ExternalId: "local:" + tenantUuid.String(),
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	if err := db.Create(&tenant).Error; err != nil {
+		return model.Tenant{}, err
+	}
+	return tenant, nil
+}
+
+// GetTenant returns the tenant with the uuid.
+func GetTenant(db *gorm.DB, tenantUuid string) (model.Tenant, error) {
+	var tenant model.Tenant
+	db.Model(&model.Tenant{}).Where("uu_id = ?", tenantUuid).Find(&tenant)
+	if tenant.ID == 0 {
+		return model.Tenant{}, ErrTenantNotFound
+	}
+	return tenant, nil
+}
+
+// ListTenants returns all the tenants of the node.
+func ListTenants(db *gorm.DB) ([]model.Tenant, error) {
+	var tenants []model.Tenant
+	err := db.Model(&model.Tenant{}).Order("id asc").Find(&tenants).Error
+	return tenants, err
+}
+
+// TenantOfSubject returns the tenant of an identity of the auth backend, it is created on the first request of the
+// identity.
+func TenantOfSubject(db *gorm.DB, subject string, name string) (model.Tenant, error) {
+	var tenant model.Tenant
+	// the identities the admins mapped onto an existing tenant
+	db.Raw("select t.* from tenants t join tenant_subjects s on s.tenant_id = t.id where s.subject = ?", subject).Scan(&tenant)
+	if tenant.ID != 0 {
+		return tenant, nil
+	}
+	db.Model(&model.Tenant{}).Where("external_id = ?", subject).Find(&tenant)
+	if tenant.ID != 0 {
+		return tenant, nil
+	}
+
+	tenantUuid, err := uuid.NewUUID()
+	if err != nil {
+		return model.Tenant{}, err
+	}
+	tenant = model.Tenant{
+		UuId:       tenantUuid.String(),
+		Name:       name,
+		ExternalId: subject,
+		CreatedAt:  time.Now(),
+		UpdatedAt:  time.Now(),
+	}
+	// two requests of a new identity can race, the unique index keeps one tenant
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&tenant).Error; err != nil {
+		return model.Tenant{}, err
+	}
+	if err := db.Model(&model.Tenant{}).Where("external_id = ?", subject).First(&tenant).Error; err != nil {
+		return model.Tenant{}, err
+	}
+	return tenant, nil
+}
+
+// remoteTenantSubject is the identity of the tenant of a token of estuary-auth. The service only says if a token is
+// valid, so the tenant is the token itself, by its hash, unless the admins mapped it onto a tenant, see MapRemoteToken.
+func remoteTenantSubject(token string) string {
+	return "estuary-auth:" + HashApiKey(token)
+}
+
+// MapRemoteToken makes a token of estuary-auth an identity of an existing tenant. The service doesn't say which account
+// a token belongs to, so the new token of a rotated key has to be mapped onto the tenant of the old one to keep its
+// data. A token that is already mapped is moved to the tenant.
+func MapRemoteToken(db *gorm.DB, authenticator IAuthenticator, token string, tenantId int64) error {
+	if token == "" {
+		return errors.New("the token is required")
+	}
+	tenantSubject := model.TenantSubject{
+		Subject:   remoteTenantSubject(token),
+		TenantID:  tenantId,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "subject"}},
+		DoUpdates: clause.AssignmentColumns([]string{"tenant_id", "updated_at"}),
+	}).Create(&tenantSubject).Error; err != nil {
+		return err
+	}
+	// the token may have been seen before it was mapped
+	if tenantAuthenticator, ok := authenticator.(*tenantAuthenticator); ok {
+		tenantAuthenticator.tenants.Delete(tenantSubject.Subject)
+	}
+	return nil
+}
+
+// jwtTenantSubject is the identity of the tenant of a json web token, its subject at its issuer.
+func jwtTenantSubject(issuer string, subject string) string {
+	return "jwt:" + issuer + "|" + subject
+}
+
+// MigrateTenants moves the records owned by a raw bearer token, from before the tenants, to the tenant of the token,
+// and clears the token. The keys of the node issued before the tenants each get a tenant of their own. It only touches
+// the records without a tenant, so it runs on every start.
+func MigrateTenants(db *gorm.DB, staticKey string) error {
+	var apiKeys []model.ApiKey
+	db.Model(&model.ApiKey{}).Where("tenant_id = 0 or tenant_id is null").Find(&apiKeys)
+	for _, apiKey := range apiKeys {
+		tenant, err := CreateTenant(db, apiKey.Label)
+		if err != nil {
+			return err
+		}
+		if err := db.Model(&model.ApiKey{}).Where("id = ?", apiKey.ID).Update("tenant_id", tenant.ID).Error; err != nil {
+			return err
+		}
+	}
+
+	// the unique index of the idempotency keys on the token is replaced by the one on the tenant, the cleared tokens
+	// would collide
+	if db.Migrator().HasIndex(&model.IdempotencyKey{}, "idx_idempotency_key_owner") {
+		if err := db.Migrator().DropIndex(&model.IdempotencyKey{}, "idx_idempotency_key_owner"); err != nil {
+			return err
+		}
+	}
+
+	// the columns that held the token of the owner
+	ownerColumns := []struct {
+		table  string
+		column string
+	}{
+		{"contents", "requesting_api_key"},
+		{"replication_groups", "requesting_api_key"},
+		{"wallets", "owner"},
+		{"idempotency_keys", "requesting_api_key"},
+	}
+	for _, owner := range ownerColumns {
+		if !db.Migrator().HasColumn(owner.table, owner.column) {
+			continue
+		}
+		var tokens []string
+		db.Table(owner.table).Where(owner.column+" <> '' and (tenant_id = 0 or tenant_id is null)").Distinct().Pluck(owner.column, &tokens)
+		for _, token := range tokens {
+			tenantId, err := legacyTokenTenant(db, staticKey, token)
+			if err != nil {
+				return err
+			}
+			if tenantId == 0 {
+				continue
+			}
+			err = db.Table(owner.table).Where(owner.column+" = ?", token).
+				Updates(map[string]interface{}{"tenant_id": tenantId, owner.column: ""}).Error
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// the batch imports take the tenant of their contents
+	return db.Exec("update batch_imports set tenant_id = (select c.tenant_id from batch_import_contents bic, contents c where bic.content_id = c.id and bic.batch_import_id = batch_imports.id limit 1) " +
+		"where (tenant_id = 0 or tenant_id is null) and exists (select 1 from batch_import_contents bic where bic.batch_import_id = batch_imports.id)").Error
+}
+
+// legacyTokenTenant returns the tenant of a token stored before the tenants, zero for the wallet of the node.
+func legacyTokenTenant(db *gorm.DB, staticKey string, token string) (int64, error) {
+	if token == "genesis" {
+		return 0, nil
+	}
+	if staticKey != "" && token == staticKey {
+		tenant, err := TenantOfSubject(db, staticKeyTenantSubject, "DELTA_AUTH")
+		return tenant.ID, err
+	}
+	if IsNodeApiKey(token) {
+		var apiKey model.ApiKey
+		db.Model(&model.ApiKey{}).Where("key_hash = ?", HashApiKey(token)).Find(&apiKey)
+		if apiKey.TenantID != 0 {
+			return apiKey.TenantID, nil
+		}
+	}
+	// the json web tokens were verified when the records were made, their claims are only read
+	if strings.Count(token, ".") == 2 {
+		claims := jwt.MapClaims{}
+		if _, _, err := new(jwt.Parser).ParseUnverified(token, claims); err == nil {
+			issuer, _ := claims["iss"].(string)
+			if subject, _ := claims["sub"].(string); subject != "" {
+				tenant, err := TenantOfSubject(db, jwtTenantSubject(issuer, subject), subject)
+				return tenant.ID, err
+			}
+		}
+	}
+	tenant, err := TenantOfSubject(db, remoteTenantSubject(token), "estuary-auth")
+	return tenant.ID, err
+}
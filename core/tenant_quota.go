@@ -0,0 +1,196 @@
+package core
+
+import (
+	c "delta/config"
+	model "delta/models"
+	"delta/utils"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrTenantQuotaExceeded is returned for a deal request over a limit of its tenant.
+var ErrTenantQuotaExceeded = errors.New("tenant quota exceeded")
+
+// TenantLimits are the limits of the deal requests of a tenant, a zero limit is unlimited.
+// @property MaxConcurrentTransfers - The contents being transferred to the storage providers at once.
+// @property MaxBytesPerDay - The bytes of the contents made in the last 24 hours.
+// @property MaxDealsPerHour - The contents made in the last hour, a replica is a content of its own.
+// @property MaxReplication - The replication of a deal request.
+type TenantLimits struct {
+	MaxConcurrentTransfers int64 `json:"max_concurrent_transfers"`
+	MaxBytesPerDay         int64 `json:"max_bytes_per_day"`
+	MaxDealsPerHour        int64 `json:"max_deals_per_hour"`
+	MaxReplication         int64 `json:"max_replication"`
+}
+
+// TenantUsage is what a tenant uses of its limits.
+type TenantUsage struct {
+	ConcurrentTransfers int64 `json:"concurrent_transfers"`
+	BytesLastDay        int64 `json:"bytes_last_day"`
+	DealsLastHour       int64 `json:"deals_last_hour"`
+}
+
+// TenantQuotaRequest is what a deal request adds to the usage of its tenant.
+// @property Deals - The contents the request makes, with the replicas.
+// @property Bytes - The size of the contents, zero if it isn't known before they are made.
+// @property Replication - The highest replication of the deals of the request.
+type TenantQuotaRequest struct {
+	Deals       int64
+	Bytes       int64
+	Replication int64
+}
+
+// TenantQuotaError is the limit a deal request is over.
+type TenantQuotaError struct {
+	Limit string
+	Max   int64
+	Used  int64
+}
+
+func (e *TenantQuotaError) Error() string {
+	if e.Limit == "replication" {
+		return fmt.Sprintf("the replication of the deals of the tenant can only be up to %d", e.Max)
+	}
+	return fmt.Sprintf("the tenant is limited to %d %s and has used %d, please try again later", e.Max, e.Limit, e.Used)
+}
+
+func (e *TenantQuotaError) Unwrap() error {
+	return ErrTenantQuotaExceeded
+}
+
+// GetTenantLimits returns the limits of a tenant, the ones set by the admins or else the ones of the config.
+func GetTenantLimits(db *gorm.DB, config *c.DeltaConfig, tenantId int64) TenantLimits {
+	limits := TenantLimits{
+		MaxConcurrentTransfers: config.TenantLimits.MaxConcurrentTransfers,
+		MaxBytesPerDay:         config.TenantLimits.MaxBytesPerDay,
+		MaxDealsPerHour:        config.TenantLimits.MaxDealsPerHour,
+		MaxReplication:         config.TenantLimits.MaxReplication,
+	}
+
+	var tenant model.Tenant
+	db.Model(&model.Tenant{}).Where("id = ?", tenantId).Find(&tenant)
+	if tenant.MaxConcurrentTransfers != 0 {
+		limits.MaxConcurrentTransfers = tenant.MaxConcurrentTransfers
+	}
+	if tenant.MaxBytesPerDay != 0 {
+		limits.MaxBytesPerDay = tenant.MaxBytesPerDay
+	}
+	if tenant.MaxDealsPerHour != 0 {
+		limits.MaxDealsPerHour = tenant.MaxDealsPerHour
+	}
+	if tenant.MaxReplication != 0 {
+		limits.MaxReplication = tenant.MaxReplication
+	}
+	return limits
+}
+
+// SetTenantLimits sets the limits of a tenant, a zero limit is the one of the config.
+func SetTenantLimits(db *gorm.DB, tenantId int64, limits TenantLimits) error {
+	if limits.MaxConcurrentTransfers < 0 || limits.MaxBytesPerDay < 0 || limits.MaxDealsPerHour < 0 || limits.MaxReplication < 0 {
+		return errors.New("the limits of a tenant can't be negative")
+	}
+	return db.Model(&model.Tenant{}).Where("id = ?", tenantId).Updates(map[string]interface{}{
+		"max_concurrent_transfers": limits.MaxConcurrentTransfers,
+		"max_bytes_per_day":        limits.MaxBytesPerDay,
+		"max_deals_per_hour":       limits.MaxDealsPerHour,
+		"max_replication":          limits.MaxReplication,
+		"updated_at":               time.Now(),
+	}).Error
+}
+
+// GetTenantUsage returns the usage of a tenant at a time. The days and the hours are the ones before the time.
+func GetTenantUsage(db *gorm.DB, tenantId int64, now time.Time) (TenantUsage, error) {
+	var usage TenantUsage
+	err := db.Model(&model.Content{}).
+		Where("tenant_id = ? and status = ?", tenantId, utils.DEAL_STATUS_TRANSFER_STARTED).
+		Count(&usage.ConcurrentTransfers).Error
+	if err != nil {
+		return TenantUsage{}, err
+	}
+	err = db.Model(&model.Content{}).Select("coalesce(sum(size), 0)").
+		Where("tenant_id = ? and created_at > ?", tenantId, now.Add(-24*time.Hour)).
+		Scan(&usage.BytesLastDay).Error
+	if err != nil {
+		return TenantUsage{}, err
+	}
+	err = db.Model(&model.Content{}).
+		Where("tenant_id = ? and created_at > ?", tenantId, now.Add(-time.Hour)).
+		Count(&usage.DealsLastHour).Error
+	if err != nil {
+		return TenantUsage{}, err
+	}
+	return usage, nil
+}
+
+// ReserveTenantQuota checks a request against the limits of its tenant and reserves what it adds to the usage, so the
+// requests of a tenant made at once can't go over a limit together. The reservation is a conditional update of the
+// tenant that keeps its row locked until the check is done. The returned func releases the reservation, once the
+// contents of the request are made and counted in the usage.
+func ReserveTenantQuota(db *gorm.DB, limits TenantLimits, tenantId int64, request TenantQuotaRequest, now time.Time) (func(), error) {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Tenant{}).Where("id = ?", tenantId).Updates(map[string]interface{}{
+			"quota_reserved_deals": gorm.Expr("quota_reserved_deals + ?", request.Deals),
+			"quota_reserved_bytes": gorm.Expr("quota_reserved_bytes + ?", request.Bytes),
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrTenantNotFound
+		}
+
+		usage, err := GetTenantUsage(tx, tenantId, now)
+		if err != nil {
+			return err
+		}
+		// the requests of the tenant being made, without this one
+		var tenant model.Tenant
+		if err := tx.Model(&model.Tenant{}).Where("id = ?", tenantId).First(&tenant).Error; err != nil {
+			return err
+		}
+		usage.DealsLastHour += tenant.QuotaReservedDeals - request.Deals
+		usage.BytesLastDay += tenant.QuotaReservedBytes - request.Bytes
+		return CheckTenantQuota(limits, usage, request)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		db.Model(&model.Tenant{}).Where("id = ?", tenantId).Updates(map[string]interface{}{
+			"quota_reserved_deals": gorm.Expr("quota_reserved_deals - ?", request.Deals),
+			"quota_reserved_bytes": gorm.Expr("quota_reserved_bytes - ?", request.Bytes),
+		})
+	}, nil
+}
+
+// ResetTenantQuotaReservations releases the reservations of all the tenants, see ReserveTenantQuota.
+func ResetTenantQuotaReservations(db *gorm.DB) error {
+	return db.Model(&model.Tenant{}).Where("quota_reserved_deals <> 0 or quota_reserved_bytes <> 0").Updates(map[string]interface{}{
+		"quota_reserved_deals": 0,
+		"quota_reserved_bytes": 0,
+	}).Error
+}
+
+// CheckTenantQuota returns a TenantQuotaError if the request would take the usage of its tenant over a limit. A limit
+// already reached rejects any request, even one whose size isn't known yet.
+func CheckTenantQuota(limits TenantLimits, usage TenantUsage, request TenantQuotaRequest) error {
+	if limits.MaxReplication > 0 && request.Replication > limits.MaxReplication {
+		return &TenantQuotaError{Limit: "replication", Max: limits.MaxReplication, Used: request.Replication}
+	}
+	if limits.MaxConcurrentTransfers > 0 && usage.ConcurrentTransfers >= limits.MaxConcurrentTransfers {
+		return &TenantQuotaError{Limit: "concurrent transfers", Max: limits.MaxConcurrentTransfers, Used: usage.ConcurrentTransfers}
+	}
+	if limits.MaxDealsPerHour > 0 &&
+		(usage.DealsLastHour >= limits.MaxDealsPerHour || usage.DealsLastHour+request.Deals > limits.MaxDealsPerHour) {
+		return &TenantQuotaError{Limit: "deals per hour", Max: limits.MaxDealsPerHour, Used: usage.DealsLastHour}
+	}
+	if limits.MaxBytesPerDay > 0 &&
+		(usage.BytesLastDay >= limits.MaxBytesPerDay || usage.BytesLastDay+request.Bytes > limits.MaxBytesPerDay) {
+		return &TenantQuotaError{Limit: "bytes per day", Max: limits.MaxBytesPerDay, Used: usage.BytesLastDay}
+	}
+	return nil
+}
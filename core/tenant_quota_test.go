@@ -0,0 +1,176 @@
+package core
+
+import (
+	c "delta/config"
+	model "delta/models"
+	"delta/utils"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckTenantQuota(t *testing.T) {
+	limits := TenantLimits{MaxConcurrentTransfers: 2, MaxBytesPerDay: 1000, MaxDealsPerHour: 10, MaxReplication: 2}
+	tests := []struct {
+		limits    TenantLimits
+		usage     TenantUsage
+		request   TenantQuotaRequest
+		wantLimit string
+	}{
+		{limits, TenantUsage{}, TenantQuotaRequest{Deals: 3, Bytes: 300, Replication: 2}, ""},
+		{limits, TenantUsage{}, TenantQuotaRequest{Deals: 4, Replication: 3}, "replication"},
+		{limits, TenantUsage{ConcurrentTransfers: 2}, TenantQuotaRequest{Deals: 1}, "concurrent transfers"},
+		{limits, TenantUsage{DealsLastHour: 9}, TenantQuotaRequest{Deals: 1}, ""},
+		{limits, TenantUsage{DealsLastHour: 9}, TenantQuotaRequest{Deals: 2}, "deals per hour"},
+		{limits, TenantUsage{DealsLastHour: 10}, TenantQuotaRequest{}, "deals per hour"},
+		{limits, TenantUsage{BytesLastDay: 900}, TenantQuotaRequest{Deals: 1, Bytes: 200}, "bytes per day"},
+		{limits, TenantUsage{BytesLastDay: 1000}, TenantQuotaRequest{Deals: 1}, "bytes per day"}, // size not known yet
+		{TenantLimits{}, TenantUsage{ConcurrentTransfers: 100, BytesLastDay: 1 << 40, DealsLastHour: 1000}, TenantQuotaRequest{Deals: 7, Replication: 6}, ""},
+	}
+	for i, tt := range tests {
+		err := CheckTenantQuota(tt.limits, tt.usage, tt.request)
+		if tt.wantLimit == "" {
+			if err != nil {
+				t.Errorf("request %d: expected no error, got %v", i, err)
+			}
+			continue
+		}
+		var quotaErr *TenantQuotaError
+		if !errors.As(err, &quotaErr) || !errors.Is(err, ErrTenantQuotaExceeded) {
+			t.Errorf("request %d: expected a TenantQuotaError, got %v", i, err)
+			continue
+		}
+		if quotaErr.Limit != tt.wantLimit {
+			t.Errorf("request %d: expected the %s limit, got %s", i, tt.wantLimit, quotaErr.Limit)
+		}
+	}
+}
+
+func TestGetTenantUsage(t *testing.T) {
+	db := newTestDB(t)
+	now := time.Now()
+	contents := []model.Content{
+		{TenantID: 1, Size: 100, Status: utils.DEAL_STATUS_TRANSFER_STARTED, CreatedAt: now.Add(-10 * time.Minute)},
+		{TenantID: 1, Size: 200, Status: utils.DEAL_STATUS_TRANSFER_STARTED, CreatedAt: now.Add(-2 * time.Hour)},
+		{TenantID: 1, Size: 400, Status: utils.CONTENT_DEAL_ACTIVE, CreatedAt: now.Add(-30 * time.Minute)},
+		{TenantID: 1, Size: 800, Status: utils.CONTENT_DEAL_ACTIVE, CreatedAt: now.Add(-48 * time.Hour)},
+		{TenantID: 2, Size: 1600, Status: utils.DEAL_STATUS_TRANSFER_STARTED, CreatedAt: now.Add(-10 * time.Minute)},
+	}
+	if err := db.Create(&contents).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		tenantId int64
+		want     TenantUsage
+	}{
+		{1, TenantUsage{ConcurrentTransfers: 2, BytesLastDay: 700, DealsLastHour: 2}},
+		{2, TenantUsage{ConcurrentTransfers: 1, BytesLastDay: 1600, DealsLastHour: 1}},
+		{3, TenantUsage{}},
+	}
+	for _, tt := range tests {
+		usage, err := GetTenantUsage(db, tt.tenantId, now)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if usage != tt.want {
+			t.Errorf("tenant %d: expected %+v, got %+v", tt.tenantId, tt.want, usage)
+		}
+	}
+}
+
+func TestGetTenantLimits(t *testing.T) {
+	db := newTestDB(t)
+	var config c.DeltaConfig
+	config.TenantLimits.MaxDealsPerHour = 100
+	config.TenantLimits.MaxReplication = 2
+
+	tenant, err := CreateTenant(db, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if limits := GetTenantLimits(db, &config, tenant.ID); limits != (TenantLimits{MaxDealsPerHour: 100, MaxReplication: 2}) {
+		t.Fatalf("expected the limits of the config, got %+v", limits)
+	}
+
+	if err := SetTenantLimits(db, tenant.ID, TenantLimits{MaxConcurrentTransfers: 5, MaxDealsPerHour: 10}); err != nil {
+		t.Fatal(err)
+	}
+	want := TenantLimits{MaxConcurrentTransfers: 5, MaxDealsPerHour: 10, MaxReplication: 2}
+	if limits := GetTenantLimits(db, &config, tenant.ID); limits != want {
+		t.Fatalf("expected %+v, got %+v", want, limits)
+	}
+
+	if err := SetTenantLimits(db, tenant.ID, TenantLimits{MaxBytesPerDay: -1}); err == nil {
+		t.Fatal("expected an error for a negative limit")
+	}
+}
+
+func TestReserveTenantQuota(t *testing.T) {
+	db := newTestDB(t)
+	// sqlite has one writer at a time, the requests wait for the connection instead of failing on a locked table
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	tenant, err := CreateTenant(db, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Create(&model.Content{TenantID: tenant.ID, Size: 100, Status: utils.CONTENT_DEAL_ACTIVE, CreatedAt: time.Now()})
+	limits := TenantLimits{MaxDealsPerHour: 5, MaxBytesPerDay: 1000}
+
+	// the requests made at once can't go over the limit together
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var releases []func()
+	rejected := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			release, err := ReserveTenantQuota(db, limits, tenant.ID, TenantQuotaRequest{Deals: 1, Bytes: 100}, time.Now())
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				releases = append(releases, release)
+			} else if errors.Is(err, ErrTenantQuotaExceeded) {
+				rejected++
+			} else {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(releases) != 4 || rejected != 6 {
+		t.Fatalf("expected 4 requests reserved and 6 rejected, got %d and %d", len(releases), rejected)
+	}
+
+	// the released requests didn't make contents, their quota is free again
+	for _, release := range releases {
+		release()
+	}
+	if _, err := ReserveTenantQuota(db, limits, tenant.ID, TenantQuotaRequest{Deals: 4, Bytes: 400}, time.Now()); err != nil {
+		t.Fatalf("expected the released quota to be reserved again, got %v", err)
+	}
+	if _, err := ReserveTenantQuota(db, limits, tenant.ID, TenantQuotaRequest{Deals: 1}, time.Now()); !errors.Is(err, ErrTenantQuotaExceeded) {
+		t.Fatalf("expected the quota to be exceeded, got %v", err)
+	}
+
+	// the reservations of a stopped node are reset on start
+	if err := ResetTenantQuotaReservations(db); err != nil {
+		t.Fatal(err)
+	}
+	var stored model.Tenant
+	db.Model(&model.Tenant{}).Where("id = ?", tenant.ID).First(&stored)
+	if stored.QuotaReservedDeals != 0 || stored.QuotaReservedBytes != 0 {
+		t.Fatalf("expected no reservation, got %+v", stored)
+	}
+
+	if _, err := ReserveTenantQuota(db, limits, tenant.ID+1, TenantQuotaRequest{Deals: 1}, time.Now()); !errors.Is(err, ErrTenantNotFound) {
+		t.Fatalf("expected an unknown tenant to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,273 @@
+package core
+
+import (
+	"context"
+	c "delta/config"
+	model "delta/models"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTenantOfSubject(t *testing.T) {
+	db := newTestDB(t)
+
+	tests := []struct {
+		subject  string
+		wantSame string // the subject of an earlier call that must give the same tenant
+	}{
+		{"jwt:https://issuer|alice", ""},
+		{"jwt:https://issuer|bob", ""},
+		{"jwt:https://issuer|alice", "jwt:https://issuer|alice"},
+		{"jwt:https://other|alice", ""},
+	}
+	tenants := map[string]int64{}
+	for i, tt := range tests {
+		tenant, err := TenantOfSubject(db, tt.subject, "caller")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.wantSame != "" {
+			if tenant.ID != tenants[tt.wantSame] {
+				t.Errorf("subject %d: expected tenant %d, got %d", i, tenants[tt.wantSame], tenant.ID)
+			}
+			continue
+		}
+		for subject, id := range tenants {
+			if id == tenant.ID {
+				t.Errorf("subject %d: expected a new tenant, got the one of %s", i, subject)
+			}
+		}
+		tenants[tt.subject] = tenant.ID
+	}
+
+	all, err := ListTenants(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected 3 tenants, got %d", len(all))
+	}
+	if _, err := GetTenant(db, "unknown"); !errors.Is(err, ErrTenantNotFound) {
+		t.Fatalf("expected ErrTenantNotFound, got %v", err)
+	}
+}
+
+func TestCreateApiKeyTenant(t *testing.T) {
+	db := newTestDB(t)
+
+	first, _, err := CreateApiKey(db, CreateApiKeyParam{Label: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, _, err := CreateApiKey(db, CreateApiKeyParam{Label: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.TenantID == 0 || first.TenantID == second.TenantID {
+		t.Fatalf("expected a new tenant for each key, got %d and %d", first.TenantID, second.TenantID)
+	}
+
+	// a rotated key keeps the tenant of the key it replaces
+	rotated, _, err := CreateApiKey(db, CreateApiKeyParam{TenantID: first.TenantID, Label: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.TenantID != first.TenantID {
+		t.Fatalf("expected tenant %d, got %d", first.TenantID, rotated.TenantID)
+	}
+}
+
+func TestTenantAuthenticator(t *testing.T) {
+	db := newTestDB(t)
+	apiKey, key, err := CreateApiKey(db, CreateApiKeyParam{Label: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var config c.DeltaConfig
+	config.Common.Mode = "standalone"
+	config.Standalone.APIKey = "static-key"
+	authenticator, err := NewAuthenticator(db, &config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	caller, err := authenticator.Authenticate(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caller.TenantID != apiKey.TenantID {
+		t.Fatalf("expected the tenant of the key %d, got %d", apiKey.TenantID, caller.TenantID)
+	}
+
+	first, err := authenticator.Authenticate(context.Background(), "static-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := authenticator.Authenticate(context.Background(), "static-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.TenantID == 0 || first.TenantID == apiKey.TenantID || first.TenantID != second.TenantID {
+		t.Fatalf("expected one tenant of its own for the static key, got %d and %d", first.TenantID, second.TenantID)
+	}
+}
+
+func TestMapRemoteToken(t *testing.T) {
+	db := newTestDB(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result": {"validated": true, "details": "checked"}}`)
+	}))
+	defer server.Close()
+	authenticator := &tenantAuthenticator{DB: db, Backend: NewRemoteAuthenticator(server.URL, time.Second, time.Minute, 0, 0, model.API_KEY_ROLE_DEALMAKER)}
+	ctx := context.Background()
+
+	old, err := authenticator.Authenticate(ctx, "EST-old")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rotated, err := authenticator.Authenticate(ctx, "EST-rotated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.TenantID == old.TenantID {
+		t.Fatal("expected a tenant of its own for a token that isn't mapped")
+	}
+
+	// the rotated token was seen before it was mapped, the mapping replaces its tenant
+	if err := MapRemoteToken(db, authenticator, "EST-rotated", old.TenantID); err != nil {
+		t.Fatal(err)
+	}
+	rotated, err = authenticator.Authenticate(ctx, "EST-rotated")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.TenantID != old.TenantID {
+		t.Fatalf("expected the tenant of the old token %d, got %d", old.TenantID, rotated.TenantID)
+	}
+
+	// a mapped token can be moved to another tenant
+	tenant, err := CreateTenant(db, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := MapRemoteToken(db, authenticator, "EST-rotated", tenant.ID); err != nil {
+		t.Fatal(err)
+	}
+	if mapped, err := TenantOfSubject(db, remoteTenantSubject("EST-rotated"), ""); err != nil || mapped.ID != tenant.ID {
+		t.Fatalf("expected the tenant %d, got %d (%v)", tenant.ID, mapped.ID, err)
+	}
+
+	if err := MapRemoteToken(db, authenticator, "", tenant.ID); err == nil {
+		t.Fatal("expected an error for an empty token")
+	}
+}
+
+func TestMigrateTenants(t *testing.T) {
+	db := newTestDB(t)
+	// the columns of the owner from before the tenants
+	for _, column := range []string{"alter table contents add column requesting_api_key text", "alter table wallets add column owner text"} {
+		if err := db.Exec(column).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	nodeKey, key, err := CreateApiKey(db, CreateApiKeyParam{Label: "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a key of the node issued before the tenants
+	if err := db.Model(&model.ApiKey{}).Where("id = ?", nodeKey.ID).Update("tenant_id", 0).Error; err != nil {
+		t.Fatal(err)
+	}
+	jwt := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"https://issuer","sub":"alice"}`)) + ".c2ln"
+
+	tokens := []string{"static-key", key, jwt, "remote-token", "remote-token"}
+	for _, token := range tokens {
+		content := model.Content{Name: token}
+		if err := db.Create(&content).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Exec("update contents set requesting_api_key = ? where id = ?", token, content.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, owner := range []string{"genesis", key} {
+		wallet := model.Wallet{Addr: owner}
+		if err := db.Create(&wallet).Error; err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Exec("update wallets set owner = ? where id = ?", owner, wallet.ID).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := MigrateTenants(db, "static-key"); err != nil {
+		t.Fatal(err)
+	}
+	// it only touches the records without a tenant
+	if err := MigrateTenants(db, "static-key"); err != nil {
+		t.Fatal(err)
+	}
+
+	var migratedKey model.ApiKey
+	db.Model(&model.ApiKey{}).Where("id = ?", nodeKey.ID).First(&migratedKey)
+	if migratedKey.TenantID == 0 {
+		t.Fatal("expected a tenant for the key issued before the tenants")
+	}
+	staticTenant, err := TenantOfSubject(db, staticKeyTenantSubject, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	jwtTenant, err := TenantOfSubject(db, jwtTenantSubject("https://issuer", "alice"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	remoteTenant, err := TenantOfSubject(db, remoteTenantSubject("remote-token"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contentTests := []struct {
+		name       string
+		wantTenant int64
+	}{
+		{"static-key", staticTenant.ID},
+		{key, migratedKey.TenantID},
+		{jwt, jwtTenant.ID},
+		{"remote-token", remoteTenant.ID},
+	}
+	for i, tt := range contentTests {
+		var contents []model.Content
+		db.Model(&model.Content{}).Where("name = ?", tt.name).Find(&contents)
+		for _, content := range contents {
+			if content.TenantID != tt.wantTenant {
+				t.Errorf("content %d: expected tenant %d, got %d", i, tt.wantTenant, content.TenantID)
+			}
+		}
+	}
+	var remaining int64
+	db.Table("contents").Where("requesting_api_key <> ''").Count(&remaining)
+	if remaining != 0 {
+		t.Fatalf("expected the tokens to be cleared, %d left", remaining)
+	}
+
+	walletTests := []struct {
+		addr       string
+		wantTenant int64
+	}{
+		{"genesis", 0},
+		{key, migratedKey.TenantID},
+	}
+	for i, tt := range walletTests {
+		var wallet model.Wallet
+		db.Model(&model.Wallet{}).Where("addr = ?", tt.addr).First(&wallet)
+		if wallet.TenantID != tt.wantTenant {
+			t.Errorf("wallet %d: expected tenant %d, got %d", i, tt.wantTenant, wallet.TenantID)
+		}
+	}
+}
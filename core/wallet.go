@@ -15,8 +15,9 @@ import (
 	"time"
 )
 
+// WalletParam is the tenant the wallets belong to.
 type WalletParam struct {
-	RequestingApiKey string
+	TenantID int64
 }
 
 type GetWalletParam struct {
@@ -93,7 +94,7 @@ func (w WalletService) Create(param CreateWalletParam) (AddWalletResult, error)
 	hexedKey := hex.EncodeToString(address.Payload())
 	walletToDb := &model.Wallet{
 		Addr:       address.String(),
-		Owner:      param.RequestingApiKey,
+		TenantID:   param.TenantID,
 		KeyType:    string(param.KeyType),
 		PrivateKey: hexedKey,
 		CreatedAt:  time.Time{},
@@ -107,7 +108,7 @@ func (w WalletService) Create(param CreateWalletParam) (AddWalletResult, error)
 	}, nil
 }
 
-func (w WalletService) ImportWithHex(hexKey string, auth string) (ImportWalletResult, error) {
+func (w WalletService) ImportWithHex(hexKey string, tenantId int64) (ImportWalletResult, error) {
 	hexString, err := hex.DecodeString(hexKey)
 	if err != nil {
 		panic(err)
@@ -122,7 +123,7 @@ func (w WalletService) ImportWithHex(hexKey string, auth string) (ImportWalletRe
 	}
 	result, err := w.Import(ImportWalletParam{
 		WalletParam: WalletParam{
-			RequestingApiKey: auth,
+			TenantID: tenantId,
 		},
 		KeyType:    importWithHexKey.KeyType,
 		PrivateKey: bKey,
@@ -157,7 +158,7 @@ func (w WalletService) Import(param ImportWalletParam) (ImportWalletResult, erro
 	walletToDb := &model.Wallet{
 		UuId:       walletUuid.String(),
 		Addr:       address.String(),
-		Owner:      param.RequestingApiKey,
+		TenantID:   param.TenantID,
 		KeyType:    string(param.KeyType),
 		PrivateKey: hexedWallet,
 		CreatedAt:  time.Time{},
@@ -173,7 +174,7 @@ func (w WalletService) Import(param ImportWalletParam) (ImportWalletResult, erro
 
 // Remove Deleting the wallet from the database.
 func (w WalletService) Remove(param RemoveWalletParam) (DeleteWalletResult, error) {
-	err := w.DeltaNode.DB.Delete(&model.Wallet{}).Where("owner = ? and addr = ?", param.RequestingApiKey, param.Address).Error
+	err := w.DeltaNode.DB.Where("tenant_id = ? and addr = ?", param.TenantID, param.Address).Delete(&model.Wallet{}).Error
 	if err != nil {
 		return DeleteWalletResult{
 			Message:       "Wallet not found",
@@ -189,7 +190,7 @@ func (w WalletService) Remove(param RemoveWalletParam) (DeleteWalletResult, erro
 // List A function that takes a WalletParam and returns a list of model.Wallet and an error.
 func (w WalletService) List(param WalletParam) ([]model.Wallet, error) {
 	var wallets []model.Wallet
-	w.DeltaNode.DB.Model(&model.Wallet{}).Where("owner = ?", param.RequestingApiKey).Find(&wallets)
+	w.DeltaNode.DB.Model(&model.Wallet{}).Where("tenant_id = ?", param.TenantID).Find(&wallets)
 	return wallets, nil
 	// Getting the wallet from the database.
 }
@@ -197,7 +198,7 @@ func (w WalletService) List(param WalletParam) ([]model.Wallet, error) {
 // Getting the wallet from the database.
 func (w WalletService) Get(param GetWalletParam) (model.Wallet, error) {
 	var wallet model.Wallet
-	w.DeltaNode.DB.Model(&model.Wallet{}).Where("owner = ? and addr = ?", param.RequestingApiKey, param.Address).Find(&wallet)
+	w.DeltaNode.DB.Model(&model.Wallet{}).Where("tenant_id = ? and addr = ?", param.TenantID, param.Address).Find(&wallet)
 	return wallet, nil
 }
 
@@ -167,8 +167,8 @@ func TestWalletService_ImportWithHex(t *testing.T) {
 		DeltaNode *DeltaNode
 	}
 	type args struct {
-		hexKey string
-		auth   string
+		hexKey   string
+		tenantId int64
 	}
 	tests := []struct {
 		name    string
@@ -185,7 +185,7 @@ func TestWalletService_ImportWithHex(t *testing.T) {
 				Context:   tt.fields.Context,
 				DeltaNode: tt.fields.DeltaNode,
 			}
-			got, err := w.ImportWithHex(tt.args.hexKey, tt.args.auth)
+			got, err := w.ImportWithHex(tt.args.hexKey, tt.args.tenantId)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("ImportWithHex() error = %v, wantErr %v", err, tt.wantErr)
 				return
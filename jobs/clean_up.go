@@ -3,7 +3,6 @@ package jobs
 import (
 	"context"
 	"delta/core"
-	"delta/utils"
 	"fmt"
 	model "delta/models"
 	"github.com/ipfs/go-cid"
@@ -27,7 +26,7 @@ func NewContentCleanUpProcessor(ln *core.DeltaNode) IProcessor {
 }
 
 // Run Cleaning up the database.
-func (i ContentCleanUpProcessor) Run() error {
+func (i ContentCleanUpProcessor) Run(ctx context.Context) error {
 
 	// clear up finished CID deals.
 	var contentsOnline []model.Content
@@ -39,7 +38,7 @@ func (i ContentCleanUpProcessor) Run() error {
 			fmt.Println("error in decoding cid", err)
 			continue
 		}
-		err = i.LightNode.Node.Blockservice.DeleteBlock(context.Background(), cidD)
+		err = i.LightNode.Node.Blockservice.DeleteBlock(ctx, cidD)
 		if err != nil {
 			fmt.Println("error in deleting block", err)
 			continue
@@ -55,7 +54,7 @@ func (i ContentCleanUpProcessor) Run() error {
 			fmt.Println("error in decoding cid", err)
 			continue
 		}
-		err = i.LightNode.Node.Blockservice.DeleteBlock(context.Background(), cidD)
+		err = i.LightNode.Node.Blockservice.DeleteBlock(ctx, cidD)
 		if err != nil {
 			fmt.Println("error in deleting block", err)
 			continue
@@ -74,34 +73,27 @@ func (i ContentCleanUpProcessor) Run() error {
 			fmt.Println("error in decoding cid", err)
 			continue
 		}
-		err = i.LightNode.Node.Blockservice.DeleteBlock(context.Background(), cidD)
+		err = i.LightNode.Node.Blockservice.DeleteBlock(ctx, cidD)
 		if err != nil {
 			fmt.Println("error in deleting block", err)
 			continue
 		}
 	}
 
-	// clear up cids that are older than 3 days.
-	var oldContents []model.Content
-	i.LightNode.DB.Model(&model.Content{}).Where("status not in(?,?,?,?) and created_at < ?", "transfer-failed", "deal-proposal-failed", "transfer-finished", "deal-proposal-sent", time.Now().AddDate(0, 0, -3)).Find(&oldContents)
-
-	for _, content := range oldContents {
-
+	// fail the contents that are older than 3 days and still not transferred, then clear up their cids.
+	_, err := core.FailStaleContents(i.LightNode.DB, time.Now(), 3*24*time.Hour, func(content model.Content) {
 		cidD, err := cid.Decode(content.Cid)
 		if err != nil {
 			fmt.Println("error in decoding cid", err)
-			continue
+			return
 		}
-		err = i.LightNode.Node.Blockservice.DeleteBlock(context.Background(), cidD)
+		err = i.LightNode.Node.Blockservice.DeleteBlock(ctx, cidD)
 		if err != nil {
 			fmt.Println("error in deleting block", err)
-			continue
 		}
-
-		content.Status = utils.DEAL_STATUS_TRANSFER_FAILED
-		content.LastMessage = "Transfer failed. Record is older than 3 days."
-		i.LightNode.DB.Save(&content)
-
+	})
+	if err != nil {
+		fmt.Println("error in failing old contents", err)
 	}
 	return nil
 }
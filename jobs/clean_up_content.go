@@ -25,7 +25,7 @@ func NewItemContentCleanUpProcessor(ln *core.DeltaNode, content model.Content) I
 }
 
 // Run Cleaning up the database.
-func (i ItemContentCleanUpProcessor) Run() error {
+func (i ItemContentCleanUpProcessor) Run(ctx context.Context) error {
 
 	// clear up finished CID deals.
 	var contentsOnline []model.Content
@@ -37,7 +37,7 @@ func (i ItemContentCleanUpProcessor) Run() error {
 			fmt.Println("error in decoding cid", err)
 			continue
 		}
-		err = i.LightNode.Node.Blockservice.DeleteBlock(context.Background(), cidD)
+		err = i.LightNode.Node.Blockservice.DeleteBlock(ctx, cidD)
 		if err != nil {
 			fmt.Println("error in deleting block", err)
 			continue
@@ -56,7 +56,7 @@ func (i ItemContentCleanUpProcessor) Run() error {
 			fmt.Println("error in decoding cid", err)
 			continue
 		}
-		err = i.LightNode.Node.Blockservice.DeleteBlock(context.Background(), cidD)
+		err = i.LightNode.Node.Blockservice.DeleteBlock(ctx, cidD)
 		if err != nil {
 			fmt.Println("error in deleting block", err)
 			continue
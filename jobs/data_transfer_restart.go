@@ -26,8 +26,18 @@ func NewDataTransferRestartProcessor(ln *core.DeltaNode, contentDeal model.Conte
 	}
 }
 
+// JobQueue is the dispatcher queue the processor runs on
+func (d DataTransferRestartListenerProcessor) JobQueue() string {
+	return utils.JOB_QUEUE_DEAL_MAKING
+}
+
+// JobContent is the content the job works on
+func (d DataTransferRestartListenerProcessor) JobContent() int64 {
+	return d.ContentDeal.Content
+}
+
 // Run Restarting the data transfer.
-func (d DataTransferRestartListenerProcessor) Run() error {
+func (d DataTransferRestartListenerProcessor) Run(ctx context.Context) error {
 	// get the deal data transfer state pull deals
 	dtChan, err := utils.GetChannelID(d.ContentDeal.DTChan)
 	if err != nil {
@@ -35,7 +45,7 @@ func (d DataTransferRestartListenerProcessor) Run() error {
 		return err
 	}
 	channelId := dtChan
-	st, err := d.LightNode.FilClient.TransferStatus(context.Background(), &channelId)
+	st, err := d.LightNode.FilClient.TransferStatus(ctx, &channelId)
 	if err != nil && err != filclient.ErrNoTransferFound {
 		fmt.Println(err)
 		return err
@@ -45,7 +55,7 @@ func (d DataTransferRestartListenerProcessor) Run() error {
 		return fmt.Errorf("no data transfer state was found")
 	}
 
-	err = d.LightNode.FilClient.RestartTransfer(context.Background(), &channelId)
+	err = d.LightNode.FilClient.RestartTransfer(ctx, &channelId)
 	if err != nil {
 		return err
 	}
@@ -1,13 +1,13 @@
 package jobs
 
 import (
+	"context"
 	"delta/core"
 	"delta/utils"
 	"fmt"
 	model "delta/models"
 	"github.com/application-research/filclient"
 	datatransfer "github.com/filecoin-project/go-data-transfer"
-	"strconv"
 	"time"
 )
 
@@ -25,38 +25,30 @@ func NewDataTransferStatusListenerProcessor(ln *core.DeltaNode) IProcessor {
 }
 
 // Run It's a function that is called when the data transfer status changes.
-func (d DataTransferStatusListenerProcessor) Run() error {
+func (d DataTransferStatusListenerProcessor) Run(ctx context.Context) error {
 	d.LightNode.FilClient.Libp2pTransferMgr.Subscribe(func(dbid uint, fst filclient.ChannelState) {
 		fmt.Println("Data Transfer Status Listener: ", fst.Status)
 		switch fst.Status {
 		case datatransfer.Requested:
-			d.LightNode.DB.Model(&model.ContentDeal{}).Where("id = ?", dbid).Updates(model.ContentDeal{
+			core.UpdateContentDeal(d.LightNode.DB, int64(dbid), model.ContentDeal{
 				TransferStarted: time.Now(),
-			})
+			}, utils.STATUS_SOURCE_DATA_TRANSFER)
 		case datatransfer.TransferFinished, datatransfer.Completed:
-			transferId, err := strconv.Atoi(fst.TransferID)
-			if err != nil {
-				fmt.Println(err)
-			}
-			d.LightNode.DB.Model(&model.ContentDeal{}).Where("id = ?", dbid).Updates(model.ContentDeal{
-				DealID:           int64(transferId),
+			// the transfer id is not a deal id, the deal id is resolved from the publish message
+			core.UpdateContentDeal(d.LightNode.DB, int64(dbid), model.ContentDeal{
 				TransferFinished: time.Now(),
-				SealedAt:         time.Now(),
 				LastMessage:      utils.DEAL_STATUS_TRANSFER_FINISHED,
-			})
-			d.LightNode.DB.Model(&model.Content{}).Where("id = (select content from content_deals cd where cd.id = ?)", dbid).Updates(model.Content{
-				Status: utils.DEAL_STATUS_TRANSFER_FINISHED,
-			})
+			}, utils.STATUS_SOURCE_DATA_TRANSFER)
+			d.transitionContentOfDeal(dbid, utils.DEAL_STATUS_TRANSFER_FINISHED, utils.DEAL_STATUS_TRANSFER_FINISHED)
 		case datatransfer.Failed:
-			var contentDeal model.ContentDeal
-			d.LightNode.DB.Model(&model.ContentDeal{}).Where("id = ?", dbid).Updates(model.ContentDeal{
+			core.UpdateContentDeal(d.LightNode.DB, int64(dbid), model.ContentDeal{
 				FailedAt: time.Now(),
-			}).Find(&contentDeal)
-			d.LightNode.DB.Model(&model.Content{}).Joins("left join content_deals as cd on cd.content = c.id").Where("cd.id = ?", dbid).Updates(model.Content{
-				Status: utils.DEAL_STATUS_TRANSFER_FAILED,
-			})
+			}, utils.STATUS_SOURCE_DATA_TRANSFER)
+			var contentDeal model.ContentDeal
+			d.LightNode.DB.Model(&model.ContentDeal{}).Where("id = ?", dbid).Find(&contentDeal)
+			d.transitionContentOfDeal(dbid, utils.DEAL_STATUS_TRANSFER_FAILED, utils.DEAL_STATUS_TRANSFER_FAILED)
 
-			d.LightNode.Dispatcher.AddJobAndDispatch(NewDataTransferRestartProcessor(d.LightNode, contentDeal), 1)
+			d.LightNode.Dispatcher.AddJob(NewDataTransferRestartProcessor(d.LightNode, contentDeal))
 		default:
 		}
 	})
@@ -64,3 +56,15 @@ func (d DataTransferStatusListenerProcessor) Run() error {
 
 	return nil
 }
+
+// transitionContentOfDeal moves the content of a content deal to the status of its transfer
+func (d DataTransferStatusListenerProcessor) transitionContentOfDeal(dealId uint, status string, message string) {
+	var content model.Content
+	d.LightNode.DB.Model(&model.Content{}).Where("id = (select content from content_deals cd where cd.id = ?)", dealId).Find(&content)
+	if content.ID == 0 {
+		return
+	}
+	if err := core.TransitionContentStatus(d.LightNode.DB, &content, status, message, utils.STATUS_SOURCE_DATA_TRANSFER); err != nil {
+		fmt.Println("Data Transfer Status Listener: ", err)
+	}
+}
@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+	"delta/core"
+	"delta/utils"
+	"fmt"
+)
+
+// DealActivationTrackerProcessor It's a struct that contains a pointer to a DeltaNode.
+// @property LightNode - This is the node whose published deals are tracked on chain.
+type DealActivationTrackerProcessor struct {
+	LightNode *core.DeltaNode
+}
+
+// NewDealActivationTrackerProcessor `NewDealActivationTrackerProcessor` creates a new `DealActivationTrackerProcessor` struct and returns it
+func NewDealActivationTrackerProcessor(ln *core.DeltaNode) IProcessor {
+	return &DealActivationTrackerProcessor{
+		LightNode: ln,
+	}
+}
+
+// JobQueue is the dispatcher queue the processor runs on
+func (d DealActivationTrackerProcessor) JobQueue() string {
+	return utils.JOB_QUEUE_STATUS_CHECK
+}
+
+// Run Recording the activation and the slashing of every published deal from the storage market actor.
+func (d DealActivationTrackerProcessor) Run(ctx context.Context) error {
+	checked, err := core.NewDealStatusService(*d.LightNode).TrackPublishedDeals(ctx)
+	fmt.Println("Tracked the on-chain state of", checked, "deals")
+	return err
+}
@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"context"
+	"delta/core"
+	"delta/utils"
+	"fmt"
+)
+
+// DealFaultMonitorProcessor It's a struct that contains a pointer to a DeltaNode.
+// @property LightNode - This is the node whose active deals are monitored.
+type DealFaultMonitorProcessor struct {
+	LightNode *core.DeltaNode
+}
+
+// NewDealFaultMonitorProcessor `NewDealFaultMonitorProcessor` creates a new `DealFaultMonitorProcessor` struct and
+// returns it
+func NewDealFaultMonitorProcessor(ln *core.DeltaNode) IProcessor {
+	return &DealFaultMonitorProcessor{
+		LightNode: ln,
+	}
+}
+
+// JobQueue is the dispatcher queue the processor runs on
+func (d DealFaultMonitorProcessor) JobQueue() string {
+	return utils.JOB_QUEUE_STATUS_CHECK
+}
+
+// Run Checking the active deals of every storage provider for slashing, termination and sector faults, then replacing
+// the slashed deals of the contents that opted in. Each replacement is handed to the deal maker.
+func (d DealFaultMonitorProcessor) Run(ctx context.Context) error {
+	head, err := d.LightNode.LotusApiNode.ChainHead(ctx)
+	if err != nil {
+		return err
+	}
+	db := d.LightNode.DB.WithContext(ctx)
+	dealsByMiner, err := core.GetActiveDealsByMiner(db, int64(head.Height()))
+	if err != nil {
+		return err
+	}
+
+	faultService := core.NewDealFaultService(*d.LightNode)
+	for miner, deals := range dealsByMiner {
+		faults, err := faultService.CheckMinerDeals(ctx, miner, deals, int64(head.Height()))
+		if err != nil {
+			fmt.Println("Error checking the deals of", miner, err)
+			continue
+		}
+		for _, fault := range faults {
+			fmt.Println("Deal", fault.Deal.DealID, "of content", fault.Deal.Content, "with", miner, "is", fault.Kind)
+		}
+	}
+
+	deals, err := core.GetDealsToReplace(db, d.LightNode.Config.DealFaults.MaxReplacementAttempts)
+	if err != nil {
+		return err
+	}
+	replaced := 0
+	for _, toReplace := range deals {
+		if reason := missingContentData(ctx, d.LightNode, toReplace.Content); reason != "" {
+			fmt.Println("Skipping the replacement of content", toReplace.Content.ID, reason)
+			if err := core.SkipDealReplacement(db, toReplace, reason); err != nil {
+				fmt.Println("Error skipping the replacement of content", toReplace.Content.ID, err)
+			}
+			continue
+		}
+
+		replacement, pieceComm, err := faultService.ReplaceDeal(toReplace, int64(head.Height()))
+		if err != nil {
+			fmt.Println("Error replacing the slashed deal of content", toReplace.Content.ID, err)
+			continue
+		}
+		d.LightNode.Dispatcher.AddJob(NewStorageDealMakerProcessor(d.LightNode, replacement, pieceComm))
+		replaced++
+	}
+	fmt.Println("Replaced", replaced, "slashed deals")
+	return nil
+}
@@ -0,0 +1,33 @@
+package jobs
+
+import (
+	"context"
+	"delta/core"
+	"delta/utils"
+	"fmt"
+)
+
+// DealPublishResolverProcessor It's a struct that contains a pointer to a DeltaNode.
+// @property LightNode - This is the node whose deal ids are resolved from the publish messages.
+type DealPublishResolverProcessor struct {
+	LightNode *core.DeltaNode
+}
+
+// NewDealPublishResolverProcessor `NewDealPublishResolverProcessor` creates a new `DealPublishResolverProcessor` struct and returns it
+func NewDealPublishResolverProcessor(ln *core.DeltaNode) IProcessor {
+	return &DealPublishResolverProcessor{
+		LightNode: ln,
+	}
+}
+
+// JobQueue is the dispatcher queue the processor runs on
+func (d DealPublishResolverProcessor) JobQueue() string {
+	return utils.JOB_QUEUE_STATUS_CHECK
+}
+
+// Run Resolving the on-chain deal ids from the confirmed PublishStorageDeals messages of the storage providers.
+func (d DealPublishResolverProcessor) Run(ctx context.Context) error {
+	resolved, err := core.NewDealStatusService(*d.LightNode).ResolvePublishedDeals(ctx)
+	fmt.Println("Resolved the deal ids of", resolved, "deals")
+	return err
+}
@@ -0,0 +1,82 @@
+package jobs
+
+import (
+	"context"
+	"delta/core"
+	model "delta/models"
+	"delta/utils"
+	"fmt"
+
+	"github.com/ipfs/go-cid"
+)
+
+// DealRenewalProcessor It's a struct that contains a pointer to a DeltaNode.
+// @property LightNode - This is the node whose deals about to expire are renewed.
+type DealRenewalProcessor struct {
+	LightNode *core.DeltaNode
+}
+
+// NewDealRenewalProcessor `NewDealRenewalProcessor` creates a new `DealRenewalProcessor` struct and returns it
+func NewDealRenewalProcessor(ln *core.DeltaNode) IProcessor {
+	return &DealRenewalProcessor{
+		LightNode: ln,
+	}
+}
+
+// JobQueue is the dispatcher queue the processor runs on
+func (d DealRenewalProcessor) JobQueue() string {
+	return utils.JOB_QUEUE_STATUS_CHECK
+}
+
+// Run Renewing the active deals that end within the renewal window, of the contents that opted in. Each renewal is
+// handed to the deal maker.
+func (d DealRenewalProcessor) Run(ctx context.Context) error {
+	head, err := d.LightNode.LotusApiNode.ChainHead(ctx)
+	if err != nil {
+		return err
+	}
+	renewalConfig := d.LightNode.Config.DealRenewal
+	deals, err := core.GetDealsToRenew(d.LightNode.DB.WithContext(ctx), int64(head.Height()), int64(renewalConfig.Window), renewalConfig.MaxAttempts)
+	if err != nil {
+		return err
+	}
+
+	renewalService := core.NewDealRenewalService(*d.LightNode)
+	renewed := 0
+	for _, toRenew := range deals {
+		if reason := missingContentData(ctx, d.LightNode, toRenew.Content); reason != "" {
+			fmt.Println("Skipping the renewal of content", toRenew.Content.ID, reason)
+			if err := core.SkipDealRenewal(d.LightNode.DB, toRenew, reason); err != nil {
+				fmt.Println("Error skipping the renewal of content", toRenew.Content.ID, err)
+			}
+			continue
+		}
+
+		renewal, pieceComm, err := renewalService.RenewDeal(toRenew, int64(head.Height()))
+		if err != nil {
+			fmt.Println("Error renewing the deal of content", toRenew.Content.ID, err)
+			continue
+		}
+		d.LightNode.Dispatcher.AddJob(NewStorageDealMakerProcessor(d.LightNode, renewal, pieceComm))
+		renewed++
+	}
+	fmt.Println("Renewed", renewed, "deals about to expire")
+	return nil
+}
+
+// missingContentData returns why the data of a content can't be sent again for a new deal, or an empty string if it
+// can. An end-to-end deal transfers the data again, it must still be in the blockstore, the imports are sent by the
+// client.
+func missingContentData(ctx context.Context, node *core.DeltaNode, content model.Content) string {
+	if content.ConnectionMode != utils.CONNECTION_MODE_E2E {
+		return ""
+	}
+	contentCid, err := cid.Decode(content.Cid)
+	if err != nil {
+		return "invalid cid " + content.Cid
+	}
+	if has, err := node.Node.Blockstore.Has(ctx, contentCid); err != nil || !has {
+		return "the data of the content is not in the blockstore anymore"
+	}
+	return ""
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"delta/core"
 	model "delta/models"
+	"delta/utils"
 	"encoding/base64"
 	"fmt"
 	fc "github.com/application-research/filclient"
@@ -20,7 +21,17 @@ type DealStatusCheck struct {
 	Content   *model.Content
 }
 
-func (d DealStatusCheck) Run() error {
+// JobQueue is the dispatcher queue the processor runs on
+func (d DealStatusCheck) JobQueue() string {
+	return utils.JOB_QUEUE_STATUS_CHECK
+}
+
+// JobContent is the content the job works on
+func (d DealStatusCheck) JobContent() int64 {
+	return d.Content.ID
+}
+
+func (d DealStatusCheck) Run(ctx context.Context) error {
 	var contentDeals []model.ContentDeal
 	// get the latest content deal of the content
 	d.LightNode.DB.Where("content = ?", d.Content.ID).Order("created_at desc").Find(&contentDeals)
@@ -30,7 +41,7 @@ func (d DealStatusCheck) Run() error {
 		return errFilc
 	}
 
-	for _, contentDeal := range contentDeals {
+	for i, contentDeal := range contentDeals {
 
 		if contentDeal.DealUUID == "" {
 			return nil
@@ -52,20 +63,28 @@ func (d DealStatusCheck) Run() error {
 		}
 
 		// get the status
-		status, err := filcOfContent.DealStatus(context.Background(), miner, cidProp, &dealUuid)
+		status, err := filcOfContent.DealStatus(ctx, miner, cidProp, &dealUuid)
 		if err != nil {
 			return err
 		}
-		contentDeal.DealID = int64(status.DealID)
+
+		// the deal id is resolved from the publish message, see core.DealStatusService.ResolvePublishedDeals
+		if status.PublishCid != nil {
+			contentDeal.PublishMessageCid = status.PublishCid.String()
+		}
 
 		if status.State != storagemarket.StorageDealUnknown {
-			d.Content.Status = storagemarket.DealStates[status.State]
-			d.Content.LastMessage = storagemarket.DealStatesDescriptions[status.State]
 			contentDeal.LastMessage = storagemarket.DealStatesDescriptions[status.State]
-			contentDeal.DealID = int64(status.DealID)
 		}
-		d.LightNode.DB.Save(&contentDeal)
-		d.LightNode.DB.Save(&d.Content)
+		core.SaveContentDeal(d.LightNode.DB, &contentDeal, utils.STATUS_SOURCE_DEAL_STATUS_CHECK)
+
+		// only the latest deal drives the status of the content
+		if status.State != storagemarket.StorageDealUnknown && i == 0 {
+			err = core.TransitionContentStatus(d.LightNode.DB, d.Content, storagemarket.DealStates[status.State], storagemarket.DealStatesDescriptions[status.State], utils.STATUS_SOURCE_DEAL_STATUS_CHECK)
+			if err != nil {
+				return err
+			}
+		}
 	}
 	return nil
 }
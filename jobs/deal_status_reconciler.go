@@ -0,0 +1,45 @@
+package jobs
+
+import (
+	"context"
+	"delta/core"
+	"delta/utils"
+	"fmt"
+	"time"
+)
+
+// DealStatusReconcilerProcessor It's a struct that contains a pointer to a DeltaNode.
+// @property LightNode - This is the node whose contents in a non-final state are checked.
+type DealStatusReconcilerProcessor struct {
+	LightNode *core.DeltaNode
+}
+
+// NewDealStatusReconcilerProcessor `NewDealStatusReconcilerProcessor` creates a new `DealStatusReconcilerProcessor` struct and returns it
+func NewDealStatusReconcilerProcessor(ln *core.DeltaNode) IProcessor {
+	return &DealStatusReconcilerProcessor{
+		LightNode: ln,
+	}
+}
+
+// JobQueue is the dispatcher queue the processor runs on
+func (d DealStatusReconcilerProcessor) JobQueue() string {
+	return utils.JOB_QUEUE_STATUS_CHECK
+}
+
+// Run Scheduling a deal status check for a batch of contents in a non-final state, the checks of the same storage
+// provider are spaced out.
+func (d DealStatusReconcilerProcessor) Run(ctx context.Context) error {
+	tracking := d.LightNode.Config.DealTracking
+	contents, err := core.GetContentsToReconcile(d.LightNode.DB.WithContext(ctx), tracking.StatusCheckBatchSize)
+	if err != nil {
+		return err
+	}
+
+	delays := core.ReconciliationDelays(contents, time.Duration(tracking.StatusCheckMinerSpacing)*time.Second)
+	for i := range contents {
+		content := contents[i].Content
+		d.LightNode.Dispatcher.ScheduleJob(NewDealStatusCheck(d.LightNode, &content), time.Now().Add(delays[i]))
+	}
+	fmt.Println("Scheduled the deal status check of", len(contents), "contents")
+	return nil
+}
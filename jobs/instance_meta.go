@@ -1,6 +1,7 @@
 package jobs
 
 import (
+	"context"
 	"delta/core"
 	model "delta/models"
 	"runtime"
@@ -21,7 +22,7 @@ func NewInstanceMetaProcessor(ln *core.DeltaNode, contentDeal model.ContentDeal)
 }
 
 // Run It's checking if the CPU or Mem is above the meta set.
-func (d InstanceMetaProcessor) Run() error {
+func (d InstanceMetaProcessor) Run(ctx context.Context) error {
 	// check if CPU or Mem is above the meta set
 	memStats := &runtime.MemStats{}
 	runtime.ReadMemStats(memStats)
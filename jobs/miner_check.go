@@ -1,6 +1,7 @@
 package jobs
 
 import (
+	"context"
 	"delta/core"
 	"encoding/json"
 	"fmt"
@@ -24,7 +25,7 @@ func NewMinerCheckProcessor(ln *core.DeltaNode) IProcessor {
 }
 
 // Run Getting the list of miners and their prices from the API and storing them in the database.
-func (m MinerCheckProcessor) Run() error {
+func (m MinerCheckProcessor) Run(ctx context.Context) error {
 
 	// remove any record of the miner on the list
 	m.LightNode.DB.Transaction(func(tx *gorm.DB) error {
@@ -0,0 +1,30 @@
+package jobs
+
+import (
+	"context"
+	"delta/core"
+	"delta/utils"
+)
+
+// MinerReputationProcessor It's a struct that contains a pointer to a DeltaNode.
+// @property LightNode - This is the node whose content deals are scored.
+type MinerReputationProcessor struct {
+	LightNode *core.DeltaNode
+}
+
+// NewMinerReputationProcessor `NewMinerReputationProcessor` creates a new `MinerReputationProcessor` struct and returns it
+func NewMinerReputationProcessor(ln *core.DeltaNode) IProcessor {
+	return &MinerReputationProcessor{
+		LightNode: ln,
+	}
+}
+
+// JobQueue is the dispatcher queue the processor runs on
+func (m MinerReputationProcessor) JobQueue() string {
+	return utils.JOB_QUEUE_CLEANUP
+}
+
+// Run Computing the reputation of every storage provider from the outcome of its content deals.
+func (m MinerReputationProcessor) Run(ctx context.Context) error {
+	return core.UpdateMinerReputations(m.LightNode.DB.WithContext(ctx))
+}
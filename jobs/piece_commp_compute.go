@@ -4,6 +4,7 @@ import (
 	"context"
 	"delta/core"
 	"delta/utils"
+	"encoding/json"
 	"io"
 	"time"
 
@@ -42,8 +43,30 @@ func NewPieceCommpProcessor(ln *core.DeltaNode, content model.Content) IProcesso
 	}
 }
 
+// JobQueue is the dispatcher queue the processor runs on
+func (i PieceCommpProcessor) JobQueue() string {
+	return utils.JOB_QUEUE_COMMP
+}
+
+// JobType is the job table type of the processor
+func (i PieceCommpProcessor) JobType() string {
+	return utils.JOB_TYPE_PIECE_COMMP
+}
+
+// JobContent is the content the job works on
+func (i PieceCommpProcessor) JobContent() int64 {
+	return i.Content.ID
+}
+
+// JobPayload is what gets stored in the job table to rebuild the processor after a restart
+func (i PieceCommpProcessor) JobPayload() (string, error) {
+	payload, err := json.Marshal(PieceCommpJobPayload{ContentId: i.Content.ID})
+	return string(payload), err
+}
+
 // Run The process of generating the commp.
-func (i PieceCommpProcessor) Run() error {
+func (i PieceCommpProcessor) Run(ctx context.Context) error {
+	i.Context = ctx
 
 	// if you already have the piece entry for the CID, let's just create a new record with the same commp
 	var content model.Content
@@ -53,31 +76,30 @@ func (i PieceCommpProcessor) Run() error {
 	i.LightNode.DB.Model(&model.PieceCommitment{}).Where("cid = ?", i.Content.Cid).Find(&existingCommp)
 	if existingCommp.ID != 0 {
 		// just assign it if it's already there.
-		i.Content.Status = utils.CONTENT_PIECE_ASSIGNED
+		if err := core.TransitionContentStatus(i.LightNode.DB, &i.Content, utils.CONTENT_PIECE_ASSIGNED, "", utils.STATUS_SOURCE_PIECE_COMMP); err != nil {
+			return err
+		}
 		i.Content.PieceCommitmentId = existingCommp.ID
-		i.Content.UpdatedAt = time.Now()
-		i.LightNode.DB.Save(&i.Content)
+		i.LightNode.DB.Model(&model.Content{}).Where("id = ?", i.Content.ID).Update("piece_commitment_id", existingCommp.ID)
 
 		// then launch the deal maker with the content and the existing commp
 		item := NewStorageDealMakerProcessor(i.LightNode, i.Content, existingCommp)
-		i.LightNode.Dispatcher.AddJobAndDispatch(item, 1)
+		i.LightNode.Dispatcher.AddJob(item)
 		return nil
 	}
 
-	i.Content.Status = utils.CONTENT_PIECE_COMPUTING
-	i.Content.UpdatedAt = time.Now()
-	i.LightNode.DB.Save(&content)
+	if err := core.TransitionContentStatus(i.LightNode.DB, &i.Content, utils.CONTENT_PIECE_COMPUTING, "", utils.STATUS_SOURCE_PIECE_COMMP); err != nil {
+		return err
+	}
 
 	payloadCid, err := cid.Decode(i.Content.Cid)
 	if err != nil {
-		i.Content.Status = utils.CONTENT_PIECE_COMPUTING_FAILED
-		i.Content.LastMessage = err.Error()
-		i.Content.UpdatedAt = time.Now()
-		i.LightNode.DB.Save(&i.Content)
+		core.TransitionContentStatus(i.LightNode.DB, &i.Content, utils.CONTENT_PIECE_COMPUTING_FAILED, err.Error(), utils.STATUS_SOURCE_PIECE_COMMP)
+		return err
 	}
 
 	// prepare the commp
-	node, err := i.LightNode.Node.GetFile(context.Background(), payloadCid)
+	node, err := i.LightNode.Node.GetFile(ctx, payloadCid)
 	nodeCopy := node
 
 	bytesFromCar, err := io.ReadAll(nodeCopy)
@@ -93,11 +115,7 @@ func (i PieceCommpProcessor) Run() error {
 
 		pieceInfo, err := i.CommpService.GenerateCommp(node)
 		if err != nil {
-			i.LightNode.DB.Model(&i.Content).Where("id = ?", i.Content.ID).Updates(model.Content{
-				Status:      utils.CONTENT_FAILED_TO_PROCESS,
-				LastMessage: err.Error(),
-				UpdatedAt:   time.Now(),
-			})
+			core.TransitionContentStatus(i.LightNode.DB, &i.Content, utils.CONTENT_FAILED_TO_PROCESS, err.Error(), utils.STATUS_SOURCE_PIECE_COMMP)
 			return err
 		}
 		pieceCid = pieceInfo.PieceCID
@@ -109,13 +127,9 @@ func (i PieceCommpProcessor) Run() error {
 	} else {
 
 		if i.Content.ConnectionMode == utils.CONNECTION_MODE_IMPORT {
-			pieceCid, payloadSize, unPaddedPieceSize, err = filclient.GeneratePieceCommitment(context.Background(), payloadCid, i.LightNode.Node.Blockstore)
+			pieceCid, payloadSize, unPaddedPieceSize, err = filclient.GeneratePieceCommitment(ctx, payloadCid, i.LightNode.Node.Blockstore)
 			if err != nil {
-				i.LightNode.DB.Model(&i.Content).Where("id = ?", i.Content.ID).Updates(model.Content{
-					Status:      utils.CONTENT_FAILED_TO_PROCESS,
-					LastMessage: err.Error(),
-					UpdatedAt:   time.Now(),
-				})
+				core.TransitionContentStatus(i.LightNode.DB, &i.Content, utils.CONTENT_FAILED_TO_PROCESS, err.Error(), utils.STATUS_SOURCE_PIECE_COMMP)
 				return err
 			}
 			paddedPieceSize = abi.PaddedPieceSize(payloadSize)
@@ -124,11 +138,7 @@ func (i PieceCommpProcessor) Run() error {
 			pieceCid, payloadSize, unPaddedPieceSize, err = i.CommpService.GenerateCommPFile(i.Context, payloadCid, i.LightNode.Node.Blockstore)
 			paddedPieceSize = unPaddedPieceSize.Padded()
 			if err != nil {
-				i.LightNode.DB.Model(&i.Content).Where("id = ?", i.Content.ID).Updates(model.Content{
-					Status:      utils.CONTENT_FAILED_TO_PROCESS,
-					LastMessage: err.Error(),
-					UpdatedAt:   time.Now(),
-				})
+				core.TransitionContentStatus(i.LightNode.DB, &i.Content, utils.CONTENT_FAILED_TO_PROCESS, err.Error(), utils.STATUS_SOURCE_PIECE_COMMP)
 				return err
 			}
 		}
@@ -136,7 +146,7 @@ func (i PieceCommpProcessor) Run() error {
 
 	if err != nil {
 		// put this back to the queue
-		i.LightNode.Dispatcher.AddJobAndDispatch(NewPieceCommpProcessor(i.LightNode, i.Content), 1)
+		i.LightNode.Dispatcher.AddJob(NewPieceCommpProcessor(i.LightNode, i.Content))
 		return err
 	}
 
@@ -155,14 +165,15 @@ func (i PieceCommpProcessor) Run() error {
 	i.LightNode.DB.Create(commpRec)
 
 	// update the content record
-	i.Content.Status = utils.CONTENT_PIECE_ASSIGNED
+	if err := core.TransitionContentStatus(i.LightNode.DB, &i.Content, utils.CONTENT_PIECE_ASSIGNED, "", utils.STATUS_SOURCE_PIECE_COMMP); err != nil {
+		return err
+	}
 	i.Content.PieceCommitmentId = commpRec.ID
-	i.Content.UpdatedAt = time.Now()
-	i.LightNode.DB.Save(&i.Content)
+	i.LightNode.DB.Model(&model.Content{}).Where("id = ?", i.Content.ID).Update("piece_commitment_id", commpRec.ID)
 
 	// add this to the job queue
 	item := NewStorageDealMakerProcessor(i.LightNode, i.Content, *commpRec)
-	i.LightNode.Dispatcher.AddJobAndDispatch(item, 1)
+	i.LightNode.Dispatcher.AddJob(item)
 
 	return nil
 }
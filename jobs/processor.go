@@ -3,6 +3,10 @@ package jobs
 import (
 	"context"
 	"delta/core"
+	model "delta/models"
+	"delta/utils"
+	"encoding/json"
+	"fmt"
 )
 
 type JobExecutable func() error
@@ -10,7 +14,7 @@ type JobExecutable func() error
 // IProcessor is an interface that has a Run method that returns an error.
 // @property {error} Run - This is the main function of the processor. It will be called by the processor manager.
 type IProcessor interface {
-	Run() error
+	Run(ctx context.Context) error
 }
 
 // Processor `Processor` is a struct that contains a `context.Context` and a `*core.DeltaNode`.
@@ -20,3 +24,48 @@ type Processor struct {
 	Context   context.Context
 	LightNode *core.DeltaNode
 }
+
+// PieceCommpJobPayload is the job table payload of a PieceCommpProcessor
+type PieceCommpJobPayload struct {
+	ContentId int64 `json:"content_id"`
+}
+
+// StorageDealMakerJobPayload is the job table payload of a StorageDealMakerProcessor
+type StorageDealMakerJobPayload struct {
+	ContentId         int64 `json:"content_id"`
+	PieceCommitmentId int64 `json:"piece_commitment_id"`
+}
+
+// register the factories used to rebuild the persisted jobs after a restart
+func init() {
+	core.RegisterJobFactory(utils.JOB_TYPE_PIECE_COMMP, func(ln *core.DeltaNode, payload string) (core.IProcessor, error) {
+		var jobPayload PieceCommpJobPayload
+		if err := json.Unmarshal([]byte(payload), &jobPayload); err != nil {
+			return nil, err
+		}
+		var content model.Content
+		ln.DB.Model(&model.Content{}).Where("id = ?", jobPayload.ContentId).Find(&content)
+		if content.ID == 0 {
+			return nil, fmt.Errorf("content %d not found", jobPayload.ContentId)
+		}
+		return NewPieceCommpProcessor(ln, content), nil
+	})
+
+	core.RegisterJobFactory(utils.JOB_TYPE_STORAGE_DEAL_MAKER, func(ln *core.DeltaNode, payload string) (core.IProcessor, error) {
+		var jobPayload StorageDealMakerJobPayload
+		if err := json.Unmarshal([]byte(payload), &jobPayload); err != nil {
+			return nil, err
+		}
+		var content model.Content
+		ln.DB.Model(&model.Content{}).Where("id = ?", jobPayload.ContentId).Find(&content)
+		if content.ID == 0 {
+			return nil, fmt.Errorf("content %d not found", jobPayload.ContentId)
+		}
+		var pieceComm model.PieceCommitment
+		ln.DB.Model(&model.PieceCommitment{}).Where("id = ?", jobPayload.PieceCommitmentId).Find(&pieceComm)
+		if pieceComm.ID == 0 {
+			return nil, fmt.Errorf("piece commitment %d not found", jobPayload.PieceCommitmentId)
+		}
+		return NewStorageDealMakerProcessor(ln, content, pieceComm), nil
+	})
+}
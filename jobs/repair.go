@@ -1,6 +1,7 @@
 package jobs
 
 import (
+	"context"
 	"delta/core"
 )
 
@@ -16,6 +17,6 @@ func NewRepairProcessor(ln *core.DeltaNode) IProcessor {
 }
 
 // Run DB heavy process. We need to check the status of the content and requeue the job if needed.
-func (i RepairProcessor) Run() error {
+func (i RepairProcessor) Run(ctx context.Context) error {
 	return nil
 }
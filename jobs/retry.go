@@ -23,14 +23,14 @@ func NewRetryProcessor(ln *core.DeltaNode) IProcessor {
 
 // Run DB heavy process. We need to check the status of the content and requeue the job if needed.
 // Checking the status of the content and requeue the job if needed.
-func (i RetryProcessor) Run() error {
+func (i RetryProcessor) Run(ctx context.Context) error {
 
 	// collect all cids
 	var cidsToDelete []cid.Cid
 
 	// if the content is hanging in the middle of the process after a day, let's retry it.
 	var contents []model.Content
-	i.LightNode.DB.Model(&model.Content{}).Where("status not in(?,?,?,?) and created_at > ?", "transfer-failed", "deal-proposal-failed", "transfer-finished", "deal-proposal-sent", time.Now().Add(-24*time.Hour)).Find(&contents)
+	i.LightNode.DB.Model(&model.Content{}).Where("status not in(?,?,?,?,?) and created_at > ?", "transfer-failed", "deal-proposal-failed", "transfer-finished", "deal-proposal-sent", utils.CONTENT_CANCELLED, time.Now().Add(-24*time.Hour)).Find(&contents)
 
 	// Checking the status of the content and requeue the job if needed.
 	for _, content := range contents {
@@ -45,11 +45,11 @@ func (i RetryProcessor) Run() error {
 				CreatedAt: time.Now(),
 				UpdatedAt: time.Now(),
 			})
-			i.LightNode.Dispatcher.AddJobAndDispatch(NewPieceCommpProcessor(i.LightNode, content), 1)
+			i.LightNode.Dispatcher.AddJob(NewPieceCommpProcessor(i.LightNode, content))
 		} else if content.Status == utils.CONTENT_PIECE_COMPUTED || content.Status == utils.CONTENT_DEAL_SENDING_PROPOSAL || content.Status == utils.CONTENT_DEAL_MAKING_PROPOSAL {
 			var pieceCommp model.PieceCommitment
 			i.LightNode.DB.Model(&model.PieceCommitment{}).Where("id = (select piece_commitment_id from contents c where c.id = ?)", content.ID).Find(&pieceCommp)
-			i.LightNode.Dispatcher.AddJobAndDispatch(NewStorageDealMakerProcessor(i.LightNode, content, pieceCommp), 1)
+			i.LightNode.Dispatcher.AddJob(NewStorageDealMakerProcessor(i.LightNode, content, pieceCommp))
 		} else if content.Status == utils.CONTENT_FAILED_TO_PIN || content.Status == utils.DEAL_STATUS_TRANSFER_FAILED || content.Status == utils.CONTENT_DEAL_PROPOSAL_FAILED || content.Status == utils.CONTENT_PIECE_COMPUTING_FAILED {
 			// delete/ignore
 			cidToDelete, err := cid.Decode(content.Cid)
@@ -60,9 +60,10 @@ func (i RetryProcessor) Run() error {
 			cidsToDelete = append(cidsToDelete, cidToDelete)
 		} else {
 			// fail it entirely
-			content.Status = utils.CONTENT_FAILED_TO_PROCESS
-			content.LastMessage = "failed to process even after retrying."
-			i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(content)
+			if err := core.TransitionContentStatus(i.LightNode.DB, &content, utils.CONTENT_FAILED_TO_PROCESS, "failed to process even after retrying.", utils.STATUS_SOURCE_RETRY); err != nil {
+				fmt.Println("error in failing content", err)
+				continue
+			}
 			cidToDelete, err := cid.Decode(content.Cid)
 			if err != nil {
 				fmt.Println("error in decoding cid", err)
@@ -74,7 +75,7 @@ func (i RetryProcessor) Run() error {
 	}
 
 	// delete the cids
-	err := i.LightNode.Node.DAGService.RemoveMany(context.Background(), cidsToDelete)
+	err := i.LightNode.Node.DAGService.RemoveMany(ctx, cidsToDelete)
 	if err != nil {
 		fmt.Println("error in unpinning cid", err)
 	}
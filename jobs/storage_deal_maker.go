@@ -72,9 +72,31 @@ func NewStorageDealMakerProcessor(ln *core.DeltaNode, content model.Content, com
 	}
 }
 
+// JobQueue is the dispatcher queue the processor runs on
+func (i StorageDealMakerProcessor) JobQueue() string {
+	return utils.JOB_QUEUE_DEAL_MAKING
+}
+
+// JobType is the job table type of the processor
+func (i StorageDealMakerProcessor) JobType() string {
+	return utils.JOB_TYPE_STORAGE_DEAL_MAKER
+}
+
+// JobContent is the content the job works on
+func (i StorageDealMakerProcessor) JobContent() int64 {
+	return i.Content.ID
+}
+
+// JobPayload is what gets stored in the job table to rebuild the processor after a restart
+func (i StorageDealMakerProcessor) JobPayload() (string, error) {
+	payload, err := json.Marshal(StorageDealMakerJobPayload{ContentId: i.Content.ID, PieceCommitmentId: i.PieceComm.ID})
+	return string(payload), err
+}
+
 // Run The above code is a function that is part of the StorageDealMakerProcessor struct. It is a function that is called when
 // the StorageDealMakerProcessor is run. It calls the makeStorageDeal function, which is defined in the same file.
-func (i StorageDealMakerProcessor) Run() error {
+func (i StorageDealMakerProcessor) Run(ctx context.Context) error {
+	i.Context = ctx
 	err := i.makeStorageDeal(i.Content, i.PieceComm)
 	if err != nil {
 		fmt.Println(err)
@@ -83,23 +105,96 @@ func (i StorageDealMakerProcessor) Run() error {
 	return nil
 }
 
+// handleDealError classifies the error of a failed deal, records the category on the content deal and retries the
+// deal according to the retry policy of the category.
+func (i *StorageDealMakerProcessor) handleDealError(content *model.Content, pieceComm *model.PieceCommitment, deal *model.ContentDeal, dealErr error) error {
+	category := core.ClassifyDealError(dealErr)
+	policy := core.GetDealRetryPolicy(i.LightNode.Config, category)
+
+	// errors before the proposal is sent have no deal yet, record the failed attempt anyway.
+	if deal.ID == 0 {
+		deal.Content = content.ID
+		deal.Failed = true
+		deal.FailedAt = time.Now()
+		deal.LastMessage = dealErr.Error()
+		deal.FailureCategory = category
+		deal.CreatedAt = time.Now()
+		deal.UpdatedAt = time.Now()
+		core.SaveContentDeal(i.LightNode.DB, deal, utils.STATUS_SOURCE_DEAL_MAKER)
+	} else {
+		core.UpdateContentDeal(i.LightNode.DB, deal.ID, model.ContentDeal{
+			Failed:          true,
+			FailedAt:        time.Now(),
+			LastMessage:     dealErr.Error(),
+			FailureCategory: category,
+			UpdatedAt:       time.Now(),
+		}, utils.STATUS_SOURCE_DEAL_MAKER)
+	}
+	if err := core.TransitionContentStatus(i.LightNode.DB, content, utils.CONTENT_DEAL_PROPOSAL_FAILED, dealErr.Error(), utils.STATUS_SOURCE_DEAL_MAKER); err != nil {
+		return err
+	}
+
+	if !content.AutoRetry || policy.MaxAttempts == 0 {
+		return dealErr
+	}
+
+	// check the retry limits of the category and of the content, if one is reached then stop retrying
+	var categoryAttempts, dealCount int64
+	i.LightNode.DB.Model(&model.ContentDeal{}).Where("content = ? and failure_category = ?", content.ID, category).Count(&categoryAttempts)
+	i.LightNode.DB.Model(&model.ContentDeal{}).Where("content = ?", content.ID).Count(&dealCount)
+	if int(categoryAttempts) >= policy.MaxAttempts || int(dealCount) >= i.LightNode.Config.Common.MaxAutoRetry {
+		i.LightNode.DB.Model(&model.Content{}).Where("id = ?", content.ID).Updates(map[string]interface{}{
+			"last_message": "Retry limit reached: " + dealErr.Error(),
+			"auto_retry":   false,
+			"updated_at":   time.Now(),
+		})
+		return dealErr
+	}
+
+	// re-assign a miner, one that wasn't tried yet
+	if policy.SwitchProvider {
+		minerAssignService := core.NewMinerAssignmentService(*i.LightNode)
+		if _, errOnPv := minerAssignService.SwitchContentMiner(*content, deal.Miner); errOnPv != nil {
+			return errOnPv
+		}
+	}
+
+	// and dispatch the job again once the backoff is over, the category backoff replaces the base when it is set
+	backoffBase := time.Duration(i.LightNode.Config.DealRetry.BackoffBase) * time.Second
+	if policy.Backoff > 0 {
+		backoffBase = policy.Backoff
+	}
+	backoffCap := time.Duration(i.LightNode.Config.DealRetry.BackoffCap) * time.Second
+	nextRetryAt := time.Now().Add(core.DealRetryBackoff(backoffBase, backoffCap, int(dealCount)))
+	i.LightNode.DB.Model(&model.Content{}).Where("id = ?", content.ID).Updates(model.Content{
+		NextRetryAt: nextRetryAt,
+		UpdatedAt:   time.Now(),
+	})
+	i.LightNode.Dispatcher.ScheduleJob(NewStorageDealMakerProcessor(i.LightNode, *content, *pieceComm), nextRetryAt)
+	return dealErr
+}
+
+// failDealProposal moves the content to deal proposal failed with the error as its message, and returns the error.
+func (i *StorageDealMakerProcessor) failDealProposal(content *model.Content, dealErr error) error {
+	if err := core.TransitionContentStatus(i.LightNode.DB, content, utils.CONTENT_DEAL_PROPOSAL_FAILED, dealErr.Error(), utils.STATUS_SOURCE_DEAL_MAKER); err != nil {
+		fmt.Println(err)
+	}
+	return dealErr
+}
+
 // Making a deal with the miner.
 func (i *StorageDealMakerProcessor) makeStorageDeal(content *model.Content, pieceComm *model.PieceCommitment) error {
 
 	// update the status
-	var contentToUpdate model.Content
-	i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Find(&contentToUpdate)
-	contentToUpdate.Status = utils.CONTENT_DEAL_MAKING_PROPOSAL //"making-deal-proposal"
-	contentToUpdate.UpdatedAt = time.Now()
-	i.LightNode.DB.Save(&contentToUpdate)
+	if err := core.TransitionContentStatus(i.LightNode.DB, content, utils.CONTENT_DEAL_MAKING_PROPOSAL, "", utils.STATUS_SOURCE_DEAL_MAKER); err != nil {
+		return err
+	}
+	i.LightNode.DB.Model(&model.Content{}).Where("id = ?", content.ID).Update("next_retry_at", time.Time{})
 
 	// any error here, fail the content
 	var miner, errOnMinerAddr = i.GetAssignedMinerForContent(*content)
 	if errOnMinerAddr != nil {
-		contentToUpdate.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED //"failed"
-		contentToUpdate.LastMessage = errOnMinerAddr.Error()
-		contentToUpdate.UpdatedAt = time.Now()
-		return errOnMinerAddr
+		return i.failDealProposal(content, errOnMinerAddr)
 	}
 	minerAddress := miner.Address
 
@@ -107,69 +202,42 @@ func (i *StorageDealMakerProcessor) makeStorageDeal(content *model.Content, piec
 	var filClient, errOnFilc = i.GetAssignedFilclientForContent(*content)
 
 	if errOnFilc != nil {
-		contentToUpdate.UpdatedAt = time.Now()
-		contentToUpdate.LastMessage = errOnFilc.Error()
-		contentToUpdate.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED //"failed"
-		i.LightNode.DB.Save(&contentToUpdate)
-		return errOnFilc
+		return i.failDealProposal(content, errOnFilc)
 	}
 
 	// prep the proposal
 	var dealProposal, errOnDealPrep = i.GetDealProposalForContent(*content)
 
 	if errOnDealPrep != nil {
-		contentToUpdate.UpdatedAt = time.Now()
-		contentToUpdate.LastMessage = errOnDealPrep.Error()
-		contentToUpdate.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED //"failed"
-		i.LightNode.DB.Save(&contentToUpdate)
-		return errOnDealPrep
+		return i.failDealProposal(content, errOnDealPrep)
 	}
 
 	var priceBigInt types.BigInt
 	if !dealProposal.VerifiedDeal {
 		unverifiedDealPrice, errPrice := types.BigFromString(dealProposal.UnverifiedDealMaxPrice)
 		if errPrice != nil {
-			contentToUpdate.UpdatedAt = time.Now()
-			contentToUpdate.LastMessage = errPrice.Error()
-			contentToUpdate.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED //"failed"
-			i.LightNode.DB.Save(&contentToUpdate)
-			return errPrice
+			return i.failDealProposal(content, errPrice)
 		}
-		_, errLockFunds := filClient.LockMarketFunds(context.Background(), types.FIL(unverifiedDealPrice))
+		_, errLockFunds := filClient.LockMarketFunds(i.Context, types.FIL(unverifiedDealPrice))
 		if errLockFunds != nil {
-			contentToUpdate.UpdatedAt = time.Now()
-			contentToUpdate.LastMessage = errLockFunds.Error()
-			contentToUpdate.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED //"failed"
-			i.LightNode.DB.Save(&contentToUpdate)
-			return errLockFunds
+			return i.failDealProposal(content, errLockFunds)
 		}
-		bigIntBalance, errBalance := i.LightNode.LotusApiNode.WalletBalance(context.Background(), filClient.ClientAddr)
+		bigIntBalance, errBalance := i.LightNode.LotusApiNode.WalletBalance(i.Context, filClient.ClientAddr)
 		if errBalance != nil {
-			contentToUpdate.UpdatedAt = time.Now()
-			contentToUpdate.LastMessage = errBalance.Error()
-			contentToUpdate.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED //"failed"
-			i.LightNode.DB.Save(&contentToUpdate)
-			return errBalance
+			return i.failDealProposal(content, errBalance)
 		}
 		// check if the balance is enough
 		if unverifiedDealPrice.GreaterThan(bigIntBalance) {
-			contentToUpdate.UpdatedAt = time.Now()
-			contentToUpdate.LastMessage = "insufficient funds"
-			contentToUpdate.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED //"failed"
-			contentToUpdate.AutoRetry = false                           // stop retrying if the balance is not enough. it won't work.
-			i.LightNode.DB.Save(&contentToUpdate)
-			return xerrors.New("insufficient funds")
+			// stop retrying if the balance is not enough. it won't work.
+			i.LightNode.DB.Model(&model.Content{}).Where("id = ?", content.ID).Update("auto_retry", false)
+			return i.failDealProposal(content, xerrors.New("insufficient funds"))
 		}
 
 		priceBigInt = unverifiedDealPrice
 	} else {
 		verifiedPrice, errVerPrice := types.BigFromString("0")
 		if errVerPrice != nil {
-			contentToUpdate.UpdatedAt = time.Now()
-			contentToUpdate.LastMessage = errVerPrice.Error()
-			contentToUpdate.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED //"failed"
-			i.LightNode.DB.Save(&contentToUpdate)
-			return errVerPrice
+			return i.failDealProposal(content, errVerPrice)
 		}
 		priceBigInt = verifiedPrice
 	}
@@ -181,31 +249,18 @@ func (i *StorageDealMakerProcessor) makeStorageDeal(content *model.Content, piec
 	duration := abi.ChainEpoch(dealDuration)
 	payloadCid, err := cid.Decode(pieceComm.Cid)
 	if err != nil {
-		contentToUpdate.UpdatedAt = time.Now()
-		contentToUpdate.LastMessage = err.Error()
-		contentToUpdate.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED //"failed"
-		i.LightNode.DB.Save(&contentToUpdate)
-		return err
+		return i.failDealProposal(content, err)
 	}
 
 	pieceCid, err := cid.Decode(pieceComm.Piece)
 	if err != nil {
-		contentToUpdate.UpdatedAt = time.Now()
-		contentToUpdate.LastMessage = err.Error()
-		contentToUpdate.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED //"failed"
-		i.LightNode.DB.Save(&contentToUpdate)
-		return err
-
+		return i.failDealProposal(content, err)
 	}
 
 	// label deal
 	label, err := market.NewLabelFromString(dealProposal.Label)
 	if err != nil {
-		contentToUpdate.UpdatedAt = time.Now()
-		contentToUpdate.LastMessage = err.Error()
-		contentToUpdate.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED //"failed"
-		i.LightNode.DB.Save(&contentToUpdate)
-		return err
+		return i.failDealProposal(content, err)
 	}
 
 	prop, err := filClient.MakeDealWithOptions(i.Context, minerAddress, payloadCid, priceBigInt, duration,
@@ -221,71 +276,9 @@ func (i *StorageDealMakerProcessor) makeStorageDeal(content *model.Content, piec
 			PayloadSize: uint64(pieceComm.Size),
 		}),
 	)
-	if err != nil {
-		contentToUpdate.UpdatedAt = time.Now()
-		contentToUpdate.LastMessage = err.Error()
-		contentToUpdate.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED //"failed"
-		i.LightNode.DB.Save(&contentToUpdate)
-		return err
-	}
-
 	if err != nil {
 		fmt.Println(err)
-		switch {
-		case strings.Contains(err.Error(), "miner connection failed: failed to dial"),
-			strings.Contains(err.Error(), "opening stream to miner: failed to open stream to peer: protocol not supported"),
-			strings.Contains(err.Error(), "error getting deal protocol for miner connecting"):
-			if content.AutoRetry {
-				// check the auto retry limit if it's reached then stop retrying
-				var dealCount int64
-				i.LightNode.DB.Model(&model.ContentDeal{}).Where("content = ?", content.ID).Count(&dealCount)
-				if int(dealCount) >= i.LightNode.Config.Common.MaxAutoRetry {
-					content.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED
-					content.LastMessage = "Retry limit reached"
-					content.AutoRetry = false
-					content.UpdatedAt = time.Now()
-					i.LightNode.DB.Save(&content)
-					return nil
-				}
-
-				minerAssignService := core.NewMinerAssignmentService(*i.LightNode)
-				provider, errOnPv := minerAssignService.GetSPWithGivenBytes(i.Content.Size)
-
-				if errOnPv != nil {
-					content.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED
-					content.LastMessage = err.Error()
-					content.UpdatedAt = time.Now()
-					i.LightNode.DB.Save(&content)
-					return errOnPv
-				}
-
-				// create new content miner record.
-				contentMiner := &model.ContentMiner{
-					Content:   content.ID,
-					Miner:     provider.Address,
-					UpdatedAt: time.Now(),
-					CreatedAt: time.Now(),
-				}
-				if err := i.LightNode.DB.Create(contentMiner).Error; err != nil {
-					return xerrors.Errorf("failed to create database entry for content miner: %w", err)
-				}
-
-				// and dispatch the job again
-				i.LightNode.Dispatcher.AddJobAndDispatch(NewStorageDealMakerProcessor(i.LightNode, *content, *pieceComm), 1)
-			} else {
-				content.Status = utils.CONTENT_DEAL_PROPOSAL_FAILED
-				content.LastMessage = err.Error()
-				content.UpdatedAt = time.Now()
-				i.LightNode.DB.Save(&content)
-			}
-		default:
-			i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(model.Content{
-				Status:      utils.CONTENT_DEAL_PROPOSAL_FAILED, //"failed",
-				LastMessage: err.Error(),
-				UpdatedAt:   time.Now(),
-			})
-		}
-		return err
+		return i.handleDealError(content, pieceComm, &model.ContentDeal{Miner: minerAddress.String()}, err)
 	}
 
 	dealProp := prop.DealProposal
@@ -301,90 +294,13 @@ func (i *StorageDealMakerProcessor) makeStorageDeal(content *model.Content, piec
 	propnd, err := cborutil.AsIpld(dealProp)
 
 	if err != nil {
-		i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(model.Content{
-			Status:      utils.CONTENT_DEAL_PROPOSAL_FAILED, //"failed",
-			LastMessage: err.Error(),
-			UpdatedAt:   time.Now(),
-		})
-		return err
+		return i.failDealProposal(content, err)
 	}
 
 	dealUUID := uuid.New()
 	proto, err := filClient.DealProtocolForMiner(i.Context, minerAddress)
 	if err != nil {
-		switch {
-		// there are cases where error occurs before the deal is even sent to the miner.
-		case strings.Contains(err.Error(), "failed to send request: stream reset"),
-			strings.Contains(err.Error(), "proposal piece size is invalid"),
-			strings.Contains(err.Error(), "piece size less than minimum required size"),
-			strings.Contains(err.Error(), "storage price per epoch less than asking price"),
-			strings.Contains(err.Error(), "miner connection failed: failed to dial"),
-			strings.Contains(err.Error(), "failed to dial"),
-			strings.Contains(err.Error(), "deal proposal is identical to deal"),
-			strings.Contains(err.Error(), "provider has insufficient funds to accept deal"),
-			strings.Contains(err.Error(), "opening stream to miner: failed to open stream to peer: protocol not supported"),
-			strings.Contains(err.Error(), "miner is not considering online storage deals"),
-			strings.Contains(err.Error(), "miner is not accepting unverified storage deals"),
-			strings.Contains(err.Error(), "Deal rejected | Under maintenance, retry later"),
-			strings.Contains(err.Error(), "Deal rejected | Price below acceptance for such deal"),
-			strings.Contains(err.Error(), "Deal rejected | Such deal is not accepted"),
-			strings.Contains(err.Error(), "Deal rejected | Error | like a spam"),
-			strings.Contains(err.Error(), "does not support any deal making protocol"),
-			strings.Contains(err.Error(), "failed validation: server error: getting chain head"),
-			strings.Contains(err.Error(), "Error 2 (Worker balance too low)"),
-			strings.Contains(err.Error(), "send proposal rpc:"):
-			if content.AutoRetry {
-				var dealCount int64
-				i.LightNode.DB.Model(&model.ContentDeal{}).Where("content = ?", content.ID).Count(&dealCount)
-				if int(dealCount) >= i.LightNode.Config.Common.MaxAutoRetry {
-					i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(model.Content{
-						Status:      utils.CONTENT_DEAL_PROPOSAL_FAILED, //"failed",
-						LastMessage: "Retry limit reached",
-						AutoRetry:   false,
-						UpdatedAt:   time.Now(),
-					})
-					return nil
-				}
-				minerAssignService := core.NewMinerAssignmentService(*i.LightNode)
-				provider, errOnPv := minerAssignService.GetSPWithGivenBytes(content.Size)
-				if errOnPv != nil {
-					// just fail it then
-					i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(model.Content{
-						Status:      utils.CONTENT_DEAL_PROPOSAL_FAILED, //"failed",
-						LastMessage: err.Error(),
-						UpdatedAt:   time.Now(),
-					})
-					return errOnPv
-				}
-
-				// create new content miner record.
-				contentMiner := &model.ContentMiner{
-					Content:   content.ID,
-					Miner:     provider.Address,
-					UpdatedAt: time.Now(),
-					CreatedAt: time.Now(),
-				}
-				if err := i.LightNode.DB.Create(contentMiner).Error; err != nil {
-					return xerrors.Errorf("failed to create database entry for content miner: %w", err)
-				}
-
-				// and dispatch the job again
-				i.LightNode.Dispatcher.AddJobAndDispatch(NewStorageDealMakerProcessor(i.LightNode, *content, *pieceComm), 1)
-			} else {
-				i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(model.Content{
-					Status:      utils.CONTENT_DEAL_PROPOSAL_FAILED, //"failed",
-					LastMessage: err.Error(),
-					UpdatedAt:   time.Now(),
-				})
-			}
-		default:
-			i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(model.Content{
-				Status:      utils.CONTENT_DEAL_PROPOSAL_FAILED, //"failed",
-				LastMessage: err.Error(),
-				UpdatedAt:   time.Now(),
-			})
-		}
-		return err
+		return i.handleDealError(content, pieceComm, &model.ContentDeal{Miner: minerAddress.String()}, err)
 	}
 	deal := &model.ContentDeal{
 		Content:             content.ID,
@@ -396,8 +312,8 @@ func (i *StorageDealMakerProcessor) makeStorageDeal(content *model.Content, piec
 		CreatedAt:           time.Now(),
 		UpdatedAt:           time.Now(),
 	}
-	if err := i.LightNode.DB.Create(deal).Error; err != nil {
-		i.LightNode.Dispatcher.AddJobAndDispatch(NewStorageDealMakerProcessor(i.LightNode, *content, *pieceComm), 1)
+	if err := core.SaveContentDeal(i.LightNode.DB, deal, utils.STATUS_SOURCE_DEAL_MAKER); err != nil {
+		i.LightNode.Dispatcher.AddJob(NewStorageDealMakerProcessor(i.LightNode, *content, *pieceComm))
 		return xerrors.Errorf("failed to create database entry for deal: %w", err)
 	}
 
@@ -412,112 +328,23 @@ func (i *StorageDealMakerProcessor) makeStorageDeal(content *model.Content, piec
 		UpdatedAt: time.Now(),
 	})
 
-	i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(model.Content{
-		Status: utils.CONTENT_DEAL_SENDING_PROPOSAL, //"sending-deal-proposal",
-	})
+	if err := core.TransitionContentStatus(i.LightNode.DB, content, utils.CONTENT_DEAL_SENDING_PROPOSAL, "", utils.STATUS_SOURCE_DEAL_MAKER); err != nil {
+		return err
+	}
 
 	// send the proposal.
 	_, errProp := i.sendProposalV120(i.Context, *prop, propnd.Cid(), dealUUID, uint(deal.ID), dealProposal)
 
 	// check all errors
 	if errProp != nil {
-		contentToUpdate = model.Content{
-			Status:      utils.CONTENT_DEAL_PROPOSAL_FAILED,
-			LastMessage: errProp.Error(),
-			UpdatedAt:   time.Now(),
-		}
-		contentDealToUpdate := model.ContentDeal{
-			LastMessage: errProp.Error(),
-			Failed:      true,
-			UpdatedAt:   time.Now(),
-		}
-		switch {
-		// we only retry if the error is one of these
-		case strings.Contains(errProp.Error(), "failed to send request: stream reset"),
-			strings.Contains(errProp.Error(), "proposal piece size is invalid"),
-			strings.Contains(errProp.Error(), "piece size less than minimum required size"),
-			strings.Contains(errProp.Error(), "storage price per epoch less than asking price"),
-			strings.Contains(errProp.Error(), "miner connection failed: failed to dial"),
-			strings.Contains(errProp.Error(), "failed to dial"),
-			strings.Contains(errProp.Error(), "connection limited. rate: Wait(n=3) would exceed context deadline"),
-			strings.Contains(errProp.Error(), "deal proposal is identical to deal"),
-			strings.Contains(errProp.Error(), "provider has insufficient funds to accept deal"),
-			strings.Contains(errProp.Error(), "opening stream to miner: failed to open stream to peer: protocol not supported"),
-			strings.Contains(errProp.Error(), "miner is not considering online storage deals"),
-			strings.Contains(errProp.Error(), "miner is not accepting unverified storage deals"),
-			strings.Contains(errProp.Error(), "Deal rejected | Under maintenance, retry later"),
-			strings.Contains(errProp.Error(), "Deal rejected | Price below acceptance for such deal"),
-			strings.Contains(errProp.Error(), "Deal rejected | Such deal is not accepted"),
-			strings.Contains(errProp.Error(), "Deal rejected | Error | like a spam"),
-			strings.Contains(errProp.Error(), "failed validation: server error: getting chain head"),
-			strings.Contains(errProp.Error(), "Error 2 (Worker balance too low)"),
-			strings.Contains(errProp.Error(), "send proposal rpc:"):
-
-			i.LightNode.DB.Model(&deal).Where("id = ?", deal.ID).Updates(&contentDealToUpdate)
-			i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(&contentToUpdate)
-
-			// re-assign a miner
-			if content.AutoRetry {
-				// check the auto retry limit if it's reached then stop retrying
-				var dealCount int64
-				i.LightNode.DB.Model(&model.ContentDeal{}).Where("content = ?", content.ID).Count(&dealCount)
-				if int(dealCount) >= i.LightNode.Config.Common.MaxAutoRetry {
-					i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(model.Content{
-						Status:      utils.CONTENT_DEAL_PROPOSAL_FAILED, //"failed",
-						LastMessage: "Retry limit reached",
-						AutoRetry:   false,
-						UpdatedAt:   time.Now(),
-					})
-					return nil
-				}
-				minerAssignService := core.NewMinerAssignmentService(*i.LightNode)
-				provider, errOnPv := minerAssignService.GetSPWithGivenBytes(content.Size)
-				if errOnPv != nil {
-					// just fail it then
-					i.LightNode.DB.Model(&deal).Where("id = ?", deal.ID).Updates(&contentDealToUpdate)
-					i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(&contentToUpdate)
-					return errOnPv
-				}
-
-				// create new content miner record.
-				contentMiner := &model.ContentMiner{
-					Content:   content.ID,
-					Miner:     provider.Address,
-					UpdatedAt: time.Now(),
-					CreatedAt: time.Now(),
-				}
-				if err := i.LightNode.DB.Create(contentMiner).Error; err != nil {
-					return xerrors.Errorf("failed to create database entry for content miner: %w", err)
-				}
-
-				// and dispatch the job again
-				i.LightNode.Dispatcher.AddJobAndDispatch(NewStorageDealMakerProcessor(i.LightNode, *content, *pieceComm), 1)
-			} else {
-				i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(model.Content{
-					Status:      utils.CONTENT_DEAL_PROPOSAL_FAILED, //"failed",
-					LastMessage: errProp.Error(),
-					UpdatedAt:   time.Now(),
-				})
-			}
-			return errProp
-		default:
-			fmt.Println("default", errProp.Error())
-			i.LightNode.DB.Model(&deal).Where("id = ?", deal.ID).Updates(&contentDealToUpdate)
-			i.LightNode.DB.Model(&content).Where("id = ?", content.ID).
-				Updates(model.Content{
-					Status:      utils.CONTENT_DEAL_PROPOSAL_FAILED,
-					LastMessage: errProp.Error(),
-					UpdatedAt:   time.Now(),
-				})
-		}
-		return errProp
+		return i.handleDealError(content, pieceComm, deal, errProp)
 	}
 
 	// if this is e2e, then we need to start the data transfer.
 	if errProp == nil && content.ConnectionMode == utils.CONNECTION_MODE_E2E {
-		i.LightNode.DB.Model(&content).Where("id = ?", content.ID).Updates(model.Content{
-			Status: utils.CONTENT_DEAL_PROPOSAL_SENT, //"sending-deal-proposal",
-		})
+		if err := core.TransitionContentStatus(i.LightNode.DB, content, utils.CONTENT_DEAL_PROPOSAL_SENT, "", utils.STATUS_SOURCE_DEAL_MAKER); err != nil {
+			return err
+		}
 		propCid, err := cid.Decode(deal.PropCid)
 		contentCid, err := cid.Decode(content.Cid)
 
@@ -529,26 +356,28 @@ func (i *StorageDealMakerProcessor) makeStorageDeal(content *model.Content, piec
 
 		// if this is online then the user/sp expects the data to be transferred. if it fails, re-try.
 		if err != nil {
-			i.LightNode.Dispatcher.AddJobAndDispatch(NewStorageDealMakerProcessor(i.LightNode, *content, *pieceComm), 1)
+			core.TransitionContentStatus(i.LightNode.DB, content, utils.DEAL_STATUS_TRANSFER_FAILED, err.Error(), utils.STATUS_SOURCE_DEAL_MAKER)
+			i.LightNode.Dispatcher.AddJob(NewStorageDealMakerProcessor(i.LightNode, *content, *pieceComm))
 			return err
 		}
 
 		content.PieceCommitmentId = pieceComm.ID
-		pieceComm.Status = utils.COMMP_STATUS_COMITTED           //"committed"
-		content.Status = utils.DEAL_STATUS_TRANSFER_STARTED      //"transfer-started"
-		content.LastMessage = utils.DEAL_STATUS_TRANSFER_STARTED //"transfer-started"
-		deal.LastMessage = utils.DEAL_STATUS_TRANSFER_STARTED    //"transfer-started"
+		pieceComm.Status = utils.COMMP_STATUS_COMITTED        //"committed"
+		deal.LastMessage = utils.DEAL_STATUS_TRANSFER_STARTED //"transfer-started"
 
 		pieceComm.UpdatedAt = time.Now()
-		content.UpdatedAt = time.Now()
 		deal.UpdatedAt = time.Now()
 		deal.TransferStarted = time.Now()
 
 		deal.DTChan = channelId.String()
 		i.LightNode.DB.Transaction(func(tx *gorm.DB) error {
-			tx.Model(&model.PieceCommitment{}).Where("id = ?", pieceComm.ID).Save(pieceComm)
-			tx.Model(&model.Content{}).Where("id = ?", content.ID).Save(content)
-			tx.Model(&model.ContentDeal{}).Where("id = ?", deal.ID).Save(deal)
+			core.SavePieceCommitment(tx, pieceComm, utils.STATUS_SOURCE_DEAL_MAKER)
+			tx.Model(&model.Content{}).Where("id = ?", content.ID).Update("piece_commitment_id", pieceComm.ID)
+			core.SaveContentDeal(tx, deal, utils.STATUS_SOURCE_DEAL_MAKER)
+			// the transfer can already be over, the content doesn't go back to started then
+			if err := core.TransitionContentStatus(tx, content, utils.DEAL_STATUS_TRANSFER_STARTED, utils.DEAL_STATUS_TRANSFER_STARTED, utils.STATUS_SOURCE_DEAL_MAKER); err != nil {
+				fmt.Println(err)
+			}
 			return nil
 		})
 
@@ -557,18 +386,15 @@ func (i *StorageDealMakerProcessor) makeStorageDeal(content *model.Content, piec
 	//	if this is import, then we need to mark the deal as deal_proposal_sent.
 	if errProp == nil && content.ConnectionMode == utils.CONNECTION_MODE_IMPORT {
 		pieceComm.Status = utils.COMMP_STATUS_COMITTED //"committed"
-		content.Status = utils.CONTENT_DEAL_PROPOSAL_SENT
 		deal.LastMessage = utils.CONTENT_DEAL_PROPOSAL_SENT
 
 		pieceComm.UpdatedAt = time.Now()
-		content.UpdatedAt = time.Now()
 		deal.UpdatedAt = time.Now()
 
-		i.LightNode.DB.Transaction(func(tx *gorm.DB) error {
-			tx.Model(&pieceComm).Where("id = ?", pieceComm.ID).Save(pieceComm)
-			tx.Model(&content).Where("id = ?", content.ID).Save(content)
-			tx.Model(&deal).Where("id = ?", deal.ID).Save(deal)
-			return nil
+		return i.LightNode.DB.Transaction(func(tx *gorm.DB) error {
+			core.SavePieceCommitment(tx, pieceComm, utils.STATUS_SOURCE_DEAL_MAKER)
+			core.SaveContentDeal(tx, deal, utils.STATUS_SOURCE_DEAL_MAKER)
+			return core.TransitionContentStatus(tx, content, utils.CONTENT_DEAL_PROPOSAL_SENT, utils.CONTENT_DEAL_PROPOSAL_SENT, utils.STATUS_SOURCE_DEAL_MAKER)
 		})
 	}
 
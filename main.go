@@ -42,6 +42,7 @@ func main() {
 	commands = append(commands, cmd.SpCmd(&cfg)...)
 	commands = append(commands, cmd.StatusCmd(&cfg)...)
 	commands = append(commands, cmd.WalletCmd(&cfg)...)
+	commands = append(commands, cmd.AdminCmd(&cfg)...)
 
 	app := &cli.App{
 		Commands:    commands,
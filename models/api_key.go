@@ -0,0 +1,88 @@
+package db_models
+
+import (
+	"strings"
+	"time"
+)
+
+// the scopes of an API key
+const (
+	API_KEY_SCOPE_DEAL  = "deal"  // make, repair and retry deals
+	API_KEY_SCOPE_READ  = "read"  // read the stats and the status of the deals
+	API_KEY_SCOPE_ADMIN = "admin" // the admin endpoints: wallets, miner lists, jobs and API keys
+)
+
+// API_KEY_SCOPES are all the scopes an API key can have.
+var API_KEY_SCOPES = []string{API_KEY_SCOPE_DEAL, API_KEY_SCOPE_READ, API_KEY_SCOPE_ADMIN}
+
+// the roles of an API key, a role has the scopes of the roles below it
+const (
+	API_KEY_ROLE_ADMIN     = "admin"     // administers the node, and makes deals
+	API_KEY_ROLE_DEALMAKER = "dealmaker" // makes deals and reads their stats
+	API_KEY_ROLE_READ_ONLY = "read-only" // only reads the stats
+)
+
+// API_KEY_ROLE_SCOPES are the scopes each role allows, the scopes of a key can only narrow its role.
+var API_KEY_ROLE_SCOPES = map[string][]string{
+	API_KEY_ROLE_ADMIN:     {API_KEY_SCOPE_DEAL, API_KEY_SCOPE_READ, API_KEY_SCOPE_ADMIN},
+	API_KEY_ROLE_DEALMAKER: {API_KEY_SCOPE_DEAL, API_KEY_SCOPE_READ},
+	API_KEY_ROLE_READ_ONLY: {API_KEY_SCOPE_READ},
+}
+
+// API_KEY_ROLE_SCOPE is the scope that sets a role apart from the roles below it.
+var API_KEY_ROLE_SCOPE = map[string]string{
+	API_KEY_ROLE_ADMIN:     API_KEY_SCOPE_ADMIN,
+	API_KEY_ROLE_DEALMAKER: API_KEY_SCOPE_DEAL,
+	API_KEY_ROLE_READ_ONLY: API_KEY_SCOPE_READ,
+}
+
+// ApiKey is an API key issued by the node. Only the sha256 of the key is stored, the key itself is shown once when it
+// is created.
+type ApiKey struct {
+	ID         int64     `gorm:"primaryKey"`
+	UuId       string    `json:"uuid" gorm:"index:,option:CONCURRENTLY"`
+	TenantID   int64     `json:"tenant_id" gorm:"index:,option:CONCURRENTLY"`
+	Label      string    `json:"label"`
+	Prefix     string    `json:"prefix"` // the first characters of the key, to tell the keys apart
+	KeyHash    string    `json:"-" gorm:"uniqueIndex"`
+	Role       string    `json:"role" gorm:"default:dealmaker"`
+	Scopes     string    `json:"scopes"`     // comma separated
+	ExpiresAt  time.Time `json:"expires_at"` // zero if the key never expires
+	RevokedAt  time.Time `json:"revoked_at"` // zero if the key isn't revoked
+	LastUsedAt time.Time `json:"last_used_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// the identity of the caller in the auth backend, for the callers without a key of the node, it isn't stored
+	Subject string `json:"-" gorm:"-"`
+}
+
+// HasRole returns true if the API key can act as the role: its role is the role or above it, and its scopes kept the
+// scope of the role.
+func (k ApiKey) HasRole(role string) bool {
+	scope, ok := API_KEY_ROLE_SCOPE[role]
+	return ok && k.HasScope(scope)
+}
+
+// HasScope returns true if the API key was issued with the scope, and its role allows the scope.
+func (k ApiKey) HasScope(scope string) bool {
+	if !RoleAllowsScope(k.Role, scope) {
+		return false
+	}
+	for _, s := range strings.Split(k.Scopes, ",") {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// RoleAllowsScope returns true if the role allows the scope.
+func RoleAllowsScope(role string, scope string) bool {
+	for _, s := range API_KEY_ROLE_SCOPES[role] {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
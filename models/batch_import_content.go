@@ -9,6 +9,7 @@ type BatchImport struct {
 	ID        int64     `gorm:"primaryKey"`
 	Uuid      string    `json:"uuid" gorm:"index:,option:CONCURRENTLY"`
 	Status    string    `json:"status"`
+	TenantID  int64     `json:"tenant_id" gorm:"index:,option:CONCURRENTLY"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
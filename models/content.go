@@ -9,13 +9,19 @@ type Content struct {
 	Name              string    `json:"name"`
 	Size              int64     `json:"size"`
 	Cid               string    `json:"cid"`
-	RequestingApiKey  string    `json:"requesting_api_key,omitempty"`
+	TenantID          int64     `json:"tenant_id" gorm:"index:,option:CONCURRENTLY"`
 	PieceCommitmentId int64     `json:"piece_commitment_id,omitempty"`
 	Status            string    `json:"status"`
 	RequestType       string    `json:"request_type"`    // default signed, or unsigned
 	ConnectionMode    string    `json:"connection_mode"` // offline or online
 	AutoRetry         bool      `json:"auto_retry"`
+	NextRetryAt       time.Time `json:"next_retry_at"`
+	AutoRenew         bool      `json:"auto_renew"`
+	AutoReplace       bool      `json:"auto_replace"` // replace the deal if it is slashed or terminated
 	LastMessage       string    `json:"last_message"`
+	ReplicationGroup  int64     `json:"replication_group,omitempty" gorm:"index:,option:CONCURRENTLY"`
+	RenewalOf         int64     `json:"renewal_of,omitempty" gorm:"index:,option:CONCURRENTLY"`     // the content whose deal this content renews
+	ReplacementOf     int64     `json:"replacement_of,omitempty" gorm:"index:,option:CONCURRENTLY"` // the content whose slashed deal this content replaces
 	CreatedAt         time.Time `json:"created_at"`
 	UpdatedAt         time.Time `json:"updated_at"`
 }
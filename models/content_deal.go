@@ -12,16 +12,24 @@ type ContentDeal struct {
 	DealUUID            string    `json:"dealUuid"`
 	Miner               string    `json:"miner"`
 	DealID              int64     `json:"dealId"`
+	PublishMessageCid   string    `json:"publishMessageCid"`
 	Failed              bool      `json:"failed"`
 	Verified            bool      `json:"verified"`
 	Slashed             bool      `json:"slashed"`
+	Faulty              bool      `json:"faulty"` // the sector of the deal is faulty, it can still recover
 	FailedAt            time.Time `json:"failedAt,omitempty"`
 	DTChan              string    `json:"dtChan" gorm:"index"`
 	TransferStarted     time.Time `json:"transferStarted"`
 	TransferFinished    time.Time `json:"transferFinished"`
 	OnChainAt           time.Time `json:"onChainAt"`
 	SealedAt            time.Time `json:"sealedAt"`
+	SectorStartEpoch    int64     `json:"sectorStartEpoch"` // the activation epoch of the deal, -1 until activated
+	SlashEpoch          int64     `json:"slashEpoch"`
+	SectorNumber        int64     `json:"sectorNumber"`
+	DealStartEpoch      int64     `json:"dealStartEpoch"`
+	DealEndEpoch        int64     `json:"dealEndEpoch"`
 	LastMessage         string    `json:"lastMessage"`
+	FailureCategory     string    `json:"failure_category,omitempty" gorm:"index:,option:CONCURRENTLY"`
 	DealProtocolVersion string    `json:"deal_protocol_version"`
 	MinerVersion        string    `json:"miner_version,omitempty"`
 	CreatedAt           time.Time `json:"created_at"`
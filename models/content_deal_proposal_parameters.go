@@ -16,6 +16,7 @@ type ContentDealProposalParameters struct {
 	SkipIPNIAnnounce       bool      `json:"skip_ipni_announce"`
 	VerifiedDeal           bool      `json:"verified_deal"`
 	UnverifiedDealMaxPrice string    `json:"unverified_deal_max_price"`
+	MinerSelectionStrategy string    `json:"miner_selection_strategy,omitempty"`
 	CreatedAt              time.Time `json:"created_at" json:"created-at"`
 	UpdatedAt              time.Time `json:"updated_at" json:"updated-at"`
 }
@@ -2,6 +2,8 @@ package db_models
 
 import (
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type ContentMiner struct {
@@ -12,6 +14,11 @@ type ContentMiner struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// BeforeCreate refuses to assign a suspended, blocked or not allowed miner to a content.
+func (u *ContentMiner) BeforeCreate(tx *gorm.DB) (err error) {
+	return CheckMinerAccess(tx.Session(&gorm.Session{NewDB: true}), u.Miner)
+}
+
 //func (u *ContentMiner) AfterSave(tx *gorm.DB) (err error) {
 //
 //	var instanceFromDb InstanceMeta
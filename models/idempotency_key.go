@@ -0,0 +1,26 @@
+package db_models
+
+import "time"
+
+const (
+	IDEMPOTENCY_KEY_IN_PROGRESS = "in-progress"
+	IDEMPOTENCY_KEY_COMPLETED   = "completed"
+)
+
+// IdempotencyKey is a request made with an Idempotency-Key header and the response it got. A repeat of the request
+// with the same key gets the same response until the key expires.
+type IdempotencyKey struct {
+	ID                  int64     `gorm:"primaryKey"`
+	Key                 string    `json:"key" gorm:"uniqueIndex:idx_idempotency_key_tenant"`
+	TenantID            int64     `json:"-" gorm:"uniqueIndex:idx_idempotency_key_tenant"`
+	Method              string    `json:"method"`
+	Path                string    `json:"path"`
+	RequestHash         string    `json:"request_hash"` // sha256 of the request body
+	Status              string    `json:"status"`       // in-progress or completed
+	ResponseStatus      int       `json:"response_status"`
+	ResponseContentType string    `json:"response_content_type"`
+	ResponseBody        string    `json:"response_body"`
+	ExpiresAt           time.Time `json:"expires_at" gorm:"index:,option:CONCURRENTLY"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package db_models
+
+import (
+	"time"
+)
+
+// Job is a durable entry of the dispatcher queue. Processors that can be rebuilt from a payload are written here
+// so that queued and in-flight work survives a node restart.
+type Job struct {
+	ID             int64     `gorm:"primaryKey"`
+	Type           string    `json:"type" gorm:"index:,option:CONCURRENTLY"`
+	Content        int64     `json:"content" gorm:"index:,option:CONCURRENTLY"`
+	Payload        string    `json:"payload"`
+	Status         string    `json:"status" gorm:"index:,option:CONCURRENTLY"` // queued, running, completed, failed
+	Attempts       int       `json:"attempts"`
+	NextRunAt      time.Time `json:"next_run_at"`
+	LeaseOwner     string    `json:"lease_owner"`
+	LeaseExpiresAt time.Time `json:"lease_expires_at"`
+	LastMessage    string    `json:"last_message"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
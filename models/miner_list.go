@@ -0,0 +1,51 @@
+package db_models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	MINER_LIST_BLOCKED = "blocked"
+	MINER_LIST_ALLOWED = "allowed"
+)
+
+// MinerListEntry is a storage provider on the blocklist or on the allowlist of the node. When the allowlist has
+// entries only the providers on it can be assigned.
+type MinerListEntry struct {
+	ID        int64     `gorm:"primaryKey"`
+	Miner     string    `json:"miner" gorm:"index:,option:CONCURRENTLY"`
+	ListType  string    `json:"list_type" gorm:"index:,option:CONCURRENTLY"` // blocked or allowed
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CheckMinerAccess returns an error if the storage provider is suspended, blocked or missing from a non-empty
+// allowlist.
+func CheckMinerAccess(tx *gorm.DB, miner string) error {
+	var minerInfo MinerInfo
+	tx.Model(&MinerInfo{}).Where("addr = ? and suspended = ?", miner, true).Limit(1).Find(&minerInfo)
+	if minerInfo.ID != 0 {
+		return fmt.Errorf("miner %s is suspended: %s", miner, minerInfo.SuspendedReason)
+	}
+
+	var blocked MinerListEntry
+	tx.Model(&MinerListEntry{}).Where("miner = ? and list_type = ?", miner, MINER_LIST_BLOCKED).Limit(1).Find(&blocked)
+	if blocked.ID != 0 {
+		return fmt.Errorf("miner %s is blocked: %s", miner, blocked.Reason)
+	}
+
+	var allowed, allowedCount int64
+	tx.Model(&MinerListEntry{}).Where("list_type = ?", MINER_LIST_ALLOWED).Count(&allowedCount)
+	if allowedCount == 0 {
+		return nil
+	}
+	tx.Model(&MinerListEntry{}).Where("miner = ? and list_type = ?", miner, MINER_LIST_ALLOWED).Count(&allowed)
+	if allowed == 0 {
+		return fmt.Errorf("miner %s is not on the allowlist", miner)
+	}
+	return nil
+}
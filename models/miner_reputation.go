@@ -0,0 +1,21 @@
+package db_models
+
+import (
+	"time"
+)
+
+// MinerReputation is the track record of a storage provider computed from the outcome of its content deals.
+// Durations are in seconds.
+type MinerReputation struct {
+	ID                   int64     `gorm:"primaryKey"`
+	Miner                string    `json:"miner" gorm:"uniqueIndex"`
+	TotalDeals           int64     `json:"total_deals"`
+	SucceededDeals       int64     `json:"succeeded_deals"`
+	FailedDeals          int64     `json:"failed_deals"`
+	SuccessRate          float64   `json:"success_rate"`
+	MedianTimeToTransfer int64     `json:"median_time_to_transfer"`
+	MedianTimeToChain    int64     `json:"median_time_to_chain"`
+	Score                float64   `json:"score"`
+	CreatedAt            time.Time `json:"created_at"`
+	UpdatedAt            time.Time `json:"updated_at"`
+}
@@ -0,0 +1,17 @@
+package db_models
+
+import (
+	"time"
+)
+
+// ReplicationGroup links the copies of a content made by a deal request with replication. The source content and
+// every replica point to the group with their ReplicationGroup field.
+type ReplicationGroup struct {
+	ID            int64     `gorm:"primaryKey"`
+	SourceContent int64     `json:"source_content" gorm:"index:,option:CONCURRENTLY"`
+	Copies        int       `json:"copies"` // the source content and its replicas
+	Diversity     string    `json:"diversity,omitempty"`
+	TenantID      int64     `json:"tenant_id" gorm:"index:,option:CONCURRENTLY"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
@@ -0,0 +1,36 @@
+package db_models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StatusTransition is a status change of a content, a content deal or a piece commitment.
+type StatusTransition struct {
+	ID         int64     `gorm:"primaryKey"`
+	ObjectType string    `json:"object_type" gorm:"index:idx_status_transition_object"`
+	ObjectID   int64     `json:"object_id" gorm:"index:idx_status_transition_object"`
+	OldStatus  string    `json:"old_status"`
+	NewStatus  string    `json:"new_status"`
+	Message    string    `json:"message"`
+	Source     string    `json:"source"` // the job or the component that changed the status
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RecordStatusTransition appends a status change to the transitions of the object, nothing is recorded if the status
+// didn't change.
+func RecordStatusTransition(tx *gorm.DB, objectType string, objectId int64, oldStatus string, newStatus string, message string, source string) error {
+	if oldStatus == newStatus {
+		return nil
+	}
+	return tx.Create(&StatusTransition{
+		ObjectType: objectType,
+		ObjectID:   objectId,
+		OldStatus:  oldStatus,
+		NewStatus:  newStatus,
+		Message:    message,
+		Source:     source,
+		CreatedAt:  time.Now(),
+	}).Error
+}
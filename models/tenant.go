@@ -0,0 +1,37 @@
+package db_models
+
+import (
+	"time"
+)
+
+// Tenant is an account of the node. It owns the contents, the wallets, the batch imports and the replication groups
+// made with its API keys, so a key can be rotated without orphaning them.
+type Tenant struct {
+	ID         int64  `gorm:"primaryKey"`
+	UuId       string `json:"uuid" gorm:"index:,option:CONCURRENTLY"`
+	Name       string `json:"name"`
+	ExternalId string `json:"external_id" gorm:"uniqueIndex"` // the identity of the tenant in the auth backend
+
+	// the limits of the tenant, the ones of the config if zero
+	MaxConcurrentTransfers int64 `json:"max_concurrent_transfers"`
+	MaxBytesPerDay         int64 `json:"max_bytes_per_day"`
+	MaxDealsPerHour        int64 `json:"max_deals_per_hour"`
+	MaxReplication         int64 `json:"max_replication"`
+
+	// the usage of the deal requests being made, see core.ReserveTenantQuota
+	QuotaReservedDeals int64 `json:"-"`
+	QuotaReservedBytes int64 `json:"-"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TenantSubject maps another identity of the auth backend onto an existing tenant, e.g. the rotated token of an
+// estuary-auth account, so the tenant keeps its contents and wallets.
+type TenantSubject struct {
+	ID        int64     `gorm:"primaryKey"`
+	Subject   string    `json:"subject" gorm:"uniqueIndex"`
+	TenantID  int64     `json:"tenant_id" gorm:"index:,option:CONCURRENTLY"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
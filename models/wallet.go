@@ -9,7 +9,7 @@ type Wallet struct {
 	ID         int64     `gorm:"primaryKey"`
 	UuId       string    `json:"uuid"`
 	Addr       string    `json:"addr"`
-	Owner      string    `json:"owner"`
+	TenantID   int64     `json:"tenant_id" gorm:"index:,option:CONCURRENTLY"` // zero for the wallet of the node
 	KeyType    string    `json:"key_type"`
 	PrivateKey string    `json:"private_key"`
 	CreatedAt  time.Time `json:"created_at"`
@@ -30,11 +30,73 @@ const (
 	DEAL_STATUS_TRANSFER_STARTED  = "transfer-started"
 	DEAL_STATUS_TRANSFER_FINISHED = "transfer-finished"
 	DEAL_STATUS_TRANSFER_FAILED   = "transfer-failed"
+	DEAL_STATUS_PROPOSED          = "proposed"
+	DEAL_STATUS_FAILED            = "failed"
+	DEAL_STATUS_FAULTY            = "faulty"
+
+	CONTENT_CANCELLED = "cancelled"
+
+	CONTENT_DEAL_ACTIVE  = "active"
+	CONTENT_DEAL_SLASHED = "slashed"
+
+	DEAL_STATE_PUBLISHED  = "published"
+	DEAL_STATE_ACTIVE     = "active"
+	DEAL_STATE_SLASHED    = "slashed"
+	DEAL_STATE_TERMINATED = "terminated"
+
+	MINER_SELECTION_REMOTE_API           = "remote-api"
+	MINER_SELECTION_STATIC_ALLOWLIST     = "static-allowlist"
+	MINER_SELECTION_WEIGHTED_ROUND_ROBIN = "weighted-round-robin"
+	MINER_SELECTION_LOWEST_PRICE         = "lowest-price"
+
+	AUTH_BACKEND_LOCAL  = "local"  // the api keys issued by the node and DELTA_AUTH
+	AUTH_BACKEND_REMOTE = "remote" // the estuary-auth service
+	AUTH_BACKEND_JWT    = "jwt"    // the json web tokens signed by a key of the jwks file
+
+	REPLICATION_DIVERSITY_OWNER   = "owner"   // every copy on a provider with a different owner address
+	REPLICATION_DIVERSITY_NETWORK = "network" // every copy on a provider in a different ip network
+
+	DEAL_ERROR_TRANSIENT_NETWORK  = "transient-network"
+	DEAL_ERROR_PRICE_REJECTED     = "sp-rejected-price"
+	DEAL_ERROR_DEAL_TYPE_REJECTED = "sp-rejected-deal-type"
+	DEAL_ERROR_PIECE_TOO_SMALL    = "piece-too-small"
+	DEAL_ERROR_INSUFFICIENT_FUNDS = "insufficient-funds"
+	DEAL_ERROR_UNKNOWN            = "unknown"
 
 	BATCH_IMPORT_STATUS_COMPLETED = "completed"
 	BATCH_IMPORT_STATUS_FAILED    = "failed"
 	BATCH_IMPORT_STATUS_STARTED   = "started"
 
+	JOB_STATUS_QUEUED    = "queued"
+	JOB_STATUS_RUNNING   = "running"
+	JOB_STATUS_COMPLETED = "completed"
+	JOB_STATUS_FAILED    = "failed"
+	JOB_STATUS_CANCELLED = "cancelled"
+
+	JOB_QUEUE_COMMP        = "commp"
+	JOB_QUEUE_DEAL_MAKING  = "deal-making"
+	JOB_QUEUE_STATUS_CHECK = "status-check"
+	JOB_QUEUE_CLEANUP      = "cleanup"
+
+	STATUS_OBJECT_CONTENT          = "content"
+	STATUS_OBJECT_CONTENT_DEAL     = "content-deal"
+	STATUS_OBJECT_PIECE_COMMITMENT = "piece-commitment"
+
+	STATUS_SOURCE_API               = "api"
+	STATUS_SOURCE_PIECE_COMMP       = "piece-commp"
+	STATUS_SOURCE_DEAL_MAKER        = "storage-deal-maker"
+	STATUS_SOURCE_DATA_TRANSFER     = "data-transfer"
+	STATUS_SOURCE_DEAL_STATUS_CHECK = "deal-status-check"
+	STATUS_SOURCE_DEAL_TRACKER      = "deal-activation-tracker"
+	STATUS_SOURCE_DEAL_PUBLISH      = "deal-publish-resolver"
+	STATUS_SOURCE_DEAL_FAULT        = "deal-fault-monitor"
+	STATUS_SOURCE_RETRY             = "retry"
+	STATUS_SOURCE_CLEAN_UP          = "clean-up"
+	STATUS_SOURCE_STATUS_LOGGER     = "status-logger"
+
+	JOB_TYPE_PIECE_COMMP        = "piece-commp"
+	JOB_TYPE_STORAGE_DEAL_MAKER = "storage-deal-maker"
+
 	COMMP_STATUS_OPEN     = "open"
 	COMMP_STATUS_COMITTED = "committed"
 
@@ -13,7 +13,7 @@ func DateToHeight(time time.Time) int64 {
 }
 
 func HeightToDate(height int64) time.Time {
-	return time.Unix(HeightToUnix(height)*1000, 0)
+	return time.Unix(HeightToUnix(height), 0)
 }
 
 func DateToUnixEpoch(dateStr string, timeStr string) int64 {